@@ -0,0 +1,324 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// options are the options of the generation.
+type options struct {
+	// Dir is the directory of the package declaring the types.
+	Dir string
+	// Types are the names of the struct types to convert.
+	Types []string
+	// Output is the name of the generated file, ignored when parsing the
+	// package.
+	Output string
+}
+
+// generator writes the conversion functions of the struct types of a package.
+type generator struct {
+	pkg   string
+	types map[string]ast.Expr
+	buf   bytes.Buffer
+	// queue holds the struct types whose function is to be written, done
+	// the ones queued already
+	queue   []string
+	done    map[string]bool
+	base64  bool
+	scratch int
+}
+
+// generate returns the source of the conversion functions of the types.
+func generate(opts options) ([]byte, error) {
+	if len(opts.Types) == 0 {
+		return nil, errors.New("no types to convert")
+	}
+	g, err := parsePackage(opts.Dir, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range opts.Types {
+		if _, ok := g.types[name].(*ast.StructType); !ok {
+			return nil, fmt.Errorf("%s is not a struct type of package %s", name, g.pkg)
+		}
+		g.enqueue(name)
+	}
+	for len(g.queue) > 0 {
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+		if err := g.function(name); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by shipper-genconv; DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.pkg)
+	if g.base64 {
+		fmt.Fprintln(&out, `"encoding/base64"`)
+		fmt.Fprintln(&out)
+	}
+	fmt.Fprintln(&out, `"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"`)
+	fmt.Fprintln(&out, `"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"`)
+	fmt.Fprintln(&out, ")")
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %w", err)
+	}
+	return src, nil
+}
+
+// parsePackage parses the type declarations of the package in dir, except
+// the ones of the output file.
+func parsePackage(dir, output string) (*generator, error) {
+	p, err := build.Default.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the package: %w", err)
+	}
+	g := &generator{pkg: p.Name, types: map[string]ast.Expr{}, done: map[string]bool{}}
+	fset := token.NewFileSet()
+	for _, name := range p.GoFiles {
+		if name == output {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(p.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				g.types[ts.Name.Name] = ts.Type
+			}
+		}
+	}
+	return g, nil
+}
+
+func (g *generator) enqueue(name string) {
+	if !g.done[name] {
+		g.done[name] = true
+		g.queue = append(g.queue, name)
+	}
+}
+
+// functionName is the name of the conversion function of the struct type.
+func functionName(typeName string) string {
+	return typeName + "Struct"
+}
+
+// function writes the conversion function of the struct type.
+func (g *generator) function(name string) error {
+	st := g.types[name].(*ast.StructType)
+	fmt.Fprintf(&g.buf, "\n// %s returns the Struct of the %s, like helpers.NewValue.\n", functionName(name), name)
+	fmt.Fprintf(&g.buf, "func %s(x *%s) *messages.Struct {\n", functionName(name), name)
+	fmt.Fprintf(&g.buf, "if x == nil {\nreturn nil\n}\n")
+	if err := g.structBody(st, "x", "data", name); err != nil {
+		return err
+	}
+	fmt.Fprintf(&g.buf, "return &messages.Struct{Data: data}\n}\n")
+	return nil
+}
+
+// structBody writes the statements declaring the map data holding the
+// fields of src.
+func (g *generator) structBody(st *ast.StructType, src, data, context string) error {
+	type field struct {
+		key, name string
+		typ       ast.Expr
+	}
+	var fields []field
+	for _, f := range st.Fields.List {
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		if len(names) == 0 {
+			// embedded, named after its type like in NewValue
+			switch t := f.Type.(type) {
+			case *ast.Ident:
+				names = append(names, t.Name)
+			case *ast.StarExpr:
+				if id, ok := t.X.(*ast.Ident); ok {
+					names = append(names, id.Name)
+				}
+			}
+			if len(names) == 0 {
+				return fmt.Errorf("%s: unsupported embedded field", context)
+			}
+		}
+		tag := ""
+		if f.Tag != nil {
+			unquoted, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return fmt.Errorf("%s: invalid tag %s", context, f.Tag.Value)
+			}
+			tag = reflect.StructTag(unquoted).Get("json")
+		}
+		tagName := strings.Split(tag, ",")[0]
+		for _, name := range names {
+			if !ast.IsExported(name) || tagName == "-" {
+				continue
+			}
+			key := name
+			if tagName != "" {
+				key = tagName
+			}
+			fields = append(fields, field{key: key, name: name, typ: f.Type})
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "%s := make(map[string]*messages.Value, %d)\n", data, len(fields))
+	for _, f := range fields {
+		dst := fmt.Sprintf("%s[%q]", data, f.key)
+		if err := g.assign(dst, f.typ, src+"."+f.name, context+"."+f.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// basicConversions are the constructors of the values of the predeclared
+// types, and the type their argument is converted to.
+var basicConversions = map[string][2]string{
+	"string":  {"NewStringValue", "string"},
+	"bool":    {"NewBoolValue", "bool"},
+	"int":     {"NewInt64Value", "int64"},
+	"int8":    {"NewInt32Value", "int32"},
+	"int16":   {"NewInt32Value", "int32"},
+	"int32":   {"NewInt32Value", "int32"},
+	"rune":    {"NewInt32Value", "int32"},
+	"int64":   {"NewInt64Value", "int64"},
+	"uint":    {"NewUint64Value", "uint64"},
+	"uint8":   {"NewUint32Value", "uint32"},
+	"byte":    {"NewUint32Value", "uint32"},
+	"uint16":  {"NewUint32Value", "uint32"},
+	"uint32":  {"NewUint32Value", "uint32"},
+	"uint64":  {"NewUint64Value", "uint64"},
+	"uintptr": {"NewUint64Value", "uint64"},
+	"float32": {"NewFloat32Value", "float32"},
+	"float64": {"NewFloat64Value", "float64"},
+}
+
+// assign writes the statements setting dst to the Value of src, of type t.
+func (g *generator) assign(dst string, t ast.Expr, src, context string) error {
+	switch t := t.(type) {
+	case *ast.Ident:
+		if c, ok := basicConversions[t.Name]; ok {
+			if c[1] != t.Name {
+				src = c[1] + "(" + src + ")"
+			}
+			fmt.Fprintf(&g.buf, "%s = helpers.%s(%s)\n", dst, c[0], src)
+			return nil
+		}
+		underlying, ok := g.types[t.Name]
+		if !ok {
+			return fmt.Errorf("%s: unsupported type %s", context, t.Name)
+		}
+		if _, ok := underlying.(*ast.StructType); ok {
+			g.enqueue(t.Name)
+			fmt.Fprintf(&g.buf, "%s = helpers.NewStructValue(%s(&%s))\n", dst, functionName(t.Name), src)
+			return nil
+		}
+		if id, ok := underlying.(*ast.Ident); ok {
+			if c, ok := basicConversions[id.Name]; ok {
+				// a named basic type needs the conversion
+				fmt.Fprintf(&g.buf, "%s = helpers.%s(%s(%s))\n", dst, c[0], c[1], src)
+				return nil
+			}
+		}
+		return g.assign(dst, underlying, src, context)
+
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok && pkg.Name == "time" && t.Sel.Name == "Time" {
+			fmt.Fprintf(&g.buf, "%s = helpers.NewTimestampValue(%s)\n", dst, src)
+			return nil
+		}
+		return fmt.Errorf("%s: unsupported type from another package", context)
+
+	case *ast.StarExpr:
+		fmt.Fprintf(&g.buf, "if %s == nil {\n%s = helpers.NewNullValue()\n} else {\n", src, dst)
+		elem := "*" + src
+		if id, ok := t.X.(*ast.Ident); ok {
+			if _, ok := g.types[id.Name].(*ast.StructType); ok {
+				// the pointer is passed as it is
+				g.enqueue(id.Name)
+				fmt.Fprintf(&g.buf, "%s = helpers.NewStructValue(%s(%s))\n}\n", dst, functionName(id.Name), src)
+				return nil
+			}
+		}
+		if err := g.assign(dst, t.X, elem, context); err != nil {
+			return err
+		}
+		fmt.Fprintln(&g.buf, "}")
+		return nil
+
+	case *ast.ArrayType:
+		if id, ok := t.Elt.(*ast.Ident); ok && t.Len == nil && (id.Name == "byte" || id.Name == "uint8") {
+			// base64 encoded like in NewValue
+			g.base64 = true
+			fmt.Fprintf(&g.buf, "%s = helpers.NewStringValue(base64.StdEncoding.EncodeToString(%s))\n", dst, src)
+			return nil
+		}
+		n := g.next()
+		list, i := "l"+n, "i"+n
+		fmt.Fprintf(&g.buf, "{\n%s := make([]*messages.Value, len(%s))\n", list, src)
+		fmt.Fprintf(&g.buf, "for %s := range %s {\n", i, src)
+		if err := g.assign(list+"["+i+"]", t.Elt, src+"["+i+"]", context+"[]"); err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.buf, "}\n%s = helpers.NewListValue(&messages.ListValue{Values: %s})\n}\n", dst, list)
+		return nil
+
+	case *ast.MapType:
+		if key, ok := t.Key.(*ast.Ident); !ok || key.Name != "string" {
+			return fmt.Errorf("%s: the keys of the maps must be strings", context)
+		}
+		n := g.next()
+		data, k, v := "d"+n, "k"+n, "v"+n
+		fmt.Fprintf(&g.buf, "{\n%s := make(map[string]*messages.Value, len(%s))\n", data, src)
+		fmt.Fprintf(&g.buf, "for %s, %s := range %s {\n", k, v, src)
+		if err := g.assign(data+"["+k+"]", t.Value, v, context+"[]"); err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.buf, "}\n%s = helpers.NewStructValue(&messages.Struct{Data: %s})\n}\n", dst, data)
+		return nil
+
+	case *ast.StructType:
+		data := "d" + g.next()
+		fmt.Fprintln(&g.buf, "{")
+		if err := g.structBody(t, src, data, context); err != nil {
+			return err
+		}
+		fmt.Fprintf(&g.buf, "%s = helpers.NewStructValue(&messages.Struct{Data: %s})\n}\n", dst, data)
+		return nil
+
+	default:
+		return fmt.Errorf("%s: unsupported type", context)
+	}
+}
+
+// next returns a suffix for the names of the variables of a nested block.
+func (g *generator) next() string {
+	g.scratch++
+	return strconv.Itoa(g.scratch)
+}
@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateExample(t *testing.T) {
+	// the checked-in file is up to date
+	want, err := os.ReadFile(filepath.Join("internal", "example", "host_shipper.go"))
+	require.NoError(t, err)
+	got, err := generate(options{Dir: filepath.Join("internal", "example"), Types: []string{"Host"}, Output: "host_shipper.go"})
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := map[string]struct {
+		src   string
+		types []string
+		err   string
+	}{
+		"no types": {
+			src: "type T struct{}",
+			err: "no types to convert",
+		},
+		"unknown type": {
+			src:   "type T struct{}",
+			types: []string{"U"},
+			err:   "U is not a struct type of package p",
+		},
+		"not a struct": {
+			src:   "type T string",
+			types: []string{"T"},
+			err:   "T is not a struct type of package p",
+		},
+		"interface": {
+			src:   "type T struct{ A interface{} }",
+			types: []string{"T"},
+			err:   "T.A: unsupported type",
+		},
+		"map key": {
+			src:   "type T struct{ A map[int]string }",
+			types: []string{"T"},
+			err:   "T.A: the keys of the maps must be strings",
+		},
+		"other package": {
+			src:   "import \"net\"\n\ntype T struct{ A net.IP }",
+			types: []string{"T"},
+			err:   "T.A: unsupported type from another package",
+		},
+		"nested": {
+			src:   "type T struct{ A []U }\n\ntype U struct{ B chan int }",
+			types: []string{"T"},
+			err:   "U.B: unsupported type",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "p.go"), []byte("package p\n\n"+tc.src+"\n"), 0o600))
+			_, err := generate(options{Dir: dir, Types: tc.types, Output: "t_shipper.go"})
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Code generated by shipper-genconv; DO NOT EDIT.
+
+package example
+
+import (
+	"encoding/base64"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// HostStruct returns the Struct of the Host, like helpers.NewValue.
+func HostStruct(x *Host) *messages.Struct {
+	if x == nil {
+		return nil
+	}
+	data := make(map[string]*messages.Value, 19)
+	data["name"] = helpers.NewStringValue(x.Name)
+	{
+		l1 := make([]*messages.Value, len(x.IP))
+		for i1 := range x.IP {
+			l1[i1] = helpers.NewStringValue(x.IP[i1])
+		}
+		data["IP"] = helpers.NewListValue(&messages.ListValue{Values: l1})
+	}
+	data["port"] = helpers.NewInt64Value(int64(x.Port))
+	data["Small"] = helpers.NewInt32Value(int32(x.Small))
+	data["Uptime"] = helpers.NewInt64Value(x.Uptime)
+	data["PID"] = helpers.NewUint32Value(x.PID)
+	data["Load"] = helpers.NewFloat64Value(x.Load)
+	data["Ratio"] = helpers.NewFloat32Value(x.Ratio)
+	data["Up"] = helpers.NewBoolValue(x.Up)
+	data["Level"] = helpers.NewStringValue(string(x.Level))
+	data["Started"] = helpers.NewTimestampValue(x.Started)
+	data["Token"] = helpers.NewStringValue(base64.StdEncoding.EncodeToString(x.Token))
+	{
+		d2 := make(map[string]*messages.Value, len(x.Labels))
+		for k2, v2 := range x.Labels {
+			d2[k2] = helpers.NewStringValue(v2)
+		}
+		data["Labels"] = helpers.NewStructValue(&messages.Struct{Data: d2})
+	}
+	if x.Parent == nil {
+		data["Parent"] = helpers.NewNullValue()
+	} else {
+		data["Parent"] = helpers.NewStructValue(HostStruct(x.Parent))
+	}
+	data["OS"] = helpers.NewStructValue(OSStruct(&x.OS))
+	{
+		l3 := make([]*messages.Value, len(x.Children))
+		for i3 := range x.Children {
+			l3[i3] = helpers.NewStructValue(HostStruct(&x.Children[i3]))
+		}
+		data["Children"] = helpers.NewListValue(&messages.ListValue{Values: l3})
+	}
+	{
+		d4 := make(map[string]*messages.Value, len(x.Limits))
+		for k4, v4 := range x.Limits {
+			if v4 == nil {
+				d4[k4] = helpers.NewNullValue()
+			} else {
+				d4[k4] = helpers.NewInt64Value(int64(*v4))
+			}
+		}
+		data["Limits"] = helpers.NewStructValue(&messages.Struct{Data: d4})
+	}
+	{
+		l5 := make([]*messages.Value, len(x.Checksum))
+		for i5 := range x.Checksum {
+			l5[i5] = helpers.NewUint64Value(x.Checksum[i5])
+		}
+		data["Checksum"] = helpers.NewListValue(&messages.ListValue{Values: l5})
+	}
+	{
+		d6 := make(map[string]*messages.Value, 1)
+		d6["region"] = helpers.NewStringValue(x.Location.Region)
+		data["location"] = helpers.NewStructValue(&messages.Struct{Data: d6})
+	}
+	return &messages.Struct{Data: data}
+}
+
+// OSStruct returns the Struct of the OS, like helpers.NewValue.
+func OSStruct(x *OS) *messages.Struct {
+	if x == nil {
+		return nil
+	}
+	data := make(map[string]*messages.Value, 1)
+	data["family"] = helpers.NewStringValue(x.Family)
+	return &messages.Struct{Data: data}
+}
@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package example
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+)
+
+func TestHostStruct(t *testing.T) {
+	started := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	limit := 10
+	h := &Host{
+		Name:     "h",
+		Hostname: "skipped",
+		IP:       []string{"10.0.0.1"},
+		Port:     9200,
+		Small:    -1,
+		Uptime:   5,
+		PID:      42,
+		Load:     0.5,
+		Ratio:    0.25,
+		Up:       true,
+		Level:    "info",
+		Started:  started,
+		Token:    []byte{1, 2, 3},
+		Labels:   map[string]string{"env": "prod"},
+		OS:       OS{Family: "linux"},
+		Limits:   map[string]*int{"files": &limit, "procs": nil},
+		Checksum: [2]uint64{1, 2},
+		private:  "skipped",
+	}
+	h.Location.Region = "eu"
+	h.Children = []Host{{Name: "child", Parent: &Host{Name: "parent"}}}
+
+	child := map[string]interface{}{
+		"name": "child", "IP": []interface{}{}, "port": int64(0), "Small": int32(0), "Uptime": int64(0),
+		"PID": uint32(0), "Load": 0.0, "Ratio": float32(0), "Up": false, "Level": "",
+		"Started": time.Time{}, "Token": "", "Labels": map[string]interface{}{},
+		"OS": map[string]interface{}{"family": ""}, "Children": []interface{}{},
+		"Limits": map[string]interface{}{}, "Checksum": []interface{}{uint64(0), uint64(0)},
+		"location": map[string]interface{}{"region": ""},
+	}
+	want := map[string]interface{}{
+		"name":     "h",
+		"IP":       []interface{}{"10.0.0.1"},
+		"port":     int64(9200),
+		"Small":    int32(-1),
+		"Uptime":   int64(5),
+		"PID":      uint32(42),
+		"Load":     0.5,
+		"Ratio":    float32(0.25),
+		"Up":       true,
+		"Level":    "info",
+		"Started":  started,
+		"Token":    "AQID",
+		"Labels":   map[string]interface{}{"env": "prod"},
+		"Parent":   nil,
+		"OS":       map[string]interface{}{"family": "linux"},
+		"Children": []interface{}{child},
+		"Limits":   map[string]interface{}{"files": int64(10), "procs": nil},
+		"Checksum": []interface{}{uint64(1), uint64(2)},
+		"location": map[string]interface{}{"region": "eu"},
+	}
+	s := HostStruct(h)
+	got := helpers.AsMap(s)
+	parent := got["Children"].([]interface{})[0].(map[string]interface{})["Parent"]
+	delete(got["Children"].([]interface{})[0].(map[string]interface{}), "Parent")
+	require.Equal(t, want, got)
+	require.Equal(t, "parent", parent.(map[string]interface{})["name"])
+	require.Nil(t, HostStruct(nil))
+
+	// the same as NewValue for the types it supports
+	os := &OS{Family: "linux"}
+	v, err := helpers.NewValue(*os)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(v.GetStructValue(), OSStruct(os)))
+}
+
+func BenchmarkOSStruct(b *testing.B) {
+	os := OS{Family: "linux"}
+	b.Run("generated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = OSStruct(&os)
+		}
+	})
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := helpers.NewValue(os); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
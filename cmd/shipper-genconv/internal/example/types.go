@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package example holds the types converted by the generated functions of
+// the shipper-genconv tests.
+package example
+
+import "time"
+
+//go:generate go run ../.. -type Host
+
+// Level is a named basic type.
+type Level string
+
+// Host covers every kind of field supported by the generator.
+type Host struct {
+	Name     string `json:"name"`
+	Hostname string `json:"-"`
+	IP       []string
+	Port     int `json:"port,omitempty"`
+	Small    int8
+	Uptime   int64
+	PID      uint32
+	Load     float64
+	Ratio    float32
+	Up       bool
+	Level    Level
+	Started  time.Time
+	Token    []byte
+	Labels   map[string]string
+	Parent   *Host
+	OS       OS
+	Children []Host
+	Limits   map[string]*int
+	Checksum [2]uint64
+	Location struct {
+		Region string `json:"region"`
+	} `json:"location"`
+	private string
+}
+
+// OS is a struct type referred to by Host.
+type OS struct {
+	Family string `json:"family"`
+}
@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Command shipper-genconv generates the functions converting Go struct types
+// to Structs without reflection, for the integrations with fixed schemas
+// that want to keep helpers.NewValue out of their hot paths. It's meant to
+// be run by go generate in the package declaring the types:
+//
+//	//go:generate go run github.com/elastic/elastic-agent-shipper-client/cmd/shipper-genconv -type Host,Process
+//
+// For every type T, and every struct type of the package T refers to, the
+// generated TStruct(x *T) *messages.Struct returns the Struct of x, nil when
+// x is nil. The keys and the values are the ones of helpers.NewValue: the
+// keys are the names of the exported fields or of their json tags, the
+// fields tagged "-" are skipped and the byte slices are base64 strings.
+// The nil pointers are null values, and the named types of the package are
+// converted like their underlying type.
+//
+// The field types are the predeclared types, time.Time, the types of the
+// package, and the pointers, slices, arrays and maps with string keys of
+// those. Any other type is an error. The strings aren't checked to be valid
+// UTF-8, invalid ones make the marshaling of the events fail.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		opts  options
+		types string
+	)
+	flag.StringVar(&types, "type", "", "comma-separated names of the struct types to convert, required")
+	flag.StringVar(&opts.Dir, "dir", ".", "directory of the package declaring the types")
+	flag.StringVar(&opts.Output, "output", "", "name of the generated file in the directory, <first type>_shipper.go by default")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -type T[,T...] [flags]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if types == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.Types = strings.Split(types, ",")
+	if opts.Output == "" {
+		opts.Output = strings.ToLower(opts.Types[0]) + "_shipper.go"
+	}
+
+	src, err := generate(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(opts.Dir, opts.Output), src, 0o644); err != nil { //nolint:gosec // generated source files are readable
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// benchConfig configures a run.
+type benchConfig struct {
+	// Batch is the number of events per request.
+	Batch int
+	// Concurrency is the number of concurrent publishers.
+	Concurrency int
+	// Rate is the maximum number of events per second, zero means no limit.
+	Rate float64
+	// Duration stops the run after the given time, zero means no limit.
+	Duration time.Duration
+	// Events stops the run after the given number of events, zero means no limit.
+	Events uint64
+	// LagInterval is how often the persisted index is polled.
+	LagInterval time.Duration
+}
+
+// run publishes events from src until the duration or the number of events
+// is reached, or ctx is done.
+func run(ctx context.Context, config benchConfig, producer proto.ProducerClient, src source) report {
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+	var opts []client.Option
+	if config.Rate > 0 {
+		opts = append(opts, client.WithRateLimit(client.NewRateLimiter(client.RateLimitConfig{EventsPerSecond: config.Rate}, nil)))
+	}
+	c := client.New(producer, opts...)
+
+	var s stats
+	var accepted acceptedIndex
+	lagCtx, stopLag := context.WithCancel(ctx)
+	lagDone := make(chan struct{})
+	go func() {
+		defer close(lagDone)
+		watchLag(lagCtx, producer, config.LagInterval, &accepted, &s)
+	}()
+
+	remaining := int64(config.Events)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				n := config.Batch
+				if config.Events > 0 {
+					left := atomic.AddInt64(&remaining, -int64(n))
+					if left < 0 {
+						n += int(left)
+					}
+					if n <= 0 {
+						return
+					}
+				}
+				req := &messages.PublishRequest{Events: src.next(n)}
+				size := gproto.Size(req)
+				begin := time.Now()
+				reply, err := c.Publish(ctx, req)
+				var partial *client.PartialError
+				if err != nil && !errors.Is(err, client.ErrShipperRestarted) && !errors.As(err, &partial) {
+					if ctx.Err() == nil {
+						s.failed()
+					}
+					continue
+				}
+				s.published(int(reply.GetAcceptedCount()), size, time.Since(begin))
+				accepted.update(reply)
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	stopLag()
+	<-lagDone
+	return s.report(elapsed)
+}
+
+// acceptedIndex is the highest accepted index of the current shipper.
+type acceptedIndex struct {
+	mu    sync.Mutex
+	uuid  string
+	index uint64
+}
+
+func (a *acceptedIndex) update(reply *messages.PublishReply) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if reply.GetUuid() != a.uuid {
+		a.uuid, a.index = reply.GetUuid(), 0
+	}
+	if reply.GetAcceptedIndex() > a.index {
+		a.index = reply.GetAcceptedIndex()
+	}
+}
+
+// lag returns how many accepted events are not persisted yet.
+func (a *acceptedIndex) lag(persisted *messages.PersistedIndexReply) (uint64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if persisted.GetUuid() != a.uuid {
+		return 0, false
+	}
+	if persisted.GetPersistedIndex() >= a.index {
+		return 0, true
+	}
+	return a.index - persisted.GetPersistedIndex(), true
+}
+
+// watchLag follows the persisted index of the shipper until ctx is done.
+func watchLag(ctx context.Context, producer proto.ProducerClient, interval time.Duration, accepted *acceptedIndex, s *stats) {
+	for ctx.Err() == nil {
+		stream, err := producer.PersistedIndex(ctx, &messages.PersistedIndexRequest{PollingInterval: durationpb.New(interval)})
+		if err != nil {
+			return
+		}
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				break
+			}
+			if lag, ok := accepted.lag(reply); ok {
+				s.persisted(lag)
+			}
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	shippertest "github.com/elastic/elastic-agent-shipper-client/pkg/testing"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
+)
+
+func TestRun(t *testing.T) {
+	m := shippertest.StartMockShipper(shippertest.MockConfig{PersistInterval: 5 * time.Millisecond})
+	defer m.Stop()
+	conn, err := m.Dial()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	config := benchConfig{Batch: 10, Concurrency: 3, Events: 95, LagInterval: time.Millisecond}
+	r := run(context.Background(), config, proto.NewProducerClient(conn), newGeneratorSource(generator.DefaultConfig()))
+	require.Equal(t, uint64(95), r.Events)
+	require.Equal(t, 10, r.Batches)
+	require.Zero(t, r.Errors)
+	require.NotZero(t, r.Bytes)
+	require.True(t, r.P50 <= r.P99 && r.P99 <= r.Max)
+	require.Len(t, m.Events(), 95)
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("{\"message\":\"a\"}\n\n{\"message\":\"b\"}\n"), 0o600))
+
+	src, err := newFileSource([]string{path})
+	require.NoError(t, err)
+	events := src.next(3)
+	require.Equal(t, "a", events[0].GetFields().GetData()["message"].GetStringValue())
+	require.Equal(t, "b", events[1].GetFields().GetData()["message"].GetStringValue())
+	require.Equal(t, "a", events[2].GetFields().GetData()["message"].GetStringValue())
+
+	require.NoError(t, os.WriteFile(path, []byte("{\"message\":\n"), 0o600))
+	_, err = newFileSource([]string{path})
+	require.ErrorContains(t, err, "line 1")
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+	require.Equal(t, time.Duration(50), percentile(sorted, 50))
+	require.Equal(t, time.Duration(99), percentile(sorted, 99))
+	require.Equal(t, time.Duration(1), percentile(sorted[:1], 99))
+	require.Zero(t, percentile(nil, 50))
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Command shipperbench load-tests a shipper: it publishes the documents of
+// NDJSON files, or synthetic events, and reports the throughput, the latency
+// of the publish calls and how far the persisted index lags behind.
+//
+//	shipperbench -endpoint unix:///path/to/shipper.sock -concurrency 4 -rate 10000 events.ndjson
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
+)
+
+func main() {
+	var (
+		endpoint = flag.String("endpoint", "localhost:50051", "shipper endpoint: host:port, unix:///path or npipe:///name")
+		config   benchConfig
+		gen      = generator.DefaultConfig()
+	)
+	flag.IntVar(&config.Batch, "batch", 100, "number of events per request")
+	flag.IntVar(&config.Concurrency, "concurrency", 1, "number of concurrent publishers")
+	flag.Float64Var(&config.Rate, "rate", 0, "maximum events per second, 0 for no limit")
+	flag.DurationVar(&config.Duration, "duration", 10*time.Second, "duration of the run, 0 for no limit")
+	flag.Uint64Var(&config.Events, "events", 0, "number of events to publish, 0 for no limit")
+	flag.DurationVar(&config.LagInterval, "lag-interval", 100*time.Millisecond, "how often the persisted index is polled")
+	flag.IntVar(&gen.Fields, "fields", gen.Fields, "number of fields per object of the synthetic events")
+	flag.IntVar(&gen.Depth, "depth", gen.Depth, "nesting depth of the synthetic events")
+	flag.IntVar(&gen.StringSize, "string-size", gen.StringSize, "size of the strings of the synthetic events")
+	flag.IntVar(&gen.Cardinality, "cardinality", gen.Cardinality, "distinct values per string field of the synthetic events, 0 for unique values")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file.ndjson...]\n\nWithout files, synthetic events are published.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if config.Batch <= 0 || config.Concurrency <= 0 {
+		fmt.Fprintln(os.Stderr, "batch and concurrency must be positive")
+		os.Exit(2)
+	}
+	if config.Duration == 0 && config.Events == 0 {
+		fmt.Fprintln(os.Stderr, "warning: no duration or number of events, stop with Ctrl-C")
+	}
+
+	var src source = newGeneratorSource(gen)
+	if flag.NArg() > 0 {
+		files, err := newFileSource(flag.Args())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		src = files
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	conn, err := client.Dial(ctx, *endpoint)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	run(ctx, config, proto.NewProducerClient(conn), src).print(os.Stdout)
+}
@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/ndjson"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
+)
+
+// source provides the events to publish, it's safe for concurrent use.
+type source interface {
+	// next returns the next n events.
+	next(n int) []*messages.Event
+}
+
+// fileSource cycles over the documents read from NDJSON files.
+type fileSource struct {
+	mu     sync.Mutex
+	events []*messages.Event
+	pos    int
+}
+
+func newFileSource(paths []string) (*fileSource, error) {
+	s := &fileSource{}
+	for _, path := range paths {
+		if err := s.load(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(s.events) == 0 {
+		return nil, errors.New("no events found in the input files")
+	}
+	return s, nil
+}
+
+func (s *fileSource) load(path string) error {
+	return ndjson.ReadFile(path, template, func(event *messages.Event) error {
+		s.events = append(s.events, event)
+		return nil
+	})
+}
+
+func (s *fileSource) next(n int) []*messages.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = s.events[s.pos]
+		s.pos = (s.pos + 1) % len(s.events)
+	}
+	return events
+}
+
+// template holds the fields of the events read from files.
+var template = &messages.Event{
+	Source:     &messages.Source{InputId: "shipperbench"},
+	DataStream: &messages.DataStream{Type: "logs", Dataset: "shipperbench", Namespace: "default"},
+}
+
+// generatorSource produces synthetic events.
+type generatorSource struct {
+	mu        sync.Mutex
+	generator *generator.Generator
+}
+
+func newGeneratorSource(config generator.Config) *generatorSource {
+	return &generatorSource{generator: generator.New(config)}
+}
+
+func (s *generatorSource) next(n int) []*messages.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generator.Events(n)
+}
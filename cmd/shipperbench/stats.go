@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// stats collects the results of the publish calls.
+type stats struct {
+	mu        sync.Mutex
+	batches   int
+	events    uint64
+	bytes     uint64
+	errors    int
+	latencies []time.Duration
+	maxLag    uint64
+	lag       uint64
+}
+
+func (s *stats) published(events, bytes int, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches++
+	s.events += uint64(events)
+	s.bytes += uint64(bytes)
+	s.latencies = append(s.latencies, latency)
+}
+
+func (s *stats) failed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors++
+}
+
+// persisted records the number of accepted events not persisted yet.
+func (s *stats) persisted(lag uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lag = lag
+	if lag > s.maxLag {
+		s.maxLag = lag
+	}
+}
+
+// report is the summary of a run.
+type report struct {
+	Duration     time.Duration
+	Batches      int
+	Events       uint64
+	Bytes        uint64
+	Errors       int
+	P50          time.Duration
+	P90          time.Duration
+	P99          time.Duration
+	Max          time.Duration
+	MaxLag       uint64
+	RemainingLag uint64
+}
+
+func (s *stats) report(elapsed time.Duration) report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	r := report{
+		Duration:     elapsed,
+		Batches:      s.batches,
+		Events:       s.events,
+		Bytes:        s.bytes,
+		Errors:       s.errors,
+		P50:          percentile(s.latencies, 50),
+		P90:          percentile(s.latencies, 90),
+		P99:          percentile(s.latencies, 99),
+		MaxLag:       s.maxLag,
+		RemainingLag: s.lag,
+	}
+	if len(s.latencies) > 0 {
+		r.Max = s.latencies[len(s.latencies)-1]
+	}
+	return r
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+func (r report) print(w io.Writer) {
+	seconds := r.Duration.Seconds()
+	fmt.Fprintf(w, "duration:       %s\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "batches:        %d (%d errors)\n", r.Batches, r.Errors)
+	fmt.Fprintf(w, "events:         %d (%.0f/s)\n", r.Events, float64(r.Events)/seconds)
+	fmt.Fprintf(w, "bytes:          %d (%.2f MB/s)\n", r.Bytes, float64(r.Bytes)/seconds/1e6)
+	fmt.Fprintf(w, "latency:        p50=%s p90=%s p99=%s max=%s\n", r.P50, r.P90, r.P99, r.Max)
+	fmt.Fprintf(w, "persisted lag:  max=%d remaining=%d events\n", r.MaxLag, r.RemainingLag)
+}
@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/ndjson"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func publish(ctx context.Context, producer proto.ProducerClient, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("publish", flag.ContinueOnError)
+	batch := flags.Int("batch", 100, "number of events per request")
+	inputID := flags.String("input-id", "shipperctl", "input id of the events")
+	dataset := flags.String("dataset", "generic", "data stream dataset of the events")
+	namespace := flags.String("namespace", "default", "data stream namespace of the events")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no NDJSON file given")
+	}
+	if *batch <= 0 {
+		return errors.New("the batch size must be positive")
+	}
+	template := &messages.Event{
+		Source:     &messages.Source{InputId: *inputID},
+		DataStream: &messages.DataStream{Type: "logs", Dataset: *dataset, Namespace: *namespace},
+	}
+
+	c := client.New(producer)
+	var (
+		events []*messages.Event
+		total  uint32
+		last   *messages.PublishReply
+	)
+	flush := func() error {
+		if len(events) == 0 {
+			return nil
+		}
+		reply, err := c.Publish(ctx, &messages.PublishRequest{Uuid: last.GetUuid(), Events: events})
+		if err != nil && !errors.Is(err, client.ErrShipperRestarted) {
+			return fmt.Errorf("failed to publish: %w", err)
+		}
+		total += reply.GetAcceptedCount()
+		last = reply
+		events = events[:0]
+		return nil
+	}
+	for _, path := range flags.Args() {
+		err := ndjson.ReadFile(path, template, func(event *messages.Event) error {
+			events = append(events, event)
+			if len(events) < *batch {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "published %d events\nuuid:           %s\naccepted index: %d\n", total, last.GetUuid(), last.GetAcceptedIndex())
+	return nil
+}
+
+func persistedIndex(ctx context.Context, producer proto.ProducerClient, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("persisted-index", flag.ContinueOnError)
+	follow := flags.Bool("follow", false, "keep printing the persisted index when it changes")
+	interval := flags.Duration("interval", time.Second, "polling interval with -follow")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	req := &messages.PersistedIndexRequest{}
+	if *follow {
+		req.PollingInterval = durationpb.New(*interval)
+	}
+
+	stream, err := producer.PersistedIndex(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to get the persisted index: %w", err)
+	}
+	for {
+		reply, err := stream.Recv()
+		if errors.Is(err, io.EOF) || (err != nil && *follow && ctx.Err() != nil) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get the persisted index: %w", err)
+		}
+		fmt.Fprintf(out, "%s %d\n", reply.GetUuid(), reply.GetPersistedIndex())
+		if !*follow {
+			return nil
+		}
+	}
+}
+
+// shipperInfo is what the info command prints.
+type shipperInfo struct {
+	UUID           string `json:"uuid"`
+	PersistedIndex uint64 `json:"persisted_index"`
+	Latency        string `json:"latency"`
+}
+
+func info(ctx context.Context, producer proto.ProducerClient, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("info", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the information as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	stream, err := producer.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to query the shipper: %w", err)
+	}
+	reply, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to query the shipper: %w", err)
+	}
+	i := shipperInfo{
+		UUID:           reply.GetUuid(),
+		PersistedIndex: reply.GetPersistedIndex(),
+		Latency:        time.Since(start).Round(time.Microsecond).String(),
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(i)
+	}
+	fmt.Fprintf(out, "uuid:            %s\npersisted index: %d\nlatency:         %s\n", i.UUID, i.PersistedIndex, i.Latency)
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	shippertest "github.com/elastic/elastic-agent-shipper-client/pkg/testing"
+)
+
+func startShipper(t *testing.T) (*shippertest.MockShipper, proto.ProducerClient) {
+	t.Helper()
+	m := shippertest.StartMockShipper(shippertest.MockConfig{})
+	t.Cleanup(m.Stop)
+	conn, err := m.Dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return m, proto.NewProducerClient(conn)
+}
+
+func TestPublish(t *testing.T) {
+	m, producer := startShipper(t)
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("{\"message\":\"hello\"}\n", 5)), 0o600))
+
+	var out bytes.Buffer
+	err := publish(context.Background(), producer, []string{"-batch", "2", "-dataset", "test", path}, &out)
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "published 5 events")
+	require.Contains(t, out.String(), "accepted index: 5")
+	require.Len(t, m.Events(), 5)
+	require.Equal(t, "test", m.Events()[0].GetDataStream().GetDataset())
+
+	require.Error(t, publish(context.Background(), producer, nil, &out))
+}
+
+func TestPersistedIndex(t *testing.T) {
+	m, producer := startShipper(t)
+	var out bytes.Buffer
+	require.NoError(t, persistedIndex(context.Background(), producer, nil, &out))
+	require.Equal(t, fmt.Sprintf("%s 0\n", m.UUID()), out.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	out.Reset()
+	require.NoError(t, persistedIndex(ctx, producer, []string{"-follow", "-interval", "1ms"}, &out))
+	require.True(t, strings.HasPrefix(out.String(), m.UUID()))
+}
+
+func TestInfo(t *testing.T) {
+	m, producer := startShipper(t)
+	var out bytes.Buffer
+	require.NoError(t, info(context.Background(), producer, []string{"-json"}, &out))
+	var i shipperInfo
+	require.NoError(t, json.Unmarshal(out.Bytes(), &i))
+	require.Equal(t, m.UUID(), i.UUID)
+	require.Zero(t, i.PersistedIndex)
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Command shipperctl talks to a shipper from the command line:
+//
+//	shipperctl [-endpoint addr] publish [-batch n] file.ndjson...
+//	shipperctl [-endpoint addr] persisted-index [-follow] [-interval d]
+//	shipperctl [-endpoint addr] info [-json]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+)
+
+// command is a subcommand of shipperctl.
+type command struct {
+	usage string
+	run   func(ctx context.Context, producer proto.ProducerClient, args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"publish":         {usage: "publish the documents of NDJSON files", run: publish},
+	"persisted-index": {usage: "print the persisted index of the shipper", run: persistedIndex},
+	"info":            {usage: "print the uuid and the state of the shipper", run: info},
+}
+
+func main() {
+	endpoint := flag.String("endpoint", "localhost:50051", "shipper endpoint: host:port, unix:///path or npipe:///name")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of the command, 0 for no timeout")
+	flag.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "Usage: %s [flags] <command> [command flags] [args]\n\nCommands:\n", os.Args[0])
+		for _, name := range []string{"publish", "persisted-index", "info"} {
+			fmt.Fprintf(w, "  %-16s %s\n", name, commands[name].usage)
+		}
+		fmt.Fprintln(w, "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if *timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	conn, err := client.Dial(ctx, *endpoint)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	if err := cmd.run(ctx, proto.NewProducerClient(conn), flag.Args()[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", flag.Arg(0), err)
+		conn.Close()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"go.elastic.co/fastjson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+type options struct {
+	// Summary only prints the sizes.
+	Summary bool
+	// Top is the number of largest events and fields in the summary.
+	Top int
+}
+
+// fieldSize is the size of a top-level field of an event on the wire.
+type fieldSize struct {
+	Event int
+	Name  string
+	Size  int
+}
+
+// inspect decodes the serialized request and writes it to w.
+func inspect(data []byte, w io.Writer, opts options) error {
+	var req messages.PublishRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return fmt.Errorf("failed to decode the request: %w", err)
+	}
+	if opts.Summary {
+		return summary(&req, len(data), w, opts.Top)
+	}
+
+	var out fastjson.Writer
+	out.RawString(`{"uuid":`)
+	out.String(req.GetUuid())
+	out.RawString(`,"size":`)
+	out.Int64(int64(len(data)))
+	out.RawString(`,"events":[`)
+	for i, event := range req.GetEvents() {
+		if i > 0 {
+			out.RawByte(',')
+		}
+		if err := writeEvent(&out, i, event); err != nil {
+			return fmt.Errorf("failed to encode event %d: %w", i, err)
+		}
+	}
+	out.RawString("]}")
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, out.Bytes(), "", "  "); err != nil {
+		return fmt.Errorf("failed to format the request: %w", err)
+	}
+	pretty.WriteByte('\n')
+	_, err := pretty.WriteTo(w)
+	return err
+}
+
+func writeEvent(out *fastjson.Writer, i int, event *messages.Event) error {
+	out.RawString(`{"index":`)
+	out.Int64(int64(i))
+	out.RawString(`,"size":`)
+	out.Int64(int64(proto.Size(event)))
+	if event.GetTimestamp() != nil {
+		out.RawString(`,"timestamp":"`)
+		out.Time(event.GetTimestamp().AsTime(), time.RFC3339Nano)
+		out.RawByte('"')
+	}
+	out.RawString(`,"source":{"input_id":`)
+	out.String(event.GetSource().GetInputId())
+	out.RawString(`,"stream_id":`)
+	out.String(event.GetSource().GetStreamId())
+	out.RawString(`},"data_stream":{"type":`)
+	out.String(event.GetDataStream().GetType())
+	out.RawString(`,"dataset":`)
+	out.String(event.GetDataStream().GetDataset())
+	out.RawString(`,"namespace":`)
+	out.String(event.GetDataStream().GetNamespace())
+	out.RawString(`},"metadata":`)
+	if err := event.GetMetadata().MarshalFastJSON(out); err != nil {
+		return err
+	}
+	out.RawString(`,"fields":`)
+	if err := event.GetFields().MarshalFastJSON(out); err != nil {
+		return err
+	}
+	out.RawString(`,"field_sizes":{`)
+	for j, f := range fieldSizes(i, event) {
+		if j > 0 {
+			out.RawByte(',')
+		}
+		out.String(f.Name)
+		out.RawByte(':')
+		out.Int64(int64(f.Size))
+	}
+	out.RawString("}}")
+	return nil
+}
+
+// fieldSizes returns the sizes of the top-level fields of the event, largest first.
+func fieldSizes(i int, event *messages.Event) []fieldSize {
+	var sizes []fieldSize
+	for name, value := range event.GetFields().GetData() {
+		entry := &messages.Struct{Data: map[string]*messages.Value{name: value}}
+		sizes = append(sizes, fieldSize{Event: i, Name: name, Size: proto.Size(entry)})
+	}
+	sort.Slice(sizes, func(a, b int) bool {
+		if sizes[a].Size != sizes[b].Size {
+			return sizes[a].Size > sizes[b].Size
+		}
+		return sizes[a].Name < sizes[b].Name
+	})
+	return sizes
+}
+
+func summary(req *messages.PublishRequest, size int, w io.Writer, top int) error {
+	type eventSize struct {
+		index, size int
+	}
+	var (
+		events []eventSize
+		fields []fieldSize
+	)
+	for i, event := range req.GetEvents() {
+		events = append(events, eventSize{index: i, size: proto.Size(event)})
+		fields = append(fields, fieldSizes(i, event)...)
+	}
+	sort.SliceStable(events, func(a, b int) bool { return events[a].size > events[b].size })
+	sort.SliceStable(fields, func(a, b int) bool { return fields[a].Size > fields[b].Size })
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "uuid:   %s\nsize:   %d bytes\nevents: %d\n", req.GetUuid(), size, len(events))
+	fmt.Fprintln(&buf, "\nlargest events:")
+	for i := 0; i < len(events) && i < top; i++ {
+		fmt.Fprintf(&buf, "  #%-6d %10d bytes\n", events[i].index, events[i].size)
+	}
+	fmt.Fprintln(&buf, "\nlargest fields:")
+	for i := 0; i < len(fields) && i < top; i++ {
+		fmt.Fprintf(&buf, "  #%-6d %-30s %10d bytes\n", fields[i].Event, fields[i].Name, fields[i].Size)
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestInspect(t *testing.T) {
+	events := testutil.NewEvents(0, 2)
+	events[1].Fields.Data["large"] = helpers.NewStringValue(strings.Repeat("x", 1000))
+	data, err := proto.Marshal(&messages.PublishRequest{Uuid: "uuid", Events: events})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, inspect(data, &out, options{}))
+	var doc struct {
+		UUID   string `json:"uuid"`
+		Size   int    `json:"size"`
+		Events []struct {
+			Size       int                    `json:"size"`
+			Fields     map[string]interface{} `json:"fields"`
+			FieldSizes map[string]int         `json:"field_sizes"`
+		} `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &doc))
+	require.Equal(t, "uuid", doc.UUID)
+	require.Equal(t, len(data), doc.Size)
+	require.Len(t, doc.Events, 2)
+	require.Equal(t, proto.Size(events[1]), doc.Events[1].Size)
+	require.Equal(t, events[0].GetFields().GetData()["message"].GetStringValue(), doc.Events[0].Fields["message"])
+	require.Greater(t, doc.Events[1].FieldSizes["large"], 1000)
+
+	out.Reset()
+	require.NoError(t, inspect(data, &out, options{Summary: true, Top: 1}))
+	require.Contains(t, out.String(), "events: 2")
+	require.Regexp(t, `largest fields:\n  #1 +large `, out.String())
+
+	require.Error(t, inspect([]byte("not a request"), &out, options{}))
+}
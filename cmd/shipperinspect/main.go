@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Command shipperinspect decodes a serialized PublishRequest, read from a
+// file or stdin, and prints it as JSON with the size of every event and of
+// every field, to find what makes a request too large.
+//
+//	shipperinspect [-summary] [-top n] [request.bin]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	var opts options
+	flag.BoolVar(&opts.Summary, "summary", false, "only print the sizes, not the events")
+	flag.IntVar(&opts.Top, "top", 10, "number of largest events and fields listed in the summary")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [file]\n\nThe request is read from stdin without a file.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	in := io.Reader(os.Stdin)
+	if flag.NArg() > 0 {
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
+	}
+	data, err := io.ReadAll(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read the request: %v\n", err)
+		os.Exit(1)
+	}
+	if err := inspect(data, os.Stdout, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
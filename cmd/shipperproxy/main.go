@@ -0,0 +1,105 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Command shipperproxy sits between an input and a shipper and forwards all
+// the calls. It can log and record the traffic, add latency and drop a
+// fraction of the batches, to reproduce delivery issues.
+//
+//	shipperproxy -listen unix:///tmp/proxy.sock -upstream unix:///path/to/shipper.sock -drop 0.1
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	shippertest "github.com/elastic/elastic-agent-shipper-client/pkg/testing"
+)
+
+func main() {
+	var config proxyConfig
+	listenAddr := flag.String("listen", "localhost:50052", "endpoint to listen on: host:port or unix:///path")
+	upstreamAddr := flag.String("upstream", "localhost:50051", "shipper endpoint: host:port, unix:///path or npipe:///name")
+	verbose := flag.Bool("log", false, "log every PublishEvents call")
+	record := flag.String("record", "", "record the PublishEvents calls to this file, to replay them later")
+	flag.DurationVar(&config.Latency, "latency", 0, "latency added to every PublishEvents call")
+	flag.DurationVar(&config.Jitter, "jitter", 0, "maximum random latency added on top of -latency")
+	flag.Float64Var(&config.DropRate, "drop", 0, "fraction of PublishEvents calls failing with Unavailable, between 0 and 1")
+	flag.Int64Var(&config.Seed, "seed", 1, "seed of the random drops and jitter")
+	flag.Parse()
+
+	if err := run(*listenAddr, *upstreamAddr, *verbose, *record, config); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(listenAddr, upstreamAddr string, verbose bool, record string, config proxyConfig) error {
+	if config.DropRate < 0 || config.DropRate > 1 {
+		return errors.New("the drop rate must be between 0 and 1")
+	}
+	if err := logp.DevelopmentSetup(); err != nil {
+		return fmt.Errorf("failed to set up logging: %w", err)
+	}
+	log := logp.NewLogger("shipperproxy")
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	conn, err := client.Dial(ctx, upstreamAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var interceptors []grpc.UnaryServerInterceptor
+	if verbose {
+		interceptors = append(interceptors, logCalls(log))
+	}
+	if record != "" {
+		f, err := os.Create(record)
+		if err != nil {
+			return fmt.Errorf("failed to create the recording: %w", err)
+		}
+		defer f.Close()
+		interceptors = append(interceptors, shippertest.NewRecorder(f).UnaryServerInterceptor())
+	}
+
+	lis, err := listen(listenAddr)
+	if err != nil {
+		return err
+	}
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	proto.RegisterProducerServer(srv, newProxy(proto.NewProducerClient(conn), config))
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+	log.Infow("proxy started", "listen", listenAddr, "upstream", upstreamAddr)
+	return srv.Serve(lis)
+}
+
+// listen listens on a host:port or unix:///path endpoint.
+func listen(endpoint string) (net.Listener, error) {
+	network, addr := "tcp", endpoint
+	if strings.HasPrefix(endpoint, "unix://") {
+		network, addr = "unix", strings.TrimPrefix(endpoint, "unix://")
+		// a socket left by a previous run
+		_ = os.Remove(addr)
+	}
+	lis, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", endpoint, err)
+	}
+	return lis, nil
+}
@@ -0,0 +1,142 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// proxyConfig configures the faults injected by the proxy.
+type proxyConfig struct {
+	// Latency is added to every PublishEvents call.
+	Latency time.Duration
+	// Jitter is the maximum random latency added on top of Latency.
+	Jitter time.Duration
+	// DropRate is the fraction of PublishEvents calls failing with
+	// Unavailable instead of being forwarded, between 0 and 1.
+	DropRate float64
+	// Seed makes the drops and the jitter reproducible.
+	Seed int64
+}
+
+// proxy forwards the calls it receives to the upstream shipper.
+type proxy struct {
+	proto.UnimplementedProducerServer
+
+	upstream proto.ProducerClient
+	config   proxyConfig
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newProxy(upstream proto.ProducerClient, config proxyConfig) *proxy {
+	return &proxy{
+		upstream: upstream,
+		config:   config,
+		rand:     rand.New(rand.NewSource(config.Seed)), //nolint:gosec // not used for security
+	}
+}
+
+// fault returns the latency to add to a call and whether to drop it.
+func (p *proxy) fault() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	latency := p.config.Latency
+	if p.config.Jitter > 0 {
+		latency += time.Duration(p.rand.Int63n(int64(p.config.Jitter)))
+	}
+	return latency, p.rand.Float64() < p.config.DropRate
+}
+
+// PublishEvents implements proto.ProducerServer.
+func (p *proxy) PublishEvents(ctx context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	latency, drop := p.fault()
+	if latency > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-time.After(latency):
+		}
+	}
+	if drop {
+		return nil, status.Error(codes.Unavailable, "batch dropped by shipperproxy")
+	}
+	return p.upstream.PublishEvents(ctx, req)
+}
+
+// PersistedIndex implements proto.ProducerServer.
+func (p *proxy) PersistedIndex(req *messages.PersistedIndexRequest, srv proto.Producer_PersistedIndexServer) error {
+	stream, err := p.upstream.PersistedIndex(srv.Context(), req)
+	if err != nil {
+		return err
+	}
+	for {
+		reply, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := srv.Send(reply); err != nil {
+			return err
+		}
+	}
+}
+
+// Flush implements proto.ProducerServer.
+func (p *proxy) Flush(ctx context.Context, req *messages.FlushRequest) (*messages.FlushReply, error) {
+	return p.upstream.Flush(ctx, req)
+}
+
+// WhoAmI implements proto.ProducerServer.
+func (p *proxy) WhoAmI(ctx context.Context, req *messages.WhoAmIRequest) (*messages.WhoAmIReply, error) {
+	return p.upstream.WhoAmI(ctx, req)
+}
+
+// GetCursor implements proto.ProducerServer.
+func (p *proxy) GetCursor(ctx context.Context, req *messages.GetCursorRequest) (*messages.GetCursorReply, error) {
+	return p.upstream.GetCursor(ctx, req)
+}
+
+// GetGlobalMetadata implements proto.ProducerServer.
+func (p *proxy) GetGlobalMetadata(ctx context.Context, req *messages.GetGlobalMetadataRequest) (*messages.GetGlobalMetadataReply, error) {
+	return p.upstream.GetGlobalMetadata(ctx, req)
+}
+
+// logCalls logs every PublishEvents call going through the proxy.
+func logCalls(log *logp.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		request, _ := req.(*messages.PublishRequest)
+		fields := []interface{}{
+			"method", info.FullMethod,
+			"events", len(request.GetEvents()),
+			"duration", time.Since(start),
+		}
+		if err != nil {
+			log.Warnw("call failed", append(fields, "error", err)...)
+			return resp, err
+		}
+		reply, _ := resp.(*messages.PublishReply)
+		log.Infow("call", append(fields, "uuid", reply.GetUuid(), "accepted", reply.GetAcceptedCount(), "accepted_index", reply.GetAcceptedIndex())...)
+		return resp, err
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	shippertest "github.com/elastic/elastic-agent-shipper-client/pkg/testing"
+)
+
+// startProxy starts a proxy in front of a mock shipper.
+func startProxy(t *testing.T, config proxyConfig) (*shippertest.MockShipper, proto.ProducerClient) {
+	t.Helper()
+	m := shippertest.StartMockShipper(shippertest.MockConfig{})
+	t.Cleanup(m.Stop)
+	upstream, err := m.Dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { upstream.Close() })
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	proto.RegisterProducerServer(srv, newProxy(proto.NewProducerClient(upstream), config))
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return m, proto.NewProducerClient(conn)
+}
+
+func TestProxyForwards(t *testing.T) {
+	m, producer := startProxy(t, proxyConfig{})
+	ctx := context.Background()
+
+	reply, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testutil.NewEvents(0, 3)})
+	require.NoError(t, err)
+	require.Equal(t, m.UUID(), reply.GetUuid())
+	require.Len(t, m.Events(), 3)
+
+	stream, err := producer.PersistedIndex(ctx, &messages.PersistedIndexRequest{PollingInterval: durationpb.New(time.Millisecond)})
+	require.NoError(t, err)
+	persisted, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), persisted.GetPersistedIndex())
+}
+
+func TestProxyFaults(t *testing.T) {
+	m, producer := startProxy(t, proxyConfig{Latency: 20 * time.Millisecond, DropRate: 0.5, Seed: 1})
+
+	var dropped int
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		_, err := producer.PublishEvents(context.Background(), &messages.PublishRequest{Events: testutil.NewEvents(0, 1)})
+		if err != nil {
+			require.Equal(t, codes.Unavailable, status.Code(err))
+			dropped++
+		}
+	}
+	require.GreaterOrEqual(t, time.Since(start), 20*20*time.Millisecond)
+	require.True(t, dropped > 0 && dropped < 20, "dropped %d", dropped)
+	require.Len(t, m.Events(), 20-dropped)
+}
@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package ndjson reads events from NDJSON documents for the commands.
+package ndjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+const (
+	// maxLineSize is the maximum size of a document.
+	maxLineSize = 16 * 1024 * 1024
+	// internedStrings is the number of strings shared by the documents of a reader.
+	internedStrings = 4096
+)
+
+// Event converts a JSON document into an event, the document is stored in
+// the fields and the other fields of the event come from the template.
+func Event(doc []byte, template *messages.Event) (*messages.Event, error) {
+	return event(doc, template, nil)
+}
+
+// event is Event interning the strings of the document with in.
+func event(doc []byte, template *messages.Event, in *helpers.Interner) (*messages.Event, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(doc, &m); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	fields, err := in.NewStruct(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert the document: %w", err)
+	}
+	return &messages.Event{
+		Timestamp:  timestamppb.New(time.Now()),
+		Source:     template.GetSource(),
+		DataStream: template.GetDataStream(),
+		Metadata:   template.GetMetadata(),
+		Fields:     fields,
+	}, nil
+}
+
+// Read calls fn with the event of every document read from r, empty lines
+// are skipped. The keys and the short values repeated in the documents share
+// their storage. The errors mention the line of the invalid document.
+func Read(r io.Reader, template *messages.Event, fn func(*messages.Event) error) error {
+	in := helpers.NewInterner(internedStrings)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		e, err := event(scanner.Bytes(), template, in)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the documents: %w", err)
+	}
+	return nil
+}
+
+// ReadFile is Read for the file at path.
+func ReadFile(path string, template *messages.Event, fn func(*messages.Event) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	if err := Read(f, template, fn); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
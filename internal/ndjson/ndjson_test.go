@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package ndjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestRead(t *testing.T) {
+	template := &messages.Event{Source: &messages.Source{InputId: "test"}}
+	var events []*messages.Event
+	err := Read(strings.NewReader("{\"message\":\"a\"}\n\n{\"message\":\"b\",\"n\":1}\n"), template, func(e *messages.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "b", events[1].GetFields().GetData()["message"].GetStringValue())
+	require.Equal(t, "test", events[1].GetSource().GetInputId())
+	require.NotNil(t, events[0].GetTimestamp())
+
+	err = Read(strings.NewReader("{}\n{\"message\":\n"), nil, func(*messages.Event) error { return nil })
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 2")
+}
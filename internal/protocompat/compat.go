@@ -0,0 +1,204 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package protocompat finds the changes between two versions of the API
+// that break the compatibility on the wire.
+package protocompat
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Change is a breaking change.
+type Change struct {
+	// Element is the full name of the changed element.
+	Element string
+	// Description describes the change.
+	Description string
+}
+
+func (c Change) String() string {
+	return c.Element + ": " + c.Description
+}
+
+// Load decodes a serialized FileDescriptorSet.
+func Load(data []byte) (*protoregistry.Files, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode the descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+// Compare returns the wire breaking changes from the old to the new files,
+// sorted by element. Renames are not breaking on the wire, only the
+// messages, enums and services themselves are looked up by name.
+func Compare(old, new *protoregistry.Files) []Change {
+	c := &comparer{new: new}
+	old.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		c.messages(fd.Messages())
+		c.enums(fd.Enums())
+		for i := 0; i < fd.Services().Len(); i++ {
+			c.service(fd.Services().Get(i))
+		}
+		return true
+	})
+	sort.SliceStable(c.changes, func(i, j int) bool { return c.changes[i].Element < c.changes[j].Element })
+	return c.changes
+}
+
+type comparer struct {
+	new     *protoregistry.Files
+	changes []Change
+}
+
+func (c *comparer) add(element protoreflect.FullName, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{Element: string(element), Description: fmt.Sprintf(format, args...)})
+}
+
+func (c *comparer) find(name protoreflect.FullName) protoreflect.Descriptor {
+	d, err := c.new.FindDescriptorByName(name)
+	if err != nil {
+		return nil
+	}
+	return d
+}
+
+func (c *comparer) messages(messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		old := messages.Get(i)
+		if old.IsMapEntry() {
+			continue
+		}
+		new, ok := c.find(old.FullName()).(protoreflect.MessageDescriptor)
+		if !ok {
+			c.add(old.FullName(), "message removed")
+			continue
+		}
+		c.fields(old, new)
+		c.messages(old.Messages())
+		c.enums(old.Enums())
+	}
+}
+
+func (c *comparer) fields(old, new protoreflect.MessageDescriptor) {
+	for i := 0; i < old.Fields().Len(); i++ {
+		of := old.Fields().Get(i)
+		nf := new.Fields().ByNumber(of.Number())
+		if nf == nil {
+			if !new.ReservedRanges().Has(of.Number()) {
+				c.add(of.FullName(), "field %d removed without being reserved", of.Number())
+			}
+			continue
+		}
+		if wireType(of) != wireType(nf) {
+			c.add(of.FullName(), "field %d changed from %s to %s", of.Number(), typeName(of), typeName(nf))
+		} else if (of.Kind() == protoreflect.MessageKind || of.Kind() == protoreflect.GroupKind) && !of.IsMap() {
+			if of.Message().FullName() != nf.Message().FullName() {
+				c.add(of.FullName(), "field %d changed from %s to %s", of.Number(), typeName(of), typeName(nf))
+			}
+		}
+		if of.IsList() != nf.IsList() || of.IsMap() != nf.IsMap() {
+			c.add(of.FullName(), "field %d changed from %s to %s", of.Number(), cardinality(of), cardinality(nf))
+		} else if of.IsMap() && (wireType(of.MapKey()) != wireType(nf.MapKey()) || wireType(of.MapValue()) != wireType(nf.MapValue())) {
+			c.add(of.FullName(), "field %d changed from map<%s, %s> to map<%s, %s>", of.Number(),
+				typeName(of.MapKey()), typeName(of.MapValue()), typeName(nf.MapKey()), typeName(nf.MapValue()))
+		}
+		if of.ContainingOneof() == nil && nf.ContainingOneof() != nil && !nf.ContainingOneof().IsSynthetic() {
+			c.add(of.FullName(), "field %d moved into oneof %s", of.Number(), nf.ContainingOneof().Name())
+		}
+	}
+}
+
+func (c *comparer) enums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		old := enums.Get(i)
+		new, ok := c.find(old.FullName()).(protoreflect.EnumDescriptor)
+		if !ok {
+			c.add(old.FullName(), "enum removed")
+			continue
+		}
+		for j := 0; j < old.Values().Len(); j++ {
+			v := old.Values().Get(j)
+			if new.Values().ByNumber(v.Number()) == nil && !new.ReservedRanges().Has(v.Number()) {
+				c.add(v.FullName(), "enum value %d removed without being reserved", v.Number())
+			}
+		}
+	}
+}
+
+func (c *comparer) service(old protoreflect.ServiceDescriptor) {
+	new, ok := c.find(old.FullName()).(protoreflect.ServiceDescriptor)
+	if !ok {
+		c.add(old.FullName(), "service removed")
+		return
+	}
+	for i := 0; i < old.Methods().Len(); i++ {
+		om := old.Methods().Get(i)
+		nm := new.Methods().ByName(om.Name())
+		switch {
+		case nm == nil:
+			c.add(om.FullName(), "method removed")
+		case om.Input().FullName() != nm.Input().FullName():
+			c.add(om.FullName(), "request changed from %s to %s", om.Input().FullName(), nm.Input().FullName())
+		case om.Output().FullName() != nm.Output().FullName():
+			c.add(om.FullName(), "response changed from %s to %s", om.Output().FullName(), nm.Output().FullName())
+		case om.IsStreamingClient() != nm.IsStreamingClient() || om.IsStreamingServer() != nm.IsStreamingServer():
+			c.add(om.FullName(), "streaming changed")
+		}
+	}
+}
+
+// wireType groups the kinds that are encoded the same way.
+func wireType(f protoreflect.FieldDescriptor) string {
+	switch f.Kind() {
+	case protoreflect.BoolKind, protoreflect.EnumKind, protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind:
+		return "varint"
+	case protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return "zigzag"
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return "fixed32"
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return "fixed64"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "bytes"
+	case protoreflect.MessageKind:
+		return "message"
+	case protoreflect.GroupKind:
+		return "group"
+	}
+	return f.Kind().String()
+}
+
+func typeName(f protoreflect.FieldDescriptor) string {
+	if f.Message() != nil {
+		return string(f.Message().FullName())
+	}
+	if f.Enum() != nil {
+		return string(f.Enum().FullName())
+	}
+	return f.Kind().String()
+}
+
+func cardinality(f protoreflect.FieldDescriptor) string {
+	switch {
+	case f.IsMap():
+		return "map"
+	case f.IsList():
+		return "repeated"
+	}
+	return "singular"
+}
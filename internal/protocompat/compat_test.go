@@ -0,0 +1,108 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package protocompat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+)
+
+// apiSet returns the descriptor set of the API, to be modified by the tests.
+func apiSet(t *testing.T) *descriptorpb.FileDescriptorSet {
+	t.Helper()
+	var set descriptorpb.FileDescriptorSet
+	require.NoError(t, gproto.Unmarshal(proto.DescriptorSet(), &set))
+	return &set
+}
+
+func file(set *descriptorpb.FileDescriptorSet, name string) *descriptorpb.FileDescriptorProto {
+	for _, f := range set.File {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func message(f *descriptorpb.FileDescriptorProto, name string) *descriptorpb.DescriptorProto {
+	for _, m := range f.MessageType {
+		if m.GetName() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func compare(t *testing.T, old, new *descriptorpb.FileDescriptorSet) []string {
+	t.Helper()
+	oldFiles, err := protodesc.NewFiles(old)
+	require.NoError(t, err)
+	newFiles, err := protodesc.NewFiles(new)
+	require.NoError(t, err)
+	var changes []string
+	for _, c := range Compare(oldFiles, newFiles) {
+		changes = append(changes, c.String())
+	}
+	return changes
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	files, err := Load(proto.DescriptorSet())
+	require.NoError(t, err)
+	require.Empty(t, Compare(files, files))
+}
+
+func TestCompareFields(t *testing.T) {
+	old := apiSet(t)
+	new := apiSet(t)
+	event := message(file(new, "messages/publish.proto"), "Event")
+	// remove metadata, change the type of the timestamp
+	event.Field = append(event.Field[:3], event.Field[4:]...)
+	event.Field[0].TypeName = gproto.String(".elastic.agent.shipper.v1.messages.Source")
+	request := message(file(new, "messages/publish.proto"), "PublishRequest")
+	request.Field[0].Type = descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum()
+	request.Field[1].Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+
+	require.Equal(t, []string{
+		"elastic.agent.shipper.v1.messages.Event.metadata: field 4 removed without being reserved",
+		"elastic.agent.shipper.v1.messages.Event.timestamp: field 1 changed from google.protobuf.Timestamp to elastic.agent.shipper.v1.messages.Source",
+		"elastic.agent.shipper.v1.messages.PublishRequest.events: field 2 changed from repeated to singular",
+	}, compare(t, old, new))
+
+	// reserving the number of a removed field is fine, string and bytes are compatible
+	event.ReservedRange = append(event.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{Start: gproto.Int32(4), End: gproto.Int32(5)})
+	require.Len(t, compare(t, old, new), 2)
+}
+
+func TestCompareServices(t *testing.T) {
+	old := apiSet(t)
+	new := apiSet(t)
+	service := file(new, "shipper.proto").Service[0]
+	service.Method[0].OutputType = gproto.String(".elastic.agent.shipper.v1.messages.PublishRequest")
+	service.Method[1].ServerStreaming = gproto.Bool(false)
+	require.Equal(t, []string{
+		"elastic.agent.shipper.v1.Producer.PersistedIndex: streaming changed",
+		"elastic.agent.shipper.v1.Producer.PublishEvents: response changed from elastic.agent.shipper.v1.messages.PublishReply to elastic.agent.shipper.v1.messages.PublishRequest",
+	}, compare(t, old, new))
+
+	service.Method = service.Method[:1]
+	require.Contains(t, compare(t, old, new), "elastic.agent.shipper.v1.Producer.PersistedIndex: method removed")
+}
+
+func TestCompareEnums(t *testing.T) {
+	old := apiSet(t)
+	new := apiSet(t)
+	enum := file(new, "messages/struct.proto").EnumType[0]
+	enum.Value = append(enum.Value, &descriptorpb.EnumValueDescriptorProto{Name: gproto.String("OTHER"), Number: gproto.Int32(1)})
+	// adding values is fine
+	require.Empty(t, compare(t, old, new))
+	require.Equal(t, []string{"elastic.agent.shipper.v1.messages.OTHER: enum value 1 removed without being reserved"}, compare(t, new, old))
+}
@@ -0,0 +1,22 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package unsafeconv converts between []byte and string on the hot paths of
+// the JSON marshaling and parsing. By default the conversions copy, like the
+// Go conversions. With the shipperunsafe build tag they share the memory of
+// their argument instead, for the deployments squeezing CPU on busy hosts.
+//
+// Ownership: the result of String and Bytes may share the memory of their
+// argument, so
+//   - the bytes given to String must not be modified while the string is in
+//     use, and the string must not outlive the bytes' owner reusing them,
+//   - the bytes returned by Bytes must never be modified, they may be the
+//     read-only memory of a string constant.
+//
+// The callers only use them where the result is read and dropped before
+// the argument changes, e.g. passed to a hash or to json.Unmarshal.
+package unsafeconv
+
+// ZeroCopy is true when the conversions share the memory of their argument.
+const ZeroCopy = zeroCopy
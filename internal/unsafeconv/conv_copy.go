@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !shipperunsafe
+// +build !shipperunsafe
+
+package unsafeconv
+
+const zeroCopy = false
+
+// String returns a copy of b as a string.
+func String(b []byte) string {
+	return string(b)
+}
+
+// Bytes returns a copy of s as a byte slice.
+func Bytes(s string) []byte {
+	return []byte(s)
+}
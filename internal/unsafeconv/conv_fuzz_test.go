@@ -0,0 +1,35 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build go1.18
+// +build go1.18
+
+package unsafeconv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func FuzzConversions(f *testing.F) {
+	for _, s := range []string{"", "a", "hello, world", "é😀", "\xff\x00"} {
+		f.Add([]byte(s))
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		s := String(b)
+		require.Equal(t, string(b), s)
+		require.Len(t, s, len(b))
+
+		back := Bytes(s)
+		require.True(t, bytes.Equal(b, back))
+		require.Equal(t, len(back), cap(back))
+		if len(b) > 0 {
+			// the memory is shared only with the shipperunsafe tag
+			require.Equal(t, ZeroCopy, &back[0] == &b[0])
+		}
+	})
+}
@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build shipperunsafe
+// +build shipperunsafe
+
+package unsafeconv
+
+import "unsafe"
+
+const zeroCopy = true
+
+// String returns b as a string sharing its memory, see the package
+// documentation for the ownership rules.
+func String(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// Bytes returns s as a byte slice sharing its memory, that must never be
+// modified.
+func Bytes(s string) []byte {
+	if s == "" {
+		return nil
+	}
+	return *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		cap int
+	}{s, len(s)}))
+}
@@ -8,23 +8,43 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	devtools "github.com/elastic/elastic-agent-libs/dev-tools/mage"
 	"github.com/elastic/elastic-agent-libs/dev-tools/mage/gotool"
 	"github.com/magefile/mage/mg"
 	"github.com/magefile/mage/sh"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/protocompat"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/jsonschema"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
 )
 
 const (
-	protoDest = "./pkg/proto"
+	protoDest     = "./pkg/proto"
+	descriptorSet = "./pkg/proto/shipper.protoset"
+	eventSchema   = "./api/event.schema.json"
 
 	goProtocGenGo     = "google.golang.org/protobuf/cmd/protoc-gen-go@v1.28"
 	goProtocGenGoGRPC = "google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.2"
+	goProtocGenGoVT   = "github.com/planetscale/vtprotobuf/cmd/protoc-gen-go-vtproto@v0.3.0"
 	goLicenserRepo    = "github.com/elastic/go-licenser@v0.4.1"
+
+	// licenseHeader is the header go-licenser adds to the Go files.
+	licenseHeader = `// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+`
 )
 
 var (
@@ -43,6 +63,12 @@ var (
 		"api/vendor",
 	)
 
+	// Messages allocated from a pool by the vtproto generated code.
+	vtprotoPooled = []string{
+		"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages.PublishRequest",
+		"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages.Event",
+	}
+
 	// Add here files that have their own license that must remain untouched
 	goLicenserExcluded = []string{
 		"api/vendor",
@@ -54,7 +80,7 @@ var (
 
 // Update updates all the generated code out of the spec
 func Update() {
-	mg.SerialDeps(GenerateGo, License)
+	mg.SerialDeps(GenerateGo, Descriptors, JSONSchema, Mocks, License)
 }
 
 // InstallProtoGo installs required plugins for protoc
@@ -67,6 +93,10 @@ func InstallProtoGo() error {
 	if err != nil {
 		return err
 	}
+	err = gotool.Install(gotool.Install.Package(goProtocGenGoVT))
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -79,58 +109,345 @@ func InstallLicenser() error {
 // GenerateGo regenerates the Go files out of .proto files
 func GenerateGo() error {
 	mg.Deps(InstallProtoGo)
+	return generateGo(protoDest)
+}
 
-	var (
-		importFlags []string
-		toCompile   []string
-	)
+// generateGo compiles the .proto files to Go files in the dest directory.
+func generateGo(dest string) error {
+	toCompile, err := listProtoFiles(".")
+	if err != nil {
+		return err
+	}
 
+	var importFlags []string
 	for _, p := range protoPackages {
 		importFlags = append(importFlags, "-I"+p)
 	}
 
+	args := append(
+		[]string{
+			"--go_out=" + dest,
+			"--go-grpc_out=" + dest,
+			"--go_opt=paths=source_relative",
+			"--go-grpc_opt=paths=source_relative",
+			"--go-vtproto_out=" + dest,
+			"--go-vtproto_opt=paths=source_relative,features=marshal+unmarshal+size+pool",
+		},
+		importFlags...,
+	)
+	for _, m := range vtprotoPooled {
+		args = append(args, "--go-vtproto_opt=pool="+m)
+	}
+	args = append(args, toCompile...)
+
+	log.Printf("Compiling %d packages...\n", len(protoPackages))
+	if err := sh.Run("protoc", args...); err != nil {
+		return fmt.Errorf("failed to compile protobuf: %w", err)
+	}
+
+	return nil
+}
+
+// listProtoFiles lists the .proto files of the packages to compile, in the
+// API found in the root directory.
+func listProtoFiles(root string) ([]string, error) {
+	var toCompile []string
 	for _, p := range protoPackagesToCompile {
 		log.Printf("Listing the %s package...\n", p)
 
-		files, err := ioutil.ReadDir(p)
+		files, err := ioutil.ReadDir(path.Join(root, p))
 		if err != nil {
-			return fmt.Errorf("failed to read the proto package directory %s: %w", p, err)
+			return nil, fmt.Errorf("failed to read the proto package directory %s: %w", p, err)
 		}
 		for _, f := range files {
 			if path.Ext(f.Name()) != ".proto" {
 				continue
 			}
-			toCompile = append(toCompile, path.Join(p, f.Name()))
+			toCompile = append(toCompile, path.Join(root, p, f.Name()))
 		}
 	}
+	return toCompile, nil
+}
 
-	args := append(
-		[]string{
-			"--go_out=" + protoDest,
-			"--go-grpc_out=" + protoDest,
-			"--go_opt=paths=source_relative",
-			"--go-grpc_opt=paths=source_relative",
-		},
-		importFlags...,
-	)
+// CheckGenerated regenerates the Go files into a temporary directory and fails
+// if they differ from the committed ones, printing the diff of each file. It
+// catches generated files edited by hand or generated with other versions of
+// protoc and its plugins.
+func CheckGenerated() error {
+	mg.Deps(InstallProtoGo)
 
-	args = append(args, toCompile...)
+	dir, err := ioutil.TempDir("", "shipper-proto")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
 
-	log.Printf("Compiling %d packages...\n", len(protoPackages))
-	err := sh.Run("protoc", args...)
+	if err := generateGo(dir); err != nil {
+		return err
+	}
+	generated, err := listGenerated(dir)
 	if err != nil {
-		return fmt.Errorf("failed to compile protobuf: %w", err)
+		return err
+	}
+	committed, err := listGenerated(protoDest)
+	if err != nil {
+		return err
+	}
+
+	var drifted []string
+	for file := range committed {
+		if !generated[file] {
+			log.Printf("%s is not generated from any .proto file\n", path.Join(protoDest, file))
+			drifted = append(drifted, file)
+		}
+	}
+	for file := range generated {
+		want := path.Join(protoDest, file)
+		got := path.Join(dir, file)
+		if !committed[file] {
+			log.Printf("%s is missing\n", want)
+			drifted = append(drifted, file)
+			continue
+		}
+		same, err := sameGenerated(want, got)
+		if err != nil {
+			return err
+		}
+		if same {
+			continue
+		}
+		drifted = append(drifted, file)
+		// git diff exits with 1 when the files differ
+		_ = sh.RunV("git", "diff", "--no-index", "--", want, got)
+	}
+
+	if len(drifted) > 0 {
+		sort.Strings(drifted)
+		return fmt.Errorf("the generated files are out of date, run `mage update`: %s", strings.Join(drifted, ", "))
+	}
+	log.Println("The generated files are up to date")
+	return nil
+}
+
+// listGenerated returns the paths of the .pb.go files in dir, relative to it.
+func listGenerated(dir string) (map[string]bool, error) {
+	files := map[string]bool{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(p, ".pb.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the generated files in %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// sameGenerated compares a committed file with the freshly generated one.
+// The license header added by `mage license` is copied over to the generated
+// file first, so it's part of the diff when they differ.
+func sameGenerated(committed, generated string) (bool, error) {
+	want, err := os.ReadFile(committed)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", committed, err)
+	}
+	got, err := os.ReadFile(generated)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", generated, err)
+	}
+	if bytes.HasPrefix(want, []byte(licenseHeader)) && !bytes.HasPrefix(got, []byte(licenseHeader)) {
+		got = append([]byte(licenseHeader), got...)
+		if err := os.WriteFile(generated, got, 0o644); err != nil {
+			return false, fmt.Errorf("failed to write %s: %w", generated, err)
+		}
+	}
+	return bytes.Equal(want, got), nil
+}
+
+// Descriptors writes the FileDescriptorSet of the API, with its imports, for
+// grpcurl, Buf and dynamic clients. It's embedded in the proto package.
+func Descriptors() error {
+	args := []string{
+		"--descriptor_set_out=" + descriptorSet,
+		"--include_imports",
+	}
+	for _, p := range protoPackages {
+		args = append(args, "-I"+p)
+	}
+	// every API file is imported by shipper.proto or listed here
+	args = append(args, "api/shipper.proto", "api/messages/error_details.proto")
+
+	log.Printf("Writing the descriptor set to %s...\n", descriptorSet)
+	if err := sh.Run("protoc", args...); err != nil {
+		return fmt.Errorf("failed to generate the descriptor set: %w", err)
+	}
+	return nil
+}
+
+// JSONSchema writes the JSON Schema of the JSON form of the events.
+func JSONSchema() error {
+	data, err := jsonschema.Event().Marshal()
+	if err != nil {
+		return err
+	}
+	log.Printf("Writing the event schema to %s...\n", eventSchema)
+	if err := os.WriteFile(eventSchema, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write the event schema: %w", err)
 	}
+	return nil
+}
 
+// Mocks generates the gomock mocks of the gRPC interfaces in pkg/proto/mocks.
+func Mocks() error {
+	log.Println("Generating the mocks...")
+	if err := sh.Run("go", "generate", "./"+path.Join(protoDest, "mocks")); err != nil {
+		return fmt.Errorf("failed to generate the mocks: %w", err)
+	}
 	return nil
 }
 
+// Corpus writes count synthetic NDJSON documents to the given file, for
+// benchmarks and load tests. The shape of the documents can be tuned with the
+// CORPUS_FIELDS, CORPUS_DEPTH, CORPUS_STRING_SIZE, CORPUS_CARDINALITY and
+// CORPUS_SEED environment variables.
+func Corpus(file string, count int) error {
+	config := generator.DefaultConfig()
+	for env, v := range map[string]*int{
+		"CORPUS_FIELDS":      &config.Fields,
+		"CORPUS_DEPTH":       &config.Depth,
+		"CORPUS_STRING_SIZE": &config.StringSize,
+		"CORPUS_CARDINALITY": &config.Cardinality,
+	} {
+		if s := os.Getenv(env); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", env, err)
+			}
+			*v = n
+		}
+	}
+	if s := os.Getenv("CORPUS_SEED"); s != "" {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid CORPUS_SEED: %w", err)
+		}
+		config.Seed = seed
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create the corpus: %w", err)
+	}
+	defer f.Close()
+	if err := generator.New(config).WriteNDJSON(f, count); err != nil {
+		return err
+	}
+	log.Printf("Wrote %d documents to %s\n", count, file)
+	return f.Close()
+}
+
 // Check runs all the checks
 func Check() {
-	mg.Deps(devtools.Deps.CheckModuleTidy, CheckLicenseHeaders)
+	mg.Deps(devtools.Deps.CheckModuleTidy, CheckLicenseHeaders, CheckBreaking, CheckGenerated)
 	mg.Deps(devtools.CheckNoChanges)
 }
 
+// CheckBreaking fails if the API has wire breaking changes since the last
+// released tag, or since the git reference in BREAKING_AGAINST.
+func CheckBreaking() error {
+	ref := os.Getenv("BREAKING_AGAINST")
+	if ref == "" {
+		tag, err := sh.Output("git", "describe", "--tags", "--abbrev=0")
+		if err != nil {
+			log.Println("No released tag, skipping the breaking change detection")
+			return nil
+		}
+		ref = tag
+	}
+
+	dir, err := ioutil.TempDir("", "shipper-breaking")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the API of the reference is compiled like the working tree, the
+	// committed descriptor sets may be stale or missing
+	archive := filepath.Join(dir, "api.tar")
+	if err := sh.Run("git", "archive", "--output="+archive, ref, "api"); err != nil {
+		return fmt.Errorf("failed to extract the API of %s: %w", ref, err)
+	}
+	previousDir := filepath.Join(dir, "previous")
+	if err := os.Mkdir(previousDir, 0o755); err != nil {
+		return err
+	}
+	if err := sh.Run("tar", "-xf", archive, "-C", previousDir); err != nil {
+		return fmt.Errorf("failed to extract the API of %s: %w", ref, err)
+	}
+
+	previous, err := compileDescriptors(previousDir, filepath.Join(dir, "previous.protoset"))
+	if err != nil {
+		return fmt.Errorf("%s: %w", ref, err)
+	}
+	current, err := compileDescriptors(".", filepath.Join(dir, "current.protoset"))
+	if err != nil {
+		return err
+	}
+	oldFiles, err := protocompat.Load(previous)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ref, err)
+	}
+	newFiles, err := protocompat.Load(current)
+	if err != nil {
+		return err
+	}
+
+	changes := protocompat.Compare(oldFiles, newFiles)
+	for _, c := range changes {
+		log.Printf("breaking change: %s\n", c)
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%d breaking changes since %s", len(changes), ref)
+	}
+	log.Printf("No breaking change since %s\n", ref)
+	return nil
+}
+
+// compileDescriptors compiles the API found in the root directory to a
+// descriptor set with its imports, written to out, and returns it.
+func compileDescriptors(root, out string) ([]byte, error) {
+	toCompile, err := listProtoFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{
+		"--descriptor_set_out=" + out,
+		"--include_imports",
+	}
+	for _, p := range protoPackages {
+		args = append(args, "-I"+path.Join(root, p))
+	}
+	args = append(args, toCompile...)
+
+	if err := sh.Run("protoc", args...); err != nil {
+		return nil, fmt.Errorf("failed to compile the descriptor set: %w", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the descriptor set: %w", err)
+	}
+	return data, nil
+}
+
 // License applies the right license header.
 func License() error {
 	mg.Deps(InstallLicenser)
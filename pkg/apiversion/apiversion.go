@@ -0,0 +1,111 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package apiversion contains the version of the shipper API implemented by
+// this module, and the compatibility rules between clients and shippers of
+// different versions.
+//
+// The major version is the one of the protobuf package, elastic.agent.shipper.v1,
+// the minor version is increased with every addition to the API:
+//
+//	1.0 the PublishEvents and PersistedIndex calls
+//	1.1 the compressed events of the publish requests
+//	1.2 the string table of the publish requests
+//	1.3 the metadata deltas of the events
+//	1.4 the Flush call
+//	1.5 the WhoAmI call
+//	1.6 the pipeline and raw_index fields of the events
+//	1.7 the op_type and document_id fields of the events
+//	1.8 the raw timestamps of the events
+//	1.9 the cursors of the publish requests and the GetCursor call
+//	1.10 the global metadata of the shipper
+//	1.11 the bytes values
+//	1.12 the event count of the compressed events
+package apiversion
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Header is the metadata key in which the clients and the shippers send
+// their API version, see interceptor.ClientVersionCheck.
+const Header = "shipper-api-version"
+
+// Version is an API version.
+type Version struct {
+	Major int
+	Minor int
+}
+
+var (
+	// Current is the API version implemented by this module.
+	Current = Version{Major: 1, Minor: 12}
+	// MinServer is the oldest shipper API version the clients of this
+	// module work with. The additions of the newer versions are only used
+	// when they are enabled explicitly.
+	MinServer = Version{Major: 1, Minor: 0}
+	// MinClient is the oldest client API version the shippers built with
+	// this module accept.
+	MinClient = Version{Major: 1, Minor: 0}
+)
+
+// ErrIncompatible is returned by Check for versions that can't work together.
+var ErrIncompatible = errors.New("incompatible shipper API versions")
+
+// Parse parses a version in the major.minor form.
+func Parse(s string) (Version, error) {
+	major, minor, ok := cut(s, ".")
+	if !ok {
+		return Version{}, fmt.Errorf("invalid API version %q, want major.minor", s)
+	}
+	var v Version
+	var err error
+	if v.Major, err = strconv.Atoi(major); err != nil || v.Major < 0 {
+		return Version{}, fmt.Errorf("invalid major version in %q", s)
+	}
+	if v.Minor, err = strconv.Atoi(minor); err != nil || v.Minor < 0 {
+		return Version{}, fmt.Errorf("invalid minor version in %q", s)
+	}
+	return v, nil
+}
+
+// String returns the version in the major.minor form.
+func (v Version) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+// Less returns whether v is older than other.
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+// Check returns an error wrapping ErrIncompatible if a client and a shipper
+// with these API versions can't work together: the major versions must be
+// the same, and neither side can be older than the minimum version the
+// other supports.
+func Check(client, server Version) error {
+	switch {
+	case client.Major != server.Major:
+		return fmt.Errorf("%w: the client implements API version %s and the shipper %s, the major versions must match", ErrIncompatible, client, server)
+	case server.Less(MinServer):
+		return fmt.Errorf("%w: the shipper implements API version %s, the client requires %s or later", ErrIncompatible, server, MinServer)
+	case client.Less(MinClient):
+		return fmt.Errorf("%w: the client implements API version %s, the shipper requires %s or later", ErrIncompatible, client, MinClient)
+	}
+	return nil
+}
+
+// cut is strings.Cut, which needs Go 1.18.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
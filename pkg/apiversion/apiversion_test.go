@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package apiversion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, err := Parse("1.3")
+	require.NoError(t, err)
+	require.Equal(t, Version{Major: 1, Minor: 3}, v)
+	require.Equal(t, "1.3", v.String())
+
+	for _, s := range []string{"", "1", "1.", ".1", "a.1", "1.b", "-1.0", "1.-2", "1.2.3"} {
+		_, err := Parse(s)
+		require.Error(t, err, s)
+	}
+
+	current, err := Parse(Current.String())
+	require.NoError(t, err)
+	require.Equal(t, Current, current)
+}
+
+func TestCheck(t *testing.T) {
+	require.NoError(t, Check(Current, Current))
+	require.NoError(t, Check(Version{1, 0}, Version{1, 9}))
+	require.NoError(t, Check(Version{1, 9}, Version{1, 0}))
+
+	err := Check(Version{2, 0}, Version{1, 3})
+	require.ErrorIs(t, err, ErrIncompatible)
+	require.Contains(t, err.Error(), "major versions must match")
+
+	require.True(t, Version{1, 3}.Less(Version{2, 0}))
+	require.True(t, Version{1, 2}.Less(Version{1, 3}))
+	require.False(t, Version{1, 3}.Less(Version{1, 3}))
+}
+
+func TestCheckMinimums(t *testing.T) {
+	minServer, minClient := MinServer, MinClient
+	defer func() { MinServer, MinClient = minServer, minClient }()
+	MinServer = Version{1, 2}
+	MinClient = Version{1, 1}
+
+	err := Check(Current, Version{1, 1})
+	require.ErrorIs(t, err, ErrIncompatible)
+	require.Contains(t, err.Error(), "requires 1.2 or later")
+
+	err = Check(Version{1, 0}, Current)
+	require.ErrorIs(t, err, ErrIncompatible)
+	require.Contains(t, err.Error(), "requires 1.1 or later")
+}
@@ -0,0 +1,431 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.elastic.co/fastjson"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
+)
+
+var sink interface{}
+
+// realisticFields returns the fields of a typical event and of a large one.
+func realisticFields() []namedFields {
+	large := generator.DefaultConfig()
+	large.Fields = 30
+	large.Depth = 3
+	return []namedFields{
+		{"typical", testutil.NewEvent(0).GetFields()},
+		{"large", generator.New(large).Event().GetFields()},
+	}
+}
+
+type namedFields struct {
+	name   string
+	fields *messages.Struct
+}
+
+func BenchmarkAsInterface(b *testing.B) {
+	for _, f := range realisticFields() {
+		value := helpers.NewStructValue(f.fields)
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sink = helpers.AsInterface(value)
+			}
+		})
+	}
+}
+
+func BenchmarkMarshalFastJSON(b *testing.B) {
+	for _, f := range realisticFields() {
+		fields := f.fields
+		b.Run(f.name+"/struct", func(b *testing.B) {
+			var w fastjson.Writer
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w.Reset()
+				if err := fields.MarshalFastJSON(&w); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.SetBytes(int64(w.Size()))
+		})
+		b.Run(f.name+"/value", func(b *testing.B) {
+			value := helpers.NewStructValue(fields)
+			var w fastjson.Writer
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w.Reset()
+				if err := value.MarshalFastJSON(&w); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.SetBytes(int64(w.Size()))
+		})
+	}
+}
+
+// BenchmarkStringToPrint measures the JSON of the logs. Run it with and
+// without -tags shipperunsafe to compare the copy of the printed bytes.
+func BenchmarkStringToPrint(b *testing.B) {
+	for _, f := range realisticFields() {
+		fields := f.fields
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				sink = fields.StringToPrint()
+			}
+		})
+	}
+}
+
+// BenchmarkNewStruct measures the conversion of JSON documents, with and
+// without interning their strings. It keeps the last batch of converted
+// documents and reports the heap they retain.
+func BenchmarkMarshalMetrics(b *testing.B) {
+	// a metric event, with a few gauges that couldn't be computed
+	metrics := &messages.Struct{Data: map[string]*messages.Value{}}
+	for i := 0; i < 50; i++ {
+		v := float64(i) / 3
+		if i%10 == 0 {
+			v = math.NaN()
+		}
+		metrics.Data[fmt.Sprintf("gauge_%d", i)] = helpers.NewFloat64Value(v)
+	}
+
+	for _, p := range []struct {
+		name   string
+		policy messages.NonFinitePolicy
+	}{
+		{"null", messages.NonFiniteNull},
+		{"string", messages.NonFiniteString},
+	} {
+		opts := messages.JSONOptions{NonFinite: p.policy}
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var w fastjson.Writer
+			for i := 0; i < b.N; i++ {
+				w.Reset()
+				if err := opts.MarshalStruct(&w, metrics); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkNewStruct(b *testing.B) {
+	const batch = 1000
+	for _, f := range realisticFields() {
+		doc, err := json.Marshal(helpers.AsMap(f.fields))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, c := range []struct {
+			name string
+			in   *helpers.Interner
+		}{{"plain", nil}, {"interned", helpers.NewInterner(4096)}} {
+			b.Run(f.name+"/"+c.name, func(b *testing.B) {
+				kept := make([]*messages.Struct, batch)
+				var before runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					var m map[string]interface{}
+					if err := json.Unmarshal(doc, &m); err != nil {
+						b.Fatal(err)
+					}
+					s, err := c.in.NewStruct(m)
+					if err != nil {
+						b.Fatal(err)
+					}
+					kept[i%batch] = s
+				}
+				b.StopTimer()
+
+				var after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if b.N >= batch && after.HeapAlloc > before.HeapAlloc {
+					b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/batch, "retained-B/doc")
+				}
+				runtime.KeepAlive(kept)
+			})
+		}
+	}
+}
+
+// BenchmarkCodec compares the proto package and the vtproto generated code
+// used by the codec package on a publish request.
+func BenchmarkCodec(b *testing.B) {
+	req := &messages.PublishRequest{Events: testutil.NewEvents(0, 100)}
+	data, err := gproto.Marshal(req)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("marshal/proto", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := gproto.Marshal(req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("marshal/vtproto", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if _, err := req.MarshalVT(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unmarshal/proto", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if err := gproto.Unmarshal(data, &messages.PublishRequest{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("unmarshal/vtproto", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			if err := (&messages.PublishRequest{}).UnmarshalVT(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkSortedStruct compares building and encoding the fields as a
+// map-backed Struct and as the experimental slice-backed SortedStruct.
+func BenchmarkSortedStruct(b *testing.B) {
+	for _, f := range realisticFields() {
+		m := helpers.AsMap(f.fields)
+		b.Run(f.name+"/map", func(b *testing.B) {
+			var buf []byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s, err := helpers.NewStruct(m)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if buf, err = (gproto.MarshalOptions{}).MarshalAppend(buf[:0], s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(f.name+"/slice", func(b *testing.B) {
+			var buf []byte
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s, err := helpers.NewSortedStruct(m)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if buf, err = s.AppendProto(buf[:0]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkBuildEvent measures building and dropping an event, allocated or
+// taken from the pools.
+func BenchmarkDecodeStruct(b *testing.B) {
+	// a multi-MB document, like a large audit event
+	config := generator.DefaultConfig()
+	config.Fields = 30
+	config.Depth = 3
+	gen := generator.New(config)
+	entries := make([]interface{}, 1000)
+	for i := range entries {
+		entries[i] = helpers.AsMap(gen.Event().GetFields())
+	}
+	doc, err := json.Marshal(map[string]interface{}{"entries": entries})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("unmarshal", func(b *testing.B) {
+		b.SetBytes(int64(len(doc)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var m map[string]interface{}
+			if err := json.Unmarshal(doc, &m); err != nil {
+				b.Fatal(err)
+			}
+			s, err := helpers.NewStruct(m)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink = s
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.SetBytes(int64(len(doc)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s, err := helpers.NewStructDecoder(bytes.NewReader(doc)).Decode()
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink = s
+		}
+	})
+}
+
+func BenchmarkBuildEvent(b *testing.B) {
+	keys := []string{"message", "host", "level", "offset", "path"}
+	fill := func(s *messages.Struct, i int) {
+		for _, k := range keys {
+			s.Data[k] = helpers.NewInt64Value(int64(i))
+		}
+	}
+
+	b.Run("allocated", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e := &messages.Event{
+				Metadata: &messages.Struct{Data: map[string]*messages.Value{}},
+				Fields:   &messages.Struct{Data: map[string]*messages.Value{}},
+			}
+			fill(e.Metadata, i)
+			fill(e.Fields, i)
+			sink = e
+		}
+	})
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e := helpers.AcquireEvent()
+			e.Metadata = helpers.AcquireStruct()
+			e.Fields = helpers.AcquireStruct()
+			fill(e.Metadata, i)
+			fill(e.Fields, i)
+			helpers.ReleaseEvent(e)
+		}
+	})
+}
+
+// BenchmarkTimestamp compares an input parsing the timestamps it reads with
+// one passing them raw to the shipper.
+func BenchmarkTimestamp(b *testing.B) {
+	raw := time.Date(2022, 1, 2, 3, 4, 5, 123456789, time.UTC).Format(time.RFC3339Nano)
+
+	b.Run("parsed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			t, err := time.Parse(time.RFC3339Nano, raw)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink = &messages.Event{Timestamp: timestamppb.New(t)}
+		}
+	})
+	b.Run("raw", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink = &messages.Event{TimestampRaw: raw}
+		}
+	})
+}
+
+func BenchmarkDecodeEnvelope(b *testing.B) {
+	data, err := gproto.Marshal(testutil.NewEvent(0))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e := &messages.Event{}
+			if err := gproto.Unmarshal(data, e); err != nil {
+				b.Fatal(err)
+			}
+			sink = e.GetDataStream()
+		}
+	})
+	b.Run("lazy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e, err := helpers.NewLazyEvent(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			sink = e.DataStream()
+		}
+	})
+}
+
+func BenchmarkMarshalMetricSample(b *testing.B) {
+	ts := timestamppb.Now()
+	ds := &messages.DataStream{Type: "metrics", Dataset: "system.cpu", Namespace: "default"}
+	metric := &messages.MetricEvent{
+		Timestamp:  ts,
+		Name:       "system.cpu.total.pct",
+		Value:      0.42,
+		Unit:       "percent",
+		Dimensions: map[string]string{"host.name": "host-1", "cloud.region": "eu-west-1"},
+		DataStream: ds,
+	}
+	event := &messages.Event{
+		Timestamp:  ts,
+		DataStream: ds,
+		Fields: &messages.Struct{Data: map[string]*messages.Value{
+			"metric": helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+				"name":  helpers.NewStringValue(metric.GetName()),
+				"value": helpers.NewFloat64Value(metric.GetValue()),
+				"unit":  helpers.NewStringValue(metric.GetUnit()),
+			}}),
+			"host":  helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{"name": helpers.NewStringValue("host-1")}}),
+			"cloud": helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{"region": helpers.NewStringValue("eu-west-1")}}),
+		}},
+	}
+
+	for _, bc := range []struct {
+		name string
+		msg  gproto.Message
+	}{
+		{"metric_event", metric},
+		{"event", event},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ReportMetric(float64(gproto.Size(bc.msg)), "bytes/sample")
+			for i := 0; i < b.N; i++ {
+				data, err := gproto.Marshal(bc.msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				sink = data
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package benchmark
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
+)
+
+type compressor struct {
+	name   string
+	writer func(w io.Writer) (io.WriteCloser, error)
+	reader func(r io.Reader) (io.Reader, error)
+}
+
+// compressors are the candidates for the compression of the batches.
+var compressors = []compressor{
+	{
+		name:   "none",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return nopCloser{w}, nil },
+		reader: func(r io.Reader) (io.Reader, error) { return r, nil },
+	},
+	{
+		name:   "gzip-fastest",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, gzip.BestSpeed) },
+		reader: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	},
+	{
+		name:   "gzip-default",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, gzip.DefaultCompression) },
+		reader: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	},
+	{
+		name:   "zlib-default",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriterLevel(w, zlib.DefaultCompression) },
+		reader: func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+	},
+	{
+		name:   "flate-huffman",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return flate.NewWriter(w, flate.HuffmanOnly) },
+		reader: func(r io.Reader) (io.Reader, error) { return flate.NewReader(r), nil },
+	},
+	{
+		// the framed format, like the snappy compressors of gRPC
+		name:   "snappy",
+		writer: func(w io.Writer) (io.WriteCloser, error) { return snappy.NewBufferedWriter(w), nil },
+		reader: func(r io.Reader) (io.Reader, error) { return snappy.NewReader(r), nil },
+	},
+	{
+		name: "zstd-fastest",
+		writer: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+		},
+		reader: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r, zstd.WithDecoderConcurrency(1)) },
+	},
+	{
+		name: "zstd-default",
+		writer: func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		},
+		reader: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r, zstd.WithDecoderConcurrency(1)) },
+	},
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+type corpus struct {
+	name string
+	data []byte
+}
+
+// corpora are serialized PublishRequests of different shapes.
+func corpora(b *testing.B) []corpus {
+	b.Helper()
+	small := generator.DefaultConfig()
+	large := generator.DefaultConfig()
+	large.Fields = 30
+	large.Depth = 3
+	large.StringSize = 64
+	unique := generator.DefaultConfig()
+	unique.Cardinality = 0
+
+	var out []corpus
+	for _, c := range []struct {
+		name   string
+		config generator.Config
+	}{{"small", small}, {"large", large}, {"unique", unique}} {
+		buf, err := gproto.Marshal(&messages.PublishRequest{Events: generator.New(c.config).Events(100)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		out = append(out, corpus{name: c.name, data: buf})
+	}
+	return out
+}
+
+func compress(c compressor, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := c.writer(&buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BenchmarkCompress reports the cost and the ratio of compressing a batch.
+func BenchmarkCompress(b *testing.B) {
+	for _, corpus := range corpora(b) {
+		data := corpus.data
+		for _, c := range compressors {
+			b.Run(fmt.Sprintf("%s/%s", corpus.name, c.name), func(b *testing.B) {
+				var compressed []byte
+				var err error
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if compressed, err = compress(c, data); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(len(data))/float64(len(compressed)), "ratio")
+			})
+		}
+	}
+}
+
+// BenchmarkDecompress reports the cost of decompressing a batch.
+func BenchmarkDecompress(b *testing.B) {
+	for _, corpus := range corpora(b) {
+		data := corpus.data
+		for _, c := range compressors {
+			b.Run(fmt.Sprintf("%s/%s", corpus.name, c.name), func(b *testing.B) {
+				compressed, err := compress(c, data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(len(data)))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					r, err := c.reader(bytes.NewReader(compressed))
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err := io.Copy(io.Discard, r); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
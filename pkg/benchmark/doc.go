@@ -0,0 +1,8 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package benchmark contains benchmarks of the whole publishing path, run with
+//
+//	go test -run XXX -bench . ./pkg/benchmark
+package benchmark
@@ -0,0 +1,152 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/server"
+)
+
+var batchSizes = []int{1, 10, 100, 1000}
+
+// benchServer accepts all the events it receives.
+type benchServer struct {
+	proto.UnimplementedProducerServer
+	tracker *server.IndexTracker
+}
+
+func (s *benchServer) PublishEvents(_ context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	return s.tracker.Accept(req.GetUuid(), len(req.GetEvents())), nil
+}
+
+// publishStream is a bidirectional version of PublishEvents, it only
+// exists to compare the unary call to a stream.
+func (s *benchServer) publishStream(stream grpc.ServerStream) error {
+	for {
+		req := &messages.PublishRequest{}
+		if err := stream.RecvMsg(req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := stream.SendMsg(s.tracker.Accept(req.GetUuid(), len(req.GetEvents()))); err != nil {
+			return err
+		}
+	}
+}
+
+var streamDesc = grpc.StreamDesc{StreamName: "PublishStream", ServerStreams: true, ClientStreams: true}
+
+var benchServiceDesc = grpc.ServiceDesc{
+	ServiceName: "benchmark.Producer",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName: streamDesc.StreamName,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			return srv.(*benchServer).publishStream(stream)
+		},
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+// startServer starts a server over bufconn and returns a connection to it.
+func startServer(b *testing.B) *grpc.ClientConn {
+	b.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(64 * 1024 * 1024))
+	bs := &benchServer{tracker: server.NewIndexTracker()}
+	proto.RegisterProducerServer(srv, bs)
+	srv.RegisterService(&benchServiceDesc, bs)
+	go func() { _ = srv.Serve(lis) }()
+	b.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(64*1024*1024)),
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// reportThroughput reports the events and bytes published per second since start.
+func reportThroughput(b *testing.B, req *messages.PublishRequest, start time.Time) {
+	b.SetBytes(int64(gproto.Size(req)))
+	b.ReportMetric(float64(b.N*len(req.GetEvents()))/time.Since(start).Seconds(), "events/s")
+}
+
+// BenchmarkPublishUnary measures a PublishEvents call: marshaling, the
+// transfer, unmarshaling on the server and the reply.
+func BenchmarkPublishUnary(b *testing.B) {
+	for _, size := range batchSizes {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			client := proto.NewProducerClient(startServer(b))
+			req := &messages.PublishRequest{Events: testutil.NewEvents(0, size)}
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.PublishEvents(ctx, req); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportThroughput(b, req, start)
+		})
+	}
+}
+
+// BenchmarkPublishStream measures the same exchange as BenchmarkPublishUnary
+// over a single long lived stream.
+func BenchmarkPublishStream(b *testing.B) {
+	for _, size := range batchSizes {
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			conn := startServer(b)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stream, err := conn.NewStream(ctx, &streamDesc, "/benchmark.Producer/PublishStream")
+			if err != nil {
+				b.Fatal(err)
+			}
+			req := &messages.PublishRequest{Events: testutil.NewEvents(0, size)}
+			reply := &messages.PublishReply{}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			start := time.Now()
+			for i := 0; i < b.N; i++ {
+				if err := stream.SendMsg(req); err != nil {
+					b.Fatal(err)
+				}
+				if err := stream.RecvMsg(reply); err != nil {
+					b.Fatal(err)
+				}
+			}
+			reportThroughput(b, req, start)
+		})
+	}
+}
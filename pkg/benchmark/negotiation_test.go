@@ -0,0 +1,111 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/interceptor"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/server"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/testing/generator"
+)
+
+// countingConn counts the bytes written by the client.
+type countingConn struct {
+	net.Conn
+	written *int64
+}
+
+func (c countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddInt64(c.written, int64(n))
+	return n, err
+}
+
+// startNegotiatingServer starts a server advertising its compressors, and
+// returns a client negotiating the given ones with the counter of the bytes
+// it writes.
+func startNegotiatingServer(b *testing.B, preferred string) (proto.ProducerClient, *int64) {
+	b.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(append(interceptor.ServerCompression(), grpc.MaxRecvMsgSize(64*1024*1024))...)
+	proto.RegisterProducerServer(srv, &benchServer{tracker: server.NewIndexTracker()})
+	go func() { _ = srv.Serve(lis) }()
+	b.Cleanup(srv.Stop)
+
+	var written int64
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			conn, err := lis.DialContext(ctx)
+			return countingConn{Conn: conn, written: &written}, err
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(64 * 1024 * 1024)),
+	}
+	if preferred != "" {
+		opts = append(opts, interceptor.ClientCompression(preferred)...)
+	}
+	conn, err := grpc.Dial("bufnet", opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	return proto.NewProducerClient(conn), &written
+}
+
+// BenchmarkPublishCompression measures PublishEvents calls with the
+// negotiated compression, reporting the bytes sent per call next to the
+// time spent compressing.
+func BenchmarkPublishCompression(b *testing.B) {
+	large := generator.DefaultConfig()
+	large.Fields = 30
+	large.Depth = 3
+	large.StringSize = 64
+
+	for _, c := range []struct {
+		name   string
+		config generator.Config
+	}{{"small", generator.DefaultConfig()}, {"large", large}} {
+		req := &messages.PublishRequest{Events: generator.New(c.config).Events(100)}
+		for _, compressor := range []string{"", gzip.Name, interceptor.Zstd} {
+			name := compressor
+			if name == "" {
+				name = "none"
+			}
+			b.Run(fmt.Sprintf("%s/%s", c.name, name), func(b *testing.B) {
+				client, written := startNegotiatingServer(b, compressor)
+				ctx := context.Background()
+				// negotiates the compression
+				if _, err := client.PublishEvents(ctx, req); err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				atomic.StoreInt64(written, 0)
+				start := time.Now()
+				for i := 0; i < b.N; i++ {
+					if _, err := client.PublishEvents(ctx, req); err != nil {
+						b.Fatal(err)
+					}
+				}
+				reportThroughput(b, req, start)
+				b.ReportMetric(float64(atomic.LoadInt64(written))/float64(b.N), "wire-B/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+var publishers = []int{1, 4, 16}
+
+// BenchmarkPublishSweep publishes batches of every size from several
+// concurrent publishers sharing a connection, b.N is the total number of
+// batches. It is meant to pick the default batching settings.
+func BenchmarkPublishSweep(b *testing.B) {
+	for _, size := range batchSizes {
+		for _, n := range publishers {
+			b.Run(fmt.Sprintf("batch=%d/publishers=%d", size, n), func(b *testing.B) {
+				c := client.New(proto.NewProducerClient(startServer(b)))
+				req := &messages.PublishRequest{Events: testutil.NewEvents(0, size)}
+				ctx := context.Background()
+
+				var remaining int64 = int64(b.N)
+				var wg sync.WaitGroup
+				errs := make(chan error, n)
+				b.ReportAllocs()
+				b.ResetTimer()
+				start := time.Now()
+				for i := 0; i < n; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for atomic.AddInt64(&remaining, -1) >= 0 {
+							if _, err := c.Publish(ctx, req); err != nil {
+								errs <- err
+								return
+							}
+						}
+					}()
+				}
+				wg.Wait()
+				b.StopTimer()
+				close(errs)
+				if err := <-errs; err != nil {
+					b.Fatal(err)
+				}
+				reportThroughput(b, req, start)
+			})
+		}
+	}
+}
@@ -0,0 +1,663 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// ErrClosed is returned when publishing through a closed producer.
+var ErrClosed = errors.New("producer is closed")
+
+// ErrMemoryBudget is returned when publishing would exceed MaxInFlightBytes
+// and FailWhenFull is set.
+var ErrMemoryBudget = errors.New("in-flight bytes budget exceeded")
+
+// ErrHalted fails the batches following a failed batch in ordered mode.
+var ErrHalted = errors.New("a previous batch failed, the producer must be resumed")
+
+// AsyncConfig configures an AsyncProducer.
+type AsyncConfig struct {
+	// QueueSize is the number of batches that can wait to be sent before
+	// Publish starts blocking.
+	QueueSize int
+	// PersistedIndexInterval is the polling interval requested from the shipper
+	// for persisted index updates.
+	PersistedIndexInterval time.Duration
+	// MaxInFlight is the number of batches sent concurrently, zero means one.
+	// Batches sent concurrently may be delivered in any order.
+	MaxInFlight int
+	// Ordered guarantees that a batch is never delivered ahead of an earlier
+	// one: batches are sent one at a time, and once a batch fails all the
+	// following ones fail with ErrHalted until Resume is called. MaxInFlight
+	// is ignored.
+	Ordered bool
+	// MaxInFlightBytes bounds the serialized size of the events published and
+	// not persisted yet, including the batches being filled by a Batcher.
+	// Zero means no limit. A batch larger than the limit is only accepted when
+	// nothing else is in flight.
+	MaxInFlightBytes int
+	// FailWhenFull makes publishing return ErrMemoryBudget instead of blocking
+	// when MaxInFlightBytes is reached.
+	FailWhenFull bool
+	// Checkpoints, if set, stores the cursor of the last batch published with
+	// PublishCursor once it and all the batches queued before it are persisted.
+	Checkpoints CheckpointStore
+	// OnCheckpointError is called when storing a checkpoint fails, the
+	// checkpoint is stored again when the next batch is persisted.
+	OnCheckpointError func(error)
+}
+
+// DefaultAsyncConfig returns the default AsyncProducer configuration.
+func DefaultAsyncConfig() AsyncConfig {
+	return AsyncConfig{
+		QueueSize:              16,
+		PersistedIndexInterval: time.Second,
+	}
+}
+
+// Future tracks a batch of events published through an AsyncProducer.
+type Future struct {
+	events []*messages.Event
+	cursor []byte
+
+	accepted  chan struct{}
+	persisted chan struct{}
+
+	// epoch of the producer when the batch was queued
+	epoch uint64
+	// bytes is the part of the in-flight budget held by the batch, reserved is
+	// set when it was acquired before the batch was queued
+	bytes    int
+	reserved bool
+
+	// set before accepted is closed
+	reply *messages.PublishReply
+	// latency of the publish call and whether the shipper pushed back during
+	// it, set before accepted is closed
+	latency   time.Duration
+	congested bool
+	// set before persisted is closed, or before both are closed on failure
+	err error
+}
+
+func newFuture(events []*messages.Event) *Future {
+	return &Future{
+		events:    events,
+		accepted:  make(chan struct{}),
+		persisted: make(chan struct{}),
+	}
+}
+
+// Accepted is closed once the shipper accepted the batch or the batch failed.
+func (f *Future) Accepted() <-chan struct{} {
+	return f.accepted
+}
+
+// Persisted is closed once the persisted index of the shipper covers the batch
+// or the batch failed.
+func (f *Future) Persisted() <-chan struct{} {
+	return f.persisted
+}
+
+// Reply returns the reply of the shipper, nil until the batch is accepted.
+func (f *Future) Reply() *messages.PublishReply {
+	select {
+	case <-f.accepted:
+		return f.reply
+	default:
+		return nil
+	}
+}
+
+// Err returns the error that failed the batch, nil until the batch is resolved.
+func (f *Future) Err() error {
+	select {
+	case <-f.persisted:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until the batch is persisted, failed, or the context is done.
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.persisted:
+		return f.err
+	}
+}
+
+func (f *Future) accept(reply *messages.PublishReply) {
+	f.reply = reply
+	close(f.accepted)
+}
+
+func (f *Future) persist() {
+	close(f.persisted)
+}
+
+func (f *Future) fail(err error) {
+	f.err = err
+	select {
+	case <-f.accepted:
+	default:
+		close(f.accepted)
+	}
+	close(f.persisted)
+}
+
+// AsyncProducer publishes batches of events in the background.
+//
+// Batches are sent through the Client in the order they are queued, see
+// AsyncConfig for the ordering guarantees. Publish returns a Future that is resolved when the batch is accepted and again when the persisted index
+// reported by the shipper covers it.
+type AsyncProducer struct {
+	client *Client
+	config AsyncConfig
+
+	queue  chan *Future
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// closeMu protects closed, it's held while a batch is counted as
+	// outstanding, never while it waits for the budget or the queue. closing
+	// is closed when the producer stops accepting batches, to wake them up,
+	// and queueing tracks the batches still on their way to the queue.
+	closeMu   sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	closeOnce sync.Once
+	queueing  sync.WaitGroup
+
+	mu        sync.Mutex
+	uuid      string
+	persisted uint64
+	pending   []*Future
+	// epoch is incremented on Resume, halted is set when a batch fails in
+	// ordered mode
+	epoch  uint64
+	halted bool
+
+	// outstanding is the number of queued events not resolved yet, inFlight
+	// is the size of the events holding budget, resolved is closed and
+	// replaced every time they change
+	outstandingMu sync.Mutex
+	outstanding   int
+	inFlight      int
+	resolved      chan struct{}
+
+	// chain holds the queued batches in order until they are covered by
+	// a stored checkpoint
+	chainMu sync.Mutex
+	chain   []*Future
+}
+
+// NewAsyncProducer creates a new AsyncProducer and starts publishing in the background.
+func NewAsyncProducer(c *Client, config AsyncConfig) *AsyncProducer {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &AsyncProducer{
+		client:   c,
+		config:   config,
+		queue:    make(chan *Future, config.QueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+		closing:  make(chan struct{}),
+		resolved: make(chan struct{}),
+	}
+	c.register(p)
+
+	workers := config.MaxInFlight
+	if workers < 1 || config.Ordered {
+		workers = 1
+	}
+	p.wg.Add(workers + 1)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			p.publishLoop()
+		}()
+	}
+	go func() {
+		defer p.wg.Done()
+		p.persistedLoop()
+	}()
+	return p
+}
+
+// Publish queues the events for publishing. It only blocks if the queue is full.
+func (p *AsyncProducer) Publish(ctx context.Context, events []*messages.Event) (*Future, error) {
+	f := newFuture(events)
+	if err := p.enqueue(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// PublishCursor queues the events like Publish, cursor is the position of
+// the input following the last event. It's stored as a checkpoint once the
+// batch is persisted, see AsyncConfig.Checkpoints.
+//
+// A failed batch holds the checkpoint back until Resume is called.
+func (p *AsyncProducer) PublishCursor(ctx context.Context, events []*messages.Event, cursor []byte) (*Future, error) {
+	f := newFuture(events)
+	f.cursor = cursor
+	if err := p.enqueue(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *AsyncProducer) enqueue(ctx context.Context, f *Future) error {
+	// the batch is outstanding from here, so drain waits for it or for it to
+	// give up once closing is closed
+	p.closeMu.RLock()
+	if p.closed {
+		p.closeMu.RUnlock()
+		return ErrClosed
+	}
+	p.mu.Lock()
+	f.epoch = p.epoch
+	p.mu.Unlock()
+	p.track(len(f.events), 0)
+	p.chainAppend(f)
+	p.queueing.Add(1)
+	p.closeMu.RUnlock()
+	defer p.queueing.Done()
+
+	acquired := 0
+	if !f.reserved {
+		for _, e := range f.events {
+			acquired += proto.Size(e)
+		}
+		if err := p.acquire(ctx, acquired); err != nil {
+			p.unqueue(f, 0)
+			return err
+		}
+		f.bytes = acquired
+		f.reserved = true
+	}
+
+	select {
+	case <-ctx.Done():
+		p.unqueue(f, acquired)
+		return ctx.Err()
+	case <-p.closing:
+		p.unqueue(f, acquired)
+		return ErrClosed
+	case p.queue <- f:
+		return nil
+	}
+}
+
+// unqueue reverts the accounting of a batch that could not be queued.
+func (p *AsyncProducer) unqueue(f *Future, acquired int) {
+	p.track(-len(f.events), -acquired)
+	p.chainRemove(f)
+	if acquired > 0 {
+		f.bytes, f.reserved = 0, false
+	}
+}
+
+// tryAcquire takes bytes from the in-flight budget if they are available.
+func (p *AsyncProducer) tryAcquire(bytes int) bool {
+	p.outstandingMu.Lock()
+	defer p.outstandingMu.Unlock()
+	max := p.config.MaxInFlightBytes
+	if max > 0 && p.inFlight > 0 && p.inFlight+bytes > max {
+		return false
+	}
+	p.inFlight += bytes
+	return true
+}
+
+// acquire takes bytes from the in-flight budget, blocking until they are
+// available unless FailWhenFull is set.
+func (p *AsyncProducer) acquire(ctx context.Context, bytes int) error {
+	for {
+		if p.tryAcquire(bytes) {
+			return nil
+		}
+		if p.config.FailWhenFull {
+			return ErrMemoryBudget
+		}
+
+		p.outstandingMu.Lock()
+		resolved := p.resolved
+		p.outstandingMu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.closing:
+			return ErrClosed
+		case <-resolved:
+		}
+	}
+}
+
+// InFlightBytes returns the size of the events published and not persisted yet.
+func (p *AsyncProducer) InFlightBytes() int {
+	p.outstandingMu.Lock()
+	defer p.outstandingMu.Unlock()
+	return p.inFlight
+}
+
+// Resume lets an ordered producer send batches again after a failure. The
+// batches queued before Resume fail with ErrHalted, the caller is expected to
+// publish again the failed batch and the following ones, in order.
+//
+// With a checkpoint store, the failed batches stop holding the checkpoint back.
+func (p *AsyncProducer) Resume() {
+	p.mu.Lock()
+	p.epoch++
+	p.halted = false
+	p.mu.Unlock()
+
+	p.checkpoint()
+}
+
+func (p *AsyncProducer) chainAppend(f *Future) {
+	if p.config.Checkpoints == nil {
+		return
+	}
+	p.chainMu.Lock()
+	defer p.chainMu.Unlock()
+	p.chain = append(p.chain, f)
+}
+
+func (p *AsyncProducer) chainRemove(f *Future) {
+	if p.config.Checkpoints == nil {
+		return
+	}
+	p.chainMu.Lock()
+	defer p.chainMu.Unlock()
+	for i, c := range p.chain {
+		if c == f {
+			p.chain = append(p.chain[:i], p.chain[i+1:]...)
+			return
+		}
+	}
+}
+
+// checkpoint stores the cursor of the last persisted batch with a cursor,
+// as long as all the batches queued before it are persisted too.
+func (p *AsyncProducer) checkpoint() {
+	if p.config.Checkpoints == nil {
+		return
+	}
+	p.mu.Lock()
+	epoch := p.epoch
+	p.mu.Unlock()
+
+	p.chainMu.Lock()
+	defer p.chainMu.Unlock()
+
+	var last *Future
+	n := 0
+	for _, f := range p.chain {
+		if !isResolved(f) {
+			break
+		}
+		if f.err != nil {
+			// batches failed before the last Resume are published again
+			if f.epoch == epoch {
+				break
+			}
+			n++
+			continue
+		}
+		if f.cursor != nil {
+			last = f
+		}
+		n++
+	}
+	if last != nil {
+		err := p.config.Checkpoints.Put(Checkpoint{
+			Cursor:        last.cursor,
+			UUID:          last.reply.GetUuid(),
+			AcceptedIndex: last.reply.GetAcceptedIndex(),
+		})
+		if err != nil {
+			if p.config.OnCheckpointError != nil {
+				p.config.OnCheckpointError(err)
+			}
+			return
+		}
+	}
+	p.chain = p.chain[n:]
+}
+
+func isResolved(f *Future) bool {
+	select {
+	case <-f.persisted:
+		return true
+	default:
+		return false
+	}
+}
+
+// track updates the number of queued events that are not resolved yet and
+// the in-flight bytes.
+func (p *AsyncProducer) track(events, bytes int) {
+	p.outstandingMu.Lock()
+	defer p.outstandingMu.Unlock()
+	p.outstanding += events
+	p.inFlight += bytes
+	close(p.resolved)
+	p.resolved = make(chan struct{})
+}
+
+// resolve marks a queued batch as persisted, or failed if err is not nil.
+func (p *AsyncProducer) resolve(f *Future, err error) {
+	if err != nil {
+		f.fail(err)
+	} else {
+		f.persist()
+	}
+	p.track(-len(f.events), -f.bytes)
+	p.checkpoint()
+}
+
+// stopQueueing makes the batches waiting to be queued fail with ErrClosed and
+// the following ones fail right away.
+func (p *AsyncProducer) stopQueueing() {
+	p.closeOnce.Do(func() { close(p.closing) })
+	p.closeMu.Lock()
+	p.closed = true
+	p.closeMu.Unlock()
+}
+
+// drain stops accepting batches and waits until all the queued ones are
+// resolved or ctx is done, then closes the producer.
+func (p *AsyncProducer) drain(ctx context.Context) int {
+	p.stopQueueing()
+
+	for {
+		p.outstandingMu.Lock()
+		outstanding, resolved := p.outstanding, p.resolved
+		p.outstandingMu.Unlock()
+		if outstanding == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			p.Close()
+			return outstanding
+		case <-resolved:
+		}
+	}
+	p.Close()
+	return 0
+}
+
+// Close stops the producer. All the batches that are not persisted yet fail with ErrClosed.
+func (p *AsyncProducer) Close() {
+	p.cancel()
+	p.stopQueueing()
+	p.queueing.Wait()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	pending := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+	for _, f := range pending {
+		p.resolve(f, ErrClosed)
+	}
+	for {
+		select {
+		case f := <-p.queue:
+			p.resolve(f, ErrClosed)
+		default:
+			return
+		}
+	}
+}
+
+func (p *AsyncProducer) publishLoop() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case f := <-p.queue:
+			p.send(f)
+		}
+	}
+}
+
+func (p *AsyncProducer) send(f *Future) {
+	if p.config.Ordered {
+		p.mu.Lock()
+		halted := p.halted || f.epoch != p.epoch
+		p.mu.Unlock()
+		if halted {
+			p.resolve(f, ErrHalted)
+			return
+		}
+	}
+
+	start := time.Now()
+	reply, err := p.client.publish(p.ctx, protoBatch{&messages.PublishRequest{Events: f.events}}, func(a Attempt) {
+		if (a.Err == nil && a.Accepted < a.Submitted) || status.Code(a.Err) == codes.ResourceExhausted {
+			f.congested = true
+		}
+	})
+	f.latency = time.Since(start)
+	if err != nil {
+		if p.ctx.Err() != nil {
+			err = ErrClosed
+		}
+		p.halt()
+		p.resolve(f, err)
+		return
+	}
+	if int(reply.GetAcceptedCount()) < len(f.events) {
+		p.halt()
+		p.resolve(f, fmt.Errorf("the shipper accepted %d of %d events", reply.GetAcceptedCount(), len(f.events)))
+		return
+	}
+	f.accept(reply)
+
+	p.mu.Lock()
+	if reply.GetUuid() == p.uuid && reply.GetAcceptedIndex() <= p.persisted {
+		p.mu.Unlock()
+		p.resolve(f, nil)
+		return
+	}
+	p.pending = append(p.pending, f)
+	p.mu.Unlock()
+}
+
+// halt stops an ordered producer after a failed batch.
+func (p *AsyncProducer) halt() {
+	if !p.config.Ordered {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.halted = true
+}
+
+func (p *AsyncProducer) persistedLoop() {
+	retries := 0
+	for {
+		err := p.watchPersisted()
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			retries = 0
+		}
+		retries++
+		if wait(p.ctx, p.client.retry.Backoff(retries)) != nil {
+			return
+		}
+	}
+}
+
+func (p *AsyncProducer) watchPersisted() error {
+	stream, err := p.client.producer.PersistedIndex(p.ctx, &messages.PersistedIndexRequest{
+		PollingInterval: durationpb.New(p.config.PersistedIndexInterval),
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		reply, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if p.client.restarts != nil {
+			p.client.restarts.Persisted(reply)
+		}
+		p.updatePersisted(reply)
+	}
+}
+
+func (p *AsyncProducer) updatePersisted(reply *messages.PersistedIndexReply) {
+	p.mu.Lock()
+	p.uuid = reply.GetUuid()
+	p.persisted = reply.GetPersistedIndex()
+
+	var resolved, failed []*Future
+	remaining := p.pending[:0]
+	for _, f := range p.pending {
+		switch {
+		case f.reply.GetUuid() != p.uuid:
+			failed = append(failed, f)
+		case f.reply.GetAcceptedIndex() <= p.persisted:
+			resolved = append(resolved, f)
+		default:
+			remaining = append(remaining, f)
+		}
+	}
+	p.pending = remaining
+	p.mu.Unlock()
+
+	for _, f := range resolved {
+		p.resolve(f, nil)
+	}
+	for _, f := range failed {
+		p.resolve(f, ErrShipperRestarted)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func waitFor(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestAsyncProducer(t *testing.T) {
+	ctx := context.Background()
+	producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	p := NewAsyncProducer(New(producer), DefaultAsyncConfig())
+	defer p.Close()
+
+	first, err := p.Publish(ctx, testEvents(3))
+	require.NoError(t, err)
+	second, err := p.Publish(ctx, testEvents(2))
+	require.NoError(t, err)
+
+	waitFor(t, first.Accepted())
+	waitFor(t, second.Accepted())
+	require.Equal(t, uint64(3), first.Reply().GetAcceptedIndex())
+	require.Equal(t, uint64(5), second.Reply().GetAcceptedIndex())
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 4}
+	waitFor(t, first.Persisted())
+	require.NoError(t, first.Err())
+	select {
+	case <-second.Persisted():
+		t.Fatal("the second batch is not persisted yet")
+	default:
+	}
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 5}
+	require.NoError(t, second.Wait(ctx))
+
+	// a batch already covered by the persisted index resolves right away
+	producer.index = 0
+	third, err := p.Publish(ctx, testEvents(1))
+	require.NoError(t, err)
+	require.NoError(t, third.Wait(ctx))
+}
+
+func TestAsyncProducerRestart(t *testing.T) {
+	ctx := context.Background()
+	producer := &fakeProducer{uuid: "first", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	p := NewAsyncProducer(New(producer), DefaultAsyncConfig())
+	defer p.Close()
+
+	f, err := p.Publish(ctx, testEvents(3))
+	require.NoError(t, err)
+	waitFor(t, f.Accepted())
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "second"}
+	require.ErrorIs(t, f.Wait(ctx), ErrShipperRestarted)
+}
+
+func TestAsyncProducerClose(t *testing.T) {
+	ctx := context.Background()
+	producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	p := NewAsyncProducer(New(producer), DefaultAsyncConfig())
+
+	f, err := p.Publish(ctx, testEvents(3))
+	require.NoError(t, err)
+	waitFor(t, f.Accepted())
+
+	p.Close()
+	require.ErrorIs(t, f.Wait(ctx), ErrClosed)
+	_, err = p.Publish(ctx, testEvents(1))
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+// Close must give up at its deadline even when a batch is in flight and
+// another one waits for room in the queue.
+func TestAsyncProducerCloseFullQueue(t *testing.T) {
+	ctx := context.Background()
+	producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply), gate: make(chan struct{})}
+	c := New(producer)
+	p := NewAsyncProducer(c, AsyncConfig{QueueSize: 1})
+	defer p.Close()
+
+	// the first batch is in flight, the second one fills the queue
+	inFlight, err := p.Publish(ctx, testEvents(1))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(p.queue) == 0 }, 5*time.Second, time.Millisecond)
+	_, err = p.Publish(ctx, testEvents(1))
+	require.NoError(t, err)
+
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := p.Publish(ctx, testEvents(1))
+		blocked <- err
+	}()
+	require.Eventually(t, func() bool {
+		p.outstandingMu.Lock()
+		defer p.outstandingMu.Unlock()
+		return p.outstanding == 3
+	}, 5*time.Second, time.Millisecond)
+
+	closeCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	closed := make(chan struct{})
+	var unconfirmed int
+	go func() {
+		defer close(closed)
+		unconfirmed, err = c.Close(closeCtx)
+	}()
+	waitFor(t, closed)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 2, unconfirmed)
+
+	select {
+	case err := <-blocked:
+		require.ErrorIs(t, err, ErrClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the blocked publish was not released")
+	}
+	require.ErrorIs(t, inFlight.Wait(ctx), ErrClosed)
+}
+
+func TestAsyncProducerOrdered(t *testing.T) {
+	ctx := context.Background()
+	invalid := status.Error(codes.InvalidArgument, "invalid event")
+
+	t.Run("ordered", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply), results: []fakeResult{{err: invalid}}}
+		p := NewAsyncProducer(New(producer), AsyncConfig{QueueSize: 10, Ordered: true})
+		defer p.Close()
+
+		first, err := p.Publish(ctx, testEvents(1))
+		require.NoError(t, err)
+		second, err := p.Publish(ctx, testEvents(1))
+		require.NoError(t, err)
+		waitFor(t, second.Accepted())
+		require.ErrorIs(t, first.Err(), invalid)
+		require.ErrorIs(t, second.Err(), ErrHalted)
+
+		p.Resume()
+		third, err := p.Publish(ctx, testEvents(1))
+		require.NoError(t, err)
+		waitFor(t, third.Accepted())
+		require.NotNil(t, third.Reply())
+		require.Equal(t, []int{1, 1}, producer.requestSizes())
+	})
+
+	t.Run("unordered", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply), results: []fakeResult{{err: invalid}}}
+		p := NewAsyncProducer(New(producer), AsyncConfig{QueueSize: 10})
+		defer p.Close()
+
+		first, err := p.Publish(ctx, testEvents(1))
+		require.NoError(t, err)
+		second, err := p.Publish(ctx, testEvents(1))
+		require.NoError(t, err)
+		waitFor(t, second.Accepted())
+		require.ErrorIs(t, first.Err(), invalid)
+		require.NotNil(t, second.Reply())
+	})
+}
+
+func TestAsyncProducerMaxInFlightBytes(t *testing.T) {
+	ctx := context.Background()
+	event := &messages.Event{Source: &messages.Source{InputId: "input"}}
+	size := proto.Size(event)
+	events := []*messages.Event{event, event}
+
+	t.Run("fail when full", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply, 1)}
+		p := NewAsyncProducer(New(producer), AsyncConfig{QueueSize: 10, MaxInFlightBytes: 2 * size, FailWhenFull: true})
+		defer p.Close()
+
+		f, err := p.Publish(ctx, events)
+		require.NoError(t, err)
+		require.Equal(t, 2*size, p.InFlightBytes())
+		_, err = p.Publish(ctx, events[:1])
+		require.ErrorIs(t, err, ErrMemoryBudget)
+
+		producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 2}
+		waitFor(t, f.Persisted())
+		require.Eventually(t, func() bool { return p.InFlightBytes() == 0 }, time.Second, time.Millisecond)
+		_, err = p.Publish(ctx, events[:1])
+		require.NoError(t, err)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply)}
+		p := NewAsyncProducer(New(producer), AsyncConfig{QueueSize: 10, MaxInFlightBytes: 2 * size})
+		defer p.Close()
+
+		_, err := p.Publish(ctx, events)
+		require.NoError(t, err)
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = p.Publish(timeout, events[:1])
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 2*size, p.InFlightBytes())
+	})
+
+	t.Run("batcher", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply)}
+		p := NewAsyncProducer(New(producer), AsyncConfig{QueueSize: 10, MaxInFlightBytes: 2 * size, FailWhenFull: true})
+		defer p.Close()
+		b := NewBatcher(p, BatcherConfig{MaxEvents: 100})
+
+		for i := 0; i < 2; i++ {
+			_, err := b.Add(ctx, event)
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2*size, p.InFlightBytes())
+		_, err := b.Add(ctx, event)
+		require.ErrorIs(t, err, ErrMemoryBudget)
+		// the full batch was flushed to make room
+		require.Eventually(t, func() bool { return len(producer.requestSizes()) == 1 }, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// MetadataFunc returns the metadata attached to a call, e.g. an authorization
+// header. It's called for every call and must be safe for concurrent use.
+type MetadataFunc func(ctx context.Context) (map[string]string, error)
+
+// BearerToken returns a MetadataFunc attaching the token as a bearer authorization.
+func BearerToken(token string) MetadataFunc {
+	md := map[string]string{"authorization": "Bearer " + token}
+	return func(context.Context) (map[string]string, error) {
+		return md, nil
+	}
+}
+
+// RPCCredentials are gRPC per-RPC credentials attaching the metadata returned
+// by a hook to every call. The hook can be replaced at any time, e.g. when the
+// agent issues a new token, without recreating the connection.
+type RPCCredentials struct {
+	requireTLS bool
+
+	mu sync.RWMutex
+	fn MetadataFunc
+}
+
+var _ credentials.PerRPCCredentials = (*RPCCredentials)(nil)
+
+// NewRPCCredentials creates new credentials calling fn for every call. When
+// requireTLS is set, gRPC refuses to send the credentials over a connection
+// without transport security, it must be false for local sockets.
+func NewRPCCredentials(fn MetadataFunc, requireTLS bool) *RPCCredentials {
+	return &RPCCredentials{fn: fn, requireTLS: requireTLS}
+}
+
+// Rotate replaces the hook, the calls started afterwards use the new one.
+func (c *RPCCredentials) Rotate(fn MetadataFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fn = fn
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (c *RPCCredentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	c.mu.RLock()
+	fn := c.fn
+	c.mu.RUnlock()
+	if fn == nil {
+		return nil, nil
+	}
+	return fn(ctx)
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (c *RPCCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
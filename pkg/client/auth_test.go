@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// authorizationServer replies with the authorization metadata as the uuid.
+type authorizationServer struct {
+	proto.UnimplementedProducerServer
+}
+
+func (authorizationServer) PublishEvents(ctx context.Context, _ *messages.PublishRequest) (*messages.PublishReply, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "no authorization")
+	}
+	return &messages.PublishReply{Uuid: values[0]}, nil
+}
+
+func TestRPCCredentials(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not tested on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "shipper.sock")
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	proto.RegisterProducerServer(srv, authorizationServer{})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	creds := NewRPCCredentials(BearerToken("first"), false)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, "unix://"+path, grpc.WithPerRPCCredentials(creds))
+	require.NoError(t, err)
+	defer conn.Close()
+	producer := proto.NewProducerClient(conn)
+
+	reply, err := producer.PublishEvents(ctx, &messages.PublishRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "Bearer first", reply.GetUuid())
+
+	creds.Rotate(BearerToken("second"))
+	reply, err = producer.PublishEvents(ctx, &messages.PublishRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "Bearer second", reply.GetUuid())
+
+	creds.Rotate(func(context.Context) (map[string]string, error) {
+		return nil, errors.New("token expired")
+	})
+	_, err = producer.PublishEvents(ctx, &messages.PublishRequest{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "token expired")
+}
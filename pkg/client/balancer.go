@@ -0,0 +1,316 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// BalancePolicy defines how a MultiProducer spreads the requests over its endpoints.
+type BalancePolicy int
+
+const (
+	// Failover sends everything to the first healthy endpoint, in the order
+	// they were given. Traffic goes back to a preferred endpoint once it's healthy again.
+	Failover BalancePolicy = iota
+	// RoundRobin assigns every stream to the next healthy endpoint and keeps
+	// sending it there while the endpoint is healthy, so the events of a
+	// stream are delivered in order.
+	RoundRobin
+)
+
+// Endpoint is a shipper connection used by a MultiProducer.
+type Endpoint struct {
+	// Name identifies the endpoint, e.g. its address.
+	Name string
+	// Producer is the client of the endpoint.
+	Producer proto.ProducerClient
+}
+
+// EndpointStatus describes the state of an endpoint of a MultiProducer.
+type EndpointStatus struct {
+	Name string
+	// Healthy is false while the endpoint is skipped after a failure.
+	Healthy bool
+	// UUID is the last uuid returned by the endpoint.
+	UUID string
+}
+
+// MultiConfig configures a MultiProducer.
+type MultiConfig struct {
+	// Policy is the balancing policy.
+	Policy BalancePolicy
+	// Cooldown is the time an endpoint is skipped after it was found unavailable.
+	Cooldown time.Duration
+}
+
+// DefaultMultiConfig returns the default MultiProducer configuration.
+func DefaultMultiConfig() MultiConfig {
+	return MultiConfig{
+		Policy:   Failover,
+		Cooldown: 10 * time.Second,
+	}
+}
+
+// MultiProducer is a proto.ProducerClient spreading the calls over several
+// shipper endpoints. An endpoint returning Unavailable is skipped until its
+// cooldown expires and the call is sent to the next healthy one.
+//
+// Every endpoint is a different shipper process with its own uuid. With
+// Failover, the PersistedIndex stream and the other calls returning the state
+// of a shipper, like Flush and WhoAmI, follow the active endpoint, so
+// switching endpoints looks like a shipper restart to the caller. With RoundRobin, the endpoint to follow is ambiguous and they
+// return an Unimplemented error: use one producer per endpoint to track persistence.
+//
+// Streams are identified by the stream id of the first event of a request,
+// or its input id when there is none. A request should only contain the
+// events of a single stream for the ordering guarantee to hold.
+type MultiProducer struct {
+	proto.ProducerClient
+
+	config    MultiConfig
+	endpoints []*endpointState
+
+	mu      sync.Mutex
+	next    int
+	streams map[string]int
+}
+
+type endpointState struct {
+	Endpoint
+
+	mu             sync.Mutex
+	unhealthyUntil time.Time
+	uuid           string
+}
+
+// NewMultiProducer creates a new producer over the given endpoints.
+func NewMultiProducer(endpoints []Endpoint, config MultiConfig) (*MultiProducer, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoint given")
+	}
+	p := &MultiProducer{
+		config:  config,
+		streams: make(map[string]int),
+	}
+	for _, e := range endpoints {
+		p.endpoints = append(p.endpoints, &endpointState{Endpoint: e})
+	}
+	return p, nil
+}
+
+// Endpoints returns the status of the endpoints.
+func (p *MultiProducer) Endpoints() []EndpointStatus {
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	now := time.Now()
+	for i, e := range p.endpoints {
+		e.mu.Lock()
+		statuses[i] = EndpointStatus{Name: e.Name, Healthy: e.healthyLocked(now), UUID: e.uuid}
+		e.mu.Unlock()
+	}
+	return statuses
+}
+
+// PublishEvents implements proto.ProducerClient.
+func (p *MultiProducer) PublishEvents(ctx context.Context, req *messages.PublishRequest, opts ...grpc.CallOption) (*messages.PublishReply, error) {
+	var lastErr error
+	for _, i := range p.candidates(streamKey(req)) {
+		e := p.endpoints[i]
+		reply, err := e.Producer.PublishEvents(ctx, req, opts...)
+		if err == nil {
+			e.mu.Lock()
+			e.uuid = reply.GetUuid()
+			e.mu.Unlock()
+			p.assign(streamKey(req), i)
+			return reply, nil
+		}
+		if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			return nil, err
+		}
+		e.mu.Lock()
+		e.unhealthyUntil = time.Now().Add(p.config.Cooldown)
+		e.mu.Unlock()
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// PersistedIndex implements proto.ProducerClient.
+func (p *MultiProducer) PersistedIndex(ctx context.Context, req *messages.PersistedIndexRequest, opts ...grpc.CallOption) (proto.Producer_PersistedIndexClient, error) {
+	if p.config.Policy == RoundRobin {
+		return nil, status.Error(codes.Unimplemented, "the persisted index is not available with round robin balancing")
+	}
+	var lastErr error
+	for _, i := range p.candidates("") {
+		stream, err := p.endpoints[i].Producer.PersistedIndex(ctx, req, opts...)
+		if err == nil {
+			return stream, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// Flush implements proto.ProducerClient.
+func (p *MultiProducer) Flush(ctx context.Context, req *messages.FlushRequest, opts ...grpc.CallOption) (*messages.FlushReply, error) {
+	if p.config.Policy == RoundRobin {
+		return nil, status.Error(codes.Unimplemented, "flush is not available with round robin balancing")
+	}
+	var lastErr error
+	for _, i := range p.candidates("") {
+		reply, err := p.endpoints[i].Producer.Flush(ctx, req, opts...)
+		if err == nil {
+			return reply, nil
+		}
+		if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// WhoAmI implements proto.ProducerClient.
+func (p *MultiProducer) WhoAmI(ctx context.Context, req *messages.WhoAmIRequest, opts ...grpc.CallOption) (*messages.WhoAmIReply, error) {
+	if p.config.Policy == RoundRobin {
+		return nil, status.Error(codes.Unimplemented, "the shipper identity is not available with round robin balancing")
+	}
+	var lastErr error
+	for _, i := range p.candidates("") {
+		e := p.endpoints[i]
+		reply, err := e.Producer.WhoAmI(ctx, req, opts...)
+		if err == nil {
+			e.mu.Lock()
+			e.uuid = reply.GetUuid()
+			e.mu.Unlock()
+			return reply, nil
+		}
+		if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// GetCursor implements proto.ProducerClient.
+func (p *MultiProducer) GetCursor(ctx context.Context, req *messages.GetCursorRequest, opts ...grpc.CallOption) (*messages.GetCursorReply, error) {
+	if p.config.Policy == RoundRobin {
+		return nil, status.Error(codes.Unimplemented, "cursors are not available with round robin balancing")
+	}
+	var lastErr error
+	for _, i := range p.candidates("") {
+		reply, err := p.endpoints[i].Producer.GetCursor(ctx, req, opts...)
+		if err == nil {
+			return reply, nil
+		}
+		if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// GetGlobalMetadata implements proto.ProducerClient.
+func (p *MultiProducer) GetGlobalMetadata(ctx context.Context, req *messages.GetGlobalMetadataRequest, opts ...grpc.CallOption) (*messages.GetGlobalMetadataReply, error) {
+	if p.config.Policy == RoundRobin {
+		return nil, status.Error(codes.Unimplemented, "the global metadata is not available with round robin balancing")
+	}
+	var lastErr error
+	for _, i := range p.candidates("") {
+		reply, err := p.endpoints[i].Producer.GetGlobalMetadata(ctx, req, opts...)
+		if err == nil {
+			return reply, nil
+		}
+		if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// candidates returns the endpoints to try for the given stream, in order.
+// Healthy endpoints come first, unhealthy ones are tried as a last resort.
+func (p *MultiProducer) candidates(stream string) []int {
+	now := time.Now()
+	healthy := make([]int, 0, len(p.endpoints))
+	var unhealthy []int
+	for i, e := range p.endpoints {
+		e.mu.Lock()
+		ok := e.healthyLocked(now)
+		e.mu.Unlock()
+		if ok {
+			healthy = append(healthy, i)
+		} else {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+	if p.config.Policy != RoundRobin || len(healthy) == 0 {
+		return append(healthy, unhealthy...)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	first, ok := p.streams[stream]
+	if !ok || !contains(healthy, first) {
+		first = healthy[p.next%len(healthy)]
+		p.next++
+	}
+	// keep the healthy endpoints in round robin order after the first one
+	ordered := []int{first}
+	for _, i := range healthy {
+		if i != first {
+			ordered = append(ordered, i)
+		}
+	}
+	return append(ordered, unhealthy...)
+}
+
+// assign records the endpoint a stream was delivered to.
+func (p *MultiProducer) assign(stream string, endpoint int) {
+	if p.config.Policy != RoundRobin {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streams[stream] = endpoint
+}
+
+func (e *endpointState) healthyLocked(now time.Time) bool {
+	return !now.Before(e.unhealthyUntil)
+}
+
+func streamKey(req *messages.PublishRequest) string {
+	events := req.GetEvents()
+	if len(events) == 0 {
+		return ""
+	}
+	source := events[0].GetSource()
+	if source.GetStreamId() != "" {
+		return source.GetStreamId()
+	}
+	return source.GetInputId()
+}
+
+func contains(list []int, v int) bool {
+	for _, i := range list {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func streamEvents(streamID string, n int) []*messages.Event {
+	events := testEvents(n)
+	for _, e := range events {
+		e.Source = &messages.Source{StreamId: streamID}
+	}
+	return events
+}
+
+func TestMultiProducer(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "down")
+
+	t.Run("fails over to the next endpoint", func(t *testing.T) {
+		a := &fakeProducer{uuid: "a", results: []fakeResult{{err: unavailable}}}
+		b := &fakeProducer{uuid: "b"}
+		p, err := NewMultiProducer([]Endpoint{{Name: "a", Producer: a}, {Name: "b", Producer: b}}, DefaultMultiConfig())
+		require.NoError(t, err)
+
+		reply, err := p.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(2)})
+		require.NoError(t, err)
+		require.Equal(t, "b", reply.GetUuid())
+		require.Equal(t, []EndpointStatus{
+			{Name: "a", Healthy: false},
+			{Name: "b", Healthy: true, UUID: "b"},
+		}, p.Endpoints())
+
+		// a is skipped during its cooldown
+		_, err = p.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		require.Len(t, a.requests, 1)
+		require.Len(t, b.requests, 2)
+
+		flushed, err := p.Flush(context.Background(), &messages.FlushRequest{})
+		require.NoError(t, err)
+		require.Equal(t, &messages.FlushReply{Uuid: "b", PersistedIndex: 3}, flushed)
+		whoami, err := p.WhoAmI(context.Background(), &messages.WhoAmIRequest{})
+		require.NoError(t, err)
+		require.Equal(t, "b", whoami.GetUuid())
+		_, err = p.GetCursor(context.Background(), &messages.GetCursorRequest{})
+		require.NoError(t, err)
+		global, err := p.GetGlobalMetadata(context.Background(), &messages.GetGlobalMetadataRequest{})
+		require.NoError(t, err)
+		require.Equal(t, "b", global.GetUuid())
+	})
+
+	t.Run("goes back to the preferred endpoint after the cooldown", func(t *testing.T) {
+		a := &fakeProducer{uuid: "a", results: []fakeResult{{err: unavailable}}}
+		b := &fakeProducer{uuid: "b"}
+		config := DefaultMultiConfig()
+		config.Cooldown = time.Millisecond
+		p, err := NewMultiProducer([]Endpoint{{Name: "a", Producer: a}, {Name: "b", Producer: b}}, config)
+		require.NoError(t, err)
+
+		_, err = p.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+		reply, err := p.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		require.Equal(t, "a", reply.GetUuid())
+	})
+
+	t.Run("other errors are not failed over", func(t *testing.T) {
+		a := &fakeProducer{uuid: "a", results: []fakeResult{{err: status.Error(codes.InvalidArgument, "bad")}}}
+		b := &fakeProducer{uuid: "b"}
+		p, err := NewMultiProducer([]Endpoint{{Name: "a", Producer: a}, {Name: "b", Producer: b}}, DefaultMultiConfig())
+		require.NoError(t, err)
+
+		_, err = p.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Empty(t, b.requests)
+	})
+
+	t.Run("round robin keeps streams on their endpoint", func(t *testing.T) {
+		a := &fakeProducer{uuid: "a"}
+		b := &fakeProducer{uuid: "b"}
+		config := DefaultMultiConfig()
+		config.Policy = RoundRobin
+		p, err := NewMultiProducer([]Endpoint{{Name: "a", Producer: a}, {Name: "b", Producer: b}}, config)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			for _, stream := range []string{"s1", "s2"} {
+				_, err := p.PublishEvents(context.Background(), &messages.PublishRequest{Events: streamEvents(stream, 1)})
+				require.NoError(t, err)
+			}
+		}
+		require.Len(t, a.requests, 3)
+		require.Len(t, b.requests, 3)
+		for _, req := range a.requests {
+			require.Equal(t, "s1", req.GetEvents()[0].GetSource().GetStreamId())
+		}
+
+		_, err = p.PersistedIndex(context.Background(), &messages.PersistedIndexRequest{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+		_, err = p.Flush(context.Background(), &messages.FlushRequest{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+		_, err = p.WhoAmI(context.Background(), &messages.WhoAmIRequest{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+		_, err = p.GetCursor(context.Background(), &messages.GetCursorRequest{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+		_, err = p.GetGlobalMetadata(context.Background(), &messages.GetGlobalMetadataRequest{})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("no endpoints", func(t *testing.T) {
+		_, err := NewMultiProducer(nil, DefaultMultiConfig())
+		require.Error(t, err)
+	})
+}
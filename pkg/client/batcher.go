@@ -0,0 +1,285 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// BatcherConfig configures a Batcher.
+type BatcherConfig struct {
+	// MaxEvents is the number of events that triggers a flush. In adaptive
+	// mode it's the upper bound of the batch size.
+	MaxEvents int
+	// MaxBytes is the serialized size of the events that triggers a flush.
+	// Zero means no limit.
+	MaxBytes int
+	// FlushInterval is the maximum time an event waits in a batch before a flush.
+	// Zero means batches are only flushed when full or explicitly.
+	FlushInterval time.Duration
+	// MaxInFlight is the number of flushed batches that can wait to be persisted
+	// before adding events blocks. Zero means no limit.
+	MaxInFlight int
+	// Adaptive, if set, makes the batch size follow the shipper's capacity.
+	Adaptive *AdaptiveConfig
+}
+
+// AdaptiveConfig configures the adaptive batch sizing of a Batcher.
+//
+// The batch size starts at MinEvents and grows by Increase events after every
+// full batch accepted faster than TargetLatency. It's multiplied by Decrease
+// whenever the shipper pushes back, i.e. when a batch is rejected with
+// ResourceExhausted or only partially accepted.
+type AdaptiveConfig struct {
+	// MinEvents is the initial and minimum batch size.
+	MinEvents int
+	// TargetLatency is the publish latency under which the batch size grows.
+	TargetLatency time.Duration
+	// Increase is the number of events added to the batch size.
+	Increase int
+	// Decrease is the factor applied to the batch size, between 0 and 1.
+	Decrease float64
+}
+
+// DefaultAdaptiveConfig returns the default adaptive batch sizing configuration.
+func DefaultAdaptiveConfig() AdaptiveConfig {
+	return AdaptiveConfig{
+		MinEvents:     64,
+		TargetLatency: 100 * time.Millisecond,
+		Increase:      64,
+		Decrease:      0.5,
+	}
+}
+
+// DefaultBatcherConfig returns the default Batcher configuration.
+func DefaultBatcherConfig() BatcherConfig {
+	return BatcherConfig{
+		MaxEvents:     1024,
+		MaxBytes:      4 * 1024 * 1024,
+		FlushInterval: time.Second,
+		MaxInFlight:   4,
+	}
+}
+
+// Batcher accumulates events and publishes them in batches through an AsyncProducer.
+type Batcher struct {
+	producer *AsyncProducer
+	config   BatcherConfig
+	window   chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	future *Future
+	bytes  int
+	timer  *time.Timer
+
+	sizeMu sync.Mutex
+	size   int
+}
+
+// NewBatcher creates a new Batcher publishing through the given producer.
+func NewBatcher(p *AsyncProducer, config BatcherConfig) *Batcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Batcher{
+		producer: p,
+		config:   config,
+		ctx:      ctx,
+		cancel:   cancel,
+		size:     config.MaxEvents,
+	}
+	if config.Adaptive != nil {
+		b.size = config.Adaptive.MinEvents
+	}
+	if config.MaxInFlight > 0 {
+		b.window = make(chan struct{}, config.MaxInFlight)
+	}
+	if p != nil {
+		p.client.register(b)
+	}
+	return b
+}
+
+// Add adds an event to the current batch and returns the Future of that batch.
+//
+// If the event does not fit in the current batch, the batch is flushed first.
+// Add blocks while the in-flight window is full, an error means the event was not added.
+func (b *Batcher) Add(ctx context.Context, event *messages.Event) (*Future, error) {
+	size := proto.Size(event)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+
+	if b.future != nil && b.config.MaxBytes > 0 && b.bytes+size > b.config.MaxBytes {
+		if err := b.flushLocked(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// the event takes its share of the producer in-flight budget right away,
+	// if the current batch holds what's missing it's flushed first
+	if !b.producer.tryAcquire(size) {
+		if err := b.flushLocked(ctx); err != nil {
+			return nil, err
+		}
+		if err := b.producer.acquire(ctx, size); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.future == nil {
+		b.future = newFuture(nil)
+		b.future.reserved = true
+		b.bytes = 0
+		if b.config.FlushInterval > 0 {
+			b.timer = time.AfterFunc(b.config.FlushInterval, b.flushTimer(b.future))
+		}
+	}
+	f := b.future
+	f.events = append(f.events, event)
+	f.bytes += size
+	b.bytes += size
+
+	if len(f.events) >= b.BatchSize() || (b.config.MaxBytes > 0 && b.bytes >= b.config.MaxBytes) {
+		// the event is part of the batch no matter what, if the flush fails
+		// it will be attempted again by the next call or the timer
+		_ = b.flushLocked(ctx)
+	}
+	return f, nil
+}
+
+// Flush publishes the current batch, if any.
+func (b *Batcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked(ctx)
+}
+
+// Close flushes the current batch and stops the Batcher. It does not close the producer.
+func (b *Batcher) Close(ctx context.Context) error {
+	// cancel first, a timer may be blocked on the window while holding the lock
+	b.cancel()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked(ctx)
+}
+
+// drain flushes the current batch, the events are confirmed by the producer.
+func (b *Batcher) drain(ctx context.Context) int {
+	b.cancel()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	f := b.future
+	if f != nil && b.flushLocked(ctx) != nil {
+		return len(f.events)
+	}
+	return 0
+}
+
+// BatchSize returns the number of events that triggers a flush.
+func (b *Batcher) BatchSize() int {
+	b.sizeMu.Lock()
+	defer b.sizeMu.Unlock()
+	return b.size
+}
+
+// adapt updates the batch size with the outcome of a batch.
+func (b *Batcher) adapt(f *Future) {
+	c := b.config.Adaptive
+	// a batch that was accepted can still fail later, its error is not read
+	failed := f.reply == nil
+	b.sizeMu.Lock()
+	defer b.sizeMu.Unlock()
+
+	switch {
+	case f.congested || (failed && status.Code(f.err) == codes.ResourceExhausted):
+		b.size = int(float64(b.size) * c.Decrease)
+		if b.size < c.MinEvents {
+			b.size = c.MinEvents
+		}
+	case !failed && f.latency < c.TargetLatency && len(f.events) >= b.size:
+		b.size += c.Increase
+		if b.size > b.config.MaxEvents {
+			b.size = b.config.MaxEvents
+		}
+	}
+}
+
+// flushTimer returns the function flushing the given batch when its interval expires.
+func (b *Batcher) flushTimer(f *Future) func() {
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.future == f {
+			_ = b.flushLocked(b.ctx)
+		}
+	}
+}
+
+func (b *Batcher) flushLocked(ctx context.Context) error {
+	f := b.future
+	if f == nil {
+		return nil
+	}
+
+	if b.window != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case b.window <- struct{}{}:
+		}
+	}
+	err := b.producer.enqueue(ctx, f)
+	if err != nil && !errors.Is(err, ErrClosed) {
+		if b.window != nil {
+			<-b.window
+		}
+		return err
+	}
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.future = nil
+	b.bytes = 0
+
+	if err != nil {
+		// the events can't be published anymore
+		f.fail(err)
+		b.producer.track(0, -f.bytes)
+		if b.window != nil {
+			<-b.window
+		}
+		return err
+	}
+
+	if b.config.Adaptive != nil {
+		go func() {
+			<-f.Accepted()
+			b.adapt(f)
+		}()
+	}
+	if b.window != nil {
+		go func() {
+			<-f.Persisted()
+			<-b.window
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func newTestBatcher(config BatcherConfig) (*Batcher, *fakeProducer, func()) {
+	producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	p := NewAsyncProducer(New(producer), DefaultAsyncConfig())
+	b := NewBatcher(p, config)
+	return b, producer, func() {
+		_ = b.Close(context.Background())
+		p.Close()
+	}
+}
+
+func (p *fakeProducer) requestSizes() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sizes := make([]int, len(p.requests))
+	for i, r := range p.requests {
+		sizes[i] = len(r.GetEvents())
+	}
+	return sizes
+}
+
+func TestBatcherMaxEvents(t *testing.T) {
+	b, producer, stop := newTestBatcher(BatcherConfig{MaxEvents: 3})
+	defer stop()
+	ctx := context.Background()
+
+	var futures []*Future
+	for _, e := range testEvents(7) {
+		f, err := b.Add(ctx, e)
+		require.NoError(t, err)
+		futures = append(futures, f)
+	}
+	require.Same(t, futures[0], futures[2])
+	require.NotSame(t, futures[2], futures[3])
+
+	waitFor(t, futures[5].Accepted())
+	require.Equal(t, []int{3, 3}, producer.requestSizes())
+
+	require.NoError(t, b.Flush(ctx))
+	waitFor(t, futures[6].Accepted())
+	require.Equal(t, []int{3, 3, 1}, producer.requestSizes())
+}
+
+func TestBatcherMaxBytes(t *testing.T) {
+	event := &messages.Event{Source: &messages.Source{InputId: "input"}}
+	size := proto.Size(event)
+	b, producer, stop := newTestBatcher(BatcherConfig{MaxEvents: 100, MaxBytes: 2*size + 1})
+	defer stop()
+	ctx := context.Background()
+
+	var f *Future
+	var err error
+	for i := 0; i < 3; i++ {
+		f, err = b.Add(ctx, event)
+		require.NoError(t, err)
+	}
+	require.NoError(t, b.Flush(ctx))
+	waitFor(t, f.Accepted())
+	require.Equal(t, []int{2, 1}, producer.requestSizes())
+}
+
+func TestBatcherFlushInterval(t *testing.T) {
+	b, producer, stop := newTestBatcher(BatcherConfig{MaxEvents: 100, FlushInterval: 10 * time.Millisecond})
+	defer stop()
+
+	f, err := b.Add(context.Background(), &messages.Event{})
+	require.NoError(t, err)
+	waitFor(t, f.Accepted())
+	require.Equal(t, []int{1}, producer.requestSizes())
+}
+
+func TestBatcherInFlightWindow(t *testing.T) {
+	b, producer, stop := newTestBatcher(BatcherConfig{MaxEvents: 1, MaxInFlight: 1})
+	defer stop()
+
+	first, err := b.Add(context.Background(), &messages.Event{})
+	require.NoError(t, err)
+	waitFor(t, first.Accepted())
+
+	// the first batch is not persisted, the window is full
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	second, err := b.Add(ctx, &messages.Event{})
+	require.NoError(t, err)
+	require.Error(t, b.Flush(ctx))
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 1}
+	waitFor(t, first.Persisted())
+	require.NoError(t, b.Flush(context.Background()))
+	waitFor(t, second.Accepted())
+}
+
+func TestBatcherAdaptive(t *testing.T) {
+	b, producer, stop := newTestBatcher(BatcherConfig{
+		MaxEvents: 6,
+		Adaptive:  &AdaptiveConfig{MinEvents: 2, TargetLatency: time.Minute, Increase: 2, Decrease: 0.5},
+	})
+	defer stop()
+	ctx := context.Background()
+
+	addBatch := func(n int) {
+		t.Helper()
+		var f *Future
+		for _, e := range testEvents(n) {
+			var err error
+			f, err = b.Add(ctx, e)
+			require.NoError(t, err)
+		}
+		waitFor(t, f.Accepted())
+		require.Eventually(t, func() bool { return b.BatchSize() != n }, time.Second, time.Millisecond)
+	}
+
+	require.Equal(t, 2, b.BatchSize())
+	addBatch(2)
+	require.Equal(t, 4, b.BatchSize())
+	addBatch(4)
+	require.Equal(t, 6, b.BatchSize())
+
+	// the shipper only takes part of the next batch
+	producer.mu.Lock()
+	producer.results = []fakeResult{{accept: 1}}
+	producer.mu.Unlock()
+	addBatch(6)
+	require.Equal(t, 3, b.BatchSize())
+	require.Equal(t, []int{2, 4, 6, 5}, producer.requestSizes())
+}
+
+func TestBatcherAdaptiveBounds(t *testing.T) {
+	b := NewBatcher(nil, BatcherConfig{
+		MaxEvents: 10,
+		Adaptive:  &AdaptiveConfig{MinEvents: 4, TargetLatency: time.Second, Increase: 8, Decrease: 0.1},
+	})
+
+	full := &Future{events: testEvents(4), reply: &messages.PublishReply{}}
+	b.adapt(full)
+	require.Equal(t, 10, b.BatchSize())
+
+	slow := &Future{events: testEvents(10), reply: &messages.PublishReply{}, latency: time.Minute}
+	b.adapt(slow)
+	require.Equal(t, 10, b.BatchSize())
+
+	rejected := &Future{events: testEvents(10), err: status.Error(codes.ResourceExhausted, "queue is full")}
+	b.adapt(rejected)
+	require.Equal(t, 4, b.BatchSize())
+}
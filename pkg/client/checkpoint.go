@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint is the position an input resumes from after a restart.
+//
+// Cursor is defined by the input, e.g. a file offset or a Kafka offset, and
+// follows the last event persisted by the shipper. UUID and AcceptedIndex
+// are the ones returned by the shipper for the request containing that event,
+// see publish.proto.
+type Checkpoint struct {
+	Cursor        []byte `json:"cursor"`
+	UUID          string `json:"uuid"`
+	AcceptedIndex uint64 `json:"accepted_index"`
+}
+
+// CheckpointStore stores the last checkpoint of an input.
+type CheckpointStore interface {
+	// Get returns the stored checkpoint, ok is false if there is none.
+	Get() (cp Checkpoint, ok bool, err error)
+	// Put replaces the stored checkpoint.
+	Put(cp Checkpoint) error
+}
+
+// FileCheckpointStore is a CheckpointStore keeping the checkpoint in a JSON
+// file. The file is replaced atomically on every Put.
+type FileCheckpointStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileCheckpointStore creates a store writing to the given file. The
+// directory of the file is created if needed.
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create the checkpoint directory: %w", err)
+	}
+	return &FileCheckpointStore{path: path}, nil
+}
+
+// Get implements CheckpointStore.
+func (s *FileCheckpointStore) Get() (Checkpoint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cp Checkpoint
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, false, nil
+	}
+	if err != nil {
+		return cp, false, fmt.Errorf("failed to read the checkpoint: %w", err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, false, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	return cp, true, nil
+}
+
+// Put implements CheckpointStore.
+func (s *FileCheckpointStore) Put(cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode the checkpoint: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write the checkpoint: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write the checkpoint: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync the checkpoint: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write the checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace the checkpoint: %w", err)
+	}
+	return nil
+}
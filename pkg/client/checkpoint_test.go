@@ -0,0 +1,96 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input", "checkpoint.json")
+	store, err := NewFileCheckpointStore(path)
+	require.NoError(t, err)
+
+	_, ok, err := store.Get()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	cp := Checkpoint{Cursor: []byte("offset=42"), UUID: "uuid", AcceptedIndex: 7}
+	require.NoError(t, store.Put(cp))
+
+	// the checkpoint survives a restart
+	store, err = NewFileCheckpointStore(path)
+	require.NoError(t, err)
+	got, ok, err := store.Get()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, cp, got)
+}
+
+func requireCheckpoint(t *testing.T, store CheckpointStore, cursor string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		cp, ok, err := store.Get()
+		return err == nil && ok && string(cp.Cursor) == cursor
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestAsyncProducerCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+	require.NoError(t, err)
+
+	producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	config := DefaultAsyncConfig()
+	config.Ordered = true
+	config.Checkpoints = store
+	p := NewAsyncProducer(New(producer), config)
+	defer p.Close()
+
+	first, err := p.PublishCursor(ctx, testEvents(3), []byte("3"))
+	require.NoError(t, err)
+	second, err := p.PublishCursor(ctx, testEvents(2), []byte("5"))
+	require.NoError(t, err)
+	waitFor(t, second.Accepted())
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 3}
+	require.NoError(t, first.Wait(ctx))
+	requireCheckpoint(t, store, "3")
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 5}
+	require.NoError(t, second.Wait(ctx))
+	requireCheckpoint(t, store, "5")
+	cp, _, err := store.Get()
+	require.NoError(t, err)
+	require.Equal(t, Checkpoint{Cursor: []byte("5"), UUID: "uuid", AcceptedIndex: 5}, cp)
+
+	// a failed batch holds the checkpoint back until Resume
+	producer.mu.Lock()
+	producer.results = []fakeResult{{err: status.Error(codes.InvalidArgument, "bad")}}
+	producer.mu.Unlock()
+	failed, err := p.PublishCursor(ctx, testEvents(1), []byte("6"))
+	require.NoError(t, err)
+	require.Error(t, failed.Wait(ctx))
+	halted, err := p.PublishCursor(ctx, testEvents(1), []byte("7"))
+	require.NoError(t, err)
+	require.ErrorIs(t, halted.Wait(ctx), ErrHalted)
+
+	p.Resume()
+	retried, err := p.PublishCursor(ctx, testEvents(2), []byte("7"))
+	require.NoError(t, err)
+	waitFor(t, retried.Accepted())
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 7}
+	require.NoError(t, retried.Wait(ctx))
+	requireCheckpoint(t, store, "7")
+}
@@ -0,0 +1,267 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package client contains a wrapper around the generated shipper gRPC client
+// that takes care of the behavior every input would otherwise implement by hand.
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/shipperuuid"
+)
+
+// Client wraps a proto.ProducerClient and publishes events to the shipper.
+type Client struct {
+	producer proto.ProducerClient
+	retry    RetryPolicy
+	restarts *RestartTracker
+	limiter  *RateLimiter
+	// validation is nil when the events are not validated
+	validation *helpers.ValidateOptions
+	// monitoring is nil when the stats are not published
+	monitoring *MonitoringConfig
+
+	mu       sync.Mutex
+	closed   bool
+	drainers []drainer
+
+	statsMu sync.Mutex
+	stats   Stats
+}
+
+// Stats are the counters of a Client since its creation.
+type Stats struct {
+	// Published is the number of events accepted by the shipper.
+	Published uint64
+	// Failed is the number of events of the failed publish calls that were
+	// not accepted.
+	Failed uint64
+	// Attempts is the number of PublishEvents calls.
+	Attempts uint64
+	// Retries is the number of attempts that were not the first one of a
+	// publish call.
+	Retries uint64
+	// Lag is the duration of the last publish call, from the call to the
+	// reply, including the retries and the rate limiting.
+	Lag time.Duration
+}
+
+// drainer is implemented by the publishers built on top of a Client, so
+// they can be drained when the client is closed.
+type drainer interface {
+	// drain stops accepting events and waits until the ones already
+	// accepted are delivered or ctx is done. It returns the number of
+	// events that were not confirmed.
+	drain(ctx context.Context) int
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// New creates a new Client publishing through the given producer.
+func New(producer proto.ProducerClient, opts ...Option) *Client {
+	c := &Client{
+		producer: producer,
+		retry:    DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.monitoring != nil {
+		startMonitor(c, *c.monitoring)
+	}
+	return c
+}
+
+// Stats returns the current stats of the client.
+func (c *Client) Stats() Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.stats
+}
+
+// Producer returns the underlying generated client.
+func (c *Client) Producer() proto.ProducerClient {
+	return c.producer
+}
+
+// Publish sends the request to the shipper.
+//
+// If the shipper accepts only some of the events, the remaining ones are sent
+// again according to the retry policy. Transient errors are retried the same way.
+// The returned reply contains the total number of accepted events and the
+// accepted index of the last successful attempt. When the request has
+// return_event_indexes, it also has the index of every accepted event across
+// the attempts. If the attempts run out once some of the events are accepted,
+// the reply comes with a *PartialError.
+//
+// With validation, the call fails with an *InvalidEventError if an event is
+// invalid. With a rate limiter, the call first waits until the events can be published.
+func (c *Client) Publish(ctx context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	return c.publish(ctx, protoBatch{req}, nil)
+}
+
+// PublishMetrics sends metric samples to the shipper. It behaves like
+// Publish, the samples are counted like events in the replies.
+func (c *Client) PublishMetrics(ctx context.Context, metrics []*messages.MetricEvent) (*messages.PublishReply, error) {
+	return c.publish(ctx, protoBatch{&messages.PublishRequest{Metrics: metrics}}, nil)
+}
+
+// PublishSignals sends events of any kind to the shipper. It behaves like
+// Publish, the signals are counted like events in the replies.
+func (c *Client) PublishSignals(ctx context.Context, signals []*messages.EventUnion) (*messages.PublishReply, error) {
+	return c.publish(ctx, protoBatch{&messages.PublishRequest{Signals: signals}}, nil)
+}
+
+// publish implements Publish, onAttempt is called after every attempt in
+// addition to the OnAttempt function of the retry policy.
+func (c *Client) publish(ctx context.Context, b batch, onAttempt func(Attempt)) (*messages.PublishReply, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	if err := c.validate(b); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	accepted := 0
+	retry := c.retry
+	next := retry.OnAttempt
+	retry.OnAttempt = func(a Attempt) {
+		accepted += a.Accepted
+		c.statsMu.Lock()
+		c.stats.Attempts++
+		if a.Number > 1 {
+			c.stats.Retries++
+		}
+		c.statsMu.Unlock()
+		if next != nil {
+			next(a)
+		}
+		if onAttempt != nil {
+			onAttempt(a)
+		}
+	}
+
+	reply, err := c.send(ctx, retry, b)
+	c.statsMu.Lock()
+	c.stats.Published += uint64(accepted)
+	if err != nil && err != ErrShipperRestarted {
+		c.stats.Failed += uint64(b.len() - accepted)
+	}
+	c.stats.Lag = time.Since(start)
+	c.statsMu.Unlock()
+	return reply, err
+}
+
+// send publishes the batch with the retry policy, once the rate limiter
+// allows it, and tracks the shipper restarts.
+func (c *Client) send(ctx context.Context, retry RetryPolicy, b batch) (*messages.PublishReply, error) {
+	if c.limiter != nil {
+		if _, err := c.limiter.wait(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+	if c.restarts == nil {
+		return retry.publish(ctx, c.producer, b)
+	}
+
+	if b.uuid() == "" {
+		b.setUUID(c.restarts.UUID())
+	}
+	reply, err := retry.publish(ctx, c.producer, b)
+	if reply == nil {
+		return nil, err
+	}
+	// a *PartialError comes with the accepted events, they're tracked too
+	c.restarts.Published(b.uuid(), reply)
+	if shipperuuid.Restarted(b.uuid(), reply.GetUuid()) {
+		return reply, ErrShipperRestarted
+	}
+	return reply, err
+}
+
+// Flush asks the shipper to persist the events up to index, the accepted
+// index of a reply, or all the accepted events when index is zero, e.g. before
+// checkpointing. It returns the persisted index, and ErrShipperRestarted if
+// uuid is set and the shipper restarted since it was obtained.
+func (c *Client) Flush(ctx context.Context, uuid string, index uint64) (uint64, error) {
+	reply, err := c.producer.Flush(ctx, &messages.FlushRequest{AcceptedIndex: index})
+	if err != nil {
+		return 0, err
+	}
+	if shipperuuid.Restarted(uuid, reply.GetUuid()) {
+		return reply.GetPersistedIndex(), ErrShipperRestarted
+	}
+	return reply.GetPersistedIndex(), nil
+}
+
+// Cursor returns the last cursor stored under key by the shipper, see
+// PublishRequest.cursor, or nil if there is none. It returns
+// ErrShipperRestarted if uuid is set and the shipper restarted since it was
+// obtained: the cursor is still the last persisted one.
+func (c *Client) Cursor(ctx context.Context, uuid, key string) ([]byte, error) {
+	reply, err := c.producer.GetCursor(ctx, &messages.GetCursorRequest{Key: key})
+	if err != nil {
+		return nil, err
+	}
+	if shipperuuid.Restarted(uuid, reply.GetUuid()) {
+		return reply.GetCursor(), ErrShipperRestarted
+	}
+	return reply.GetCursor(), nil
+}
+
+// GlobalMetadata returns the current global metadata of the shipper and its
+// version, for the events to reference it with their global_metadata_version
+// instead of carrying it. The versions are specific to the shipper process: a
+// publish fails with a FailedPrecondition error when the version is unknown,
+// e.g. after a restart, and the global metadata must be fetched again.
+func (c *Client) GlobalMetadata(ctx context.Context) (uint64, *messages.Struct, error) {
+	reply, err := c.producer.GetGlobalMetadata(ctx, &messages.GetGlobalMetadataRequest{})
+	if err != nil {
+		return 0, nil, err
+	}
+	return reply.GetVersion(), reply.GetMetadata(), nil
+}
+
+// Close stops accepting events and drains the publishers created on top of
+// the client, most recently created first: batches are flushed and Close waits
+// until the persisted index of the shipper covers all the events in flight.
+//
+// It returns the number of events that were not confirmed as persisted, and
+// the context error if it was done before everything was confirmed.
+func (c *Client) Close(ctx context.Context) (unconfirmed int, err error) {
+	c.mu.Lock()
+	drainers := c.drainers
+	c.drainers = nil
+	c.mu.Unlock()
+
+	for i := len(drainers) - 1; i >= 0; i-- {
+		unconfirmed += drainers[i].drain(ctx)
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	if unconfirmed > 0 {
+		return unconfirmed, ctx.Err()
+	}
+	return 0, nil
+}
+
+func (c *Client) register(d drainer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.drainers = append(c.drainers, d)
+}
@@ -0,0 +1,440 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// fakeProducer returns the scripted results in order, then accepts everything.
+type fakeProducer struct {
+	proto.ProducerClient
+
+	mu       sync.Mutex
+	uuid     string
+	index    uint64
+	results  []fakeResult
+	requests []*messages.PublishRequest
+	flushes  []*messages.FlushRequest
+	// cursors are the cursors of the fully accepted requests by key
+	cursors map[string][]byte
+	// global is the global metadata, version 1 when set
+	global *messages.Struct
+	// persisted is sent on the PersistedIndex stream
+	persisted chan *messages.PersistedIndexReply
+	// gate, if set, blocks every call until it receives a value or is closed
+	gate chan struct{}
+}
+
+type fakeResult struct {
+	accept int
+	err    error
+}
+
+func (p *fakeProducer) PublishEvents(ctx context.Context, req *messages.PublishRequest, _ ...grpc.CallOption) (*messages.PublishReply, error) {
+	if p.gate != nil {
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-p.gate:
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, req)
+
+	accept := len(req.GetEvents())
+	if len(p.results) > 0 {
+		r := p.results[0]
+		p.results = p.results[1:]
+		if r.err != nil {
+			return nil, r.err
+		}
+		accept = r.accept
+	}
+	if req.GetUuid() != "" && req.GetUuid() != p.uuid {
+		accept = 0
+	}
+	p.index += uint64(accept)
+	if len(req.GetCursor()) > 0 && accept > 0 && accept == len(req.GetEvents()) {
+		if p.cursors == nil {
+			p.cursors = make(map[string][]byte)
+		}
+		p.cursors[req.GetCursorKey()] = req.GetCursor()
+	}
+	return &messages.PublishReply{
+		Uuid:          p.uuid,
+		AcceptedCount: uint32(accept),
+		AcceptedIndex: p.index,
+	}, nil
+}
+
+func (p *fakeProducer) PersistedIndex(ctx context.Context, _ *messages.PersistedIndexRequest, _ ...grpc.CallOption) (proto.Producer_PersistedIndexClient, error) {
+	return &fakePersistedStream{ctx: ctx, replies: p.persisted}, nil
+}
+
+func (p *fakeProducer) Flush(_ context.Context, req *messages.FlushRequest, _ ...grpc.CallOption) (*messages.FlushReply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.flushes = append(p.flushes, req)
+	index := req.GetAcceptedIndex()
+	if index == 0 {
+		index = p.index
+	}
+	return &messages.FlushReply{Uuid: p.uuid, PersistedIndex: index}, nil
+}
+
+func (p *fakeProducer) WhoAmI(context.Context, *messages.WhoAmIRequest, ...grpc.CallOption) (*messages.WhoAmIReply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &messages.WhoAmIReply{Uuid: p.uuid}, nil
+}
+
+func (p *fakeProducer) GetCursor(_ context.Context, req *messages.GetCursorRequest, _ ...grpc.CallOption) (*messages.GetCursorReply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return &messages.GetCursorReply{Uuid: p.uuid, Cursor: p.cursors[req.GetKey()]}, nil
+}
+
+func (p *fakeProducer) GetGlobalMetadata(context.Context, *messages.GetGlobalMetadataRequest, ...grpc.CallOption) (*messages.GetGlobalMetadataReply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	reply := &messages.GetGlobalMetadataReply{Uuid: p.uuid}
+	if p.global != nil {
+		reply.Version = 1
+		reply.Metadata = p.global
+	}
+	return reply, nil
+}
+
+type fakePersistedStream struct {
+	grpc.ClientStream
+
+	ctx     context.Context
+	replies chan *messages.PersistedIndexReply
+}
+
+func (s *fakePersistedStream) Recv() (*messages.PersistedIndexReply, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, status.FromContextError(s.ctx.Err()).Err()
+	case r, ok := <-s.replies:
+		if !ok {
+			return nil, io.EOF
+		}
+		return r, nil
+	}
+}
+
+func testEvents(n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = &messages.Event{}
+	}
+	return events
+}
+
+func fastRetries(attempts *[]Attempt) RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	p.OnAttempt = func(a Attempt) {
+		*attempts = append(*attempts, a)
+	}
+	return p
+}
+
+func TestPublishRetries(t *testing.T) {
+	t.Run("partial accepts are retried", func(t *testing.T) {
+		var attempts []Attempt
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 2}, {accept: 0}, {accept: 1}}}
+		c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+		reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(5)})
+		require.NoError(t, err)
+		require.Equal(t, uint32(5), reply.GetAcceptedCount())
+		require.Equal(t, uint64(5), reply.GetAcceptedIndex())
+		require.Len(t, producer.requests, 4)
+		require.Len(t, producer.requests[1].GetEvents(), 3)
+		require.Len(t, producer.requests[3].GetEvents(), 2)
+		require.Len(t, attempts, 4)
+		require.Zero(t, attempts[3].Backoff)
+	})
+
+	t.Run("event indexes across attempts", func(t *testing.T) {
+		var attempts []Attempt
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 2}, {accept: 1}}}
+		policy := fastRetries(&attempts)
+		record := policy.OnAttempt
+		policy.OnAttempt = func(a Attempt) {
+			record(a)
+			// another client publishes between the attempts
+			producer.index += 10
+		}
+		c := New(producer, WithRetryPolicy(policy))
+
+		reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3), ReturnEventIndexes: true})
+		require.NoError(t, err)
+		require.Equal(t, []uint64{1, 2, 13}, reply.GetEventIndexes())
+		require.True(t, producer.requests[1].GetReturnEventIndexes())
+
+		reply, err = c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		require.Empty(t, reply.GetEventIndexes())
+	})
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		var attempts []Attempt
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{
+			{err: status.Error(codes.Unavailable, "restarting")},
+			{err: status.Error(codes.ResourceExhausted, "queue is full")},
+		}}
+		c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+		reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+		require.NoError(t, err)
+		require.Equal(t, uint32(3), reply.GetAcceptedCount())
+		require.Len(t, attempts, 3)
+		require.Equal(t, codes.Unavailable, status.Code(attempts[0].Err))
+	})
+
+	t.Run("other errors are not retried", func(t *testing.T) {
+		var attempts []Attempt
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{
+			{err: status.Error(codes.InvalidArgument, "bad event")},
+		}}
+		c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+		_, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Len(t, attempts, 1)
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		var attempts []Attempt
+		policy := fastRetries(&attempts)
+		policy.MaxAttempts = 2
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 1}, {accept: 1}}}
+		c := New(producer, WithRetryPolicy(policy))
+
+		reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+		var partial *PartialError
+		require.ErrorAs(t, err, &partial)
+		require.Equal(t, PartialError{Accepted: 2, Total: 3}, *partial)
+		require.Equal(t, uint32(2), reply.GetAcceptedCount())
+		require.Len(t, attempts, 2)
+	})
+
+	t.Run("error after a partial accept", func(t *testing.T) {
+		var attempts []Attempt
+		invalid := status.Error(codes.InvalidArgument, "bad event")
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 1}, {err: invalid}}}
+		c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+		reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+		var partial *PartialError
+		require.ErrorAs(t, err, &partial)
+		require.Equal(t, 1, partial.Accepted)
+		require.ErrorIs(t, err, invalid)
+		require.Equal(t, uint32(1), reply.GetAcceptedCount())
+	})
+
+	t.Run("retry info from the shipper is honored", func(t *testing.T) {
+		var attempts []Attempt
+		st, err := status.New(codes.ResourceExhausted, "slow down").WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(20 * time.Millisecond),
+		})
+		require.NoError(t, err)
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{err: st.Err()}}}
+		c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+		_, err = c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		require.Equal(t, 20*time.Millisecond, attempts[0].Backoff)
+	})
+
+	t.Run("deadline shorter than the backoff", func(t *testing.T) {
+		var attempts []Attempt
+		policy := fastRetries(&attempts)
+		policy.InitialBackoff = time.Minute
+		policy.MaxBackoff = time.Minute
+		producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{err: status.Error(codes.Unavailable, "down")}}}
+		c := New(producer, WithRetryPolicy(policy))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := c.Publish(ctx, &messages.PublishRequest{Events: testEvents(1)})
+		require.Equal(t, codes.Unavailable, status.Code(err))
+		require.Len(t, attempts, 1)
+	})
+
+	t.Run("uuid mismatch stops retrying", func(t *testing.T) {
+		var attempts []Attempt
+		producer := &fakeProducer{uuid: "new"}
+		c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+		reply, err := c.Publish(context.Background(), &messages.PublishRequest{Uuid: "old", Events: testEvents(1)})
+		require.NoError(t, err)
+		require.Equal(t, "new", reply.GetUuid())
+		require.Zero(t, reply.GetAcceptedCount())
+		require.Len(t, attempts, 1)
+	})
+}
+
+func TestClientStats(t *testing.T) {
+	var attempts []Attempt
+	producer := &fakeProducer{uuid: "uuid", results: []fakeResult{
+		{accept: 2},
+		{err: status.Error(codes.Unavailable, "down")},
+		{accept: 3},
+		{err: status.Error(codes.InvalidArgument, "invalid")},
+	}}
+	c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+
+	_, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(5)})
+	require.NoError(t, err)
+	_, err = c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(4)})
+	require.Error(t, err)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(5), stats.Published)
+	require.Equal(t, uint64(4), stats.Failed)
+	require.Equal(t, uint64(4), stats.Attempts)
+	require.Equal(t, uint64(2), stats.Retries)
+	require.Positive(t, int64(stats.Lag))
+	require.Len(t, attempts, 4, "the OnAttempt function of the policy is still called")
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 2}
+	require.Equal(t, 100*time.Millisecond, p.Backoff(1))
+	require.Equal(t, 400*time.Millisecond, p.Backoff(3))
+	require.Equal(t, time.Second, p.Backoff(10))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(1)
+		require.GreaterOrEqual(t, d, 50*time.Millisecond)
+		require.LessOrEqual(t, d, 150*time.Millisecond)
+	}
+
+	// the jitter doesn't go past the cap
+	for i := 0; i < 100; i++ {
+		d := p.Backoff(10)
+		require.GreaterOrEqual(t, d, 500*time.Millisecond)
+		require.LessOrEqual(t, d, time.Second)
+	}
+}
+
+func TestClientFlush(t *testing.T) {
+	producer := &fakeProducer{uuid: "uuid"}
+	c := New(producer)
+	reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+
+	persisted, err := c.Flush(context.Background(), reply.GetUuid(), 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), persisted)
+	persisted, err = c.Flush(context.Background(), "", 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), persisted)
+	require.Equal(t, []*messages.FlushRequest{{AcceptedIndex: 2}, {}}, producer.flushes)
+
+	_, err = c.Flush(context.Background(), "old", 2)
+	require.ErrorIs(t, err, ErrShipperRestarted)
+}
+
+func TestClientCursor(t *testing.T) {
+	producer := &fakeProducer{uuid: "uuid"}
+	c := New(producer)
+	reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3), Cursor: []byte("offset=42"), CursorKey: "file"})
+	require.NoError(t, err)
+
+	cursor, err := c.Cursor(context.Background(), reply.GetUuid(), "file")
+	require.NoError(t, err)
+	require.Equal(t, []byte("offset=42"), cursor)
+	cursor, err = c.Cursor(context.Background(), "", "other")
+	require.NoError(t, err)
+	require.Nil(t, cursor)
+
+	_, err = c.Cursor(context.Background(), "old", "file")
+	require.ErrorIs(t, err, ErrShipperRestarted)
+}
+
+func TestClientGlobalMetadata(t *testing.T) {
+	global := &messages.Struct{Data: map[string]*messages.Value{"host": helpers.NewStringValue("host-1")}}
+	c := New(&fakeProducer{uuid: "uuid", global: global})
+	version, metadata, err := c.GlobalMetadata(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), version)
+	require.Same(t, global, metadata)
+}
+
+func TestClientClose(t *testing.T) {
+	t.Run("waits for the events to be persisted", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply, 1)}
+		c := New(producer)
+		p := NewAsyncProducer(c, DefaultAsyncConfig())
+		b := NewBatcher(p, BatcherConfig{MaxEvents: 100})
+
+		var f *Future
+		for _, e := range testEvents(3) {
+			var err error
+			f, err = b.Add(context.Background(), e)
+			require.NoError(t, err)
+		}
+		go func() {
+			// the batch is only flushed by Close
+			<-f.Accepted()
+			producer.persisted <- &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 3}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		unconfirmed, err := c.Close(ctx)
+		require.NoError(t, err)
+		require.Zero(t, unconfirmed)
+		require.NoError(t, f.Err())
+
+		_, err = b.Add(context.Background(), &messages.Event{})
+		require.ErrorIs(t, err, ErrClosed)
+		_, err = c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.ErrorIs(t, err, ErrClosed)
+	})
+
+	t.Run("reports unconfirmed events", func(t *testing.T) {
+		producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply)}
+		c := New(producer)
+		p := NewAsyncProducer(c, DefaultAsyncConfig())
+		q := NewMemoryQueue(p, QueueConfig{})
+
+		f, err := q.Publish(context.Background(), testEvents(2))
+		require.NoError(t, err)
+		_, err = p.Publish(context.Background(), testEvents(3))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		unconfirmed, err := c.Close(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 5, unconfirmed)
+		require.ErrorIs(t, f.Err(), ErrClosed)
+	})
+}
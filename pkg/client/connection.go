@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/keepalive"
+)
+
+// ConnectionConfig tunes the gRPC connection to the shipper.
+//
+// The grpc-go defaults target remote servers and back off for up to two minutes
+// between reconnections, the shipper runs locally and is expected to be back
+// within seconds after a restart.
+type ConnectionConfig struct {
+	// KeepaliveTime is the idle time after which the client pings the shipper.
+	// The shipper must allow pings this frequent through its keepalive.EnforcementPolicy.
+	// Zero disables keepalive pings.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is the time to wait for a ping acknowledgement before
+	// closing the connection.
+	KeepaliveTimeout time.Duration
+	// MinConnectTimeout is the minimum time given to a connection attempt.
+	MinConnectTimeout time.Duration
+	// BaseDelay is the delay before the first reconnection attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between reconnection attempts.
+	MaxDelay time.Duration
+	// Multiplier is applied to the delay after every failed attempt.
+	Multiplier float64
+	// Jitter randomizes the delays by up to this fraction.
+	Jitter float64
+}
+
+// DefaultConnectionConfig returns the connection settings used by Dial.
+func DefaultConnectionConfig() ConnectionConfig {
+	return ConnectionConfig{
+		KeepaliveTime:     30 * time.Second,
+		KeepaliveTimeout:  10 * time.Second,
+		MinConnectTimeout: 2 * time.Second,
+		BaseDelay:         100 * time.Millisecond,
+		MaxDelay:          5 * time.Second,
+		Multiplier:        1.6,
+		Jitter:            0.2,
+	}
+}
+
+// DialOptions returns the gRPC dial options for the configuration.
+func (c ConnectionConfig) DialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  c.BaseDelay,
+				Multiplier: c.Multiplier,
+				Jitter:     c.Jitter,
+				MaxDelay:   c.MaxDelay,
+			},
+			MinConnectTimeout: c.MinConnectTimeout,
+		}),
+	}
+	if c.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    c.KeepaliveTime,
+			Timeout: c.KeepaliveTimeout,
+		}))
+	}
+	return opts
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"sort"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// EncodeMetadataDeltas returns a copy of the request where the metadata of
+// each event only holds the keys added or changed since the previous event,
+// with a delta listing the removed keys. An event keeps its full metadata
+// when the delta isn't smaller, e.g. when the metadata of the events are
+// unrelated. Uniform batches shrink to the metadata of the first event.
+//
+// The events of the request are not modified. The request is returned as is
+// when no event benefits from a delta. The shipper decodes it with
+// server.DecodeMetadataDeltas.
+func EncodeMetadataDeltas(req *messages.PublishRequest) *messages.PublishRequest {
+	events := req.GetEvents()
+	var encoded []*messages.Event
+	for i := 1; i < len(events); i++ {
+		metadata, delta := metadataDelta(events[i-1].GetMetadata(), events[i].GetMetadata())
+		if delta == nil {
+			continue
+		}
+		if encoded == nil {
+			encoded = append([]*messages.Event(nil), events...)
+		}
+		e := shallowCopy(events[i])
+		e.Metadata = metadata
+		e.MetadataDelta = delta
+		encoded[i] = e
+	}
+	if encoded == nil {
+		return req
+	}
+	encodedReq := requestHeader(req)
+	encodedReq.Events = encoded
+	encodedReq.StringTable = req.GetStringTable()
+	return encodedReq
+}
+
+// MetadataDeltaInterceptor returns a client interceptor encoding the
+// metadata of the events of the PublishEvents calls as deltas. The requests
+// seen by the callers are left untouched. It must come before a
+// StringTableInterceptor and a PackingInterceptor.
+func MetadataDeltaInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if r, ok := req.(*messages.PublishRequest); ok {
+			req = EncodeMetadataDeltas(r)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// metadataDelta returns the metadata holding the keys of current that are
+// not in previous or have another value, and the delta listing the keys of
+// previous missing from current. The delta is nil when it has as many keys as
+// current.
+func metadataDelta(previous, current *messages.Struct) (*messages.Struct, *messages.MetadataDelta) {
+	if current == nil || previous == nil {
+		return nil, nil
+	}
+	changed := map[string]*messages.Value{}
+	for k, v := range current.GetData() {
+		if p, ok := previous.GetData()[k]; !ok || !proto.Equal(p, v) {
+			changed[k] = v
+		}
+	}
+	var removed []string
+	for k := range previous.GetData() {
+		if _, ok := current.GetData()[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	if len(changed)+len(removed) >= len(current.GetData()) {
+		return nil, nil
+	}
+	sort.Strings(removed)
+
+	metadata := &messages.Struct{}
+	if len(changed) > 0 {
+		metadata.Data = changed
+	}
+	return metadata, &messages.MetadataDelta{RemovedKeys: removed}
+}
@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func metadataEvent(kv ...string) *messages.Event {
+	data := map[string]*messages.Value{}
+	for i := 0; i < len(kv); i += 2 {
+		data[kv[i]] = helpers.NewStringValue(kv[i+1])
+	}
+	return &messages.Event{Metadata: &messages.Struct{Data: data}}
+}
+
+func TestEncodeMetadataDeltas(t *testing.T) {
+	req := &messages.PublishRequest{Uuid: "uuid", Events: []*messages.Event{
+		metadataEvent("a", "1", "b", "2", "c", "3"),
+		metadataEvent("a", "1", "b", "2", "c", "3"),
+		metadataEvent("a", "1", "b", "changed", "c", "3"),
+		metadataEvent("a", "1", "b", "changed"),
+		metadataEvent("x", "1", "y", "2"),
+		{},
+	}}
+	original := proto.Clone(req)
+
+	encoded := EncodeMetadataDeltas(req)
+	require.True(t, proto.Equal(original, req), "the request must not change")
+	require.Equal(t, "uuid", encoded.GetUuid())
+	events := encoded.GetEvents()
+	require.Same(t, req.GetEvents()[0], events[0])
+
+	// same metadata
+	require.NotNil(t, events[1].GetMetadataDelta())
+	require.Empty(t, events[1].GetMetadata().GetData())
+	// a changed key
+	require.Empty(t, events[2].GetMetadataDelta().GetRemovedKeys())
+	require.Equal(t, []string{"b"}, keys(events[2].GetMetadata()))
+	// a removed key
+	require.Equal(t, []string{"c"}, events[3].GetMetadataDelta().GetRemovedKeys())
+	require.Empty(t, events[3].GetMetadata().GetData())
+	// unrelated metadata and no metadata are left as is
+	require.Same(t, req.GetEvents()[4], events[4])
+	require.Same(t, req.GetEvents()[5], events[5])
+
+	require.Less(t, proto.Size(encoded), proto.Size(req))
+}
+
+func TestEncodeMetadataDeltasUnrelated(t *testing.T) {
+	req := &messages.PublishRequest{Events: []*messages.Event{
+		metadataEvent("a", "1"),
+		metadataEvent("a", "2"),
+	}}
+	require.Same(t, req, EncodeMetadataDeltas(req))
+}
+
+func keys(s *messages.Struct) []string {
+	var keys []string
+	for k := range s.GetData() {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestEncodeMetadataDeltasKeepsFields(t *testing.T) {
+	e := populatedEvent(t)
+	e.Metadata = metadataEvent("a", "1", "b", "changed").GetMetadata()
+	req := &messages.PublishRequest{Events: []*messages.Event{metadataEvent("a", "1", "b", "2"), e}}
+
+	encoded := EncodeMetadataDeltas(req)
+	require.NotNil(t, encoded.GetEvents()[1].GetMetadataDelta())
+	requireEventKept(t, e, encoded.GetEvents()[1], "metadata", "metadata_delta")
+}
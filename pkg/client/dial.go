@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	unixScheme  = "unix://"
+	npipeScheme = "npipe://"
+)
+
+// Dial connects to a shipper endpoint using the agent-style endpoint format:
+//   - unix:///path/to/socket for Unix domain sockets
+//   - npipe:///name for Windows named pipes
+//   - host:port for TCP
+//
+// Connections are insecure unless transport credentials are given in opts.
+// The DefaultConnectionConfig settings apply, opts can override them.
+func Dial(ctx context.Context, endpoint string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOpts, err := EndpointDialOptions(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	dialOpts = append(dialOpts, DefaultConnectionConfig().DialOptions()...)
+	dialOpts = append(dialOpts, opts...)
+
+	conn, err := grpc.DialContext(ctx, "passthrough:///"+endpointAddress(endpoint), dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial shipper at %s: %w", endpoint, err)
+	}
+	return conn, nil
+}
+
+// EndpointDialOptions returns the dial options needed to connect to the given endpoint.
+func EndpointDialOptions(endpoint string) ([]grpc.DialOption, error) {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+
+	switch {
+	case strings.HasPrefix(endpoint, unixScheme):
+		opts = append(opts, grpc.WithContextDialer(dialUnix))
+	case strings.HasPrefix(endpoint, npipeScheme):
+		opts = append(opts, grpc.WithContextDialer(dialNamedPipe))
+	case strings.Contains(endpoint, "://"):
+		return nil, fmt.Errorf("unsupported shipper endpoint %q", endpoint)
+	}
+	return opts, nil
+}
+
+// endpointAddress strips the scheme from the endpoint.
+func endpointAddress(endpoint string) string {
+	switch {
+	case strings.HasPrefix(endpoint, unixScheme):
+		return strings.TrimPrefix(endpoint, unixScheme)
+	case strings.HasPrefix(endpoint, npipeScheme):
+		return npipePath(strings.TrimPrefix(endpoint, npipeScheme))
+	}
+	return endpoint
+}
+
+// npipePath converts the name of a named pipe into its full path.
+func npipePath(name string) string {
+	name = strings.TrimLeft(name, `/\`)
+	if strings.HasPrefix(name, `.\pipe\`) || strings.HasPrefix(name, "./pipe/") {
+		name = name[len(`.\pipe\`):]
+	}
+	return `\\.\pipe\` + strings.ReplaceAll(name, "/", `\`)
+}
+
+func dialUnix(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", addr)
+}
@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !windows
+// +build !windows
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+func dialNamedPipe(_ context.Context, _ string) (net.Conn, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+type acceptAllServer struct {
+	proto.UnimplementedProducerServer
+}
+
+func (acceptAllServer) PublishEvents(_ context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	return &messages.PublishReply{Uuid: "uuid", AcceptedCount: uint32(len(req.GetEvents()))}, nil
+}
+
+func TestEndpointAddress(t *testing.T) {
+	cases := map[string]string{
+		"unix:///var/run/shipper.sock": "/var/run/shipper.sock",
+		"npipe:///shipper":             `\\.\pipe\shipper`,
+		`npipe:///.\pipe\shipper`:      `\\.\pipe\shipper`,
+		"npipe:///elastic/shipper":     `\\.\pipe\elastic\shipper`,
+		"localhost:50051":              "localhost:50051",
+	}
+	for endpoint, expected := range cases {
+		require.Equal(t, expected, endpointAddress(endpoint), endpoint)
+	}
+
+	_, err := EndpointDialOptions("http://localhost")
+	require.Error(t, err)
+}
+
+func TestDialUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets are not tested on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "shipper.sock")
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	proto.RegisterProducerServer(srv, acceptAllServer{})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, "unix://"+path, grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := New(proto.NewProducerClient(conn)).Publish(ctx, &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+}
+
+func TestConnectionConfigDialOptions(t *testing.T) {
+	config := DefaultConnectionConfig()
+	require.Len(t, config.DialOptions(), 2)
+
+	config.KeepaliveTime = 0
+	require.Len(t, config.DialOptions(), 1)
+}
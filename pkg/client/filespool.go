@@ -0,0 +1,276 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+const (
+	spoolDataFile = "spool.dat"
+	spoolAckFile  = "spool.ack"
+	// record header: id, data length, data checksum
+	spoolHeaderSize = 8 + 4 + 4
+)
+
+// FileSpool is a Spool storing batches in an append-only file.
+//
+// Every batch is written as a record with its ID and a checksum, the ID of the
+// last acknowledged batch is stored in a separate file, followed by the ID of
+// the oldest batch and its number of acknowledged events when it's partially
+// acknowledged. When all the batches are acknowledged the data file is
+// truncated. A partially written record, e.g. after a crash, is discarded when
+// the spool is opened.
+type FileSpool struct {
+	mu      sync.Mutex
+	dir     string
+	data    *os.File
+	size    int64
+	entries []spoolEntry
+	nextID  uint64
+	acked   uint64
+	// partial is the number of acknowledged events of the batch partialID
+	partialID uint64
+	partial   int
+}
+
+type spoolEntry struct {
+	id     uint64
+	offset int64
+	length int
+}
+
+// OpenFileSpool opens the spool stored in the given directory, creating it if needed.
+func OpenFileSpool(dir string) (*FileSpool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create the spool directory: %w", err)
+	}
+	s := &FileSpool{dir: dir}
+
+	ack, err := os.ReadFile(filepath.Join(dir, spoolAckFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read the spool acknowledgement: %w", err)
+	default:
+		if err := s.parseAck(string(ack)); err != nil {
+			return nil, fmt.Errorf("invalid spool acknowledgement: %w", err)
+		}
+	}
+	s.nextID = s.acked + 1
+
+	s.data, err = os.OpenFile(filepath.Join(dir, spoolDataFile), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the spool: %w", err)
+	}
+	if err := s.load(); err != nil {
+		s.data.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseAck parses the content of the acknowledgement file, the acknowledged
+// ID optionally followed by the partially acknowledged batch.
+func (s *FileSpool) parseAck(ack string) error {
+	fields := strings.Fields(ack)
+	if len(fields) != 1 && len(fields) != 3 {
+		return fmt.Errorf("%d fields, want 1 or 3", len(fields))
+	}
+	var err error
+	if s.acked, err = strconv.ParseUint(fields[0], 10, 64); err != nil {
+		return err
+	}
+	if len(fields) == 3 {
+		if s.partialID, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+			return err
+		}
+		if s.partial, err = strconv.Atoi(fields[2]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAck replaces the acknowledgement file.
+func (s *FileSpool) writeAck(acked, partialID uint64, partial int) error {
+	content := strconv.FormatUint(acked, 10)
+	if partial > 0 {
+		content += " " + strconv.FormatUint(partialID, 10) + " " + strconv.Itoa(partial)
+	}
+	tmp := filepath.Join(s.dir, spoolAckFile+".tmp")
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write the spool acknowledgement: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, spoolAckFile)); err != nil {
+		return fmt.Errorf("failed to write the spool acknowledgement: %w", err)
+	}
+	return nil
+}
+
+// load scans the data file and indexes the batches that are not acknowledged.
+func (s *FileSpool) load() error {
+	var header [spoolHeaderSize]byte
+	var offset int64
+	for {
+		if _, err := s.data.ReadAt(header[:], offset); err != nil {
+			break
+		}
+		id := binary.BigEndian.Uint64(header[0:8])
+		length := int(binary.BigEndian.Uint32(header[8:12]))
+		checksum := binary.BigEndian.Uint32(header[12:16])
+
+		buf := make([]byte, length)
+		if _, err := s.data.ReadAt(buf, offset+spoolHeaderSize); err != nil || crc32.ChecksumIEEE(buf) != checksum {
+			break
+		}
+		if id > s.acked {
+			s.entries = append(s.entries, spoolEntry{id: id, offset: offset + spoolHeaderSize, length: length})
+		}
+		if id >= s.nextID {
+			s.nextID = id + 1
+		}
+		offset += spoolHeaderSize + int64(length)
+	}
+
+	// drop anything after the last valid record
+	if err := s.data.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate the spool: %w", err)
+	}
+	s.size = offset
+	return nil
+}
+
+// Append implements Spool.
+func (s *FileSpool) Append(events []*messages.Event) (uint64, error) {
+	buf, err := proto.Marshal(&messages.PublishRequest{Events: events})
+	if err != nil {
+		return 0, fmt.Errorf("failed to serialize the events: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	record := make([]byte, spoolHeaderSize+len(buf))
+	binary.BigEndian.PutUint64(record[0:8], id)
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(buf)))
+	binary.BigEndian.PutUint32(record[12:16], crc32.ChecksumIEEE(buf))
+	copy(record[spoolHeaderSize:], buf)
+
+	if _, err := s.data.WriteAt(record, s.size); err != nil {
+		return 0, fmt.Errorf("failed to write to the spool: %w", err)
+	}
+	if err := s.data.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync the spool: %w", err)
+	}
+
+	s.entries = append(s.entries, spoolEntry{id: id, offset: s.size + spoolHeaderSize, length: len(buf)})
+	s.size += int64(len(record))
+	s.nextID++
+	return id, nil
+}
+
+// Peek implements Spool.
+func (s *FileSpool) Peek() (uint64, []*messages.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.entries) == 0 {
+		return 0, nil, io.EOF
+	}
+	entry := s.entries[0]
+	buf := make([]byte, entry.length)
+	if _, err := s.data.ReadAt(buf, entry.offset); err != nil {
+		return 0, nil, fmt.Errorf("failed to read from the spool: %w", err)
+	}
+	var req messages.PublishRequest
+	if err := proto.Unmarshal(buf, &req); err != nil {
+		return 0, nil, fmt.Errorf("failed to deserialize the events: %w", err)
+	}
+	events := req.GetEvents()
+	if entry.id == s.partialID && s.partial <= len(events) {
+		events = events[s.partial:]
+	}
+	return entry.id, events, nil
+}
+
+// Ack implements Spool.
+func (s *FileSpool) Ack(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id <= s.acked {
+		return nil
+	}
+	partialID, partial := s.partialID, s.partial
+	if partialID <= id {
+		partialID, partial = 0, 0
+	}
+	if err := s.writeAck(id, partialID, partial); err != nil {
+		return err
+	}
+	s.acked = id
+	s.partialID, s.partial = partialID, partial
+
+	var i int
+	for i < len(s.entries) && s.entries[i].id <= id {
+		i++
+	}
+	s.entries = s.entries[i:]
+	if len(s.entries) == 0 {
+		if err := s.data.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate the spool: %w", err)
+		}
+		s.size = 0
+	}
+	return nil
+}
+
+// AckPartial implements Spool.
+func (s *FileSpool) AckPartial(id uint64, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n <= 0 || id <= s.acked {
+		return nil
+	}
+	partial := n
+	if id == s.partialID {
+		partial += s.partial
+	}
+	if err := s.writeAck(s.acked, id, partial); err != nil {
+		return err
+	}
+	s.partialID, s.partial = id, partial
+	return nil
+}
+
+// Len implements Spool.
+func (s *FileSpool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Close implements Spool.
+func (s *FileSpool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Close()
+}
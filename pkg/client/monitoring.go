@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// MonitoringConfig configures the self-monitoring of a Client.
+type MonitoringConfig struct {
+	// Period is how often the stats of the client are published.
+	Period time.Duration
+	// DataStream is the data stream of the monitoring events.
+	DataStream *messages.DataStream
+	// InputID is the input id of the source of the monitoring events, e.g.
+	// the id of the input owning the client.
+	InputID string
+}
+
+// DefaultMonitoringConfig returns the default MonitoringConfig.
+func DefaultMonitoringConfig() MonitoringConfig {
+	return MonitoringConfig{
+		Period: 10 * time.Second,
+		DataStream: &messages.DataStream{
+			Type:      "metrics",
+			Dataset:   "elastic_agent.shipper_client",
+			Namespace: "default",
+		},
+	}
+}
+
+// WithMonitoring publishes the stats of the client every period as an event
+// on the monitoring data stream, through the client itself, until it's
+// closed. The events hold the totals of the stats under shipper_client, and
+// the rate of the published events since the previous monitoring event. The
+// monitoring events are counted in the stats, their failures are ignored.
+func WithMonitoring(config MonitoringConfig) Option {
+	return func(c *Client) {
+		c.monitoring = &config
+	}
+}
+
+// monitor publishes the stats of a client, see WithMonitoring.
+type monitor struct {
+	client *Client
+	config MonitoringConfig
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func startMonitor(c *Client, config MonitoringConfig) {
+	m := &monitor{
+		client: c,
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	c.register(m)
+	go m.run()
+}
+
+func (m *monitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.config.Period)
+	defer ticker.Stop()
+
+	previous := m.client.Stats()
+	last := time.Now()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			stats := m.client.Stats()
+			e := m.event(now, stats, previous, now.Sub(last))
+			previous, last = stats, now
+
+			ctx, cancel := context.WithTimeout(context.Background(), m.config.Period)
+			_, _ = m.client.publish(ctx, protoBatch{&messages.PublishRequest{Events: []*messages.Event{e}}}, nil)
+			cancel()
+		}
+	}
+}
+
+// event returns the monitoring event of the stats, elapsed is the time since
+// the previous stats.
+func (m *monitor) event(now time.Time, stats, previous Stats, elapsed time.Duration) *messages.Event {
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(stats.Published-previous.Published) / elapsed.Seconds()
+	}
+	events := &messages.Struct{Data: map[string]*messages.Value{
+		"published": helpers.NewUint64Value(stats.Published),
+		"failed":    helpers.NewUint64Value(stats.Failed),
+		"rate":      helpers.NewFloat64Value(rate),
+	}}
+	lag := &messages.Struct{Data: map[string]*messages.Value{
+		"ms": helpers.NewInt64Value(stats.Lag.Milliseconds()),
+	}}
+	return &messages.Event{
+		Timestamp:  timestamppb.New(now),
+		Source:     &messages.Source{InputId: m.config.InputID},
+		DataStream: m.config.DataStream,
+		Fields: &messages.Struct{Data: map[string]*messages.Value{
+			"shipper_client": helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+				"events":   helpers.NewStructValue(events),
+				"attempts": helpers.NewUint64Value(stats.Attempts),
+				"retries":  helpers.NewUint64Value(stats.Retries),
+				"lag":      helpers.NewStructValue(lag),
+			}}),
+		}},
+	}
+}
+
+// drain stops publishing the stats, the monitoring events are not confirmed.
+func (m *monitor) drain(ctx context.Context) int {
+	close(m.stop)
+	select {
+	case <-m.done:
+	case <-ctx.Done():
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestMonitoring(t *testing.T) {
+	producer := &fakeProducer{uuid: "uuid"}
+	config := DefaultMonitoringConfig()
+	config.Period = 10 * time.Millisecond
+	config.InputID = "input"
+	c := New(producer, WithMonitoring(config))
+
+	_, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+
+	// the previous monitoring events are counted too
+	var monitoring *messages.Event
+	var stats map[string]interface{}
+	require.Eventually(t, func() bool {
+		producer.mu.Lock()
+		defer producer.mu.Unlock()
+		for _, req := range producer.requests {
+			e := req.GetEvents()[0]
+			if e.GetDataStream().GetDataset() != "elastic_agent.shipper_client" {
+				continue
+			}
+			monitoring = e
+			stats = helpers.AsMap(e.GetFields())["shipper_client"].(map[string]interface{})
+			if stats["events"].(map[string]interface{})["published"].(uint64) >= 3 {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	require.Equal(t, "input", monitoring.GetSource().GetInputId())
+	require.GreaterOrEqual(t, stats["attempts"].(uint64), uint64(1))
+	require.Zero(t, stats["retries"])
+
+	_, err = c.Close(context.Background())
+	require.NoError(t, err)
+	producer.mu.Lock()
+	n := len(producer.requests)
+	producer.mu.Unlock()
+	time.Sleep(3 * config.Period)
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+	require.Len(t, producer.requests, n, "no more monitoring events after Close")
+}
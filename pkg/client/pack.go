@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Pack returns a copy of the request with its events and its string table
+// serialized and compressed into a single blob by the gRPC compressor
+// registered under the codec name. The shipper unpacks it with server.Unpack.
+func Pack(req *messages.PublishRequest, codec string) (*messages.PublishRequest, error) {
+	compressor := encoding.GetCompressor(codec)
+	if compressor == nil {
+		return nil, fmt.Errorf("no compressor registered for %q", codec)
+	}
+	data, err := proto.Marshal(&messages.PublishRequest{
+		Events:      req.GetEvents(),
+		StringTable: req.GetStringTable(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the events: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := compressor.Compress(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress the events: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress the events: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress the events: %w", err)
+	}
+
+	packed := requestHeader(req)
+	packed.CompressedEvents = &messages.CompressedEvents{
+		Codec: codec,
+		Data:  buf.Bytes(),
+		Count: uint32(len(req.GetEvents())),
+	}
+	return packed, nil
+}
+
+// PackingInterceptor returns a client interceptor packing the events of the
+// PublishEvents calls with the codec. The requests seen by the callers, and
+// by the retries of a Client, are left untouched.
+func PackingInterceptor(codec string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if r, ok := req.(*messages.PublishRequest); ok && len(r.GetEvents()) > 0 {
+			packed, err := Pack(r, codec)
+			if err != nil {
+				return err
+			}
+			req = packed
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
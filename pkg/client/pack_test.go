@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	grpcgzip "google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestPack(t *testing.T) {
+	req := &messages.PublishRequest{Uuid: "uuid", Events: testEvents(3), BatchId: "batch", Cursor: []byte("offset=42")}
+	packed, err := Pack(req, grpcgzip.Name)
+	require.NoError(t, err)
+	require.Equal(t, "uuid", packed.GetUuid())
+	require.Equal(t, "batch", packed.GetBatchId())
+	require.Equal(t, []byte("offset=42"), packed.GetCursor())
+	require.Empty(t, packed.GetEvents())
+	require.Equal(t, grpcgzip.Name, packed.GetCompressedEvents().GetCodec())
+	require.Equal(t, uint32(3), packed.GetCompressedEvents().GetCount())
+	require.Equal(t, 3, packed.Len())
+	require.Len(t, req.GetEvents(), 3, "the request must not change")
+
+	r, err := gzip.NewReader(bytes.NewReader(packed.GetCompressedEvents().GetData()))
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	var unpacked messages.PublishRequest
+	require.NoError(t, proto.Unmarshal(data, &unpacked))
+	require.Len(t, unpacked.GetEvents(), 3)
+	for i, e := range unpacked.GetEvents() {
+		require.True(t, proto.Equal(req.GetEvents()[i], e))
+	}
+
+	_, err = Pack(req, "unknown")
+	require.Error(t, err)
+}
+
+func TestPackingInterceptor(t *testing.T) {
+	var sent []interface{}
+	invoker := func(_ context.Context, _ string, req, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		sent = append(sent, req)
+		return nil
+	}
+	intercept := PackingInterceptor(grpcgzip.Name)
+	ctx := context.Background()
+
+	req := &messages.PublishRequest{Events: testEvents(2)}
+	require.NoError(t, intercept(ctx, "/elastic.agent.shipper.v1.Producer/PublishEvents", req, &messages.PublishReply{}, nil, invoker))
+	require.NotNil(t, sent[0].(*messages.PublishRequest).GetCompressedEvents())
+	require.Len(t, req.GetEvents(), 2)
+
+	other := &messages.PersistedIndexRequest{}
+	require.NoError(t, intercept(ctx, "/elastic.agent.shipper.v1.Producer/PersistedIndex", other, nil, nil, invoker))
+	require.Same(t, other, sent[1])
+}
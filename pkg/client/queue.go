@@ -0,0 +1,245 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// ErrDropped is returned for batches dropped by a full MemoryQueue.
+var ErrDropped = errors.New("events dropped, the queue is full")
+
+// DropPolicy defines what a MemoryQueue does when it's full.
+type DropPolicy int
+
+const (
+	// Block makes Publish wait until there is enough space in the queue.
+	Block DropPolicy = iota
+	// DropNewest rejects the batch being published.
+	DropNewest
+	// DropOldest drops the oldest queued batches to make room for the new one.
+	DropOldest
+)
+
+// QueueConfig configures a MemoryQueue.
+type QueueConfig struct {
+	// MaxEvents is the maximum number of queued events. Zero means no limit.
+	MaxEvents int
+	// MaxBytes is the maximum serialized size of the queued events. Zero means no limit.
+	MaxBytes int
+	// Policy is applied when a batch does not fit in the queue.
+	Policy DropPolicy
+}
+
+// MemoryQueue is a bounded queue of batches in front of an AsyncProducer.
+//
+// It absorbs bursts while the shipper is slow, and applies its drop policy
+// once full so that the memory used by the client stays bounded.
+// A batch larger than the queue limits is only accepted into an empty queue.
+type MemoryQueue struct {
+	producer *AsyncProducer
+	config   QueueConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	closed   bool
+	draining bool
+	// forwarding is the number of events being passed to the producer
+	forwarding int
+	items      []queuedBatch
+	events     int
+	bytes      int
+	dropped    uint64
+	// changed is closed and replaced every time the queue changes
+	changed chan struct{}
+}
+
+type queuedBatch struct {
+	future *Future
+	bytes  int
+}
+
+// NewMemoryQueue creates a new queue forwarding batches to the given producer.
+func NewMemoryQueue(p *AsyncProducer, config QueueConfig) *MemoryQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &MemoryQueue{
+		producer: p,
+		config:   config,
+		ctx:      ctx,
+		cancel:   cancel,
+		changed:  make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.forwardLoop()
+	}()
+	p.client.register(q)
+	return q
+}
+
+// Publish queues the events. Depending on the drop policy, when the queue
+// is full it blocks, returns ErrDropped, or drops older batches whose futures
+// then fail with ErrDropped.
+func (q *MemoryQueue) Publish(ctx context.Context, events []*messages.Event) (*Future, error) {
+	size := 0
+	for _, e := range events {
+		size += proto.Size(e)
+	}
+	f := newFuture(events)
+
+	q.mu.Lock()
+	for {
+		if q.closed || q.draining {
+			q.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if len(q.items) == 0 || q.fits(len(events), size) {
+			q.items = append(q.items, queuedBatch{future: f, bytes: size})
+			q.events += len(events)
+			q.bytes += size
+			q.notifyLocked()
+			q.mu.Unlock()
+			return f, nil
+		}
+
+		switch q.config.Policy {
+		case DropNewest:
+			q.dropped += uint64(len(events))
+			q.mu.Unlock()
+			return nil, ErrDropped
+		case DropOldest:
+			dropped := q.popLocked()
+			q.dropped += uint64(len(dropped.future.events))
+			dropped.future.fail(ErrDropped)
+		default:
+			changed := q.changed
+			q.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-changed:
+			}
+			q.mu.Lock()
+		}
+	}
+}
+
+// Len returns the number of queued events and their size in bytes.
+func (q *MemoryQueue) Len() (events, bytes int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.events, q.bytes
+}
+
+// Dropped returns the number of events dropped since the queue was created.
+func (q *MemoryQueue) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Close stops the queue, queued batches fail with ErrClosed.
+// It does not close the producer.
+func (q *MemoryQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	q.notifyLocked()
+	q.mu.Unlock()
+
+	q.cancel()
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) > 0 {
+		q.popLocked().future.fail(ErrClosed)
+	}
+}
+
+// drain stops accepting batches and waits until the queued ones are forwarded
+// to the producer or ctx is done, then closes the queue.
+func (q *MemoryQueue) drain(ctx context.Context) int {
+	q.mu.Lock()
+	q.draining = true
+	q.notifyLocked()
+	for len(q.items) > 0 || q.forwarding > 0 {
+		changed := q.changed
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			events := q.events + q.forwarding
+			q.mu.Unlock()
+			q.Close()
+			return events
+		case <-changed:
+		}
+		q.mu.Lock()
+	}
+	q.mu.Unlock()
+	q.Close()
+	return 0
+}
+
+func (q *MemoryQueue) fits(events, bytes int) bool {
+	return (q.config.MaxEvents <= 0 || q.events+events <= q.config.MaxEvents) &&
+		(q.config.MaxBytes <= 0 || q.bytes+bytes <= q.config.MaxBytes)
+}
+
+func (q *MemoryQueue) popLocked() queuedBatch {
+	item := q.items[0]
+	q.items[0] = queuedBatch{}
+	q.items = q.items[1:]
+	q.events -= len(item.future.events)
+	q.bytes -= item.bytes
+	q.notifyLocked()
+	return item
+}
+
+func (q *MemoryQueue) notifyLocked() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func (q *MemoryQueue) forwardLoop() {
+	for {
+		q.mu.Lock()
+		for len(q.items) == 0 && !q.closed {
+			changed := q.changed
+			q.mu.Unlock()
+			<-changed
+			q.mu.Lock()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		item := q.popLocked()
+		q.forwarding = len(item.future.events)
+		q.mu.Unlock()
+
+		if err := q.producer.enqueue(q.ctx, item.future); err != nil {
+			if q.ctx.Err() != nil {
+				err = ErrClosed
+			}
+			item.future.fail(err)
+		}
+
+		q.mu.Lock()
+		q.forwarding = 0
+		q.notifyLocked()
+		q.mu.Unlock()
+	}
+}
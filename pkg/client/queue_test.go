@@ -0,0 +1,93 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// newStalledQueue returns a queue in front of a producer that doesn't publish
+// anything until the gate is opened. The first two batches published are held by
+// the producer and the queue forwarder, the next ones stay in the queue.
+func newStalledQueue(t *testing.T, config QueueConfig) (*MemoryQueue, chan struct{}) {
+	gate := make(chan struct{})
+	producer := &fakeProducer{uuid: "uuid", persisted: make(chan *messages.PersistedIndexReply), gate: gate}
+	p := NewAsyncProducer(New(producer), AsyncConfig{})
+	q := NewMemoryQueue(p, config)
+	t.Cleanup(func() {
+		q.Close()
+		p.Close()
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := q.Publish(context.Background(), testEvents(1))
+		require.NoError(t, err)
+		require.Eventually(t, func() bool {
+			events, _ := q.Len()
+			return events == 0
+		}, time.Second, time.Millisecond)
+	}
+	return q, gate
+}
+
+func TestMemoryQueueDropNewest(t *testing.T) {
+	q, _ := newStalledQueue(t, QueueConfig{MaxEvents: 3, Policy: DropNewest})
+	ctx := context.Background()
+
+	_, err := q.Publish(ctx, testEvents(2))
+	require.NoError(t, err)
+	_, err = q.Publish(ctx, testEvents(2))
+	require.ErrorIs(t, err, ErrDropped)
+	_, err = q.Publish(ctx, testEvents(1))
+	require.NoError(t, err)
+
+	events, _ := q.Len()
+	require.Equal(t, 3, events)
+	require.Equal(t, uint64(2), q.Dropped())
+}
+
+func TestMemoryQueueDropOldest(t *testing.T) {
+	q, _ := newStalledQueue(t, QueueConfig{MaxEvents: 3, Policy: DropOldest})
+	ctx := context.Background()
+
+	first, err := q.Publish(ctx, testEvents(2))
+	require.NoError(t, err)
+	second, err := q.Publish(ctx, testEvents(1))
+	require.NoError(t, err)
+	third, err := q.Publish(ctx, testEvents(2))
+	require.NoError(t, err)
+
+	require.ErrorIs(t, first.Wait(ctx), ErrDropped)
+	require.Nil(t, second.Err())
+	require.Nil(t, third.Err())
+	events, _ := q.Len()
+	require.Equal(t, 3, events)
+	require.Equal(t, uint64(2), q.Dropped())
+}
+
+func TestMemoryQueueBlock(t *testing.T) {
+	q, gate := newStalledQueue(t, QueueConfig{MaxBytes: 1, Policy: Block})
+
+	// an oversized batch is accepted in an empty queue
+	_, err := q.Publish(context.Background(), []*messages.Event{{Source: &messages.Source{InputId: "input"}}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Publish(ctx, []*messages.Event{{Source: &messages.Source{InputId: "input"}}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(gate)
+	f, err := q.Publish(context.Background(), []*messages.Event{{Source: &messages.Source{InputId: "input"}}})
+	require.NoError(t, err)
+	waitFor(t, f.Accepted())
+	require.NoError(t, f.Err())
+}
@@ -0,0 +1,143 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// RateLimitConfig configures the client-side rate limiting.
+// A zero rate disables the corresponding limit.
+type RateLimitConfig struct {
+	// EventsPerSecond is the maximum sustained rate of published events.
+	EventsPerSecond float64
+	// EventsBurst is the number of events that can be published at once
+	// above the sustained rate. It defaults to EventsPerSecond.
+	EventsBurst int
+	// BytesPerSecond is the maximum sustained rate of published bytes, as
+	// serialized on the wire.
+	BytesPerSecond float64
+	// BytesBurst is the number of bytes that can be published at once above
+	// the sustained rate. It defaults to BytesPerSecond.
+	BytesBurst int
+}
+
+// RateLimiter throttles publishing with token buckets for events and bytes.
+//
+// A batch larger than the burst is not rejected: it's delayed until the
+// bucket has refilled enough, and the following batches wait for the debt.
+//
+// The following metrics are maintained when a registry is given:
+//   - rate_limit.throttled: number of throttled calls
+//   - rate_limit.throttled_us: total time spent throttled in microseconds
+type RateLimiter struct {
+	events *tokenBucket
+	bytes  *tokenBucket
+
+	throttled   *monitoring.Uint
+	throttledUs *monitoring.Uint
+}
+
+// NewRateLimiter creates a new rate limiter. reg can be nil.
+func NewRateLimiter(config RateLimitConfig, reg *monitoring.Registry) *RateLimiter {
+	l := &RateLimiter{
+		events: newTokenBucket(config.EventsPerSecond, config.EventsBurst),
+		bytes:  newTokenBucket(config.BytesPerSecond, config.BytesBurst),
+	}
+	if reg != nil {
+		rateReg := reg.NewRegistry("rate_limit")
+		l.throttled = monitoring.NewUint(rateReg, "throttled")
+		l.throttledUs = monitoring.NewUint(rateReg, "throttled_us")
+	}
+	return l
+}
+
+// WithRateLimit throttles the Publish calls of the client.
+func WithRateLimit(l *RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// Wait blocks until the events can be published. It returns the time spent
+// waiting, or the context error if it's done first.
+func (l *RateLimiter) Wait(ctx context.Context, events []*messages.Event) (time.Duration, error) {
+	return l.wait(ctx, protoBatch{&messages.PublishRequest{Events: events}})
+}
+
+// wait implements Wait for any batch, its size is only computed when the
+// bytes are limited.
+func (l *RateLimiter) wait(ctx context.Context, b batch) (time.Duration, error) {
+	now := time.Now()
+	d := l.events.reserve(now, float64(b.len()))
+	if l.bytes != nil {
+		if bd := l.bytes.reserve(now, float64(b.size())); bd > d {
+			d = bd
+		}
+	}
+	if d <= 0 {
+		return 0, nil
+	}
+
+	if l.throttled != nil {
+		l.throttled.Inc()
+	}
+	start := time.Now()
+	err := wait(ctx, d)
+	waited := time.Since(start)
+	if l.throttledUs != nil {
+		l.throttledUs.Add(uint64(waited.Microseconds()))
+	}
+	return waited, err
+}
+
+// tokenBucket is a token bucket refilled continuously, a nil bucket never throttles.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if b <= 0 {
+		b = rate
+	}
+	return &tokenBucket{rate: rate, burst: b, tokens: b}
+}
+
+// reserve takes n tokens and returns how long the caller must wait before
+// using them. The bucket can go into debt so large requests eventually pass.
+func (b *tokenBucket) reserve(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(10, 5)
+	now := time.Now()
+
+	require.Zero(t, b.reserve(now, 5))
+	require.Equal(t, 100*time.Millisecond, b.reserve(now, 1))
+	// the debt is paid after 100ms, then 2 more tokens accumulate
+	require.Zero(t, b.reserve(now.Add(300*time.Millisecond), 2))
+	// the bucket never holds more than the burst
+	require.Equal(t, 200*time.Millisecond, b.reserve(now.Add(time.Hour), 7))
+
+	require.Zero(t, (*tokenBucket)(nil).reserve(now, 1000))
+}
+
+func TestRateLimiter(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	l := NewRateLimiter(RateLimitConfig{EventsPerSecond: 1000, EventsBurst: 10}, reg)
+	ctx := context.Background()
+
+	waited, err := l.Wait(ctx, testEvents(10))
+	require.NoError(t, err)
+	require.Zero(t, waited)
+
+	waited, err = l.Wait(ctx, testEvents(10))
+	require.NoError(t, err)
+	require.Greater(t, waited, time.Duration(0))
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	require.Equal(t, int64(1), snapshot.Ints["rate_limit.throttled"])
+	require.Greater(t, snapshot.Ints["rate_limit.throttled_us"], int64(0))
+
+	t.Run("bytes", func(t *testing.T) {
+		l := NewRateLimiter(RateLimitConfig{BytesPerSecond: 1, BytesBurst: 1}, nil)
+		events := []*messages.Event{{Source: &messages.Source{InputId: "input"}}}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := l.Wait(ctx, events)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestPublishRateLimit(t *testing.T) {
+	producer := &fakeProducer{uuid: "uuid"}
+	c := New(producer, WithRateLimit(NewRateLimiter(RateLimitConfig{EventsPerSecond: 1, EventsBurst: 1}, nil)))
+
+	_, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Publish(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, producer.requests, 1)
+}
@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// publishRequestEventsField is the field number of PublishRequest.events.
+const publishRequestEventsField = 2
+
+// PublishRaw sends events that are already serialized as messages.Event,
+// like the ones read from a disk queue or received by a proxy, without
+// decoding and marshaling them again. It behaves like Publish otherwise.
+//
+// The events are carried as unknown fields of the request, which the proto
+// marshaling writes out as they are. The interceptors working on the events
+// of the requests, like the string table or the packing ones, don't see them.
+func (c *Client) PublishRaw(ctx context.Context, events [][]byte) (*messages.PublishReply, error) {
+	return c.publish(ctx, &rawBatch{events: events}, nil)
+}
+
+// batch is a list of events to publish, retried from the first event that
+// wasn't accepted.
+type batch interface {
+	uuid() string
+	setUUID(uuid string)
+	len() int
+	// size is the serialized size of the events.
+	size() int
+	// request returns the request publishing the events starting at from.
+	request(from int) *messages.PublishRequest
+}
+
+// protoBatch publishes the events of a request, setting its uuid.
+type protoBatch struct {
+	req *messages.PublishRequest
+}
+
+func (b protoBatch) uuid() string        { return b.req.GetUuid() }
+func (b protoBatch) setUUID(uuid string) { b.req.Uuid = uuid }
+func (b protoBatch) len() int            { return b.req.Len() }
+
+func (b protoBatch) size() int {
+	size := 0
+	for _, e := range b.req.GetEvents() {
+		size += proto.Size(e)
+	}
+	for _, m := range b.req.GetMetrics() {
+		size += proto.Size(m)
+	}
+	for _, s := range b.req.GetSignals() {
+		size += proto.Size(s)
+	}
+	return size
+}
+
+func (b protoBatch) request(from int) *messages.PublishRequest {
+	req := requestHeader(b.req)
+	switch {
+	case len(b.req.GetSignals()) > 0:
+		req.Signals = b.req.GetSignals()[from:]
+	case len(b.req.GetMetrics()) > 0:
+		req.Metrics = b.req.GetMetrics()[from:]
+	default:
+		req.Events = b.req.GetEvents()[from:]
+	}
+	return req
+}
+
+// requestHeader returns a request with the fields of req that are not its
+// payload, for the encoders replacing its events to keep them.
+func requestHeader(req *messages.PublishRequest) *messages.PublishRequest {
+	return &messages.PublishRequest{
+		Uuid:               req.GetUuid(),
+		ReturnEventIndexes: req.GetReturnEventIndexes(),
+		BatchId:            req.GetBatchId(),
+		Cursor:             req.GetCursor(),
+		CursorKey:          req.GetCursorKey(),
+	}
+}
+
+// shallowCopy returns a copy of e sharing its values, for the encoders
+// replacing some fields of the events without changing the ones of the
+// callers. Every field is carried over, including the ones added later to
+// the message.
+func shallowCopy(e *messages.Event) *messages.Event {
+	c := &messages.Event{}
+	dst := c.ProtoReflect()
+	e.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		dst.Set(fd, v)
+		return true
+	})
+	dst.SetUnknown(e.ProtoReflect().GetUnknown())
+	return c
+}
+
+// rawBatch publishes serialized events.
+type rawBatch struct {
+	id     string
+	events [][]byte
+}
+
+func (b *rawBatch) uuid() string        { return b.id }
+func (b *rawBatch) setUUID(uuid string) { b.id = uuid }
+func (b *rawBatch) len() int            { return len(b.events) }
+
+func (b *rawBatch) size() int {
+	size := 0
+	for _, e := range b.events {
+		size += len(e)
+	}
+	return size
+}
+
+func (b *rawBatch) request(from int) *messages.PublishRequest {
+	events := b.events[from:]
+	n := 0
+	for _, e := range events {
+		n += protowire.SizeTag(publishRequestEventsField) + protowire.SizeBytes(len(e))
+	}
+	raw := make([]byte, 0, n)
+	for _, e := range events {
+		raw = protowire.AppendTag(raw, publishRequestEventsField, protowire.BytesType)
+		raw = protowire.AppendBytes(raw, e)
+	}
+
+	req := &messages.PublishRequest{Uuid: b.id}
+	req.ProtoReflect().SetUnknown(raw)
+	return req
+}
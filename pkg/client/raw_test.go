@@ -0,0 +1,148 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// the fake producer only sees decoded events, so the tests script how many
+// raw events it accepts.
+func TestPublishRaw(t *testing.T) {
+	var events []*messages.Event
+	var raw [][]byte
+	for i := 0; i < 3; i++ {
+		e := &messages.Event{Source: &messages.Source{InputId: fmt.Sprint(i)}}
+		data, err := proto.Marshal(e)
+		require.NoError(t, err)
+		events = append(events, e)
+		raw = append(raw, data)
+	}
+
+	var attempts []Attempt
+	producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 2}, {accept: 1}}}
+	c := New(producer, WithRetryPolicy(fastRetries(&attempts)))
+	reply, err := c.PublishRaw(context.Background(), raw)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), reply.GetAcceptedCount())
+	require.Len(t, attempts, 2)
+	require.Equal(t, 1, attempts[1].Submitted)
+
+	// the shipper decodes the raw events as regular ones
+	require.Len(t, producer.requests, 2)
+	for i, want := range [][]*messages.Event{events, events[2:]} {
+		data, err := proto.Marshal(producer.requests[i])
+		require.NoError(t, err)
+		var req messages.PublishRequest
+		require.NoError(t, proto.Unmarshal(data, &req))
+		require.Len(t, req.GetEvents(), len(want))
+		for j, e := range req.GetEvents() {
+			require.True(t, proto.Equal(want[j], e))
+		}
+	}
+}
+
+func TestPublishRawRestarts(t *testing.T) {
+	tracker := NewRestartTracker(0, func(uint64) {})
+	producer := &fakeProducer{uuid: "first", results: []fakeResult{{accept: 2}, {accept: 1}}}
+	c := New(producer, WithRestartTracker(tracker))
+
+	_, err := c.PublishRaw(context.Background(), [][]byte{{}, {}})
+	require.NoError(t, err)
+	_, err = c.PublishRaw(context.Background(), [][]byte{{}})
+	require.NoError(t, err)
+	require.Equal(t, "first", producer.requests[1].GetUuid())
+	require.Equal(t, uint64(3), tracker.Position())
+}
+
+// populatedEvent returns an event with every field set, so the tests of the
+// encoders copying the events fail when a new field isn't carried over.
+func populatedEvent(t *testing.T) *messages.Event {
+	e := &messages.Event{}
+	m := e.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		switch {
+		case fd.IsList():
+			list := m.NewField(fd).List()
+			list.Append(scalarOrMessage(t, fd, list.NewElement))
+			m.Set(fd, protoreflect.ValueOfList(list))
+		case fd.IsMap():
+			t.Fatalf("no value for the map field %s", fd.Name())
+		default:
+			m.Set(fd, scalarOrMessage(t, fd, func() protoreflect.Value { return m.NewField(fd) }))
+		}
+	}
+	return e
+}
+
+func scalarOrMessage(t *testing.T, fd protoreflect.FieldDescriptor, message func() protoreflect.Value) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return message()
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(string(fd.Name()))
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(fd.Name()))
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(1)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(fd.Number()))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(fd.Number()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(int32(fd.Number()))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(int64(fd.Number()))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(fd.Number()))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float64(fd.Number()))
+	}
+	t.Fatalf("no value for the field %s of kind %s", fd.Name(), fd.Kind())
+	return protoreflect.Value{}
+}
+
+// requireEventKept checks that got has the fields of want, but the ones an
+// encoder replaced.
+func requireEventKept(t *testing.T, want, got *messages.Event, replaced ...protoreflect.Name) {
+	t.Helper()
+	want, got = proto.Clone(want).(*messages.Event), proto.Clone(got).(*messages.Event)
+	for _, name := range replaced {
+		fd := want.ProtoReflect().Descriptor().Fields().ByName(name)
+		require.NotNil(t, fd, name)
+		want.ProtoReflect().Clear(fd)
+		got.ProtoReflect().Clear(fd)
+	}
+	require.True(t, proto.Equal(want, got), "got %v, want %v", got, want)
+}
+
+func TestShallowCopy(t *testing.T) {
+	e := populatedEvent(t)
+	unknown := protowire.AppendTag(nil, 100, protowire.VarintType)
+	e.ProtoReflect().SetUnknown(protowire.AppendVarint(unknown, 1))
+
+	c := shallowCopy(e)
+	require.NotSame(t, e, c)
+	requireEventKept(t, e, c)
+	require.Equal(t, e.ProtoReflect().GetUnknown(), c.ProtoReflect().GetUnknown())
+	// the values are shared
+	require.Same(t, e.GetFields(), c.GetFields())
+
+	c.Fields = nil
+	require.NotNil(t, e.GetFields(), "the copy must not change the event")
+}
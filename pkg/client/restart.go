@@ -0,0 +1,193 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// ErrShipperRestarted is returned when a request was rejected because the
+// shipper process changed since the uuid in the request was obtained.
+var ErrShipperRestarted = errors.New("shipper restarted")
+
+// RewindFunc is called when a shipper restart is detected. lastGood is the
+// position in the caller's data sequence from which publishing must resume,
+// all the events before it have been persisted by the previous shipper process.
+type RewindFunc func(lastGood uint64)
+
+// RestartTracker implements the at-least-once delivery pattern described
+// in publish.proto.
+//
+// It tracks the shipper uuid and the position of the caller in its own
+// data sequence, counted in events. The position advances with every accepted
+// event and becomes "good" once the shipper reports the events as persisted.
+// When the shipper uuid changes, all the events that were not persisted yet
+// are considered lost and the RewindFunc is called with the last good position.
+type RestartTracker struct {
+	rewind RewindFunc
+
+	mu       sync.Mutex
+	uuid     string
+	next     uint64
+	lastGood uint64
+	pending  []pendingPosition
+}
+
+// pendingPosition maps the accepted index of a request to the position
+// following its last event in the caller's data sequence.
+type pendingPosition struct {
+	acceptedIndex uint64
+	position      uint64
+}
+
+// NewRestartTracker creates a new tracker starting at the given position.
+func NewRestartTracker(start uint64, rewind RewindFunc) *RestartTracker {
+	return &RestartTracker{
+		rewind:   rewind,
+		next:     start,
+		lastGood: start,
+	}
+}
+
+// WithRestartTracker makes the client set the tracked uuid on requests that
+// don't have one and report every reply to the tracker. When a restart is detected,
+// Publish returns ErrShipperRestarted.
+func WithRestartTracker(t *RestartTracker) Option {
+	return func(c *Client) {
+		c.restarts = t
+	}
+}
+
+// UUID returns the last known shipper uuid, empty if it's not known yet.
+func (t *RestartTracker) UUID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.uuid
+}
+
+// Position returns the position following the last accepted event.
+func (t *RestartTracker) Position() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.next
+}
+
+// LastGood returns the position following the last persisted event.
+// This is the position an input can safely checkpoint.
+func (t *RestartTracker) LastGood() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastGood
+}
+
+// Published records the reply to a request sent with the given uuid.
+// It returns true if the reply shows that the shipper restarted, in which
+// case the RewindFunc has been called.
+func (t *RestartTracker) Published(requestUUID string, reply *messages.PublishReply) bool {
+	t.mu.Lock()
+	if t.restarted(requestUUID, reply.GetUuid()) {
+		return t.rewindLocked(reply.GetUuid())
+	}
+	t.uuid = reply.GetUuid()
+	if n := uint64(reply.GetAcceptedCount()); n > 0 {
+		t.next += n
+		t.pending = append(t.pending, pendingPosition{
+			acceptedIndex: reply.GetAcceptedIndex(),
+			position:      t.next,
+		})
+	}
+	t.mu.Unlock()
+	return false
+}
+
+// Persisted records a persisted index update from the shipper.
+// It returns true if the reply shows that the shipper restarted, in which
+// case the RewindFunc has been called.
+func (t *RestartTracker) Persisted(reply *messages.PersistedIndexReply) bool {
+	t.mu.Lock()
+	if t.restarted(t.uuid, reply.GetUuid()) {
+		return t.rewindLocked(reply.GetUuid())
+	}
+	t.uuid = reply.GetUuid()
+	var i int
+	for ; i < len(t.pending) && t.pending[i].acceptedIndex <= reply.GetPersistedIndex(); i++ {
+		t.lastGood = t.pending[i].position
+	}
+	t.pending = t.pending[i:]
+	t.mu.Unlock()
+	return false
+}
+
+// Verify asks the shipper for its uuid, e.g. on reconnect, without publishing
+// anything. It returns true if the shipper restarted, in which case the
+// RewindFunc has been called.
+func (t *RestartTracker) Verify(ctx context.Context, producer proto.ProducerClient) (bool, error) {
+	reply, err := producer.WhoAmI(ctx, &messages.WhoAmIRequest{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get the shipper uuid: %w", err)
+	}
+	t.mu.Lock()
+	if t.restarted(t.uuid, reply.GetUuid()) {
+		return t.rewindLocked(reply.GetUuid()), nil
+	}
+	t.uuid = reply.GetUuid()
+	t.mu.Unlock()
+	return false, nil
+}
+
+// Watch subscribes to persisted index updates of the shipper and records them
+// until the context is done or the stream fails.
+func (t *RestartTracker) Watch(ctx context.Context, producer proto.ProducerClient, interval time.Duration) error {
+	stream, err := producer.PersistedIndex(ctx, &messages.PersistedIndexRequest{
+		PollingInterval: durationpb.New(interval),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to the persisted index: %w", err)
+	}
+	for {
+		reply, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to receive the persisted index: %w", err)
+		}
+		t.Persisted(reply)
+	}
+}
+
+// restarted returns true if the uuid reported by the shipper does not match
+// the expected one. An empty expected uuid never matches a restart.
+func (t *RestartTracker) restarted(expected, actual string) bool {
+	return expected != "" && t.uuid != "" && expected == t.uuid && actual != expected
+}
+
+// rewindLocked forgets all the pending positions and calls the RewindFunc.
+// It must be called with the lock held and releases it.
+func (t *RestartTracker) rewindLocked(uuid string) bool {
+	t.uuid = uuid
+	t.pending = nil
+	t.next = t.lastGood
+	lastGood := t.lastGood
+	t.mu.Unlock()
+
+	if t.rewind != nil {
+		t.rewind(lastGood)
+	}
+	return true
+}
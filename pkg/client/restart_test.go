@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestRestartTracker(t *testing.T) {
+	var rewinds []uint64
+	tracker := NewRestartTracker(10, func(lastGood uint64) {
+		rewinds = append(rewinds, lastGood)
+	})
+	producer := &fakeProducer{uuid: "first", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	c := New(producer, WithRestartTracker(tracker))
+	ctx := context.Background()
+
+	// the first request learns the uuid
+	_, err := c.Publish(ctx, &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+	require.Equal(t, "first", tracker.UUID())
+	require.Equal(t, uint64(13), tracker.Position())
+	require.Equal(t, uint64(10), tracker.LastGood())
+
+	req := &messages.PublishRequest{Events: testEvents(2)}
+	_, err = c.Publish(ctx, req)
+	require.NoError(t, err)
+	require.Equal(t, "first", req.GetUuid())
+	require.Equal(t, uint64(15), tracker.Position())
+
+	// only the first request is persisted
+	require.False(t, tracker.Persisted(&messages.PersistedIndexReply{Uuid: "first", PersistedIndex: 3}))
+	require.Equal(t, uint64(13), tracker.LastGood())
+
+	// the shipper restarts, the second request is lost
+	producer.uuid = "second"
+	reply, err := c.Publish(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.ErrorIs(t, err, ErrShipperRestarted)
+	require.Zero(t, reply.GetAcceptedCount())
+	require.Equal(t, []uint64{13}, rewinds)
+	require.Equal(t, "second", tracker.UUID())
+	require.Equal(t, uint64(13), tracker.Position())
+
+	// publishing resumes from the last good position
+	_, err = c.Publish(ctx, &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint64(15), tracker.Position())
+	require.Len(t, rewinds, 1)
+}
+
+func TestRestartTrackerWatch(t *testing.T) {
+	var rewinds []uint64
+	tracker := NewRestartTracker(0, func(lastGood uint64) {
+		rewinds = append(rewinds, lastGood)
+	})
+	producer := &fakeProducer{uuid: "first", persisted: make(chan *messages.PersistedIndexReply, 10)}
+	c := New(producer, WithRestartTracker(tracker))
+
+	_, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(4)})
+	require.NoError(t, err)
+
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "first", PersistedIndex: 4}
+	producer.persisted <- &messages.PersistedIndexReply{Uuid: "second", PersistedIndex: 0}
+	close(producer.persisted)
+
+	require.NoError(t, tracker.Watch(context.Background(), producer, 0))
+	require.Equal(t, []uint64{4}, rewinds)
+	require.Equal(t, "second", tracker.UUID())
+}
+
+func TestRestartTrackerVerify(t *testing.T) {
+	var rewinds []uint64
+	tracker := NewRestartTracker(0, func(lastGood uint64) {
+		rewinds = append(rewinds, lastGood)
+	})
+	producer := &fakeProducer{uuid: "first"}
+	c := New(producer, WithRestartTracker(tracker))
+	ctx := context.Background()
+
+	// the uuid is learnt without publishing
+	restarted, err := tracker.Verify(ctx, producer)
+	require.NoError(t, err)
+	require.False(t, restarted)
+	require.Equal(t, "first", tracker.UUID())
+
+	_, err = c.Publish(ctx, &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	restarted, err = tracker.Verify(ctx, producer)
+	require.NoError(t, err)
+	require.False(t, restarted)
+
+	producer.uuid = "second"
+	restarted, err = tracker.Verify(ctx, producer)
+	require.NoError(t, err)
+	require.True(t, restarted)
+	require.Equal(t, []uint64{0}, rewinds)
+	require.Equal(t, "second", tracker.UUID())
+	require.Zero(t, tracker.Position())
+}
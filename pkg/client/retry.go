@@ -0,0 +1,239 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/shipperuuid"
+)
+
+// RetryPolicy defines how the client retries publishing events.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// Zero means there is no limit and only the context bounds the retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after every attempt.
+	Multiplier float64
+	// Jitter randomizes each delay by up to this fraction, in the range [0, 1].
+	Jitter float64
+	// RetryableCodes lists the gRPC codes considered transient.
+	RetryableCodes []codes.Code
+	// OnAttempt, if set, is called after every attempt.
+	OnAttempt func(Attempt)
+}
+
+// PartialError is returned when the shipper accepted only some of the events
+// of a request and no more attempts are possible. The reply returned with it
+// has the events accepted by all the attempts.
+type PartialError struct {
+	// Accepted is the number of events the shipper accepted.
+	Accepted int
+	// Total is the number of events of the request.
+	Total int
+	// Err is the error of the last attempt, nil if the shipper accepted only
+	// some of the events it was sent.
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("the shipper accepted %d of %d events: %v", e.Accepted, e.Total, e.Err)
+	}
+	return fmt.Sprintf("the shipper accepted %d of %d events", e.Accepted, e.Total)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// Attempt describes a single PublishEvents call made by the client.
+type Attempt struct {
+	// Number is the attempt number, starting at 1.
+	Number int
+	// Submitted is the number of events sent in this attempt.
+	Submitted int
+	// Accepted is the number of events the shipper accepted in this attempt.
+	Accepted int
+	// Err is the error returned by the attempt, if any.
+	Err error
+	// Backoff is the delay before the next attempt, zero if there is none.
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableCodes: []codes.Code{codes.Unavailable, codes.ResourceExhausted},
+	}
+}
+
+// WithRetryPolicy sets the retry policy of the client.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// Retryable returns true if the error has one of the retryable gRPC codes.
+func (p RetryPolicy) Retryable(err error) bool {
+	code := status.Code(err)
+	for _, c := range p.RetryableCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay before the given retry, starting at 1. It's never
+// above MaxBackoff, jitter included.
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(retry-1))
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1) //nolint:gosec // no need for a secure source here
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	return time.Duration(d)
+}
+
+func (p RetryPolicy) publish(ctx context.Context, producer proto.ProducerClient, b batch) (*messages.PublishReply, error) {
+	var (
+		uuid     = b.uuid()
+		events   = b.len()
+		result   = &messages.PublishReply{}
+		accepted int
+		retries  int
+	)
+
+	for attempt := 1; ; attempt++ {
+		submitted := events - accepted
+		req := b.request(accepted)
+		reply, err := producer.PublishEvents(ctx, req)
+
+		var hint time.Duration
+		switch {
+		case err != nil:
+			if !p.Retryable(err) {
+				p.notify(attempt, submitted, 0, err, 0)
+				return lastResult(result, accepted, events, err)
+			}
+			hint = retryDelay(err)
+		default:
+			result.Uuid = reply.GetUuid()
+			result.BatchId = reply.GetBatchId()
+			if shipperuuid.Restarted(uuid, reply.GetUuid()) {
+				// the shipper restarted, it's up to the caller to rewind
+				p.notify(attempt, submitted, 0, nil, 0)
+				return result, nil
+			}
+			n := int(reply.GetAcceptedCount())
+			if n > 0 {
+				accepted += n
+				result.AcceptedCount = uint32(accepted)
+				result.AcceptedIndex = reply.GetAcceptedIndex()
+				if req.GetReturnEventIndexes() {
+					// the attempts may not get consecutive indexes
+					for i := 0; i < n; i++ {
+						result.EventIndexes = append(result.EventIndexes, reply.EventIndex(i))
+					}
+				}
+			}
+			if accepted >= events {
+				p.notify(attempt, submitted, n, nil, 0)
+				return result, nil
+			}
+		}
+
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			p.notify(attempt, submitted, int(reply.GetAcceptedCount()), err, 0)
+			return lastResult(result, accepted, events, err)
+		}
+
+		retries++
+		backoff := p.Backoff(retries)
+		if hint > backoff {
+			backoff = hint
+		}
+		p.notify(attempt, submitted, int(reply.GetAcceptedCount()), err, backoff)
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			// there is no point in waiting if the next attempt can't happen in time
+			return lastResult(result, accepted, events, err)
+		}
+		if wait(ctx, backoff) != nil {
+			return lastResult(result, accepted, events, err)
+		}
+	}
+}
+
+func (p RetryPolicy) notify(number, submitted, accepted int, err error, backoff time.Duration) {
+	if p.OnAttempt == nil {
+		return
+	}
+	p.OnAttempt(Attempt{
+		Number:    number,
+		Submitted: submitted,
+		Accepted:  accepted,
+		Err:       err,
+		Backoff:   backoff,
+	})
+}
+
+// lastResult returns the outcome of the last attempt once no more retries are
+// possible. The error is a *PartialError if some events were accepted.
+func lastResult(result *messages.PublishReply, accepted, events int, err error) (*messages.PublishReply, error) {
+	if accepted == 0 && err != nil {
+		return nil, err
+	}
+	return result, &PartialError{Accepted: accepted, Total: events, Err: err}
+}
+
+// retryDelay returns the delay requested by the shipper through a RetryInfo
+// status detail, zero if there is none.
+func retryDelay(err error) time.Duration {
+	st, ok := status.FromError(err)
+	if !ok {
+		return 0
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
+
+// wait blocks for the given duration or until the context is done.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
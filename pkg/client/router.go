@@ -0,0 +1,110 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// ErrNoRoute is returned when an event does not match any route and the
+// router has no fallback client.
+var ErrNoRoute = errors.New("no route for the event data stream")
+
+// Route sends the events of the matching data streams to a client.
+type Route struct {
+	// Type is the data stream type to match, e.g. "logs" or "metrics". Empty matches any type.
+	Type string
+	// Dataset is the data stream dataset to match. Empty matches any dataset.
+	Dataset string
+	// Client publishes the matching events.
+	Client *Client
+}
+
+func (r Route) matches(ds *messages.DataStream) bool {
+	return (r.Type == "" || r.Type == ds.GetType()) &&
+		(r.Dataset == "" || r.Dataset == ds.GetDataset())
+}
+
+// RoutedReply is the result of publishing the events routed to a client.
+type RoutedReply struct {
+	Client *Client
+	// Events are the events sent to the client, in their original order.
+	Events []*messages.Event
+	Reply  *messages.PublishReply
+	Err    error
+}
+
+// Router splits the events between several clients by data stream, e.g.
+// to send metrics and logs to different shipper instances.
+//
+// Routes are matched in the order they were given, the first matching route
+// wins. Events matching no route go to the fallback client.
+type Router struct {
+	routes   []Route
+	fallback *Client
+}
+
+// NewRouter creates a new router. fallback can be nil, events without a
+// route are then rejected with ErrNoRoute.
+func NewRouter(routes []Route, fallback *Client) *Router {
+	return &Router{routes: routes, fallback: fallback}
+}
+
+// Route returns the client the event is routed to, nil if there is none.
+func (r *Router) Route(event *messages.Event) *Client {
+	ds := event.GetDataStream()
+	for _, route := range r.routes {
+		if route.matches(ds) {
+			return route.Client
+		}
+	}
+	return r.fallback
+}
+
+// Publish splits the events by route and publishes them concurrently
+// through the clients. The replies are returned in the order the clients
+// first appear in events, along with the first error that occurred.
+//
+// No event is published if one of them has no route.
+func (r *Router) Publish(ctx context.Context, events []*messages.Event) ([]RoutedReply, error) {
+	var replies []RoutedReply
+	byClient := make(map[*Client]int)
+	for _, e := range events {
+		c := r.Route(e)
+		if c == nil {
+			ds := e.GetDataStream()
+			return nil, fmt.Errorf("%w: %s-%s", ErrNoRoute, ds.GetType(), ds.GetDataset())
+		}
+		i, ok := byClient[c]
+		if !ok {
+			i = len(replies)
+			byClient[c] = i
+			replies = append(replies, RoutedReply{Client: c})
+		}
+		replies[i].Events = append(replies[i].Events, e)
+	}
+
+	var wg sync.WaitGroup
+	for i := range replies {
+		wg.Add(1)
+		go func(r *RoutedReply) {
+			defer wg.Done()
+			r.Reply, r.Err = r.Client.Publish(ctx, &messages.PublishRequest{Events: r.Events})
+		}(&replies[i])
+	}
+	wg.Wait()
+
+	for _, r := range replies {
+		if r.Err != nil {
+			return replies, r.Err
+		}
+	}
+	return replies, nil
+}
@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func dataStreamEvent(typ, dataset string) *messages.Event {
+	return &messages.Event{DataStream: &messages.DataStream{Type: typ, Dataset: dataset}}
+}
+
+func TestRouter(t *testing.T) {
+	logs := &fakeProducer{uuid: "logs"}
+	metrics := &fakeProducer{uuid: "metrics"}
+	system := &fakeProducer{uuid: "system"}
+	logsClient, metricsClient, systemClient := New(logs), New(metrics), New(system)
+
+	router := NewRouter([]Route{
+		{Type: "metrics", Dataset: "system.cpu", Client: systemClient},
+		{Type: "metrics", Client: metricsClient},
+	}, logsClient)
+
+	require.Equal(t, systemClient, router.Route(dataStreamEvent("metrics", "system.cpu")))
+	require.Equal(t, metricsClient, router.Route(dataStreamEvent("metrics", "nginx")))
+	require.Equal(t, logsClient, router.Route(dataStreamEvent("logs", "nginx")))
+	require.Equal(t, logsClient, router.Route(&messages.Event{}))
+
+	events := []*messages.Event{
+		dataStreamEvent("logs", "a"),
+		dataStreamEvent("metrics", "b"),
+		dataStreamEvent("logs", "c"),
+		dataStreamEvent("metrics", "system.cpu"),
+	}
+	replies, err := router.Publish(context.Background(), events)
+	require.NoError(t, err)
+	require.Len(t, replies, 3)
+	require.Equal(t, logsClient, replies[0].Client)
+	require.Equal(t, []*messages.Event{events[0], events[2]}, replies[0].Events)
+	require.Equal(t, uint32(2), replies[0].Reply.GetAcceptedCount())
+	require.Equal(t, metricsClient, replies[1].Client)
+	require.Equal(t, systemClient, replies[2].Client)
+
+	require.Len(t, logs.requests, 1)
+	require.Len(t, metrics.requests, 1)
+	require.Len(t, system.requests, 1)
+
+	t.Run("errors are reported per client", func(t *testing.T) {
+		metrics.results = []fakeResult{{err: status.Error(codes.InvalidArgument, "bad")}}
+		replies, err := router.Publish(context.Background(), events[:2])
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.NoError(t, replies[0].Err)
+		require.Error(t, replies[1].Err)
+	})
+
+	t.Run("no route", func(t *testing.T) {
+		router := NewRouter([]Route{{Type: "metrics", Client: metricsClient}}, nil)
+		_, err := router.Publish(context.Background(), events)
+		require.ErrorIs(t, err, ErrNoRoute)
+	})
+}
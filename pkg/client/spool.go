@@ -0,0 +1,205 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Spool persists batches of events while the shipper is unreachable.
+//
+// Every batch gets an increasing ID when appended. Acknowledging an ID
+// removes that batch and all the older ones, so a batch that was already
+// replayed is never returned again, even after the spool is reopened. The
+// same goes for the events of a batch the shipper accepted only partially.
+type Spool interface {
+	// Append persists the events and returns the ID of the batch.
+	Append(events []*messages.Event) (uint64, error)
+	// Peek returns the events of the oldest batch that are not acknowledged,
+	// or io.EOF if there is none.
+	Peek() (uint64, []*messages.Event, error)
+	// Ack removes all the batches up to the given ID.
+	Ack(id uint64) error
+	// AckPartial removes the first n events returned by Peek for the batch,
+	// the next Peek returns the remaining ones.
+	AckPartial(id uint64, n int) error
+	// Len returns the number of batches that are not acknowledged.
+	Len() int
+	// Close releases the resources held by the spool.
+	Close() error
+}
+
+// SpoolConfig configures a SpoolingPublisher.
+type SpoolConfig struct {
+	// ReplayInterval is the delay between two attempts to replay the spool.
+	ReplayInterval time.Duration
+	// OnDrop, if set, is called with the spooled events the shipper rejected
+	// with a non transient error. Those events are removed from the spool.
+	OnDrop func(events []*messages.Event, err error)
+}
+
+// DefaultSpoolConfig returns the default spool configuration.
+func DefaultSpoolConfig() SpoolConfig {
+	return SpoolConfig{
+		ReplayInterval: time.Second,
+	}
+}
+
+// SpoolingPublisher publishes events through a Client and writes them to a
+// Spool when the shipper is unreachable. The spooled events are replayed in
+// the background once the shipper is back.
+//
+// Events are delivered in order: while the spool is not empty, published
+// events are appended to it instead of being sent directly.
+type SpoolingPublisher struct {
+	client *Client
+	spool  Spool
+	config SpoolConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// mu serializes publishing and replaying
+	mu sync.Mutex
+}
+
+// NewSpoolingPublisher creates a new publisher spooling to s. The client
+// retry policy should be bounded so that Publish returns once the shipper is
+// considered unreachable.
+func NewSpoolingPublisher(c *Client, s Spool, config SpoolConfig) *SpoolingPublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &SpoolingPublisher{
+		client: c,
+		spool:  s,
+		config: config,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.replayLoop()
+	}()
+	return p
+}
+
+// Publish sends the events to the shipper. If the shipper is unreachable, or
+// older events are still spooled, the events are appended to the spool and
+// spooled is true.
+func (p *SpoolingPublisher) Publish(ctx context.Context, events []*messages.Event) (spooled bool, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.spool.Len() == 0 {
+		reply, err := p.client.Publish(ctx, &messages.PublishRequest{Events: events})
+		if err != nil && !unreachable(err) && !retriesExhausted(err) {
+			return false, err
+		}
+		accepted := int(reply.GetAcceptedCount())
+		if accepted >= len(events) {
+			return false, nil
+		}
+		events = events[accepted:]
+	}
+
+	if _, err := p.spool.Append(events); err != nil {
+		return false, fmt.Errorf("failed to spool the events: %w", err)
+	}
+	return true, nil
+}
+
+// Replay sends the spooled events to the shipper, oldest first, until the
+// spool is empty or the shipper is unreachable. The events accepted by the
+// shipper are acknowledged in the spool even when the call fails afterwards,
+// so they are not sent again.
+func (p *SpoolingPublisher) Replay(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		id, events, err := p.spool.Peek()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		reply, err := p.client.Publish(ctx, &messages.PublishRequest{Events: events})
+		if n := int(reply.GetAcceptedCount()); n > 0 && n < len(events) {
+			// persisted, so the accepted events aren't sent again after a restart
+			if err := p.spool.AckPartial(id, n); err != nil {
+				return err
+			}
+			events = events[n:]
+			if err == nil {
+				return nil
+			}
+		}
+		switch {
+		case retriesExhausted(err):
+			// the rest is sent again by the next replay
+			return nil
+		case err != nil && ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && unreachable(err):
+			return err
+		case err != nil:
+			if p.config.OnDrop != nil {
+				p.config.OnDrop(events, err)
+			}
+		}
+
+		if err := p.spool.Ack(id); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops replaying the spool. It does not close the spool.
+func (p *SpoolingPublisher) Close() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+func (p *SpoolingPublisher) replayLoop() {
+	for {
+		if wait(p.ctx, p.config.ReplayInterval) != nil {
+			return
+		}
+		_ = p.Replay(p.ctx)
+	}
+}
+
+// retriesExhausted returns true if the error is a *PartialError with no other
+// cause than the shipper accepting only some of the events.
+func retriesExhausted(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial) && partial.Err == nil
+}
+
+// unreachable returns true if the error means the events didn't reach the shipper.
+func unreachable(err error) bool {
+	var partial *PartialError
+	if errors.As(err, &partial) {
+		err = partial.Err
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
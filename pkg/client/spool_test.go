@@ -0,0 +1,283 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// numberedEvents returns events whose timestamp seconds go from first to first+n-1.
+func numberedEvents(first, n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = &messages.Event{Timestamp: &timestamppb.Timestamp{Seconds: int64(first + i)}}
+	}
+	return events
+}
+
+func eventNumbers(requests []*messages.PublishRequest) []int64 {
+	var numbers []int64
+	for _, r := range requests {
+		for _, e := range r.GetEvents() {
+			numbers = append(numbers, e.GetTimestamp().GetSeconds())
+		}
+	}
+	return numbers
+}
+
+func TestFileSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenFileSpool(dir)
+	require.NoError(t, err)
+
+	_, _, err = s.Peek()
+	require.ErrorIs(t, err, io.EOF)
+
+	first, err := s.Append(numberedEvents(0, 2))
+	require.NoError(t, err)
+	second, err := s.Append(numberedEvents(2, 3))
+	require.NoError(t, err)
+	require.Greater(t, second, first)
+	require.Equal(t, 2, s.Len())
+
+	id, events, err := s.Peek()
+	require.NoError(t, err)
+	require.Equal(t, first, id)
+	require.Len(t, events, 2)
+
+	require.NoError(t, s.Ack(first))
+	require.NoError(t, s.Close())
+
+	t.Run("acknowledged batches are not replayed after reopening", func(t *testing.T) {
+		s, err := OpenFileSpool(dir)
+		require.NoError(t, err)
+		defer s.Close()
+
+		require.Equal(t, 1, s.Len())
+		id, events, err := s.Peek()
+		require.NoError(t, err)
+		require.Equal(t, second, id)
+		require.Len(t, events, 3)
+		require.Equal(t, int64(2), events[0].GetTimestamp().GetSeconds())
+
+		require.NoError(t, s.Ack(second))
+		require.Zero(t, s.Len())
+		third, err := s.Append(numberedEvents(5, 1))
+		require.NoError(t, err)
+		require.Greater(t, third, second)
+	})
+}
+
+func TestFileSpoolTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenFileSpool(dir)
+	require.NoError(t, err)
+	_, err = s.Append(numberedEvents(0, 1))
+	require.NoError(t, err)
+	_, err = s.Append(numberedEvents(1, 1))
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// simulate a crash in the middle of the last write
+	path := filepath.Join(dir, spoolDataFile)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-1))
+
+	s, err = OpenFileSpool(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, 1, s.Len())
+
+	_, err = s.Append(numberedEvents(2, 1))
+	require.NoError(t, err)
+	require.Equal(t, 2, s.Len())
+}
+
+func TestSpoolingPublisher(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "shipper is down")
+	producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{err: unavailable}, {err: unavailable}}}
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 1
+	spool, err := OpenFileSpool(t.TempDir())
+	require.NoError(t, err)
+	defer spool.Close()
+
+	p := NewSpoolingPublisher(New(producer, WithRetryPolicy(policy)), spool, SpoolConfig{ReplayInterval: time.Hour})
+	defer p.Close()
+	ctx := context.Background()
+
+	spooled, err := p.Publish(ctx, numberedEvents(0, 2))
+	require.NoError(t, err)
+	require.True(t, spooled)
+
+	// the spool is not empty so the next events are spooled to keep the order
+	spooled, err = p.Publish(ctx, numberedEvents(2, 2))
+	require.NoError(t, err)
+	require.True(t, spooled)
+	require.Equal(t, 2, spool.Len())
+
+	require.ErrorIs(t, p.Replay(ctx), unavailable)
+	require.Equal(t, 2, spool.Len())
+
+	producer.results = []fakeResult{{accept: 1}}
+	require.NoError(t, p.Replay(ctx))
+	require.Equal(t, 2, spool.Len())
+	require.NoError(t, p.Replay(ctx))
+	require.Zero(t, spool.Len())
+
+	spooled, err = p.Publish(ctx, numberedEvents(4, 1))
+	require.NoError(t, err)
+	require.False(t, spooled)
+	require.Equal(t, []int64{0, 1, 1, 2, 3, 4}, eventNumbers(producer.requests[2:]))
+}
+
+func TestFileSpoolAckPartial(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenFileSpool(dir)
+	require.NoError(t, err)
+	first, err := s.Append(numberedEvents(0, 4))
+	require.NoError(t, err)
+	second, err := s.Append(numberedEvents(4, 1))
+	require.NoError(t, err)
+
+	require.NoError(t, s.AckPartial(first, 1))
+	require.NoError(t, s.AckPartial(first, 2))
+	require.NoError(t, s.Close())
+
+	s, err = OpenFileSpool(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	id, events, err := s.Peek()
+	require.NoError(t, err)
+	require.Equal(t, first, id)
+	require.Equal(t, []int64{3}, eventNumbers([]*messages.PublishRequest{{Events: events}}))
+
+	// acknowledging the batch forgets its acknowledged events
+	require.NoError(t, s.Ack(first))
+	id, events, err = s.Peek()
+	require.NoError(t, err)
+	require.Equal(t, second, id)
+	require.Len(t, events, 1)
+	ack, err := os.ReadFile(filepath.Join(dir, spoolAckFile))
+	require.NoError(t, err)
+	require.Equal(t, strconv.FormatUint(first, 10), string(ack))
+}
+
+func TestSpoolingPublisherRestart(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "shipper is down")
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 1
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{err: unavailable}, {accept: 2}}}
+	spool, err := OpenFileSpool(dir)
+	require.NoError(t, err)
+	p := NewSpoolingPublisher(New(producer, WithRetryPolicy(policy)), spool, SpoolConfig{ReplayInterval: time.Hour})
+	spooled, err := p.Publish(ctx, numberedEvents(0, 4))
+	require.NoError(t, err)
+	require.True(t, spooled)
+	require.NoError(t, p.Replay(ctx))
+	p.Close()
+	require.NoError(t, spool.Close())
+
+	// the events accepted before the restart are not sent again
+	producer = &fakeProducer{uuid: "uuid"}
+	spool, err = OpenFileSpool(dir)
+	require.NoError(t, err)
+	defer spool.Close()
+	p = NewSpoolingPublisher(New(producer, WithRetryPolicy(policy)), spool, SpoolConfig{ReplayInterval: time.Hour})
+	defer p.Close()
+	require.NoError(t, p.Replay(ctx))
+	require.Zero(t, spool.Len())
+	require.Equal(t, []int64{2, 3}, eventNumbers(producer.requests))
+}
+
+func TestSpoolingPublisherDrop(t *testing.T) {
+	producer := &fakeProducer{uuid: "uuid", results: []fakeResult{
+		{err: status.Error(codes.Unavailable, "shipper is down")},
+		{err: status.Error(codes.InvalidArgument, "invalid event")},
+	}}
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 1
+	spool, err := OpenFileSpool(t.TempDir())
+	require.NoError(t, err)
+	defer spool.Close()
+
+	var dropped []*messages.Event
+	p := NewSpoolingPublisher(New(producer, WithRetryPolicy(policy)), spool, SpoolConfig{
+		ReplayInterval: time.Millisecond,
+		OnDrop: func(events []*messages.Event, _ error) {
+			dropped = events
+		},
+	})
+
+	spooled, err := p.Publish(context.Background(), numberedEvents(0, 3))
+	require.NoError(t, err)
+	require.True(t, spooled)
+	require.Eventually(t, func() bool {
+		return spool.Len() == 0
+	}, time.Second, time.Millisecond)
+	p.Close()
+	require.Len(t, dropped, 3)
+}
+
+func TestSpoolingPublisherPartialThenError(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "shipper is down")
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 2
+	policy.InitialBackoff = time.Millisecond
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	// the first event is accepted before the shipper goes away, only the
+	// others are spooled
+	producer := &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 1}, {err: unavailable}}}
+	spool, err := OpenFileSpool(dir)
+	require.NoError(t, err)
+	p := NewSpoolingPublisher(New(producer, WithRetryPolicy(policy)), spool, SpoolConfig{ReplayInterval: time.Hour})
+	spooled, err := p.Publish(ctx, numberedEvents(0, 4))
+	require.NoError(t, err)
+	require.True(t, spooled)
+
+	// the replay is interrupted the same way, then the publisher restarts
+	producer.results = []fakeResult{{accept: 1}, {err: unavailable}}
+	require.ErrorIs(t, p.Replay(ctx), unavailable)
+	p.Close()
+	require.NoError(t, spool.Close())
+	require.Equal(t, []int64{0, 1, 2, 3, 1, 2, 3, 1, 2, 3, 2, 3}, eventNumbers(producer.requests))
+
+	// the accepted events are not sent again
+	producer = &fakeProducer{uuid: "uuid", results: []fakeResult{{accept: 1}, {err: status.Error(codes.InvalidArgument, "invalid event")}}}
+	spool, err = OpenFileSpool(dir)
+	require.NoError(t, err)
+	defer spool.Close()
+	var dropped []*messages.Event
+	p = NewSpoolingPublisher(New(producer, WithRetryPolicy(policy)), spool, SpoolConfig{
+		ReplayInterval: time.Hour,
+		OnDrop: func(events []*messages.Event, _ error) {
+			dropped = events
+		},
+	})
+	defer p.Close()
+	require.NoError(t, p.Replay(ctx))
+	require.Zero(t, spool.Len())
+	require.Equal(t, []int64{2, 3, 3}, eventNumbers(producer.requests))
+	require.Equal(t, []int64{3}, eventNumbers([]*messages.PublishRequest{{Events: dropped}}))
+}
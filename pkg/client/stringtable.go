@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"sort"
+
+	"google.golang.org/grpc"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// EncodeStrings returns a copy of the request where the strings repeated in
+// its events, the field keys, the string values and the data stream names,
+// are replaced by references to the string table of the request. Only the
+// strings whose references are smaller than their repetitions are in the
+// table, the most repeated ones first so they get the shortest references.
+//
+// The events of the request are not modified. The request is returned as is
+// when it already has a string table or when no string is worth a reference.
+// The shipper decodes it with server.DecodeStrings.
+func EncodeStrings(req *messages.PublishRequest) *messages.PublishRequest {
+	if len(req.GetStringTable()) > 0 || len(req.GetEvents()) == 0 {
+		return req
+	}
+
+	counts := map[string]int{}
+	for _, e := range req.GetEvents() {
+		ds := e.GetDataStream()
+		for _, s := range []string{ds.GetType(), ds.GetDataset(), ds.GetNamespace()} {
+			if s != "" {
+				counts[s]++
+			}
+		}
+		countStrings(counts, e.GetMetadata())
+		countStrings(counts, e.GetFields())
+	}
+
+	var table []string
+	for s, n := range counts {
+		if worthReference(n, len(s)) {
+			table = append(table, s)
+		}
+	}
+	if len(table) == 0 {
+		return req
+	}
+	sort.Slice(table, func(i, j int) bool {
+		if counts[table[i]] != counts[table[j]] {
+			return counts[table[i]] > counts[table[j]]
+		}
+		return table[i] < table[j]
+	})
+	enc := stringEncoder{refs: make(map[string]uint32, len(table))}
+	for i, s := range table {
+		enc.refs[s] = uint32(i + 1)
+	}
+
+	encoded := requestHeader(req)
+	encoded.Events = make([]*messages.Event, len(req.GetEvents()))
+	encoded.StringTable = table
+	for i, e := range req.GetEvents() {
+		encoded.Events[i] = enc.event(e)
+	}
+	return encoded
+}
+
+// StringTableInterceptor returns a client interceptor encoding the events of
+// the PublishEvents calls with a string table. The requests seen by the
+// callers are left untouched. It must come before a PackingInterceptor.
+func StringTableInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if r, ok := req.(*messages.PublishRequest); ok {
+			req = EncodeStrings(r)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// worthReference returns whether a string of the given length repeated n
+// times takes more space than a one or two bytes reference to an entry of
+// the table.
+func worthReference(n, length int) bool {
+	return n > 1 && n*(length-1) > length+2
+}
+
+func countStrings(counts map[string]int, s *messages.Struct) {
+	for k, v := range s.GetData() {
+		counts[k]++
+		countValue(counts, v)
+	}
+}
+
+func countValue(counts map[string]int, v *messages.Value) {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_StringValue:
+		counts[kind.StringValue]++
+	case *messages.Value_StructValue:
+		countStrings(counts, kind.StructValue)
+	case *messages.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			countValue(counts, item)
+		}
+	}
+}
+
+// stringEncoder copies events replacing the strings of the table with
+// references. The messages without such strings are shared with the original.
+type stringEncoder struct {
+	refs map[string]uint32
+}
+
+func (enc stringEncoder) event(e *messages.Event) *messages.Event {
+	encoded := shallowCopy(e)
+	encoded.DataStream = enc.dataStream(e.GetDataStream())
+	encoded.Metadata = enc.structure(e.GetMetadata())
+	encoded.Fields = enc.structure(e.GetFields())
+	return encoded
+}
+
+func (enc stringEncoder) dataStream(ds *messages.DataStream) *messages.DataStream {
+	if ds == nil {
+		return nil
+	}
+	encoded := &messages.DataStream{}
+	if encoded.TypeRef = enc.refs[ds.GetType()]; encoded.TypeRef == 0 {
+		encoded.Type = ds.GetType()
+	}
+	if encoded.DatasetRef = enc.refs[ds.GetDataset()]; encoded.DatasetRef == 0 {
+		encoded.Dataset = ds.GetDataset()
+	}
+	if encoded.NamespaceRef = enc.refs[ds.GetNamespace()]; encoded.NamespaceRef == 0 {
+		encoded.Namespace = ds.GetNamespace()
+	}
+	return encoded
+}
+
+func (enc stringEncoder) structure(s *messages.Struct) *messages.Struct {
+	if s == nil {
+		return nil
+	}
+	encoded := &messages.Struct{}
+	for k, v := range s.GetData() {
+		v = enc.value(v)
+		if ref, ok := enc.refs[k]; ok {
+			if encoded.RefData == nil {
+				encoded.RefData = map[uint32]*messages.Value{}
+			}
+			encoded.RefData[ref] = v
+			continue
+		}
+		if encoded.Data == nil {
+			encoded.Data = map[string]*messages.Value{}
+		}
+		encoded.Data[k] = v
+	}
+	return encoded
+}
+
+func (enc stringEncoder) value(v *messages.Value) *messages.Value {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_StringValue:
+		if ref, ok := enc.refs[kind.StringValue]; ok {
+			return &messages.Value{Kind: &messages.Value_StringRef{StringRef: ref}}
+		}
+	case *messages.Value_StructValue:
+		return &messages.Value{Kind: &messages.Value_StructValue{StructValue: enc.structure(kind.StructValue)}}
+	case *messages.Value_ListValue:
+		values := make([]*messages.Value, len(kind.ListValue.GetValues()))
+		for i, item := range kind.ListValue.GetValues() {
+			values[i] = enc.value(item)
+		}
+		return &messages.Value{Kind: &messages.Value_ListValue{ListValue: &messages.ListValue{Values: values}}}
+	}
+	return v
+}
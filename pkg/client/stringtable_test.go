@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestEncodeStrings(t *testing.T) {
+	req := &messages.PublishRequest{Uuid: "uuid", Events: testutil.NewEvents(0, 50), Cursor: []byte("offset=42"), CursorKey: "file"}
+	original := proto.Clone(req)
+
+	encoded := EncodeStrings(req)
+	require.True(t, proto.Equal(original, req), "the request must not change")
+	require.Equal(t, "uuid", encoded.GetUuid())
+	require.Equal(t, []byte("offset=42"), encoded.GetCursor())
+	require.Equal(t, "file", encoded.GetCursorKey())
+	require.NotEmpty(t, encoded.GetStringTable())
+	require.Less(t, proto.Size(encoded), proto.Size(req))
+
+	// the keys repeated in every event are referenced
+	fields := encoded.GetEvents()[0].GetFields()
+	require.NotContains(t, fields.GetData(), "message")
+	require.Contains(t, encoded.GetStringTable(), "message")
+	require.Equal(t, uint32(0), encoded.GetEvents()[0].GetDataStream().GetTypeRef()-encoded.GetEvents()[1].GetDataStream().GetTypeRef())
+	require.Empty(t, encoded.GetEvents()[0].GetDataStream().GetType())
+
+	// encoding is deterministic
+	require.True(t, proto.Equal(encoded, EncodeStrings(req)))
+}
+
+func TestEncodeStringsNotWorth(t *testing.T) {
+	req := &messages.PublishRequest{Events: []*messages.Event{{
+		Fields: &messages.Struct{Data: map[string]*messages.Value{
+			"a": helpers.NewStringValue("unique"),
+			"b": helpers.NewStringValue("x"),
+		}},
+	}}}
+	require.Same(t, req, EncodeStrings(req))
+
+	encoded := &messages.PublishRequest{StringTable: []string{"a"}, Events: req.GetEvents()}
+	require.Same(t, encoded, EncodeStrings(encoded))
+}
+
+func TestWorthReference(t *testing.T) {
+	require.False(t, worthReference(1, 100))
+	require.False(t, worthReference(2, 3))
+	require.True(t, worthReference(2, 5))
+	require.True(t, worthReference(10, 2))
+}
+
+func TestEncodeStringsKeepsFields(t *testing.T) {
+	e := populatedEvent(t)
+	encoded := stringEncoder{}.event(e)
+	requireEventKept(t, e, encoded, "data_stream", "metadata", "fields")
+}
@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSConfig contains the PEM-encoded material needed for a mutual TLS
+// connection to the shipper, as provided by the agent connection info.
+type TLSConfig struct {
+	// CA is the certificate authority used to verify the shipper certificate.
+	CA []byte
+	// Cert is the client certificate.
+	Cert []byte
+	// Key is the private key of the client certificate.
+	Key []byte
+	// ServerName overrides the name used to verify the shipper certificate.
+	ServerName string
+}
+
+// Build returns the tls.Config for the given material.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	pool, err := parseCA(c.CA)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    pool,
+		ServerName: c.ServerName,
+	}
+	if len(c.Cert) > 0 || len(c.Key) > 0 {
+		cert, err := tls.X509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func parseCA(caPEM []byte) (*x509.CertPool, error) {
+	if len(caPEM) == 0 {
+		return nil, errors.New("no certificate authority given")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("failed to parse the certificate authority")
+	}
+	return pool, nil
+}
+
+// TLSCredentials are gRPC transport credentials for mutual TLS whose client
+// certificate and certificate authority can be rotated without recreating
+// the connection. New handshakes, including the ones happening on reconnect,
+// use the latest material.
+type TLSCredentials struct {
+	credentials.TransportCredentials
+
+	// config is the tls.Config of the handshakes, without the RootCAs.
+	config *tls.Config
+
+	mu    sync.RWMutex
+	cert  *tls.Certificate
+	roots *x509.CertPool
+}
+
+// NewTLSCredentials creates new credentials from the given material.
+func NewTLSCredentials(c TLSConfig) (*TLSCredentials, error) {
+	cfg, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &TLSCredentials{roots: cfg.RootCAs}
+	if len(cfg.Certificates) > 0 {
+		creds.cert = &cfg.Certificates[0]
+		cfg.Certificates = nil
+	}
+	cfg.GetClientCertificate = creds.getClientCertificate
+	creds.TransportCredentials = credentials.NewTLS(cfg)
+	creds.config = cfg.Clone()
+	creds.config.RootCAs = nil
+	return creds, nil
+}
+
+// ClientHandshake verifies the shipper certificate with the latest
+// certificate authority.
+func (c *TLSCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	cfg := c.config.Clone()
+	c.mu.RLock()
+	cfg.RootCAs = c.roots
+	c.mu.RUnlock()
+	return credentials.NewTLS(cfg).ClientHandshake(ctx, authority, rawConn)
+}
+
+// Clone returns the credentials themselves, so that the clones made by gRPC
+// follow the rotations too.
+func (c *TLSCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+// Rotate replaces the client certificate.
+func (c *TLSCredentials) Rotate(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to parse the client certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	return nil
+}
+
+// RotateCA replaces the certificate authority used to verify the shipper
+// certificate.
+func (c *TLSCredentials) RotateCA(caPEM []byte) error {
+	pool, err := parseCA(caPEM)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.roots = pool
+	return nil
+}
+
+func (c *TLSCredentials) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.cert == nil {
+		// an empty certificate lets the server decide if it's acceptable
+		return &tls.Certificate{}, nil
+	}
+	return c.cert, nil
+}
@@ -0,0 +1,182 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// peerNameServer replies with the common name of the client certificate as the uuid.
+type peerNameServer struct {
+	proto.UnimplementedProducerServer
+}
+
+func (peerNameServer) PublishEvents(ctx context.Context, _ *messages.PublishRequest) (*messages.PublishReply, error) {
+	p, _ := peer.FromContext(ctx)
+	info := p.AuthInfo.(credentials.TLSInfo)
+	return &messages.PublishReply{Uuid: info.State.PeerCertificates[0].Subject.CommonName}, nil
+}
+
+func TestTLSConfigBuild(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "client", ca)
+
+	_, err := TLSConfig{}.Build()
+	require.Error(t, err)
+	_, err = TLSConfig{CA: []byte("not a certificate")}.Build()
+	require.Error(t, err)
+	_, err = TLSConfig{CA: ca.certPEM, Cert: client.certPEM}.Build()
+	require.Error(t, err)
+
+	cfg, err := TLSConfig{CA: ca.certPEM, Cert: client.certPEM, Key: client.keyPEM, ServerName: "shipper"}.Build()
+	require.NoError(t, err)
+	require.Len(t, cfg.Certificates, 1)
+	require.Equal(t, "shipper", cfg.ServerName)
+}
+
+// startTLSServer starts a peerNameServer with the given certificate, requiring
+// a client certificate signed by clientCA, and returns its address.
+func startTLSServer(t *testing.T, server, clientCA *testCert) string {
+	t.Helper()
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCA.cert)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})))
+	proto.RegisterProducerServer(srv, peerNameServer{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// publishTLS publishes to addr with creds and returns the uuid of the reply.
+func publishTLS(t *testing.T, addr string, creds credentials.TransportCredentials) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := Dial(ctx, addr, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	defer conn.Close()
+	reply, err := proto.NewProducerClient(conn).PublishEvents(ctx, &messages.PublishRequest{})
+	return reply.GetUuid(), err
+}
+
+func TestTLSCredentialsRotation(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newTestCert(t, "shipper", ca)
+	first := newTestCert(t, "first", ca)
+	second := newTestCert(t, "second", ca)
+	addr := startTLSServer(t, server, ca)
+
+	creds, err := NewTLSCredentials(TLSConfig{CA: ca.certPEM, Cert: first.certPEM, Key: first.keyPEM})
+	require.NoError(t, err)
+
+	peerName := func() string {
+		name, err := publishTLS(t, addr, creds)
+		require.NoError(t, err)
+		return name
+	}
+
+	require.Equal(t, "first", peerName())
+	require.Error(t, creds.Rotate(second.certPEM, first.keyPEM))
+	require.NoError(t, creds.Rotate(second.certPEM, second.keyPEM))
+	require.Equal(t, "second", peerName())
+}
+
+func TestTLSCredentialsCARotation(t *testing.T) {
+	oldCA := newTestCert(t, "old ca", nil)
+	newCA := newTestCert(t, "new ca", nil)
+	client := newTestCert(t, "client", oldCA)
+	// the shipper certificate is reissued by the new authority
+	addr := startTLSServer(t, newTestCert(t, "shipper", newCA), oldCA)
+
+	creds, err := NewTLSCredentials(TLSConfig{CA: oldCA.certPEM, Cert: client.certPEM, Key: client.keyPEM})
+	require.NoError(t, err)
+	_, err = publishTLS(t, addr, creds)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	require.Error(t, creds.RotateCA(nil))
+	require.Error(t, creds.RotateCA([]byte("not a certificate")))
+	require.NoError(t, creds.RotateCA(newCA.certPEM))
+	name, err := publishTLS(t, addr, creds)
+	require.NoError(t, err)
+	require.Equal(t, "client", name)
+
+	// the clones made by gRPC use the new authority too
+	name, err = publishTLS(t, addr, creds.Clone())
+	require.NoError(t, err)
+	require.Equal(t, "client", name)
+}
@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+)
+
+// InvalidEventError is returned when an event fails the validation enabled
+// with WithValidation. Nothing is sent to the shipper.
+type InvalidEventError struct {
+	// Index is the index of the invalid event in the request.
+	Index int
+	// Err is the error returned by helpers.ValidateEvent.
+	Err error
+}
+
+func (e *InvalidEventError) Error() string {
+	return fmt.Sprintf("event %d is invalid: %v", e.Index, e.Err)
+}
+
+func (e *InvalidEventError) Unwrap() error {
+	return e.Err
+}
+
+// WithValidation checks every event with helpers.ValidateEvent before it's
+// published, instead of waiting for the shipper to reject it. Raw events are
+// not checked.
+func WithValidation(opts helpers.ValidateOptions) Option {
+	return func(c *Client) {
+		c.validation = &opts
+	}
+}
+
+// validate returns an *InvalidEventError for the first invalid event of the
+// batch, when the validation is enabled.
+func (c *Client) validate(b batch) error {
+	pb, ok := b.(protoBatch)
+	if c.validation == nil || !ok {
+		return nil
+	}
+	for i, e := range pb.req.GetEvents() {
+		if err := helpers.ValidateEvent(e, *c.validation); err != nil {
+			return &InvalidEventError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
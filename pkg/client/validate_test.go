@@ -0,0 +1,38 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestPublishValidation(t *testing.T) {
+	events := testutil.NewEvents(1, 3)
+	events[1].Timestamp, events[1].TimestampRaw = nil, ""
+
+	producer := &fakeProducer{uuid: "uuid"}
+	c := New(producer, WithValidation(helpers.ValidateOptions{}))
+	_, err := c.Publish(context.Background(), &messages.PublishRequest{Events: events})
+	var invalid *InvalidEventError
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, 1, invalid.Index)
+	var errs helpers.ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	require.Equal(t, "timestamp", errs[0].Field)
+	require.Empty(t, producer.requests, "nothing is sent")
+
+	// without validation the shipper decides
+	c = New(producer)
+	_, err = c.Publish(context.Background(), &messages.PublishRequest{Events: events})
+	require.NoError(t, err)
+	require.Len(t, producer.requests, 1)
+}
@@ -0,0 +1,165 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package cloudevents converts CloudEvents 1.0, in the JSON and the protobuf
+// formats, to shipper events and back, for the integrations bridging event
+// buses into the agent pipeline.
+//
+// The context attributes of a CloudEvent are kept in the metadata of the
+// event under MetadataKey, except the time that is the timestamp of the
+// event. The data is mapped to:
+//   - the fields of the event when it's a JSON object, under a "data" field
+//     when it's another JSON value or a text that is not JSON,
+//   - the attachment of the event when it's binary,
+//   - the extensions of the event when it's a protobuf message, in the
+//     protobuf format only.
+//
+// Converting an event back gives the same CloudEvent, except that JSON data
+// that was not an object comes back as an object with a "data" key, and that
+// the time attribute is always set.
+package cloudevents
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// MetadataKey is the metadata key holding the context attributes of the
+// CloudEvent, except the time.
+const MetadataKey = "cloudevents"
+
+// SpecVersion is the CloudEvents specification version of the converted events.
+const SpecVersion = "1.0"
+
+// dataKey is the field holding the data that is not a JSON object.
+const dataKey = "data"
+
+// cloudEvent is a CloudEvent independent of its format.
+type cloudEvent struct {
+	// attributes are the context attributes, except the time
+	attributes map[string]*messages.Value
+	time       *timestamppb.Timestamp
+	// at most one of the data is set
+	data      *messages.Value
+	binary    []byte
+	protoData *anypb.Any
+}
+
+// contentType returns the datacontenttype attribute.
+func (ce *cloudEvent) contentType() string {
+	return ce.attributes["datacontenttype"].GetStringValue()
+}
+
+// jsonContentType returns true if the data with the content type is JSON.
+// An absent content type is JSON, as in the JSON format.
+func jsonContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	return mediaType == "" || mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// event returns the shipper event of the CloudEvent on the data stream. The
+// timestamp is now when the CloudEvent has no time.
+func (ce *cloudEvent) event(dataStream *messages.DataStream) (*messages.Event, error) {
+	for _, name := range []string{"id", "source", "type"} {
+		if ce.attributes[name].GetStringValue() == "" {
+			return nil, fmt.Errorf("the %s attribute is required", name)
+		}
+	}
+	if v := ce.attributes["specversion"].GetStringValue(); v != SpecVersion {
+		return nil, fmt.Errorf("unsupported specversion %q", v)
+	}
+
+	e := &messages.Event{
+		Timestamp:  ce.time,
+		DataStream: dataStream,
+		Metadata: &messages.Struct{Data: map[string]*messages.Value{
+			MetadataKey: helpers.NewStructValue(&messages.Struct{Data: ce.attributes}),
+		}},
+	}
+	if e.Timestamp == nil {
+		e.Timestamp = timestamppb.Now()
+	}
+	switch {
+	case ce.data != nil:
+		if s := ce.data.GetStructValue(); s != nil {
+			e.Fields = s
+		} else {
+			e.Fields = &messages.Struct{Data: map[string]*messages.Value{dataKey: ce.data}}
+		}
+	case ce.binary != nil:
+		e.Attachment = &messages.Attachment{ContentType: ce.contentType(), Data: ce.binary}
+	case ce.protoData != nil:
+		e.Extensions = []*anypb.Any{ce.protoData}
+	}
+	return e, nil
+}
+
+// fromEvent returns the CloudEvent of a shipper event converted by event.
+func fromEvent(e *messages.Event) (*cloudEvent, error) {
+	attributes := e.GetMetadata().GetData()[MetadataKey].GetStructValue()
+	if attributes == nil {
+		return nil, errors.New("the event has no CloudEvents attributes")
+	}
+	ce := &cloudEvent{attributes: make(map[string]*messages.Value, len(attributes.GetData())+1), time: e.GetTimestamp()}
+	for k, v := range attributes.GetData() {
+		ce.attributes[k] = v
+	}
+	if _, ok := ce.attributes["specversion"]; !ok {
+		ce.attributes["specversion"] = helpers.NewStringValue(SpecVersion)
+	}
+	for _, name := range []string{"id", "source", "type"} {
+		if ce.attributes[name].GetStringValue() == "" {
+			return nil, fmt.Errorf("the %s attribute is required", name)
+		}
+	}
+
+	switch {
+	case e.GetAttachment() != nil:
+		ce.binary = e.GetAttachment().GetData()
+		if _, ok := ce.attributes["datacontenttype"]; !ok && e.GetAttachment().GetContentType() != "" {
+			ce.attributes["datacontenttype"] = helpers.NewStringValue(e.GetAttachment().GetContentType())
+		}
+	case e.GetFields() != nil:
+		ce.data = helpers.NewStructValue(e.GetFields())
+		if v, ok := e.GetFields().GetData()[dataKey]; ok && len(e.GetFields().GetData()) == 1 && !jsonContentType(ce.contentType()) {
+			ce.data = v
+		}
+	case len(e.GetExtensions()) == 1:
+		ce.protoData = e.GetExtensions()[0]
+	case len(e.GetExtensions()) > 1:
+		return nil, errors.New("the event has more than one extension")
+	}
+	return ce, nil
+}
+
+// attributeValue checks the value of an extension attribute read from the
+// JSON format, where integers are numbers and the other types are strings.
+func attributeValue(name string, v interface{}) (*messages.Value, error) {
+	switch v := v.(type) {
+	case string:
+		return helpers.NewStringValue(v), nil
+	case bool:
+		return helpers.NewBoolValue(v), nil
+	case float64:
+		if v != float64(int32(v)) {
+			return nil, fmt.Errorf("the %s attribute is not a 32-bit integer: %v", name, v)
+		}
+		return helpers.NewInt32Value(int32(v)), nil
+	default:
+		return nil, fmt.Errorf("the %s attribute has an invalid type %T", name, v)
+	}
+}
+
+// formatTime formats a time attribute like RFC 3339 requires.
+func formatTime(ts *timestamppb.Timestamp) string {
+	return ts.AsTime().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,107 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloudevents
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// FromJSON converts a CloudEvent in the JSON format, structured mode, to a
+// shipper event on the data stream.
+func FromJSON(data []byte, dataStream *messages.DataStream) (*messages.Event, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("invalid CloudEvent: %w", err)
+	}
+
+	ce := &cloudEvent{attributes: make(map[string]*messages.Value, len(members))}
+	for name, raw := range members {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("invalid %s member: %w", name, err)
+		}
+		if v == nil {
+			// a null attribute is absent
+			continue
+		}
+
+		switch name {
+		case "data":
+			value, err := helpers.NewValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid data: %w", err)
+			}
+			ce.data = value
+		case "data_base64":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("data_base64 is not a string")
+			}
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid data_base64: %w", err)
+			}
+			ce.binary = b
+		case "time":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.New("the time attribute is not a string")
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time attribute: %w", err)
+			}
+			ce.time = timestamppb.New(t)
+		default:
+			value, err := attributeValue(name, v)
+			if err != nil {
+				return nil, err
+			}
+			ce.attributes[name] = value
+		}
+	}
+	if ce.data != nil && ce.binary != nil {
+		return nil, errors.New("the CloudEvent has both data and data_base64")
+	}
+	return ce.event(dataStream)
+}
+
+// ToJSON converts a shipper event converted from a CloudEvent back to the
+// JSON format. Protobuf data can't be represented in the JSON format.
+func ToJSON(e *messages.Event) ([]byte, error) {
+	ce, err := fromEvent(e)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string]interface{}, len(ce.attributes)+2)
+	for name, v := range ce.attributes {
+		if ts := v.GetTimestampValue(); ts != nil {
+			members[name] = formatTime(ts)
+			continue
+		}
+		members[name] = helpers.AsInterface(v)
+	}
+	if ce.time != nil {
+		members["time"] = formatTime(ce.time)
+	}
+	switch {
+	case ce.data != nil:
+		members["data"] = helpers.AsInterface(ce.data)
+	case ce.binary != nil:
+		members["data_base64"] = base64.StdEncoding.EncodeToString(ce.binary)
+	case ce.protoData != nil:
+		return nil, errors.New("protobuf data can't be represented in the JSON format")
+	}
+	return json.Marshal(members)
+}
@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloudevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+var testDataStream = &messages.DataStream{Type: "logs", Dataset: "cloudevents", Namespace: "default"}
+
+func TestJSON(t *testing.T) {
+	data := `{
+		"specversion": "1.0",
+		"id": "A234-1234-1234",
+		"source": "/mycontext",
+		"type": "com.example.someevent",
+		"time": "2018-04-05T17:31:00Z",
+		"subject": "larger-context",
+		"datacontenttype": "application/json",
+		"comexampleextension1": "value",
+		"comexampleothervalue": 5,
+		"data": {"message": "hello", "count": 2}
+	}`
+	e, err := FromJSON([]byte(data), testDataStream)
+	require.NoError(t, err)
+	require.NoError(t, e.Validate())
+	require.Equal(t, time.Date(2018, 4, 5, 17, 31, 0, 0, time.UTC), e.GetTimestamp().AsTime())
+	require.Same(t, testDataStream, e.GetDataStream())
+	attributes := e.GetMetadata().GetData()[MetadataKey].GetStructValue().GetData()
+	require.Equal(t, "A234-1234-1234", attributes["id"].GetStringValue())
+	require.Equal(t, "larger-context", attributes["subject"].GetStringValue())
+	require.Equal(t, int32(5), attributes["comexampleothervalue"].GetInt32Value())
+	require.NotContains(t, attributes, "time")
+	require.Equal(t, map[string]interface{}{"message": "hello", "count": float64(2)}, helpers.AsMap(e.GetFields()))
+
+	back, err := ToJSON(e)
+	require.NoError(t, err)
+	require.JSONEq(t, data, string(back))
+}
+
+func TestJSONData(t *testing.T) {
+	t.Run("binary", func(t *testing.T) {
+		data := `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"image/png","data_base64":"AQID"}`
+		e, err := FromJSON([]byte(data), testDataStream)
+		require.NoError(t, err)
+		require.Equal(t, &messages.Attachment{ContentType: "image/png", Data: []byte{1, 2, 3}}, e.GetAttachment())
+
+		back, err := ToJSON(e)
+		require.NoError(t, err)
+		require.Contains(t, string(back), `"data_base64":"AQID"`)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		data := `{"specversion":"1.0","id":"1","source":"/s","type":"t","datacontenttype":"text/plain","data":"hello"}`
+		e, err := FromJSON([]byte(data), testDataStream)
+		require.NoError(t, err)
+		require.Equal(t, "hello", e.GetFields().GetData()["data"].GetStringValue())
+
+		back, err := ToJSON(e)
+		require.NoError(t, err)
+		require.JSONEq(t, data[:len(data)-1]+`,"time":"`+formatTime(e.GetTimestamp())+`"}`, string(back))
+	})
+
+	t.Run("no time", func(t *testing.T) {
+		e, err := FromJSON([]byte(`{"specversion":"1.0","id":"1","source":"/s","type":"t"}`), testDataStream)
+		require.NoError(t, err)
+		require.NotNil(t, e.GetTimestamp())
+		require.Nil(t, e.GetFields())
+	})
+}
+
+func TestJSONInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"not an object":       `[]`,
+		"no id":               `{"specversion":"1.0","source":"/s","type":"t"}`,
+		"unsupported version": `{"specversion":"0.3","id":"1","source":"/s","type":"t"}`,
+		"invalid time":        `{"specversion":"1.0","id":"1","source":"/s","type":"t","time":"yesterday"}`,
+		"invalid extension":   `{"specversion":"1.0","id":"1","source":"/s","type":"t","ext":1.5}`,
+		"both data":           `{"specversion":"1.0","id":"1","source":"/s","type":"t","data":{},"data_base64":""}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := FromJSON([]byte(data), testDataStream)
+			require.Error(t, err)
+		})
+	}
+
+	_, err := ToJSON(&messages.Event{})
+	require.Error(t, err, "the event was not converted from a CloudEvent")
+}
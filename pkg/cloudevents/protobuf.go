@@ -0,0 +1,341 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloudevents
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/unsafeconv"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// The field numbers of the io.cloudevents.v1.CloudEvent message of the
+// protobuf format. The messages are encoded by hand rather than generated so
+// this module doesn't register the CloudEvents protobuf package, which would
+// conflict with the CloudEvents SDK.
+const (
+	fieldID          protowire.Number = 1
+	fieldSource      protowire.Number = 2
+	fieldSpecVersion protowire.Number = 3
+	fieldType        protowire.Number = 4
+	fieldAttributes  protowire.Number = 5
+	fieldBinaryData  protowire.Number = 6
+	fieldTextData    protowire.Number = 7
+	fieldProtoData   protowire.Number = 8
+)
+
+// The field numbers of the io.cloudevents.v1.CloudEventAttributeValue message.
+const (
+	attrBoolean   protowire.Number = 1
+	attrInteger   protowire.Number = 2
+	attrString    protowire.Number = 3
+	attrBytes     protowire.Number = 4
+	attrURI       protowire.Number = 5
+	attrURIRef    protowire.Number = 6
+	attrTimestamp protowire.Number = 7
+)
+
+// topLevelAttributes are the attributes that are fields of the CloudEvent
+// message rather than entries of its attributes map.
+var topLevelAttributes = map[string]protowire.Number{
+	"id":          fieldID,
+	"source":      fieldSource,
+	"specversion": fieldSpecVersion,
+	"type":        fieldType,
+}
+
+// FromProto converts a serialized io.cloudevents.v1.CloudEvent, the protobuf
+// format, to a shipper event on the data stream.
+func FromProto(data []byte, dataStream *messages.DataStream) (*messages.Event, error) {
+	ce := &cloudEvent{attributes: map[string]*messages.Value{}}
+	var text *string
+	err := consumeFields(data, func(num protowire.Number, value []byte) error {
+		switch num {
+		case fieldID:
+			ce.attributes["id"] = helpers.NewStringValue(string(value))
+		case fieldSource:
+			ce.attributes["source"] = helpers.NewStringValue(string(value))
+		case fieldSpecVersion:
+			ce.attributes["specversion"] = helpers.NewStringValue(string(value))
+		case fieldType:
+			ce.attributes["type"] = helpers.NewStringValue(string(value))
+		case fieldAttributes:
+			name, v, err := consumeAttribute(value)
+			if err != nil {
+				return err
+			}
+			if name == "time" {
+				if v.GetTimestampValue() == nil {
+					return errors.New("the time attribute is not a timestamp")
+				}
+				ce.time = v.GetTimestampValue()
+				return nil
+			}
+			ce.attributes[name] = v
+		case fieldBinaryData:
+			ce.binary = append([]byte{}, value...)
+		case fieldTextData:
+			s := string(value)
+			text = &s
+		case fieldProtoData:
+			a, err := consumeAny(value)
+			if err != nil {
+				return err
+			}
+			ce.protoData = a
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CloudEvent: %w", err)
+	}
+
+	if text != nil {
+		if !jsonContentType(ce.contentType()) {
+			ce.data = helpers.NewStringValue(*text)
+		} else {
+			var v interface{}
+			if err := json.Unmarshal(unsafeconv.Bytes(*text), &v); err != nil {
+				return nil, fmt.Errorf("invalid JSON text data: %w", err)
+			}
+			if ce.data, err = helpers.NewValue(v); err != nil {
+				return nil, fmt.Errorf("invalid JSON text data: %w", err)
+			}
+		}
+	}
+	return ce.event(dataStream)
+}
+
+// ToProto converts a shipper event converted from a CloudEvent back to a
+// serialized io.cloudevents.v1.CloudEvent. The data of the fields is sent as
+// text data, JSON unless the content type is not.
+func ToProto(e *messages.Event) ([]byte, error) {
+	ce, err := fromEvent(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	for _, name := range []string{"id", "source", "specversion", "type"} {
+		b = protowire.AppendTag(b, topLevelAttributes[name], protowire.BytesType)
+		b = protowire.AppendString(b, ce.attributes[name].GetStringValue())
+	}
+	// the map entries are sorted for the encoding to be deterministic
+	names := make([]string, 0, len(ce.attributes))
+	for name := range ce.attributes {
+		if _, ok := topLevelAttributes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if b, err = appendAttribute(b, name, ce.attributes[name]); err != nil {
+			return nil, err
+		}
+	}
+	if ce.time != nil {
+		if b, err = appendAttribute(b, "time", &messages.Value{Kind: &messages.Value_TimestampValue{TimestampValue: ce.time}}); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case ce.data != nil:
+		var text []byte
+		if _, ok := ce.data.GetKind().(*messages.Value_StringValue); ok && !jsonContentType(ce.contentType()) {
+			text = unsafeconv.Bytes(ce.data.GetStringValue())
+		} else if text, err = json.Marshal(helpers.AsInterface(ce.data)); err != nil {
+			return nil, fmt.Errorf("failed to marshal the data: %w", err)
+		}
+		b = protowire.AppendTag(b, fieldTextData, protowire.BytesType)
+		b = protowire.AppendBytes(b, text)
+	case ce.binary != nil:
+		b = protowire.AppendTag(b, fieldBinaryData, protowire.BytesType)
+		b = protowire.AppendBytes(b, ce.binary)
+	case ce.protoData != nil:
+		var a []byte
+		a = protowire.AppendTag(a, 1, protowire.BytesType)
+		a = protowire.AppendString(a, ce.protoData.GetTypeUrl())
+		a = protowire.AppendTag(a, 2, protowire.BytesType)
+		a = protowire.AppendBytes(a, ce.protoData.GetValue())
+		b = protowire.AppendTag(b, fieldProtoData, protowire.BytesType)
+		b = protowire.AppendBytes(b, a)
+	}
+	return b, nil
+}
+
+// consumeFields calls fb with the payload of every length-delimited field of
+// the serialized message, and fv with the value of every varint field, when
+// they are set. The other fields are skipped.
+func consumeFields(data []byte, fb func(num protowire.Number, value []byte) error, fv func(num protowire.Number, v uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch typ {
+		case protowire.BytesType:
+			value, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			if fb != nil {
+				if err := fb(num, value); err != nil {
+					return err
+				}
+			}
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			if fv != nil {
+				if err := fv(num, v); err != nil {
+					return err
+				}
+			}
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	return nil
+}
+
+// consumeAttribute decodes an entry of the attributes map.
+func consumeAttribute(entry []byte) (string, *messages.Value, error) {
+	var name string
+	var value *messages.Value
+	err := consumeFields(entry, func(num protowire.Number, data []byte) error {
+		switch num {
+		case 1:
+			name = string(data)
+		case 2:
+			v, err := consumeAttributeValue(data)
+			if err != nil {
+				return err
+			}
+			value = v
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if value == nil {
+		return "", nil, fmt.Errorf("the %s attribute has no value", name)
+	}
+	return name, value, nil
+}
+
+// consumeAttributeValue decodes a CloudEventAttributeValue. The bytes are
+// base64 encoded like in the JSON format, the URIs are strings.
+func consumeAttributeValue(data []byte) (*messages.Value, error) {
+	var value *messages.Value
+	err := consumeFields(data, func(num protowire.Number, b []byte) error {
+		switch num {
+		case attrString, attrURI, attrURIRef:
+			value = helpers.NewStringValue(string(b))
+		case attrBytes:
+			value = helpers.NewStringValue(base64.StdEncoding.EncodeToString(b))
+		case attrTimestamp:
+			ts, err := consumeTimestamp(b)
+			if err != nil {
+				return err
+			}
+			value = &messages.Value{Kind: &messages.Value_TimestampValue{TimestampValue: ts}}
+		}
+		return nil
+	}, func(num protowire.Number, v uint64) error {
+		switch num {
+		case attrBoolean:
+			value = helpers.NewBoolValue(protowire.DecodeBool(v))
+		case attrInteger:
+			value = helpers.NewInt32Value(int32(v))
+		}
+		return nil
+	})
+	return value, err
+}
+
+func consumeTimestamp(data []byte) (*timestamppb.Timestamp, error) {
+	ts := &timestamppb.Timestamp{}
+	err := consumeFields(data, nil, func(num protowire.Number, v uint64) error {
+		switch num {
+		case 1:
+			ts.Seconds = int64(v)
+		case 2:
+			ts.Nanos = int32(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ts, ts.CheckValid()
+}
+
+func consumeAny(data []byte) (*anypb.Any, error) {
+	a := &anypb.Any{}
+	err := consumeFields(data, func(num protowire.Number, b []byte) error {
+		switch num {
+		case 1:
+			a.TypeUrl = string(b)
+		case 2:
+			a.Value = append([]byte{}, b...)
+		}
+		return nil
+	}, nil)
+	return a, err
+}
+
+// appendAttribute appends an entry of the attributes map.
+func appendAttribute(b []byte, name string, v *messages.Value) ([]byte, error) {
+	var value []byte
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_BoolValue:
+		value = protowire.AppendTag(value, attrBoolean, protowire.VarintType)
+		value = protowire.AppendVarint(value, protowire.EncodeBool(kind.BoolValue))
+	case *messages.Value_Int32Value:
+		value = protowire.AppendTag(value, attrInteger, protowire.VarintType)
+		value = protowire.AppendVarint(value, uint64(kind.Int32Value))
+	case *messages.Value_StringValue:
+		value = protowire.AppendTag(value, attrString, protowire.BytesType)
+		value = protowire.AppendString(value, kind.StringValue)
+	case *messages.Value_TimestampValue:
+		var ts []byte
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(kind.TimestampValue.GetSeconds()))
+		ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(kind.TimestampValue.GetNanos()))
+		value = protowire.AppendTag(value, attrTimestamp, protowire.BytesType)
+		value = protowire.AppendBytes(value, ts)
+	default:
+		return nil, fmt.Errorf("the %s attribute has an unsupported type", name)
+	}
+
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendString(entry, name)
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, value)
+	b = protowire.AppendTag(b, fieldAttributes, protowire.BytesType)
+	return protowire.AppendBytes(b, entry), nil
+}
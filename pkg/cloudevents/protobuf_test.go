@@ -0,0 +1,203 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package cloudevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// cloudEventDescriptor returns the descriptor of io.cloudevents.v1.CloudEvent,
+// as in the cloudevents.proto file of the specification, without registering it.
+func cloudEventDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, oneof *int32) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:       proto.String(name),
+			Number:     proto.Int32(number),
+			Label:      descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:       typ.Enum(),
+			OneofIndex: oneof,
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	const (
+		str   = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		bytes = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+		msg   = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	zero := proto.Int32(0)
+
+	attributes := field("attributes", 5, msg, ".io.cloudevents.v1.CloudEvent.AttributesEntry", nil)
+	attributes.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("cloudevents.proto"),
+		Package:    proto.String("io.cloudevents.v1"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/any.proto", "google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("CloudEvent"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("id", 1, str, "", nil),
+					field("source", 2, str, "", nil),
+					field("spec_version", 3, str, "", nil),
+					field("type", 4, str, "", nil),
+					attributes,
+					field("binary_data", 6, bytes, "", zero),
+					field("text_data", 7, str, "", zero),
+					field("proto_data", 8, msg, ".google.protobuf.Any", zero),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("data")}},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("AttributesEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, str, "", nil),
+						field("value", 2, msg, ".io.cloudevents.v1.CloudEvent.CloudEventAttributeValue", nil),
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}, {
+					Name: proto.String("CloudEventAttributeValue"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("ce_boolean", 1, descriptorpb.FieldDescriptorProto_TYPE_BOOL, "", zero),
+						field("ce_integer", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", zero),
+						field("ce_string", 3, str, "", zero),
+						field("ce_bytes", 4, bytes, "", zero),
+						field("ce_uri", 5, str, "", zero),
+						field("ce_uri_ref", 6, str, "", zero),
+						field("ce_timestamp", 7, msg, ".google.protobuf.Timestamp", zero),
+					},
+					OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("attr")}},
+				}},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	return fd.Messages().ByName("CloudEvent")
+}
+
+func TestProto(t *testing.T) {
+	desc := cloudEventDescriptor(t)
+	attrDesc := desc.Messages().ByName("CloudEventAttributeValue")
+	attr := func(name string, value protoreflect.Value) protoreflect.Value {
+		v := dynamicpb.NewMessage(attrDesc)
+		v.Set(attrDesc.Fields().ByName(protoreflect.Name(name)), value)
+		return protoreflect.ValueOfMessage(v)
+	}
+	ts := time.Date(2018, 4, 5, 17, 31, 0, 0, time.UTC)
+
+	ce := dynamicpb.NewMessage(desc)
+	fields := desc.Fields()
+	ce.Set(fields.ByName("id"), protoreflect.ValueOfString("A234-1234-1234"))
+	ce.Set(fields.ByName("source"), protoreflect.ValueOfString("/mycontext"))
+	ce.Set(fields.ByName("spec_version"), protoreflect.ValueOfString("1.0"))
+	ce.Set(fields.ByName("type"), protoreflect.ValueOfString("com.example.someevent"))
+	attributes := ce.Mutable(fields.ByName("attributes")).Map()
+	attributes.Set(protoreflect.ValueOfString("time").MapKey(), attr("ce_timestamp", protoreflect.ValueOfMessage(timestamppb.New(ts).ProtoReflect())))
+	attributes.Set(protoreflect.ValueOfString("dataschema").MapKey(), attr("ce_uri", protoreflect.ValueOfString("https://example.com/schema")))
+	attributes.Set(protoreflect.ValueOfString("retries").MapKey(), attr("ce_integer", protoreflect.ValueOfInt32(3)))
+	attributes.Set(protoreflect.ValueOfString("sampled").MapKey(), attr("ce_boolean", protoreflect.ValueOfBool(true)))
+	ce.Set(fields.ByName("text_data"), protoreflect.ValueOfString(`{"message":"hello"}`))
+	data, err := proto.Marshal(ce)
+	require.NoError(t, err)
+
+	e, err := FromProto(data, testDataStream)
+	require.NoError(t, err)
+	require.NoError(t, e.Validate())
+	require.Equal(t, ts, e.GetTimestamp().AsTime())
+	require.Equal(t, map[string]interface{}{
+		"id":          "A234-1234-1234",
+		"source":      "/mycontext",
+		"specversion": "1.0",
+		"type":        "com.example.someevent",
+		"dataschema":  "https://example.com/schema",
+		"retries":     int32(3),
+		"sampled":     true,
+	}, helpers.AsMap(e.GetMetadata().GetData()[MetadataKey].GetStructValue()))
+	require.Equal(t, map[string]interface{}{"message": "hello"}, helpers.AsMap(e.GetFields()))
+
+	back, err := ToProto(e)
+	require.NoError(t, err)
+	decoded := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(back, decoded))
+	// the URI comes back as a string
+	attributes.Set(protoreflect.ValueOfString("dataschema").MapKey(), attr("ce_string", protoreflect.ValueOfString("https://example.com/schema")))
+	require.True(t, proto.Equal(ce, decoded), decoded)
+
+	again, err := ToProto(e)
+	require.NoError(t, err)
+	require.Equal(t, back, again, "the encoding is deterministic")
+}
+
+func TestProtoData(t *testing.T) {
+	base := &messages.Event{
+		Timestamp: timestamppb.Now(),
+		Metadata: &messages.Struct{Data: map[string]*messages.Value{
+			MetadataKey: helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+				"id":     helpers.NewStringValue("1"),
+				"source": helpers.NewStringValue("/s"),
+				"type":   helpers.NewStringValue("t"),
+			}}),
+		}},
+	}
+
+	t.Run("binary", func(t *testing.T) {
+		e := proto.Clone(base).(*messages.Event)
+		e.Attachment = &messages.Attachment{ContentType: "image/png", Data: []byte{1, 2, 3}}
+		data, err := ToProto(e)
+		require.NoError(t, err)
+		back, err := FromProto(data, testDataStream)
+		require.NoError(t, err)
+		require.True(t, proto.Equal(e.GetAttachment(), back.GetAttachment()))
+		require.Equal(t, "image/png", back.GetMetadata().GetData()[MetadataKey].GetStructValue().GetData()["datacontenttype"].GetStringValue())
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		e := proto.Clone(base).(*messages.Event)
+		a, err := anypb.New(timestamppb.Now())
+		require.NoError(t, err)
+		e.Extensions = []*anypb.Any{a}
+		data, err := ToProto(e)
+		require.NoError(t, err)
+		back, err := FromProto(data, testDataStream)
+		require.NoError(t, err)
+		require.Len(t, back.GetExtensions(), 1)
+		require.True(t, proto.Equal(a, back.GetExtensions()[0]))
+
+		_, err = ToJSON(e)
+		require.Error(t, err)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		e := proto.Clone(base).(*messages.Event)
+		e.GetMetadata().GetData()[MetadataKey].GetStructValue().Data["datacontenttype"] = helpers.NewStringValue("text/plain")
+		e.Fields = &messages.Struct{Data: map[string]*messages.Value{"data": helpers.NewStringValue("hello")}}
+		data, err := ToProto(e)
+		require.NoError(t, err)
+		back, err := FromProto(data, testDataStream)
+		require.NoError(t, err)
+		// the text is not JSON, it's kept as is
+		require.True(t, proto.Equal(e.GetFields(), back.GetFields()))
+	})
+
+	_, err := FromProto([]byte{0xff}, testDataStream)
+	require.Error(t, err)
+}
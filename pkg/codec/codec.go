@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package codec contains a gRPC codec using the vtproto generated methods of
+// the messages, which avoid the reflection of the generic proto marshaling.
+package codec
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/protobuf/proto"
+)
+
+// Name is the name of the codec, it replaces the default proto codec.
+const Name = "proto"
+
+// vtMessage is implemented by the messages with vtproto generated code.
+type vtMessage interface {
+	MarshalVT() ([]byte, error)
+	UnmarshalVT([]byte) error
+}
+
+// Codec marshals the messages with their MarshalVT and UnmarshalVT methods,
+// other messages are marshaled with the proto package.
+type Codec struct{}
+
+var _ encoding.Codec = Codec{}
+
+// Marshal implements encoding.Codec.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case vtMessage:
+		return m.MarshalVT()
+	case proto.Message:
+		return proto.Marshal(m)
+	}
+	return nil, fmt.Errorf("failed to marshal, message is %T, want proto.Message", v)
+}
+
+// Unmarshal implements encoding.Codec.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	switch m := v.(type) {
+	case vtMessage:
+		return m.UnmarshalVT(data)
+	case proto.Message:
+		return proto.Unmarshal(data, m)
+	}
+	return fmt.Errorf("failed to unmarshal, message is %T, want proto.Message", v)
+}
+
+// Name implements encoding.Codec.
+func (Codec) Name() string {
+	return Name
+}
+
+// DialOption returns the dial option making a client use the codec.
+func DialOption() grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.ForceCodec(Codec{}))
+}
+
+// ServerOption returns the server option making a server use the codec.
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(Codec{})
+}
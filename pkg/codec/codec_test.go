@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package codec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	shippertest "github.com/elastic/elastic-agent-shipper-client/pkg/testing"
+)
+
+// fakeVT records the calls to the vtproto methods.
+type fakeVT struct {
+	data []byte
+}
+
+func (f *fakeVT) MarshalVT() ([]byte, error) { return []byte("vt"), nil }
+func (f *fakeVT) UnmarshalVT(data []byte) error {
+	f.data = data
+	return nil
+}
+
+func TestCodec(t *testing.T) {
+	var c Codec
+	require.Equal(t, "proto", c.Name())
+
+	data, err := c.Marshal(&fakeVT{})
+	require.NoError(t, err)
+	require.Equal(t, []byte("vt"), data)
+	var f fakeVT
+	require.NoError(t, c.Unmarshal([]byte("data"), &f))
+	require.Equal(t, []byte("data"), f.data)
+
+	// the generated methods are compatible with the proto package
+	req := &messages.PublishRequest{Events: testutil.NewEvents(0, 2)}
+	data, err = c.Marshal(req)
+	require.NoError(t, err)
+	var decoded messages.PublishRequest
+	require.NoError(t, gproto.Unmarshal(data, &decoded))
+	require.True(t, gproto.Equal(req, &decoded))
+	data, err = gproto.Marshal(req)
+	require.NoError(t, err)
+	decoded.Reset()
+	require.NoError(t, c.Unmarshal(data, &decoded))
+	require.True(t, gproto.Equal(req, &decoded))
+
+	// messages without vtproto methods
+	ts := timestamppb.Now()
+	data, err = c.Marshal(ts)
+	require.NoError(t, err)
+	var decodedTS timestamppb.Timestamp
+	require.NoError(t, c.Unmarshal(data, &decodedTS))
+	require.True(t, gproto.Equal(ts, &decodedTS))
+
+	_, err = c.Marshal("not a message")
+	require.Error(t, err)
+	require.Error(t, c.Unmarshal(data, new(string)))
+}
+
+func TestCodecOverGRPC(t *testing.T) {
+	m := shippertest.StartMockShipper(shippertest.MockConfig{}, ServerOption())
+	defer m.Stop()
+	conn, err := m.Dial(DialOption())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	reply, err := proto.NewProducerClient(conn).PublishEvents(context.Background(), &messages.PublishRequest{Events: testutil.NewEvents(0, 3)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), reply.GetAcceptedCount())
+	require.True(t, gproto.Equal(testutil.NewEvent(2), m.Events()[2]))
+}
@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package grpccodec registers the shipper gRPC codec. It passes already
+// serialized messages through as they are, and marshals the other messages
+// with their vtproto generated methods when they have them.
+//
+// Importing the package registers the codec, clients select it with
+// DialOption. Servers pick it for the clients selecting it, and fall back to
+// the default proto codec for the others.
+package grpccodec
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/codec"
+)
+
+// Name is the name of the codec, sent as the content-subtype of the calls.
+const Name = "shipper"
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Raw is a serialized message. It's sent as it is, and receives the
+// serialized message without decoding it, for proxies forwarding requests or
+// replies with no need to look into them.
+type Raw []byte
+
+// Codec marshals Raw messages as they are, the other messages are marshaled
+// by codec.Codec.
+type Codec struct{}
+
+var _ encoding.Codec = Codec{}
+
+// Marshal implements encoding.Codec.
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case Raw:
+		return m, nil
+	case *Raw:
+		return *m, nil
+	}
+	return codec.Codec{}.Marshal(v)
+}
+
+// Unmarshal implements encoding.Codec.
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	if m, ok := v.(*Raw); ok {
+		// gRPC may reuse the buffer once the message is decoded
+		*m = append(Raw(nil), data...)
+		return nil
+	}
+	return codec.Codec{}.Unmarshal(data, v)
+}
+
+// Name implements encoding.Codec.
+func (Codec) Name() string {
+	return Name
+}
+
+// DialOption returns the dial option making a client use the codec.
+func DialOption() grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.CallContentSubtype(Name))
+}
+
+// ServerOption returns the server option making a server use the codec for
+// all the calls, whatever the content-subtype selected by the client.
+func ServerOption() grpc.ServerOption {
+	return grpc.ForceServerCodec(Codec{})
+}
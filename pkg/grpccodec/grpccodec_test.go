@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package grpccodec
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	shippertest "github.com/elastic/elastic-agent-shipper-client/pkg/testing"
+)
+
+const publishEvents = "/elastic.agent.shipper.v1.Producer/PublishEvents"
+
+func TestCodec(t *testing.T) {
+	var c Codec
+	require.Equal(t, c, encoding.GetCodec(Name))
+
+	data, err := c.Marshal(Raw("raw"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("raw"), data)
+	raw := Raw("pointer")
+	data, err = c.Marshal(&raw)
+	require.NoError(t, err)
+	require.Equal(t, []byte("pointer"), data)
+
+	buf := []byte("data")
+	require.NoError(t, c.Unmarshal(buf, &raw))
+	buf[0] = 'x'
+	require.Equal(t, Raw("data"), raw)
+
+	req := &messages.PublishRequest{Events: testutil.NewEvents(0, 2)}
+	data, err = c.Marshal(req)
+	require.NoError(t, err)
+	var decoded messages.PublishRequest
+	require.NoError(t, c.Unmarshal(data, &decoded))
+	require.True(t, gproto.Equal(req, &decoded))
+
+	_, err = c.Marshal("not a message")
+	require.Error(t, err)
+	require.Error(t, c.Unmarshal(data, new(string)))
+}
+
+func TestCodecOverGRPC(t *testing.T) {
+	tests := map[string]struct {
+		server []grpc.ServerOption
+	}{
+		"registered": {},
+		"forced":     {server: []grpc.ServerOption{ServerOption()}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := shippertest.StartMockShipper(shippertest.MockConfig{}, tc.server...)
+			defer m.Stop()
+			conn, err := m.Dial(DialOption())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			// generated clients are unaffected
+			reply, err := proto.NewProducerClient(conn).PublishEvents(context.Background(), &messages.PublishRequest{Events: testutil.NewEvents(0, 2)})
+			require.NoError(t, err)
+			require.Equal(t, uint32(2), reply.GetAcceptedCount())
+
+			// serialized requests and replies are passed through
+			data, err := gproto.Marshal(&messages.PublishRequest{Events: testutil.NewEvents(2, 1)})
+			require.NoError(t, err)
+			var raw Raw
+			require.NoError(t, conn.Invoke(context.Background(), publishEvents, Raw(data), &raw))
+			reply = &messages.PublishReply{}
+			require.NoError(t, gproto.Unmarshal(raw, reply))
+			require.Equal(t, uint32(1), reply.GetAcceptedCount())
+			require.True(t, gproto.Equal(testutil.NewEvent(2), m.Events()[2]))
+		})
+	}
+}
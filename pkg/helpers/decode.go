@@ -0,0 +1,144 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// maxDecodeDepth is the maximum nesting of the decoded documents, the one
+// of encoding/json.
+const maxDecodeDepth = 10000
+
+// StructDecoder reads JSON objects from a stream and builds the structs token
+// by token, without the map[string]interface{} that NewStruct converts, so
+// large documents aren't held twice in memory.
+//
+// The values are the ones of NewStruct for the result of json.Unmarshal: the
+// numbers are float64 values and invalid UTF-8 is replaced by U+FFFD.
+type StructDecoder struct {
+	dec *json.Decoder
+	in  *Interner
+}
+
+// NewStructDecoder creates a decoder reading from r. The objects can be
+// separated by any whitespace, like in NDJSON.
+func NewStructDecoder(r io.Reader) *StructDecoder {
+	return &StructDecoder{dec: json.NewDecoder(r)}
+}
+
+// NewStructDecoder is NewStructDecoder interning the keys and the string values.
+func (in *Interner) NewStructDecoder(r io.Reader) *StructDecoder {
+	return &StructDecoder{dec: json.NewDecoder(r), in: in}
+}
+
+// Decode reads the next object from the stream. It returns io.EOF when
+// there are no more objects.
+func (d *StructDecoder) Decode() (*messages.Struct, error) {
+	t, err := d.dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if t != json.Delim('{') {
+		return nil, fmt.Errorf("invalid document: got %v, want an object", t)
+	}
+	s, err := d.object(1)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	return s, nil
+}
+
+// object decodes the members of an object whose opening brace was read.
+func (d *StructDecoder) object(depth int) (*messages.Struct, error) {
+	s := &messages.Struct{Data: make(map[string]*messages.Value)}
+	for d.dec.More() {
+		t, err := d.token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := t.(string)
+		if !ok {
+			return nil, fmt.Errorf("got %v, want a key", t)
+		}
+		v, err := d.value(depth)
+		if err != nil {
+			return nil, err
+		}
+		s.Data[d.in.Intern(key)] = v
+	}
+	// the closing brace
+	if _, err := d.token(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// list decodes the elements of an array whose opening bracket was read.
+func (d *StructDecoder) list(depth int) (*messages.ListValue, error) {
+	l := &messages.ListValue{}
+	for d.dec.More() {
+		v, err := d.value(depth)
+		if err != nil {
+			return nil, err
+		}
+		l.Values = append(l.Values, v)
+	}
+	// the closing bracket
+	if _, err := d.token(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (d *StructDecoder) value(depth int) (*messages.Value, error) {
+	t, err := d.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := t.(type) {
+	case json.Delim:
+		if depth >= maxDecodeDepth {
+			return nil, fmt.Errorf("exceeded the maximum depth of %d", maxDecodeDepth)
+		}
+		if t == '[' {
+			l, err := d.list(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			return NewListValue(l), nil
+		}
+		s, err := d.object(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return NewStructValue(s), nil
+	case string:
+		return NewStringValue(d.in.Intern(t)), nil
+	case float64:
+		return NewFloat64Value(t), nil
+	case bool:
+		return NewBoolValue(t), nil
+	}
+	return NewNullValue(), nil
+}
+
+// token reads the next token within a document, where the end of the
+// stream is unexpected.
+func (d *StructDecoder) token() (json.Token, error) {
+	t, err := d.dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return t, err
+}
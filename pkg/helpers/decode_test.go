@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStructDecoder(t *testing.T) {
+	docs := []string{
+		`{"message":"hello","count":5,"ratio":0.5,"ok":true,"none":null,"tags":["a",1,[],{}],"nested":{"x":{"y":"` + "\xff" + `"}}}`,
+		`{}`,
+		`{"message":"world"}`,
+	}
+	d := NewStructDecoder(strings.NewReader(strings.Join(docs, "\n") + "\n"))
+	for _, doc := range docs {
+		var m map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(doc), &m))
+		want, err := NewStruct(m)
+		require.NoError(t, err)
+
+		got, err := d.Decode()
+		require.NoError(t, err)
+		require.True(t, proto.Equal(want, got), "got %v, want %v", got, want)
+	}
+	_, err := d.Decode()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestStructDecoderInterning(t *testing.T) {
+	in := NewInterner(10)
+	d := in.NewStructDecoder(strings.NewReader(`{"host":"a"} {"host":"a"}`))
+	first, err := d.Decode()
+	require.NoError(t, err)
+	second, err := d.Decode()
+	require.NoError(t, err)
+	require.True(t, sameStorage(first.GetData()["host"].GetStringValue(), second.GetData()["host"].GetStringValue()))
+	require.Equal(t, 2, in.Len())
+}
+
+func TestStructDecoderErrors(t *testing.T) {
+	cases := map[string]string{
+		"not an object": `["a"]`,
+		"truncated":     `{"a":[1,`,
+		"missing value": `{"a"}`,
+		"too deep":      `{"a":` + strings.Repeat("[", maxDecodeDepth) + strings.Repeat("]", maxDecodeDepth) + `}`,
+	}
+	for name, doc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewStructDecoder(strings.NewReader(doc)).Decode()
+			require.Error(t, err)
+			require.NotErrorIs(t, err, io.EOF)
+		})
+	}
+
+	_, err := NewStructDecoder(strings.NewReader(`{"a":`)).Decode()
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
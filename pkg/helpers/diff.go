@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// StructDiff lists the dotted paths of the values that differ between two
+// structs, sorted.
+type StructDiff struct {
+	// Added are the paths only in the second struct.
+	Added []string
+	// Removed are the paths only in the first struct.
+	Removed []string
+	// Changed are the paths in both structs with different values.
+	Changed []string
+}
+
+// Diff compares the structs. The nested structs are compared key by key,
+// the other values, lists included, are compared as a whole. A nil struct
+// is an empty one.
+func Diff(a, b *messages.Struct) StructDiff {
+	var d StructDiff
+	d.compare("", a, b)
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// Empty returns whether the structs are equal.
+func (d StructDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String returns the paths one per line, prefixed by + when added, - when
+// removed and ~ when changed.
+func (d StructDiff) String() string {
+	var b strings.Builder
+	for _, paths := range []struct {
+		prefix string
+		paths  []string
+	}{{"+ ", d.Added}, {"- ", d.Removed}, {"~ ", d.Changed}} {
+		for _, p := range paths.paths {
+			b.WriteString(paths.prefix)
+			b.WriteString(p)
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+func (d *StructDiff) compare(prefix string, a, b *messages.Struct) {
+	for k, va := range a.GetData() {
+		path := prefix + k
+		vb, ok := b.GetData()[k]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, path)
+		case va.GetStructValue() != nil && vb.GetStructValue() != nil:
+			d.compare(path+".", va.GetStructValue(), vb.GetStructValue())
+		case !proto.Equal(va, vb):
+			d.Changed = append(d.Changed, path)
+		}
+	}
+	for k := range b.GetData() {
+		if _, ok := a.GetData()[k]; !ok {
+			d.Added = append(d.Added, prefix+k)
+		}
+	}
+}
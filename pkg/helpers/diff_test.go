@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := NewStruct(map[string]interface{}{
+		"message": "hello",
+		"count":   5,
+		"tags":    []interface{}{"a", "b"},
+		"host":    map[string]interface{}{"name": "h", "ip": "127.0.0.1", "os": map[string]interface{}{"family": "linux"}},
+		"labels":  map[string]interface{}{"env": "prod"},
+	})
+	require.NoError(t, err)
+	b, err := NewStruct(map[string]interface{}{
+		"message": "hello",
+		"count":   uint64(5),
+		"tags":    []interface{}{"a"},
+		"host":    map[string]interface{}{"name": "h", "os": map[string]interface{}{"family": "windows"}, "arch": "x86_64"},
+		"labels":  "prod",
+		"new":     map[string]interface{}{"x": 1},
+	})
+	require.NoError(t, err)
+
+	d := Diff(a, b)
+	require.Equal(t, StructDiff{
+		Added:   []string{"host.arch", "new"},
+		Removed: []string{"host.ip"},
+		Changed: []string{"count", "host.os.family", "labels", "tags"},
+	}, d)
+	require.False(t, d.Empty())
+	require.Equal(t, "+ host.arch\n+ new\n- host.ip\n~ count\n~ host.os.family\n~ labels\n~ tags\n", d.String())
+
+	require.True(t, Diff(a, a).Empty())
+	require.True(t, Diff(nil, &messages.Struct{}).Empty())
+	require.Equal(t, []string{"count", "host", "labels", "message", "tags"}, Diff(a, nil).Removed)
+}
@@ -0,0 +1,236 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Visitor receives the values of a Struct or a Value tree one by one, like
+// the structform.Visitor of github.com/elastic/go-structform, so that events
+// can be transcoded to JSON, CBOR or UBJSON without an intermediate map.
+//
+// The methods are the ones of structform.Visitor for the kinds of a Value,
+// without the base type of the objects and the arrays that is always
+// structform.AnyType. A structform visitor, e.g. returned by cborl.NewVisitor,
+// is adapted with one method for each of OnObjectStart and OnArrayStart.
+type Visitor interface {
+	OnObjectStart(len int) error
+	OnObjectFinished() error
+	OnKey(s string) error
+	OnArrayStart(len int) error
+	OnArrayFinished() error
+
+	OnNil() error
+	OnBool(b bool) error
+	OnString(s string) error
+	OnInt32(i int32) error
+	OnInt64(i int64) error
+	OnUint32(u uint32) error
+	OnUint64(u uint64) error
+	OnFloat32(f float32) error
+	OnFloat64(f float64) error
+}
+
+// FoldStruct calls the visitor with the fields of the Struct, as an object.
+// The timestamps are RFC 3339 strings and the byte slices base64 strings,
+// like in the JSON encoding of the events.
+func FoldStruct(s *messages.Struct, v Visitor) error {
+	if err := v.OnObjectStart(len(s.GetData())); err != nil {
+		return err
+	}
+	for k, value := range s.GetData() {
+		if err := v.OnKey(k); err != nil {
+			return err
+		}
+		if err := FoldValue(value, v); err != nil {
+			return err
+		}
+	}
+	return v.OnObjectFinished()
+}
+
+// FoldValue calls the visitor with the Value, see FoldStruct. The string
+// references must be resolved first.
+func FoldValue(x *messages.Value, v Visitor) error {
+	switch kind := x.GetKind().(type) {
+	case *messages.Value_NullValue:
+		return v.OnNil()
+	case *messages.Value_BoolValue:
+		return v.OnBool(kind.BoolValue)
+	case *messages.Value_StringValue:
+		return v.OnString(kind.StringValue)
+	case *messages.Value_Int32Value:
+		return v.OnInt32(kind.Int32Value)
+	case *messages.Value_Int64Value:
+		return v.OnInt64(kind.Int64Value)
+	case *messages.Value_Uint32Value:
+		return v.OnUint32(kind.Uint32Value)
+	case *messages.Value_Uint64Value:
+		return v.OnUint64(kind.Uint64Value)
+	case *messages.Value_Float32Value:
+		return v.OnFloat32(kind.Float32Value)
+	case *messages.Value_Float64Value:
+		return v.OnFloat64(kind.Float64Value)
+	case *messages.Value_TimestampValue:
+		return v.OnString(kind.TimestampValue.AsTime().Format(time.RFC3339Nano))
+	case *messages.Value_BytesValue:
+		return v.OnString(base64.StdEncoding.EncodeToString(kind.BytesValue))
+	case *messages.Value_StructValue:
+		return FoldStruct(kind.StructValue, v)
+	case *messages.Value_ListValue:
+		values := kind.ListValue.GetValues()
+		if err := v.OnArrayStart(len(values)); err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := FoldValue(value, v); err != nil {
+				return err
+			}
+		}
+		return v.OnArrayFinished()
+	default:
+		return fmt.Errorf("can't fold a value of type %T", kind)
+	}
+}
+
+// Unfolder is a Visitor building a Value tree, to decode events from any
+// format read by a structform parser.
+type Unfolder struct {
+	value *messages.Value
+	// stack holds the objects and the arrays being built, the last one is
+	// the current one
+	stack []*messages.Value
+	key   string
+}
+
+// NewUnfolder returns an Unfolder.
+func NewUnfolder() *Unfolder {
+	return &Unfolder{}
+}
+
+// Value returns the value built by the visits, nil before a whole value was
+// visited.
+func (u *Unfolder) Value() *messages.Value {
+	if len(u.stack) > 0 {
+		return nil
+	}
+	return u.value
+}
+
+// Struct returns the object built by the visits, nil before a whole object
+// was visited.
+func (u *Unfolder) Struct() *messages.Struct {
+	return u.Value().GetStructValue()
+}
+
+// Reset clears the Unfolder to build a new value.
+func (u *Unfolder) Reset() {
+	u.value = nil
+	u.stack = u.stack[:0]
+	u.key = ""
+}
+
+func (u *Unfolder) add(v *messages.Value) error {
+	if len(u.stack) == 0 {
+		if u.value != nil {
+			return errors.New("a value was already unfolded")
+		}
+		u.value = v
+		return nil
+	}
+	switch parent := u.stack[len(u.stack)-1].GetKind().(type) {
+	case *messages.Value_StructValue:
+		parent.StructValue.Data[u.key] = v
+	case *messages.Value_ListValue:
+		parent.ListValue.Values = append(parent.ListValue.Values, v)
+	}
+	return nil
+}
+
+// OnObjectStart implements Visitor.
+func (u *Unfolder) OnObjectStart(len int) error {
+	if len < 0 {
+		len = 0
+	}
+	v := NewStructValue(&messages.Struct{Data: make(map[string]*messages.Value, len)})
+	if err := u.add(v); err != nil {
+		return err
+	}
+	u.stack = append(u.stack, v)
+	return nil
+}
+
+// OnObjectFinished implements Visitor.
+func (u *Unfolder) OnObjectFinished() error {
+	if len(u.stack) == 0 || u.stack[len(u.stack)-1].GetStructValue() == nil {
+		return errors.New("no object to finish")
+	}
+	u.stack = u.stack[:len(u.stack)-1]
+	return nil
+}
+
+// OnKey implements Visitor.
+func (u *Unfolder) OnKey(s string) error {
+	if len(u.stack) == 0 || u.stack[len(u.stack)-1].GetStructValue() == nil {
+		return fmt.Errorf("key %q outside of an object", s)
+	}
+	u.key = s
+	return nil
+}
+
+// OnArrayStart implements Visitor.
+func (u *Unfolder) OnArrayStart(len int) error {
+	if len < 0 {
+		len = 0
+	}
+	v := NewListValue(&messages.ListValue{Values: make([]*messages.Value, 0, len)})
+	if err := u.add(v); err != nil {
+		return err
+	}
+	u.stack = append(u.stack, v)
+	return nil
+}
+
+// OnArrayFinished implements Visitor.
+func (u *Unfolder) OnArrayFinished() error {
+	if len(u.stack) == 0 || u.stack[len(u.stack)-1].GetListValue() == nil {
+		return errors.New("no array to finish")
+	}
+	u.stack = u.stack[:len(u.stack)-1]
+	return nil
+}
+
+// OnNil implements Visitor.
+func (u *Unfolder) OnNil() error { return u.add(NewNullValue()) }
+
+// OnBool implements Visitor.
+func (u *Unfolder) OnBool(b bool) error { return u.add(NewBoolValue(b)) }
+
+// OnString implements Visitor.
+func (u *Unfolder) OnString(s string) error { return u.add(NewStringValue(s)) }
+
+// OnInt32 implements Visitor.
+func (u *Unfolder) OnInt32(i int32) error { return u.add(NewInt32Value(i)) }
+
+// OnInt64 implements Visitor.
+func (u *Unfolder) OnInt64(i int64) error { return u.add(NewInt64Value(i)) }
+
+// OnUint32 implements Visitor.
+func (u *Unfolder) OnUint32(i uint32) error { return u.add(NewUint32Value(i)) }
+
+// OnUint64 implements Visitor.
+func (u *Unfolder) OnUint64(i uint64) error { return u.add(NewUint64Value(i)) }
+
+// OnFloat32 implements Visitor.
+func (u *Unfolder) OnFloat32(f float32) error { return u.add(NewFloat32Value(f)) }
+
+// OnFloat64 implements Visitor.
+func (u *Unfolder) OnFloat64(f float64) error { return u.add(NewFloat64Value(f)) }
@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestFoldUnfold(t *testing.T) {
+	ts := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	s := &messages.Struct{Data: map[string]*messages.Value{
+		"null":    NewNullValue(),
+		"bool":    NewBoolValue(true),
+		"string":  NewStringValue("hello"),
+		"int32":   NewInt32Value(math.MinInt32),
+		"int64":   NewInt64Value(math.MinInt64),
+		"uint32":  NewUint32Value(math.MaxUint32),
+		"uint64":  NewUint64Value(math.MaxUint64),
+		"float32": NewFloat32Value(1.5),
+		"float64": NewFloat64Value(2.5),
+		"nested": NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+			"list": NewListValue(&messages.ListValue{Values: []*messages.Value{
+				NewStringValue("a"),
+				NewListValue(&messages.ListValue{Values: []*messages.Value{}}),
+				NewStructValue(&messages.Struct{Data: map[string]*messages.Value{}}),
+			}}),
+		}}),
+	}}
+
+	u := NewUnfolder()
+	require.NoError(t, FoldStruct(s, u))
+	require.True(t, proto.Equal(s, u.Struct()), u.Struct())
+
+	// the timestamps and the byte slices are folded to strings
+	u.Reset()
+	require.NoError(t, FoldStruct(&messages.Struct{Data: map[string]*messages.Value{
+		"ts":    NewTimestampValue(ts),
+		"bytes": NewBytesValue([]byte{1, 2, 3}),
+	}}, u))
+	require.Equal(t, map[string]interface{}{
+		"ts":    "2022-01-01T00:00:00Z",
+		"bytes": "AQID",
+	}, AsMap(u.Struct()))
+
+	require.Error(t, FoldValue(&messages.Value{Kind: &messages.Value_StringRef{StringRef: 1}}, NewUnfolder()))
+}
+
+func TestUnfolderInvalid(t *testing.T) {
+	u := NewUnfolder()
+	require.Error(t, u.OnKey("a"), "no object")
+	require.Error(t, u.OnObjectFinished())
+	require.Error(t, u.OnArrayFinished())
+
+	require.NoError(t, u.OnArrayStart(-1))
+	require.Nil(t, u.Value(), "the array is not finished")
+	require.Error(t, u.OnObjectFinished())
+	require.Error(t, u.OnKey("a"))
+	require.NoError(t, u.OnArrayFinished())
+	require.NotNil(t, u.Value())
+	require.Nil(t, u.Struct(), "the value is not an object")
+	require.Error(t, u.OnNil(), "a value was already unfolded")
+}
@@ -0,0 +1,208 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/unsafeconv"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// HashOptions selects the parts of the events covered by Hash.
+type HashOptions struct {
+	// Fields are the dotted paths of the fields to hash, all the fields are
+	// hashed when it's empty. A path matches the keys containing dots as
+	// well as the nested keys.
+	Fields []string
+	// Timestamp includes the timestamp of the event.
+	Timestamp bool
+	// DataStream includes the data stream of the event.
+	DataStream bool
+	// Metadata includes all the metadata of the event.
+	Metadata bool
+	// New creates the hash function, fnv.New64a by default.
+	New func() hash.Hash64
+}
+
+// The tags of the values in the canonical form.
+const (
+	hashNull byte = iota
+	hashBool
+	hashInt
+	hashUint
+	hashFloat
+	hashString
+	hashTimestamp
+	hashStruct
+	hashList
+	hashMissing
+	hashBytes
+)
+
+// Hash returns a hash of the parts of the event selected by the options,
+// for building event IDs and deduplicating events.
+//
+// The hash is computed over a canonical form of the values: the keys are
+// sorted, and the numbers of all the types are equal when they have the same
+// value, so 5, uint64(5) and 5.0 have the same hash. The canonical form
+// doesn't change, the hashes can be stored.
+func Hash(e *messages.Event, opts HashOptions) uint64 {
+	newHash := opts.New
+	if newHash == nil {
+		newHash = fnv.New64a
+	}
+	h := canonicalHash{h: newHash()}
+
+	if opts.Timestamp {
+		h.timestamp(e.GetTimestamp().GetSeconds(), e.GetTimestamp().GetNanos())
+	}
+	if opts.DataStream {
+		h.string(e.GetDataStream().GetType())
+		h.string(e.GetDataStream().GetDataset())
+		h.string(e.GetDataStream().GetNamespace())
+	}
+	if opts.Metadata {
+		h.structValue(e.GetMetadata())
+	}
+	if len(opts.Fields) == 0 {
+		h.structValue(e.GetFields())
+		return h.h.Sum64()
+	}
+
+	paths := append([]string(nil), opts.Fields...)
+	sort.Strings(paths)
+	for _, path := range paths {
+		h.string(path)
+		if v := lookup(e.GetFields(), path); v != nil {
+			h.value(v)
+		} else {
+			h.tag(hashMissing)
+		}
+	}
+	return h.h.Sum64()
+}
+
+// lookup returns the value at the dotted path, or nil.
+func lookup(s *messages.Struct, path string) *messages.Value {
+	for end := 0; end <= len(path); end++ {
+		if end < len(path) && path[end] != '.' {
+			continue
+		}
+		v, ok := s.GetData()[path[:end]]
+		if !ok {
+			continue
+		}
+		if end == len(path) {
+			return v
+		}
+		if found := lookup(v.GetStructValue(), path[end+1:]); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// canonicalHash writes the canonical form of the values to a hash.
+type canonicalHash struct {
+	h   hash.Hash64
+	buf [9]byte
+}
+
+func (c *canonicalHash) tag(t byte) {
+	c.buf[0] = t
+	c.h.Write(c.buf[:1]) //nolint:errcheck // hashes never fail
+}
+
+func (c *canonicalHash) uint64(t byte, v uint64) {
+	c.buf[0] = t
+	binary.BigEndian.PutUint64(c.buf[1:], v)
+	c.h.Write(c.buf[:]) //nolint:errcheck // hashes never fail
+}
+
+func (c *canonicalHash) string(s string) {
+	c.uint64(hashString, uint64(len(s)))
+	c.h.Write(unsafeconv.Bytes(s)) //nolint:errcheck // hashes never fail
+}
+
+func (c *canonicalHash) timestamp(seconds int64, nanos int32) {
+	c.uint64(hashTimestamp, uint64(seconds))
+	c.uint64(hashTimestamp, uint64(nanos))
+}
+
+func (c *canonicalHash) int64(v int64) {
+	if v >= 0 {
+		c.uint64(hashUint, uint64(v))
+	} else {
+		c.uint64(hashInt, uint64(v))
+	}
+}
+
+func (c *canonicalHash) float64(f float64) {
+	switch {
+	case f == math.Trunc(f) && f >= math.MinInt64 && f < 0:
+		c.int64(int64(f))
+	case f == math.Trunc(f) && f >= 0 && f < math.MaxUint64:
+		// also normalizes -0
+		c.uint64(hashUint, uint64(f))
+	case math.IsNaN(f):
+		c.uint64(hashFloat, math.Float64bits(math.NaN()))
+	default:
+		c.uint64(hashFloat, math.Float64bits(f))
+	}
+}
+
+func (c *canonicalHash) structValue(s *messages.Struct) {
+	c.uint64(hashStruct, uint64(s.Len()))
+	s.RangeSorted(func(k string, v *messages.Value) bool {
+		c.string(k)
+		c.value(v)
+		return true
+	})
+}
+
+func (c *canonicalHash) value(v *messages.Value) {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_BoolValue:
+		if kind.BoolValue {
+			c.uint64(hashBool, 1)
+		} else {
+			c.uint64(hashBool, 0)
+		}
+	case *messages.Value_Int32Value:
+		c.int64(int64(kind.Int32Value))
+	case *messages.Value_Int64Value:
+		c.int64(kind.Int64Value)
+	case *messages.Value_Uint32Value:
+		c.uint64(hashUint, uint64(kind.Uint32Value))
+	case *messages.Value_Uint64Value:
+		c.uint64(hashUint, kind.Uint64Value)
+	case *messages.Value_Float32Value:
+		c.float64(float64(kind.Float32Value))
+	case *messages.Value_Float64Value:
+		c.float64(kind.Float64Value)
+	case *messages.Value_StringValue:
+		c.string(kind.StringValue)
+	case *messages.Value_BytesValue:
+		c.uint64(hashBytes, uint64(len(kind.BytesValue)))
+		c.h.Write(kind.BytesValue) //nolint:errcheck // hashes never fail
+	case *messages.Value_TimestampValue:
+		c.timestamp(kind.TimestampValue.GetSeconds(), kind.TimestampValue.GetNanos())
+	case *messages.Value_StructValue:
+		c.structValue(kind.StructValue)
+	case *messages.Value_ListValue:
+		values := kind.ListValue.GetValues()
+		c.uint64(hashList, uint64(len(values)))
+		for _, v := range values {
+			c.value(v)
+		}
+	default:
+		c.tag(hashNull)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"hash"
+	"hash/crc64"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestHash(t *testing.T) {
+	event := func(fields map[string]*messages.Value) *messages.Event {
+		return &messages.Event{Fields: &messages.Struct{Data: fields}}
+	}
+	base := event(map[string]*messages.Value{
+		"message": NewStringValue("hello"),
+		"count":   NewInt64Value(5),
+		"host":    NewStructValue(&messages.Struct{Data: map[string]*messages.Value{"name": NewStringValue("h")}}),
+	})
+	h := Hash(base, HashOptions{})
+
+	// the numbers are normalized
+	for _, count := range []*messages.Value{NewInt32Value(5), NewUint32Value(5), NewUint64Value(5), NewFloat32Value(5), NewFloat64Value(5)} {
+		e := event(map[string]*messages.Value{
+			"host":    base.GetFields().GetData()["host"],
+			"count":   count,
+			"message": NewStringValue("hello"),
+		})
+		require.Equal(t, h, Hash(e, HashOptions{}), "%v", count)
+	}
+	require.Equal(t, Hash(event(map[string]*messages.Value{"n": NewFloat64Value(0)}), HashOptions{}),
+		Hash(event(map[string]*messages.Value{"n": NewFloat64Value(math.Copysign(0, -1))}), HashOptions{}))
+	require.Equal(t, Hash(event(map[string]*messages.Value{"n": NewInt64Value(-3)}), HashOptions{}),
+		Hash(event(map[string]*messages.Value{"n": NewFloat32Value(-3)}), HashOptions{}))
+
+	different := []*messages.Event{
+		event(map[string]*messages.Value{"message": NewStringValue("hello"), "count": NewFloat64Value(5.5)}),
+		event(map[string]*messages.Value{"message": NewStringValue("hello"), "count": NewStringValue("5")}),
+		event(map[string]*messages.Value{"message": NewStringValue("hellocount"), "": NewInt64Value(5)}),
+		event(map[string]*messages.Value{"message": NewStringValue("hello")}),
+		event(map[string]*messages.Value{"message": NewListValue(&messages.ListValue{Values: []*messages.Value{NewStringValue("hello")}})}),
+		event(nil),
+	}
+	seen := map[uint64]bool{h: true}
+	for _, e := range different {
+		got := Hash(e, HashOptions{})
+		require.False(t, seen[got], "%v", e)
+		seen[got] = true
+	}
+}
+
+func TestHashOptions(t *testing.T) {
+	e := &messages.Event{
+		Timestamp:  timestamppb.New(time.Unix(10, 0)),
+		DataStream: &messages.DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+		Metadata:   &messages.Struct{Data: map[string]*messages.Value{"pipeline": NewStringValue("p")}},
+		Fields: &messages.Struct{Data: map[string]*messages.Value{
+			"message":   NewStringValue("hello"),
+			"log.level": NewStringValue("info"),
+			"host":      NewStructValue(&messages.Struct{Data: map[string]*messages.Value{"name": NewStringValue("h")}}),
+		}},
+	}
+	paths := HashOptions{Fields: []string{"host.name", "log.level"}}
+	h := Hash(e, paths)
+	require.Equal(t, h, Hash(e, HashOptions{Fields: []string{"log.level", "host.name"}}), "the paths are sorted")
+
+	// the values outside of the selected paths are ignored
+	e.Fields.Data["message"] = NewStringValue("world")
+	e.Timestamp = timestamppb.Now()
+	require.Equal(t, h, Hash(e, paths))
+	e.Fields.Data["host"].GetStructValue().Data["name"] = NewStringValue("other")
+	require.NotEqual(t, h, Hash(e, paths))
+
+	// a missing path differs from a null value
+	null := &messages.Event{Fields: &messages.Struct{Data: map[string]*messages.Value{"a": NewNullValue()}}}
+	require.NotEqual(t, Hash(null, HashOptions{Fields: []string{"a"}}), Hash(null, HashOptions{Fields: []string{"b"}}))
+
+	for name, opts := range map[string]HashOptions{
+		"timestamp":   {Timestamp: true},
+		"data stream": {DataStream: true},
+		"metadata":    {Metadata: true},
+	} {
+		before := Hash(e, opts)
+		changed := &messages.Event{
+			Timestamp:  timestamppb.New(time.Unix(20, 0)),
+			DataStream: &messages.DataStream{Type: "metrics"},
+			Metadata:   &messages.Struct{},
+			Fields:     e.GetFields(),
+		}
+		require.NotEqual(t, before, Hash(changed, opts), name)
+	}
+
+	table := crc64.MakeTable(crc64.ISO)
+	custom := HashOptions{New: func() hash.Hash64 { return crc64.New(table) }}
+	require.NotEqual(t, Hash(e, HashOptions{}), Hash(e, custom))
+}
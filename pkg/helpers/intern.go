@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"sync"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// MaxInternedLength is the length above which strings are not interned, long
+// strings are rarely repeated.
+const MaxInternedLength = 64
+
+// Interner deduplicates the strings of the converted values, so the keys and
+// the common values (e.g. "log", "default", host names) repeated across the
+// events of a batch share their storage. It keeps at most a fixed number of
+// strings and starts over when it's full, following the strings in use.
+//
+// An Interner is safe for concurrent use. A nil Interner doesn't intern.
+type Interner struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	strings map[string]string
+}
+
+// NewInterner creates an interner keeping at most maxEntries strings.
+func NewInterner(maxEntries int) *Interner {
+	return &Interner{
+		maxEntries: maxEntries,
+		strings:    make(map[string]string),
+	}
+}
+
+// Intern returns the interned copy of s.
+func (in *Interner) Intern(s string) string {
+	if in == nil || len(s) > MaxInternedLength {
+		return s
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if interned, ok := in.strings[s]; ok {
+		return interned
+	}
+	if len(in.strings) >= in.maxEntries {
+		in.strings = make(map[string]string, in.maxEntries)
+	}
+	in.strings[s] = s
+	return s
+}
+
+// Len returns the number of interned strings.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.strings)
+}
+
+// NewValue is NewValue interning the keys and the string values.
+func (in *Interner) NewValue(v interface{}) (*messages.Value, error) {
+	return toValue(v, in)
+}
+
+// NewStruct is NewStruct interning the keys and the string values.
+func (in *Interner) NewStruct(v map[string]interface{}) (*messages.Struct, error) {
+	return toStruct(v, in)
+}
+
+// NewList is NewList interning the keys and the string values.
+func (in *Interner) NewList(v []interface{}) (*messages.ListValue, error) {
+	return toList(v, in)
+}
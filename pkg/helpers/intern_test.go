@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// sameStorage returns whether a and b share their bytes.
+func sameStorage(a, b string) bool {
+	return (*reflect.StringHeader)(unsafe.Pointer(&a)).Data == (*reflect.StringHeader)(unsafe.Pointer(&b)).Data //nolint:gosec // comparing pointers only
+}
+
+func TestInterner(t *testing.T) {
+	in := NewInterner(2)
+	a := in.Intern(strings.Repeat("a", 3))
+	require.True(t, sameStorage(a, in.Intern(strings.Repeat("a", 3))))
+	require.Equal(t, 1, in.Len())
+
+	long := strings.Repeat("l", MaxInternedLength+1)
+	require.False(t, sameStorage(in.Intern(long), in.Intern(strings.Repeat("l", MaxInternedLength+1))))
+	require.Equal(t, 1, in.Len())
+
+	// starts over when full
+	in.Intern("b")
+	in.Intern("c")
+	require.Equal(t, 1, in.Len())
+	require.False(t, sameStorage(a, in.Intern(strings.Repeat("a", 3))))
+
+	var nilInterner *Interner
+	require.Equal(t, "x", nilInterner.Intern("x"))
+}
+
+func TestInternerNewStruct(t *testing.T) {
+	doc := func() map[string]interface{} {
+		return map[string]interface{}{
+			strings.Repeat("k", 3): strings.Repeat("v", 3),
+			"list":                 []interface{}{strings.Repeat("v", 3)},
+			"tags":                 []string{strings.Repeat("v", 3)},
+			"nested":               map[string]interface{}{strings.Repeat("k", 3): 1},
+		}
+	}
+	in := NewInterner(100)
+	first, err := in.NewStruct(doc())
+	require.NoError(t, err)
+	second, err := in.NewStruct(doc())
+	require.NoError(t, err)
+
+	plain, err := NewStruct(doc())
+	require.NoError(t, err)
+	require.True(t, proto.Equal(plain, first))
+
+	v1 := first.GetData()["kkk"].GetStringValue()
+	require.True(t, sameStorage(v1, second.GetData()["kkk"].GetStringValue()))
+	require.True(t, sameStorage(v1, second.GetData()["list"].GetListValue().GetValues()[0].GetStringValue()))
+	require.True(t, sameStorage(v1, second.GetData()["tags"].GetListValue().GetValues()[0].GetStringValue()))
+	for k := range second.GetData()["nested"].GetStructValue().GetData() {
+		require.True(t, sameStorage(in.Intern("kkk"), k))
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Field numbers of the Event message decoded on demand.
+const (
+	eventMetadataField protowire.Number = 4
+	eventFieldsField   protowire.Number = 5
+)
+
+// LazyEvent is a serialized event whose envelope, the timestamp, the source,
+// the data stream, the attachment, the extensions, the routing hints and the write semantics, is decoded right away, while the metadata and the
+// fields are only decoded when they are accessed. It suits the paths that
+// route events on their envelope and forward them untouched with Bytes.
+//
+// A LazyEvent is not safe for concurrent use.
+type LazyEvent struct {
+	data     []byte
+	envelope messages.Event
+
+	metadata, fields               [][]byte
+	metadataDecoded, fieldsDecoded bool
+	metadataValue                  *messages.Struct
+	fieldsValue                    *messages.Struct
+}
+
+// NewLazyEvent decodes the envelope of the serialized event. The event keeps
+// a reference to data, which must not be modified.
+func NewLazyEvent(data []byte) (*LazyEvent, error) {
+	e := &LazyEvent{data: data}
+	var envelope []byte
+	for b := data; len(b) > 0; {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("invalid event: %w", protowire.ParseError(n))
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return nil, fmt.Errorf("invalid event: %w", protowire.ParseError(m))
+		}
+		field := b[:n+m]
+		b = b[n+m:]
+
+		if typ == protowire.BytesType && (num == eventMetadataField || num == eventFieldsField) {
+			value, _ := protowire.ConsumeBytes(field[n:])
+			if num == eventMetadataField {
+				e.metadata = append(e.metadata, value)
+			} else {
+				e.fields = append(e.fields, value)
+			}
+			continue
+		}
+		envelope = append(envelope, field...)
+	}
+	if err := proto.Unmarshal(envelope, &e.envelope); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	return e, nil
+}
+
+// Bytes returns the serialized event.
+func (e *LazyEvent) Bytes() []byte {
+	return e.data
+}
+
+// Timestamp returns the timestamp of the event.
+func (e *LazyEvent) Timestamp() *timestamppb.Timestamp {
+	return e.envelope.GetTimestamp()
+}
+
+// TimestampRaw returns the raw timestamp of the event.
+func (e *LazyEvent) TimestampRaw() string {
+	return e.envelope.GetTimestampRaw()
+}
+
+// Source returns the source of the event.
+func (e *LazyEvent) Source() *messages.Source {
+	return e.envelope.GetSource()
+}
+
+// DataStream returns the data stream of the event.
+func (e *LazyEvent) DataStream() *messages.DataStream {
+	return e.envelope.GetDataStream()
+}
+
+// Attachment returns the attachment of the event.
+func (e *LazyEvent) Attachment() *messages.Attachment {
+	return e.envelope.GetAttachment()
+}
+
+// Extensions returns the extensions of the event.
+func (e *LazyEvent) Extensions() []*anypb.Any {
+	return e.envelope.GetExtensions()
+}
+
+// Pipeline returns the pipeline field of the event. Unlike
+// messages.Event.PipelineHint, it doesn't fall back to the metadata, which
+// isn't decoded.
+func (e *LazyEvent) Pipeline() string {
+	return e.envelope.GetPipeline()
+}
+
+// RawIndex returns the raw_index field of the event. Unlike
+// messages.Event.RawIndexHint, it doesn't fall back to the metadata, which
+// isn't decoded.
+func (e *LazyEvent) RawIndex() string {
+	return e.envelope.GetRawIndex()
+}
+
+// OpType returns the op_type field of the event, without falling back to the
+// metadata.
+func (e *LazyEvent) OpType() messages.OpType {
+	return messages.OpType(e.envelope.GetOpType())
+}
+
+// DocumentID returns the document_id field of the event, without falling
+// back to the metadata.
+func (e *LazyEvent) DocumentID() string {
+	return e.envelope.GetDocumentId()
+}
+
+// GlobalMetadataVersion returns the version of the global metadata the
+// event references, zero when it doesn't.
+func (e *LazyEvent) GlobalMetadataVersion() uint64 {
+	return e.envelope.GetGlobalMetadataVersion()
+}
+
+// Metadata decodes the metadata of the event on the first call.
+func (e *LazyEvent) Metadata() (*messages.Struct, error) {
+	if !e.metadataDecoded {
+		s, err := decodeStruct(e.metadata)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata: %w", err)
+		}
+		e.metadataValue, e.metadataDecoded = s, true
+	}
+	return e.metadataValue, nil
+}
+
+// Fields decodes the fields of the event on the first call.
+func (e *LazyEvent) Fields() (*messages.Struct, error) {
+	if !e.fieldsDecoded {
+		s, err := decodeStruct(e.fields)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fields: %w", err)
+		}
+		e.fieldsValue, e.fieldsDecoded = s, true
+	}
+	return e.fieldsValue, nil
+}
+
+// Event returns the fully decoded event. The envelope is shared with the
+// LazyEvent.
+func (e *LazyEvent) Event() (*messages.Event, error) {
+	metadata, err := e.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := e.Fields()
+	if err != nil {
+		return nil, err
+	}
+	return &messages.Event{
+		Timestamp:             e.envelope.GetTimestamp(),
+		Source:                e.envelope.GetSource(),
+		DataStream:            e.envelope.GetDataStream(),
+		Metadata:              metadata,
+		Fields:                fields,
+		MetadataDelta:         e.envelope.GetMetadataDelta(),
+		Attachment:            e.envelope.GetAttachment(),
+		Extensions:            e.envelope.GetExtensions(),
+		Pipeline:              e.envelope.GetPipeline(),
+		RawIndex:              e.envelope.GetRawIndex(),
+		OpType:                e.envelope.GetOpType(),
+		DocumentId:            e.envelope.GetDocumentId(),
+		TimestampRaw:          e.envelope.GetTimestampRaw(),
+		GlobalMetadataVersion: e.envelope.GetGlobalMetadataVersion(),
+	}, nil
+}
+
+// decodeStruct merges the serialized occurrences of a Struct field, nil when
+// the field is absent.
+func decodeStruct(occurrences [][]byte) (*messages.Struct, error) {
+	if len(occurrences) == 0 {
+		return nil, nil
+	}
+	s := &messages.Struct{}
+	for _, b := range occurrences {
+		if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(b, s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestLazyEvent(t *testing.T) {
+	event := &messages.Event{
+		Timestamp:  timestamppb.Now(),
+		Source:     &messages.Source{InputId: "input"},
+		DataStream: &messages.DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+		Metadata:   &messages.Struct{Data: map[string]*messages.Value{"pipeline": NewStringValue("p")}},
+		Fields: &messages.Struct{Data: map[string]*messages.Value{
+			"message": NewStringValue("hello"),
+			"host":    NewStructValue(&messages.Struct{Data: map[string]*messages.Value{"name": NewStringValue("h")}}),
+		}},
+		Pipeline:              "logs-generic",
+		RawIndex:              "raw",
+		OpType:                "create",
+		DocumentId:            "id",
+		GlobalMetadataVersion: 3,
+	}
+	data, err := proto.Marshal(event)
+	require.NoError(t, err)
+
+	lazy, err := NewLazyEvent(data)
+	require.NoError(t, err)
+	require.Equal(t, data, lazy.Bytes())
+	require.True(t, proto.Equal(event.GetTimestamp(), lazy.Timestamp()))
+	require.True(t, proto.Equal(event.GetSource(), lazy.Source()))
+	require.True(t, proto.Equal(event.GetDataStream(), lazy.DataStream()))
+	require.Equal(t, "logs-generic", lazy.Pipeline())
+	require.Equal(t, "raw", lazy.RawIndex())
+	require.Equal(t, messages.OpTypeCreate, lazy.OpType())
+	require.Equal(t, "id", lazy.DocumentID())
+	require.Equal(t, uint64(3), lazy.GlobalMetadataVersion())
+	require.False(t, lazy.fieldsDecoded)
+
+	fields, err := lazy.Fields()
+	require.NoError(t, err)
+	require.True(t, proto.Equal(event.GetFields(), fields))
+	again, err := lazy.Fields()
+	require.NoError(t, err)
+	require.Same(t, fields, again)
+
+	full, err := lazy.Event()
+	require.NoError(t, err)
+	require.True(t, proto.Equal(event, full))
+}
+
+func TestLazyEventEmpty(t *testing.T) {
+	lazy, err := NewLazyEvent(nil)
+	require.NoError(t, err)
+	metadata, err := lazy.Metadata()
+	require.NoError(t, err)
+	require.Nil(t, metadata)
+	require.Nil(t, lazy.DataStream())
+}
+
+func TestLazyEventMergesOccurrences(t *testing.T) {
+	first, err := proto.Marshal(&messages.Event{Fields: &messages.Struct{Data: map[string]*messages.Value{"a": NewBoolValue(true)}}})
+	require.NoError(t, err)
+	second, err := proto.Marshal(&messages.Event{Fields: &messages.Struct{Data: map[string]*messages.Value{"b": NewBoolValue(true)}}})
+	require.NoError(t, err)
+
+	lazy, err := NewLazyEvent(append(first, second...))
+	require.NoError(t, err)
+	fields, err := lazy.Fields()
+	require.NoError(t, err)
+	require.Len(t, fields.GetData(), 2)
+}
+
+func TestLazyEventInvalid(t *testing.T) {
+	_, err := NewLazyEvent([]byte{0xff})
+	require.Error(t, err)
+
+	// a valid envelope with invalid fields only fails when they are decoded
+	data := protowire.AppendTag(nil, eventFieldsField, protowire.BytesType)
+	data = protowire.AppendBytes(data, []byte{0xff})
+	lazy, err := NewLazyEvent(data)
+	require.NoError(t, err)
+	_, err = lazy.Fields()
+	require.Error(t, err)
+	_, err = lazy.Event()
+	require.Error(t, err)
+}
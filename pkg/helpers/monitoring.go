@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/unsafeconv"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// RegistryStruct returns a snapshot of the metrics of the registry reported
+// in the mode, nested like the registry. The integers are Int64 values and
+// the floats Float64 values, so they are indexed as numbers.
+func RegistryStruct(r *monitoring.Registry, mode monitoring.Mode) *messages.Struct {
+	v := newStructVisitor()
+	r.Visit(mode, v)
+	return v.result
+}
+
+// ExpvarStruct returns a snapshot of the expvar map, nested like the map.
+// The values of the expvar.Func are converted with NewValue, the values of
+// the other custom vars are decoded from their JSON representation.
+func ExpvarStruct(m *expvar.Map) (*messages.Struct, error) {
+	x := &messages.Struct{Data: map[string]*messages.Value{}}
+	var err error
+	m.Do(func(kv expvar.KeyValue) {
+		if err != nil {
+			return
+		}
+		var value *messages.Value
+		if value, err = expvarValue(kv.Value); err != nil {
+			err = fmt.Errorf("invalid expvar %q: %w", kv.Key, err)
+			return
+		}
+		x.Data[kv.Key] = value
+	})
+	if err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func expvarValue(v expvar.Var) (*messages.Value, error) {
+	switch v := v.(type) {
+	case *expvar.Int:
+		return NewInt64Value(v.Value()), nil
+	case *expvar.Float:
+		return NewFloat64Value(v.Value()), nil
+	case *expvar.String:
+		return NewStringValue(v.Value()), nil
+	case expvar.Func:
+		return NewValue(v.Value())
+	case *expvar.Map:
+		s, err := ExpvarStruct(v)
+		if err != nil {
+			return nil, err
+		}
+		return NewStructValue(s), nil
+	default:
+		var decoded interface{}
+		if err := json.Unmarshal(unsafeconv.Bytes(v.String()), &decoded); err != nil {
+			return nil, err
+		}
+		return NewValue(decoded)
+	}
+}
+
+// NewMetricsEvent returns an event on the data stream holding the metrics,
+// e.g. a RegistryStruct or an ExpvarStruct, as its fields.
+func NewMetricsEvent(timestamp time.Time, dataStream *messages.DataStream, metrics *messages.Struct) *messages.Event {
+	return &messages.Event{
+		Timestamp:  timestamppb.New(timestamp),
+		DataStream: dataStream,
+		Fields:     metrics,
+	}
+}
+
+// structVisitor builds a Struct from a monitoring.Registry.
+type structVisitor struct {
+	result *messages.Struct
+	// stack holds the Structs of the registries being visited, the last
+	// one is the current one
+	stack []*messages.Struct
+	key   string
+}
+
+func newStructVisitor() *structVisitor {
+	return &structVisitor{}
+}
+
+func (v *structVisitor) OnRegistryStart() {
+	s := &messages.Struct{Data: map[string]*messages.Value{}}
+	if len(v.stack) == 0 {
+		v.result = s
+	} else {
+		v.set(NewStructValue(s))
+	}
+	v.stack = append(v.stack, s)
+}
+
+func (v *structVisitor) OnRegistryFinished() {
+	v.stack = v.stack[:len(v.stack)-1]
+}
+
+func (v *structVisitor) OnKey(key string)  { v.key = key }
+func (v *structVisitor) OnString(s string) { v.set(NewStringValue(s)) }
+func (v *structVisitor) OnBool(b bool)     { v.set(NewBoolValue(b)) }
+func (v *structVisitor) OnInt(i int64)     { v.set(NewInt64Value(i)) }
+func (v *structVisitor) OnFloat(f float64) { v.set(NewFloat64Value(f)) }
+
+func (v *structVisitor) OnStringSlice(s []string) {
+	values := make([]*messages.Value, len(s))
+	for i, str := range s {
+		values[i] = NewStringValue(str)
+	}
+	v.set(NewListValue(&messages.ListValue{Values: values}))
+}
+
+func (v *structVisitor) set(value *messages.Value) {
+	v.stack[len(v.stack)-1].Data[v.key] = value
+}
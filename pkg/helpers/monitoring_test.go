@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestRegistryStruct(t *testing.T) {
+	r := monitoring.NewRegistry(monitoring.Report)
+	events := r.NewRegistry("events")
+	monitoring.NewInt(events, "published").Set(42)
+	monitoring.NewFloat(r, "load").Set(0.5)
+	monitoring.NewBool(r, "ready").Set(true)
+	monitoring.NewString(r, "state").Set("running")
+	monitoring.NewInt(r, "details", monitoring.DoNotReport).Set(1)
+
+	expected := &messages.Struct{Data: map[string]*messages.Value{
+		"events": NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+			"published": NewInt64Value(42),
+		}}),
+		"load":  NewFloat64Value(0.5),
+		"ready": NewBoolValue(true),
+		"state": NewStringValue("running"),
+	}}
+	require.True(t, proto.Equal(expected, RegistryStruct(r, monitoring.Reported)))
+
+	expected.Data["details"] = NewInt64Value(1)
+	require.True(t, proto.Equal(expected, RegistryStruct(r, monitoring.Full)))
+}
+
+func TestExpvarStruct(t *testing.T) {
+	m := new(expvar.Map).Init()
+	m.Add("published", 42)
+	m.AddFloat("load", 0.5)
+	state := new(expvar.String)
+	state.Set("running")
+	m.Set("state", state)
+	m.Set("uptime", expvar.Func(func() interface{} { return map[string]interface{}{"ms": int64(10)} }))
+	nested := new(expvar.Map).Init()
+	nested.Add("failed", 1)
+	m.Set("output", nested)
+
+	s, err := ExpvarStruct(m)
+	require.NoError(t, err)
+	expected := &messages.Struct{Data: map[string]*messages.Value{
+		"published": NewInt64Value(42),
+		"load":      NewFloat64Value(0.5),
+		"state":     NewStringValue("running"),
+		"uptime": NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+			"ms": NewInt64Value(10),
+		}}),
+		"output": NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+			"failed": NewInt64Value(1),
+		}}),
+	}}
+	require.True(t, proto.Equal(expected, s), s)
+
+	m.Set("invalid", expvar.Func(func() interface{} { return make(chan int) }))
+	_, err = ExpvarStruct(m)
+	require.Error(t, err)
+}
+
+func TestNewMetricsEvent(t *testing.T) {
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	ds := &messages.DataStream{Type: "metrics", Dataset: "component", Namespace: "default"}
+	metrics := &messages.Struct{Data: map[string]*messages.Value{"published": NewInt64Value(42)}}
+	e := NewMetricsEvent(ts, ds, metrics)
+	require.Equal(t, ts, e.GetTimestamp().AsTime())
+	require.Same(t, ds, e.GetDataStream())
+	require.Same(t, metrics, e.GetFields())
+	require.NoError(t, e.Validate())
+}
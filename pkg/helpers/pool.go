@@ -0,0 +1,112 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"sync"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// maxPooledEntries is the size above which the map of a released Struct is
+// dropped instead of being reused, so a single huge event doesn't pin its
+// memory in the pool.
+const maxPooledEntries = 1024
+
+var (
+	eventPool  = sync.Pool{New: func() interface{} { return &messages.Event{} }}
+	structPool = sync.Pool{New: func() interface{} { return &messages.Struct{} }}
+)
+
+// AcquireEvent returns an empty event from the pool, to be given back with
+// ReleaseEvent once it's not used anymore, e.g. after the PublishEvents call
+// that sent it returned.
+//
+// The ownership rules are:
+//   - an event owns its Metadata and Fields, they are released with it and
+//     must not be shared with other events, use AcquireStruct to build them;
+//   - a Struct owns the Structs and ListValues of its values, recursively;
+//   - after the release nothing owned by the object must be used, and the
+//     object must be released only once.
+//
+// Building with the shipperpooldebug tag makes the releases check that the
+// object was not released already, and panic otherwise.
+func AcquireEvent() *messages.Event {
+	e := eventPool.Get().(*messages.Event)
+	acquired(e)
+	return e
+}
+
+// ReleaseEvent resets the event, releases its Metadata and Fields and gives
+// them back to the pool. Releasing nil does nothing.
+func ReleaseEvent(e *messages.Event) {
+	if e == nil {
+		return
+	}
+	released(e)
+	ReleaseStruct(e.Metadata)
+	ReleaseStruct(e.Fields)
+	e.Reset()
+	eventPool.Put(e)
+}
+
+// AcquireStruct returns an empty Struct with a map ready to be filled, to be
+// given back with ReleaseStruct or with the event owning it.
+func AcquireStruct() *messages.Struct {
+	return AcquireStructSize(0)
+}
+
+// AcquireStructSize is AcquireStruct with a hint of the number of keys the
+// Struct will hold, used to size its map when the pool has none to reuse.
+func AcquireStructSize(size int) *messages.Struct {
+	s := structPool.Get().(*messages.Struct)
+	acquired(s)
+	if s.Data == nil {
+		if size < 0 {
+			size = 0
+		}
+		s.Data = make(map[string]*messages.Value, size)
+	}
+	return s
+}
+
+// ReleaseStruct resets the Struct, releases the Structs of its values
+// recursively and gives it back to the pool, keeping its map for reuse.
+// Releasing nil does nothing.
+func ReleaseStruct(s *messages.Struct) {
+	if s == nil {
+		return
+	}
+	released(s)
+	for _, v := range s.Data {
+		releaseValue(v)
+	}
+	for _, v := range s.RefData {
+		releaseValue(v)
+	}
+
+	data := s.Data
+	if len(data) > maxPooledEntries {
+		data = nil
+	}
+	for k := range data {
+		delete(data, k)
+	}
+	s.Reset()
+	s.Data = data
+	structPool.Put(s)
+}
+
+// releaseValue releases the Structs held by the value.
+func releaseValue(v *messages.Value) {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_StructValue:
+		ReleaseStruct(kind.StructValue)
+	case *messages.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			releaseValue(item)
+		}
+	}
+}
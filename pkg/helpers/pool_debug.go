@@ -0,0 +1,28 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build shipperpooldebug
+// +build shipperpooldebug
+
+package helpers
+
+import (
+	"fmt"
+	"sync"
+)
+
+// inPool holds the pooled objects that were released and not acquired again.
+var inPool sync.Map
+
+// acquired records that a pooled object is in use.
+func acquired(obj interface{}) {
+	inPool.Delete(obj)
+}
+
+// released panics when a pooled object is released twice.
+func released(obj interface{}) {
+	if _, loaded := inPool.LoadOrStore(obj, struct{}{}); loaded {
+		panic(fmt.Sprintf("%T %p released twice", obj, obj))
+	}
+}
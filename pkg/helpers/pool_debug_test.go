@@ -0,0 +1,23 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build shipperpooldebug
+// +build shipperpooldebug
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolDoubleRelease(t *testing.T) {
+	e := AcquireEvent()
+	e.Fields = AcquireStruct()
+	shared := e.Fields
+	ReleaseEvent(e)
+	require.Panics(t, func() { ReleaseStruct(shared) })
+	require.Panics(t, func() { ReleaseEvent(e) })
+}
@@ -0,0 +1,16 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build !shipperpooldebug
+// +build !shipperpooldebug
+
+package helpers
+
+// acquired records that a pooled object is in use, only with the
+// shipperpooldebug tag.
+func acquired(interface{}) {}
+
+// released checks that a pooled object is released once, only with the
+// shipperpooldebug tag.
+func released(interface{}) {}
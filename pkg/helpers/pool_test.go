@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestPool(t *testing.T) {
+	e := AcquireEvent()
+	require.Nil(t, e.GetFields())
+	e.Timestamp = timestamppb.Now()
+	e.DataStream = &messages.DataStream{Dataset: "generic"}
+	e.Metadata = AcquireStruct()
+	e.Metadata.Data["pipeline"] = NewStringValue("p")
+	e.Fields = AcquireStruct()
+	nested := AcquireStruct()
+	nested.Data["name"] = NewStringValue("host")
+	listed := AcquireStruct()
+	e.Fields.Data["host"] = NewStructValue(nested)
+	e.Fields.Data["list"] = NewListValue(&messages.ListValue{Values: []*messages.Value{NewStructValue(listed)}})
+	fields := e.Fields
+
+	ReleaseEvent(e)
+	require.Nil(t, e.GetTimestamp())
+	require.Nil(t, e.GetDataStream())
+	require.Nil(t, e.GetFields())
+	// the released structs are empty and keep their maps
+	for _, s := range []*messages.Struct{fields, nested, listed} {
+		require.NotNil(t, s.Data)
+		require.Empty(t, s.Data)
+	}
+
+	s := AcquireStruct()
+	require.NotNil(t, s.Data)
+	require.Empty(t, s.Data)
+	ReleaseStruct(s)
+
+	for _, size := range []int{-1, 0, 100} {
+		s := AcquireStructSize(size)
+		require.NotNil(t, s.Data)
+		require.Empty(t, s.Data)
+		ReleaseStruct(s)
+	}
+
+	ReleaseEvent(nil)
+	ReleaseStruct(nil)
+}
+
+func TestPoolLargeStruct(t *testing.T) {
+	s := AcquireStruct()
+	for i := 0; i <= maxPooledEntries; i++ {
+		s.Data[string(rune('a'+i%26))+string(rune(i))] = NewNullValue()
+	}
+	ReleaseStruct(s)
+	require.Nil(t, s.Data)
+}
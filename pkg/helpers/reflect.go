@@ -0,0 +1,124 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/runtime/protoimpl"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// structPlans caches the structPlan of every struct type converted by
+// NewValue, so the types are walked once rather than for every value.
+var structPlans sync.Map // map[reflect.Type]*structPlan
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structPlan is how the values of a struct type are converted.
+type structPlan struct {
+	fields []fieldPlan
+}
+
+// fieldPlan is how a field of a struct type is converted.
+type fieldPlan struct {
+	index int
+	// key is the name of the field, or the name of its json tag
+	key     string
+	convert func(v reflect.Value, in *Interner) (*messages.Value, error)
+}
+
+// structPlanFor returns the cached plan of the struct type.
+func structPlanFor(t reflect.Type) *structPlan {
+	if p, ok := structPlans.Load(t); ok {
+		return p.(*structPlan)
+	}
+	p := &structPlan{fields: make([]fieldPlan, 0, t.NumField())}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// unexported
+			continue
+		}
+		key := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			name := strings.Split(tag, ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				key = name
+			}
+		}
+		p.fields = append(p.fields, fieldPlan{index: i, key: key, convert: converterFor(f.Type)})
+	}
+	// another goroutine may have stored the same plan meanwhile
+	actual, _ := structPlans.LoadOrStore(t, p)
+	return actual.(*structPlan)
+}
+
+// converterFor returns the conversion of the values of the type. The
+// predeclared types and the nested structs are converted without boxing
+// the value in an interface, the other ones go through toValue.
+func converterFor(t reflect.Type) func(v reflect.Value, in *Interner) (*messages.Value, error) {
+	switch t {
+	case reflect.TypeOf(""):
+		return func(v reflect.Value, in *Interner) (*messages.Value, error) {
+			s := v.String()
+			if !utf8.ValidString(s) {
+				return nil, protoimpl.X.NewError("invalid UTF-8 in string: %q", s)
+			}
+			return NewStringValue(in.Intern(s)), nil
+		}
+	case reflect.TypeOf(false):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) { return NewBoolValue(v.Bool()), nil }
+	case reflect.TypeOf(0), reflect.TypeOf(int64(0)):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) { return NewInt64Value(v.Int()), nil }
+	case reflect.TypeOf(int32(0)):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) { return NewInt32Value(int32(v.Int())), nil }
+	case reflect.TypeOf(uint(0)), reflect.TypeOf(uint64(0)):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) { return NewUint64Value(v.Uint()), nil }
+	case reflect.TypeOf(uint32(0)):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) {
+			return NewUint32Value(uint32(v.Uint())), nil
+		}
+	case reflect.TypeOf(float32(0)):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) {
+			return NewFloat32Value(float32(v.Float())), nil
+		}
+	case reflect.TypeOf(float64(0)):
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) { return NewFloat64Value(v.Float()), nil }
+	case timeType:
+		return func(v reflect.Value, _ *Interner) (*messages.Value, error) {
+			return NewTimestampValue(v.Interface().(time.Time)), nil
+		}
+	}
+	if t.Kind() == reflect.Struct {
+		return func(v reflect.Value, in *Interner) (*messages.Value, error) {
+			return structPlanFor(t).value(v, in)
+		}
+	}
+	return func(v reflect.Value, in *Interner) (*messages.Value, error) {
+		return toValue(v.Interface(), in)
+	}
+}
+
+// value converts a value of the struct type of the plan.
+func (p *structPlan) value(v reflect.Value, in *Interner) (*messages.Value, error) {
+	data := make(map[string]*messages.Value, len(p.fields))
+	for _, f := range p.fields {
+		fv, err := f.convert(v.Field(f.index), in)
+		if err != nil {
+			return nil, protoimpl.X.NewError("could not convert value of type %v in struct: %s", v.Type(), err)
+		}
+		data[f.key] = fv
+	}
+	return NewStructValue(&messages.Struct{Data: data}), nil
+}
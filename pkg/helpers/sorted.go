@@ -0,0 +1,188 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// The field numbers of the messages.Struct and messages.Value messages used
+// to encode a SortedStruct.
+const (
+	structDataField  protowire.Number = 1
+	entryKeyField    protowire.Number = 1
+	entryValueField  protowire.Number = 2
+	valueStructField protowire.Number = 10
+)
+
+// KV is an entry of a SortedStruct. Either Value or Struct is set.
+type KV struct {
+	Key   string
+	Value *messages.Value
+	// Struct is a nested object, kept as a SortedStruct
+	Struct *SortedStruct
+}
+
+// SortedStruct is an experimental representation of a Struct as a slice of
+// its entries sorted by key instead of a map, to measure what building and
+// hashing the maps costs for the small events, see BenchmarkSortedStruct in
+// the benchmark package.
+//
+// The nested objects are SortedStructs as well, except the ones in lists
+// that are Structs. A SortedStruct is encoded like the equivalent Struct,
+// so it can be sent in place of one without converting it.
+type SortedStruct struct {
+	kvs []KV
+}
+
+type byKey []KV
+
+func (s byKey) Len() int           { return len(s) }
+func (s byKey) Less(i, j int) bool { return s[i].Key < s[j].Key }
+func (s byKey) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// NewSortedStruct constructs a SortedStruct from a general-purpose Go map,
+// like NewStruct does.
+func NewSortedStruct(v map[string]interface{}) (*SortedStruct, error) {
+	s := &SortedStruct{kvs: make([]KV, 0, len(v))}
+	for k, v := range v {
+		kv := KV{Key: k}
+		var err error
+		if m, ok := v.(map[string]interface{}); ok {
+			kv.Struct, err = NewSortedStruct(m)
+		} else {
+			kv.Value, err = NewValue(v)
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.kvs = append(s.kvs, kv)
+	}
+	sort.Sort(byKey(s.kvs))
+	return s, nil
+}
+
+// Len returns the number of keys of the struct.
+func (s *SortedStruct) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.kvs)
+}
+
+func (s *SortedStruct) search(key string) int {
+	return sort.Search(len(s.kvs), func(i int) bool { return s.kvs[i].Key >= key })
+}
+
+// Get returns the entry of the key, and whether the key is present.
+func (s *SortedStruct) Get(key string) (KV, bool) {
+	if s == nil {
+		return KV{}, false
+	}
+	if i := s.search(key); i < len(s.kvs) && s.kvs[i].Key == key {
+		return s.kvs[i], true
+	}
+	return KV{}, false
+}
+
+// Set sets the entry of its key, replacing the existing one.
+func (s *SortedStruct) Set(kv KV) {
+	i := s.search(kv.Key)
+	if i < len(s.kvs) && s.kvs[i].Key == kv.Key {
+		s.kvs[i] = kv
+		return
+	}
+	s.kvs = append(s.kvs, KV{})
+	copy(s.kvs[i+1:], s.kvs[i:])
+	s.kvs[i] = kv
+}
+
+// Range calls f with the entries of the struct, sorted by key, until f
+// returns false.
+func (s *SortedStruct) Range(f func(kv KV) bool) {
+	if s == nil {
+		return
+	}
+	for _, kv := range s.kvs {
+		if !f(kv) {
+			return
+		}
+	}
+}
+
+// Struct returns the equivalent Struct. The values are shared.
+func (s *SortedStruct) Struct() *messages.Struct {
+	x := &messages.Struct{Data: make(map[string]*messages.Value, s.Len())}
+	s.Range(func(kv KV) bool {
+		x.Data[kv.Key] = kv.value()
+		return true
+	})
+	return x
+}
+
+func (kv KV) value() *messages.Value {
+	if kv.Struct != nil {
+		return NewStructValue(kv.Struct.Struct())
+	}
+	return kv.Value
+}
+
+// AppendProto appends the protobuf encoding of the equivalent Struct to b,
+// with the keys in sorted order.
+func (s *SortedStruct) AppendProto(b []byte) ([]byte, error) {
+	if s == nil {
+		return b, nil
+	}
+	var err error
+	for _, kv := range s.kvs {
+		valueSize := kv.valueSize()
+		entrySize := protowire.SizeTag(entryKeyField) + protowire.SizeBytes(len(kv.Key)) +
+			protowire.SizeTag(entryValueField) + protowire.SizeBytes(valueSize)
+		b = protowire.AppendTag(b, structDataField, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(entrySize))
+		b = protowire.AppendTag(b, entryKeyField, protowire.BytesType)
+		b = protowire.AppendString(b, kv.Key)
+		b = protowire.AppendTag(b, entryValueField, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(valueSize))
+		if kv.Struct != nil {
+			b = protowire.AppendTag(b, valueStructField, protowire.BytesType)
+			b = protowire.AppendVarint(b, uint64(kv.Struct.size()))
+			b, err = kv.Struct.AppendProto(b)
+		} else {
+			b, err = proto.MarshalOptions{}.MarshalAppend(b, kv.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// size returns the size of the encoding of the struct.
+func (s *SortedStruct) size() int {
+	if s == nil {
+		return 0
+	}
+	n := 0
+	for _, kv := range s.kvs {
+		entrySize := protowire.SizeTag(entryKeyField) + protowire.SizeBytes(len(kv.Key)) +
+			protowire.SizeTag(entryValueField) + protowire.SizeBytes(kv.valueSize())
+		n += protowire.SizeTag(structDataField) + protowire.SizeBytes(entrySize)
+	}
+	return n
+}
+
+// valueSize returns the size of the encoding of the value of the entry.
+func (kv KV) valueSize() int {
+	if kv.Struct != nil {
+		return protowire.SizeTag(valueStructField) + protowire.SizeBytes(kv.Struct.size())
+	}
+	return proto.Size(kv.Value)
+}
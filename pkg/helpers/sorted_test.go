@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestSortedStruct(t *testing.T) {
+	m := map[string]interface{}{
+		"message": "hello",
+		"count":   int64(3),
+		"host": map[string]interface{}{
+			"name": "h",
+			"os":   map[string]interface{}{"family": "linux"},
+			"ips":  []interface{}{"127.0.0.1", map[string]interface{}{"v": 6}},
+		},
+		"empty": map[string]interface{}{},
+	}
+	s, err := NewSortedStruct(m)
+	require.NoError(t, err)
+	require.Equal(t, 4, s.Len())
+
+	var keys []string
+	s.Range(func(kv KV) bool {
+		keys = append(keys, kv.Key)
+		return true
+	})
+	require.Equal(t, []string{"count", "empty", "host", "message"}, keys)
+
+	host, ok := s.Get("host")
+	require.True(t, ok)
+	require.Equal(t, 3, host.Struct.Len())
+	_, ok = s.Get("missing")
+	require.False(t, ok)
+
+	want, err := NewStruct(m)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(want, s.Struct()))
+
+	// the encoding is the one of the Struct
+	data, err := s.AppendProto(nil)
+	require.NoError(t, err)
+	decoded := &messages.Struct{}
+	require.NoError(t, proto.Unmarshal(data, decoded))
+	require.True(t, proto.Equal(want, decoded), decoded)
+	require.Equal(t, len(data), s.size())
+
+	s.Set(KV{Key: "a", Value: NewBoolValue(true)})
+	s.Set(KV{Key: "message", Value: NewStringValue("replaced")})
+	keys = keys[:0]
+	s.Range(func(kv KV) bool {
+		keys = append(keys, kv.Key)
+		return kv.Key != "host"
+	})
+	require.Equal(t, []string{"a", "count", "empty", "host"}, keys)
+	message, _ := s.Get("message")
+	require.Equal(t, "replaced", message.Value.GetStringValue())
+
+	_, err = NewSortedStruct(map[string]interface{}{"invalid": "\xff"})
+	require.Error(t, err)
+
+	var empty *SortedStruct
+	require.Equal(t, 0, empty.Len())
+	data, err = empty.AppendProto(nil)
+	require.NoError(t, err)
+	require.Empty(t, data)
+}
@@ -106,13 +106,17 @@ import (
 // The map keys must be valid UTF-8.
 // The map values are converted using NewValue.
 func NewStruct(v map[string]interface{}) (*messages.Struct, error) {
+	return toStruct(v, nil)
+}
+
+func toStruct(v map[string]interface{}, in *Interner) (*messages.Struct, error) {
 	x := &messages.Struct{Data: make(map[string]*messages.Value, len(v))}
 	for k, v := range v {
 		if !utf8.ValidString(k) {
 			return nil, protoimpl.X.NewError("invalid UTF-8 in string: %q", k)
 		}
 		var err error
-		x.Data[k], err = NewValue(v)
+		x.Data[in.Intern(k)], err = toValue(v, in)
 		if err != nil {
 			return nil, err
 		}
@@ -123,7 +127,7 @@ func NewStruct(v map[string]interface{}) (*messages.Struct, error) {
 // AsMap converts x to a general-purpose Go map.
 // The map values are converted by calling Value.AsInterface.
 func AsMap(x *messages.Struct) map[string]interface{} {
-	vs := make(map[string]interface{})
+	vs := make(map[string]interface{}, len(x.GetData()))
 	for k, v := range x.GetData() {
 		vs[k] = AsInterface(v)
 	}
@@ -175,6 +179,10 @@ func AsInterface(x *messages.Value) interface{} {
 		if v != nil {
 			return v.BoolValue
 		}
+	case *messages.Value_BytesValue:
+		if v != nil {
+			return v.BytesValue
+		}
 	case *messages.Value_StructValue:
 		if v != nil {
 			return AsMap(v.StructValue)
@@ -200,8 +208,16 @@ func AsSlice(x *messages.ListValue) []interface{} {
 // NewValue constructs a Value from a general-purpose Go interface.
 // When converting an int64 or uint64 to a NumberValue, numeric precision loss
 // is possible since they are stored as a float64.
+//
+// The structs are converted to Structs keyed by the names of their fields,
+// or the names of their json tags. The unexported fields and the ones
+// tagged "-" are skipped. How a struct type is converted is computed once
+// and cached.
 func NewValue(newValue interface{}) (*messages.Value, error) {
+	return toValue(newValue, nil)
+}
 
+func toValue(newValue interface{}, in *Interner) (*messages.Value, error) {
 	if newValue == nil {
 		return NewNullValue(), nil
 	}
@@ -229,24 +245,24 @@ func NewValue(newValue interface{}) (*messages.Value, error) {
 		if !utf8.ValidString(newValueTyped) {
 			return nil, protoimpl.X.NewError("invalid UTF-8 in string: %q", newValueTyped)
 		}
-		return NewStringValue(newValueTyped), nil
+		return NewStringValue(in.Intern(newValueTyped)), nil
 	case time.Time:
 		return NewTimestampValue(newValueTyped), nil
 
 	case map[string]interface{}:
-		sv, err := NewStruct(newValueTyped)
+		sv, err := toStruct(newValueTyped, in)
 		if err != nil {
 			return nil, protoimpl.X.NewError("error creating struct object: %q", newValueTyped)
 		}
 		return NewStructValue(sv), nil
 	case mapstr.M: // mapstr.M is just a map[string]interface, but the typecast won't recognize that
-		sv, err := NewStruct(newValueTyped)
+		sv, err := toStruct(newValueTyped, in)
 		if err != nil {
 			return nil, protoimpl.X.NewError("error creating struct object: %q", newValueTyped)
 		}
 		return NewStructValue(sv), nil
 	case []interface{}:
-		lst, err := NewList(newValueTyped)
+		lst, err := toList(newValueTyped, in)
 		if err != nil {
 			return nil, protoimpl.X.NewError("error creating list object: %q", newValueTyped)
 		}
@@ -254,7 +270,7 @@ func NewValue(newValue interface{}) (*messages.Value, error) {
 	case []string: // not strictly needed, but []string seems to be common in log events, so this will give a slight performance boost
 		strListVal := &messages.ListValue{Values: make([]*messages.Value, len(newValueTyped))}
 		for i, sv := range newValueTyped {
-			strListVal.Values[i] = NewStringValue(sv)
+			strListVal.Values[i] = NewStringValue(in.Intern(sv))
 		}
 		return NewListValue(strListVal), nil
 	case []byte:
@@ -264,31 +280,20 @@ func NewValue(newValue interface{}) (*messages.Value, error) {
 	default: // fall back to using reflection to unpack the value
 		switch reflect.TypeOf(newValueTyped).Kind() {
 		case reflect.Struct:
-			mapVal := reflect.ValueOf(newValueTyped)
-			fields := reflect.TypeOf(newValueTyped)
-			interMap := map[string]*messages.Value{}
-			for i := 0; i < mapVal.NumField(); i++ {
-				msgVal, err := NewValue(mapVal.Field(i).Interface())
-				if err != nil {
-					return nil, protoimpl.X.NewError("could not convert value of type %T in struct: %s", newValueTyped, err)
-				}
-				name := fields.Field(i).Name // is there a struct tag we should use instead?
-				interMap[name] = msgVal
-			}
-			structObj := &messages.Struct{Data: interMap}
-			return NewStructValue(structObj), nil
+			return structPlanFor(reflect.TypeOf(newValueTyped)).value(reflect.ValueOf(newValueTyped), in)
 		case reflect.Map: // we'll only end up here if we have a map that doesn't resolve to value type interface{}
-			reflected := map[string]*messages.Value{}
-			mapIter := reflect.ValueOf(newValueTyped).MapRange()
+			refVal := reflect.ValueOf(newValueTyped)
+			reflected := make(map[string]*messages.Value, refVal.Len())
+			mapIter := refVal.MapRange()
 			// hard error if the key type isn't a string
 			if reftype := reflect.TypeOf(newValueTyped).Key().Kind(); reftype != reflect.String {
 				return nil, protoimpl.X.NewError("maps must have key of type string, got %v", reftype)
 			}
 			var err error
 			for mapIter.Next() {
-				k := mapIter.Key().String()
+				k := in.Intern(mapIter.Key().String())
 				mv := mapIter.Value().Interface()
-				reflected[k], err = NewValue(mv)
+				reflected[k], err = toValue(mv, in)
 				if err != nil {
 					protoimpl.X.NewError("could not convert value of type %T in map: %s", mv, err)
 				}
@@ -300,7 +305,7 @@ func NewValue(newValue interface{}) (*messages.Value, error) {
 			listVal := &messages.ListValue{Values: make([]*messages.Value, refVal.Len())}
 			for i := 0; i < refVal.Len(); i++ {
 				var err error
-				listVal.Values[i], err = NewValue(refVal.Index(i).Interface())
+				listVal.Values[i], err = toValue(refVal.Index(i).Interface(), in)
 				if err != nil {
 					return nil, protoimpl.X.NewError("error unpacking field of type %T in array %#v: %s", refVal.Field(i).Interface(), newValueTyped, err)
 				}
@@ -364,6 +369,12 @@ func NewTimestampValue(v time.Time) *messages.Value {
 	return &messages.Value{Kind: &messages.Value_TimestampValue{TimestampValue: timestamppb.New(v)}}
 }
 
+// NewBytesValue constructs a new byte slice Value. NewValue converts a
+// []byte to a base64 string instead.
+func NewBytesValue(v []byte) *messages.Value {
+	return &messages.Value{Kind: &messages.Value_BytesValue{BytesValue: v}}
+}
+
 // NewStructValue constructs a new struct Value.
 func NewStructValue(v *messages.Struct) *messages.Value {
 	return &messages.Value{Kind: &messages.Value_StructValue{StructValue: v}}
@@ -377,10 +388,14 @@ func NewListValue(v *messages.ListValue) *messages.Value {
 // NewList constructs a ListValue from a general-purpose Go slice.
 // The slice elements are converted using NewValue.
 func NewList(v []interface{}) (*messages.ListValue, error) {
+	return toList(v, nil)
+}
+
+func toList(v []interface{}, in *Interner) (*messages.ListValue, error) {
 	x := &messages.ListValue{Values: make([]*messages.Value, len(v))}
 	for i, v := range v {
 		var err error
-		x.Values[i], err = NewValue(v)
+		x.Values[i], err = toValue(v, in)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,184 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+//go:build go1.18
+// +build go1.18
+
+package helpers
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/fastjson"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func FuzzNewValueJSON(f *testing.F) {
+	for _, doc := range []string{
+		`null`,
+		`{"message":"hello","count":5,"ratio":0.5,"ok":true,"tags":["a","b"],"nested":{"x":null}}`,
+		`[1e308,-1e-308,18446744073709551615,-9223372036854775808]`,
+		`"é😀"`,
+		`{"":{"":[[],{}]}}`,
+	} {
+		f.Add([]byte(doc))
+	}
+
+	f.Fuzz(func(t *testing.T, doc []byte) {
+		var in interface{}
+		if err := json.Unmarshal(doc, &in); err != nil {
+			t.Skip()
+		}
+		v, err := NewValue(in)
+		require.NoError(t, err)
+		require.Equal(t, in, AsInterface(v))
+	})
+}
+
+func FuzzNewValueScalars(f *testing.F) {
+	f.Add(int64(0), uint64(0), 0.0, float32(0), "")
+	f.Add(int64(math.MinInt64), uint64(math.MaxUint64), math.MaxFloat64, float32(math.SmallestNonzeroFloat32), "\xff")
+	f.Add(int64(math.MaxInt64), uint64(1<<53+1), math.Inf(-1), float32(math.NaN()), "日本")
+
+	f.Fuzz(func(t *testing.T, i int64, u uint64, f64 float64, f32 float32, s string) {
+		for _, in := range []interface{}{i, int32(i), int(i), u, uint32(u), uint(u), s} {
+			v, err := NewValue(in)
+			if str, ok := in.(string); ok && !utf8.ValidString(str) {
+				require.Error(t, err)
+				continue
+			}
+			require.NoError(t, err)
+			out := AsInterface(v)
+			// the conversions must not lose any precision
+			require.Equal(t, reflect.ValueOf(in).Convert(reflect.TypeOf(out)).Interface(), out)
+			require.Equal(t, in, reflect.ValueOf(out).Convert(reflect.TypeOf(in)).Interface())
+		}
+
+		for _, in := range []interface{}{f64, f32} {
+			v, err := NewValue(in)
+			require.NoError(t, err)
+			out := AsInterface(v)
+			require.IsType(t, in, out)
+			if math.IsNaN(reflect.ValueOf(in).Float()) {
+				require.True(t, math.IsNaN(reflect.ValueOf(out).Float()))
+				continue
+			}
+			require.Equal(t, in, out)
+		}
+	})
+}
+
+// FuzzMarshalFastJSON compares MarshalFastJSON with encoding/json on the
+// result of AsMap, both must produce the same document.
+func FuzzMarshalFastJSON(f *testing.F) {
+	f.Add([]byte("\x0b\x02ab\x07\x03\"\\\n"))
+	f.Add([]byte("\x0b\x01\x00\x0a\x02\x05\x06\x09\x08"))
+	f.Add([]byte("\x0b\x03<>&\x0b\x01\x7f\x04\xe2\x80\xa8\xff"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		s := r.structValue(0)
+
+		var w fastjson.Writer
+		require.NoError(t, s.MarshalFastJSON(&w))
+		expected, err := json.Marshal(AsMap(s))
+		if err != nil {
+			// NaN and infinities have no JSON representation, they are null
+			require.True(t, json.Valid(w.Bytes()), "invalid JSON: %s", w.Bytes())
+			return
+		}
+
+		var want, got interface{}
+		require.NoError(t, json.Unmarshal(expected, &want))
+		require.NoError(t, json.Unmarshal(w.Bytes(), &got), "invalid JSON: %s", w.Bytes())
+		require.Equal(t, want, got)
+	})
+}
+
+// fuzzReader builds values out of fuzzing data.
+type fuzzReader struct {
+	data []byte
+}
+
+func (r *fuzzReader) byte() byte {
+	if len(r.data) == 0 {
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *fuzzReader) bytes(n int) []byte {
+	if n > len(r.data) {
+		n = len(r.data)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *fuzzReader) uint64() uint64 {
+	var buf [8]byte
+	copy(buf[:], r.bytes(8))
+	return binary.LittleEndian.Uint64(buf[:])
+}
+
+func (r *fuzzReader) string() string {
+	return strings.ToValidUTF8(string(r.bytes(int(r.byte()%16))), "�")
+}
+
+func (r *fuzzReader) structValue(depth int) *messages.Struct {
+	s := &messages.Struct{Data: map[string]*messages.Value{}}
+	for n := r.byte() % 4; n > 0; n-- {
+		s.Data[r.string()] = r.value(depth + 1)
+	}
+	return s
+}
+
+func (r *fuzzReader) value(depth int) *messages.Value {
+	kind := r.byte() % 12
+	if depth > 3 && kind >= 10 {
+		kind = 0
+	}
+	switch kind {
+	case 1:
+		return NewBoolValue(r.byte()%2 == 0)
+	case 2:
+		return NewInt32Value(int32(r.uint64()))
+	case 3:
+		return NewInt64Value(int64(r.uint64()))
+	case 4:
+		return NewUint32Value(uint32(r.uint64()))
+	case 5:
+		return NewUint64Value(r.uint64())
+	case 6:
+		return NewFloat32Value(math.Float32frombits(uint32(r.uint64())))
+	case 7:
+		return NewFloat64Value(math.Float64frombits(r.uint64()))
+	case 8:
+		return NewStringValue(r.string())
+	case 9:
+		return NewTimestampValue(time.Unix(int64(r.uint64()%(1<<35)), int64(r.uint64()%1e9)).UTC())
+	case 10:
+		return NewStructValue(r.structValue(depth))
+	case 11:
+		l := &messages.ListValue{}
+		for n := r.byte() % 4; n > 0; n-- {
+			l.Values = append(l.Values, r.value(depth+1))
+		}
+		return NewListValue(l)
+	default:
+		return NewNullValue()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"reflect"
+	"strconv"
 	"time"
 
 	"testing"
@@ -236,6 +237,57 @@ func BenchmarkCustomUnmarshal(b *testing.B) {
 	}
 }
 
+// benchmarkHost is a struct like the ones of the inputs building events
+// from their own types.
+type benchmarkHost struct {
+	Name     string
+	IP       []string
+	Port     int
+	Uptime   int64
+	Load     float64
+	Up       bool
+	Labels   map[string]string
+	Started  time.Time
+	Children []benchmarkHost
+}
+
+func BenchmarkNewValueStruct(b *testing.B) {
+	host := benchmarkHost{
+		Name:    "host-1",
+		IP:      []string{"10.0.0.1", "10.0.0.2"},
+		Port:    9200,
+		Uptime:  123456,
+		Load:    0.5,
+		Up:      true,
+		Labels:  map[string]string{"env": "prod"},
+		Started: time.Now(),
+	}
+	host.Children = []benchmarkHost{host, host}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r, err := NewValue(host)
+		if err != nil {
+			b.Fatal(err)
+		}
+		result = r
+	}
+}
+
+func BenchmarkNewValueMap(b *testing.B) {
+	labels := make(map[string]string, 64)
+	for i := 0; i < 64; i++ {
+		labels["label-"+strconv.Itoa(i)] = "value"
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r, err := NewValue(labels)
+		if err != nil {
+			b.Fatal(err)
+		}
+		result = r
+	}
+}
+
 func TestStructValue(t *testing.T) {
 	testStructType := struct {
 		A int
@@ -318,6 +370,23 @@ func TestStructValue(t *testing.T) {
 				"B": NewStringValue("test"),
 			}}),
 		},
+		{
+			name: "test struct conversion with tags",
+			in: struct {
+				Name    string `json:"name,omitempty"`
+				Skipped int    `json:"-"`
+				Nested  struct {
+					Port uint32 `json:"port"`
+				} `json:"nested"`
+				Untagged float32
+				private  string
+			}{Name: "n", Skipped: 1, Untagged: 0.5, private: "p"},
+			exp: NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+				"name":     NewStringValue("n"),
+				"nested":   NewStructValue(&messages.Struct{Data: map[string]*messages.Value{"port": NewUint32Value(0)}}),
+				"Untagged": NewFloat32Value(0.5),
+			}}),
+		},
 		{
 			name: "list conversion of string type",
 			in:   []string{"value1", "value2"},
@@ -365,3 +434,55 @@ func TestStructValue(t *testing.T) {
 		})
 	}
 }
+
+func TestStructValueInvalid(t *testing.T) {
+	type invalid struct {
+		S string
+	}
+	for i := 0; i < 2; i++ {
+		// the second conversion uses the cached plan
+		_, err := NewValue(invalid{S: "\xff"})
+		require.Error(t, err)
+		_, err = NewValue(struct{ C complex64 }{})
+		require.Error(t, err)
+	}
+}
+
+func TestStructDeepUpdateMatchesMapStr(t *testing.T) {
+	base := func() mapstr.M {
+		return mapstr.M{
+			"message": "hello",
+			"host":    mapstr.M{"name": "h", "os": mapstr.M{"family": "linux"}},
+			"labels":  "none",
+			"tags":    []interface{}{"a"},
+		}
+	}
+	other := func() mapstr.M {
+		return mapstr.M{
+			"message": "world",
+			"host":    mapstr.M{"ip": "127.0.0.1", "os": mapstr.M{"family": "windows", "version": "10"}},
+			"labels":  mapstr.M{"env": "prod"},
+			"tags":    "b",
+			"new":     int64(1),
+		}
+	}
+
+	for _, overwrite := range []bool{true, false} {
+		want := base()
+		if overwrite {
+			want.DeepUpdate(other())
+		} else {
+			want.DeepUpdateNoOverwrite(other())
+		}
+
+		s, err := NewStruct(base())
+		require.NoError(t, err)
+		o, err := NewStruct(other())
+		require.NoError(t, err)
+		s.DeepUpdate(o, overwrite)
+
+		expected, err := NewStruct(want)
+		require.NoError(t, err)
+		require.Empty(t, Diff(expected, s), "overwrite: %v", overwrite)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package testutil builds reproducible events for tests.
+package testutil
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Epoch is the timestamp of the event built from the seed 0, the event of
+// the seed n is n seconds later.
+var Epoch = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	inputTypes = []string{"logfile", "winlog", "metrics", "httpjson"}
+	datasets   = []string{"system.syslog", "system.auth", "nginx.access", "generic"}
+	namespaces = []string{"default", "production", "staging"}
+	messageSet = []string{"connection accepted", "user logged in", "disk almost full", "request served"}
+)
+
+// NewEvent returns a fully populated event: every field of the event is set
+// and the fields hold a value of every kind, except the metadata delta and
+// the string references used to encode requests, and the global metadata
+// version only known by a shipper. The same seed always returns the same event.
+func NewEvent(seed int64) *messages.Event {
+	r := rand.New(rand.NewSource(seed)) //nolint:gosec // not used for security
+	ts := Epoch.Add(time.Duration(seed) * time.Second)
+	input := inputTypes[r.Intn(len(inputTypes))]
+	dataset := datasets[r.Intn(len(datasets))]
+
+	e := &messages.Event{
+		Timestamp:    timestamppb.New(ts),
+		TimestampRaw: ts.Format(time.RFC3339Nano),
+		Source: &messages.Source{
+			InputId:  fmt.Sprintf("%s-%d", input, r.Intn(10)),
+			StreamId: fmt.Sprintf("%s-%s-%d", input, dataset, r.Intn(10)),
+		},
+		DataStream: &messages.DataStream{
+			Type:      "logs",
+			Dataset:   dataset,
+			Namespace: namespaces[r.Intn(len(namespaces))],
+		},
+		Metadata: &messages.Struct{Data: map[string]*messages.Value{
+			"input": helpers.NewStringValue(input),
+		}},
+		Fields: &messages.Struct{Data: map[string]*messages.Value{
+			"message":  helpers.NewStringValue(messageSet[r.Intn(len(messageSet))]),
+			"sequence": helpers.NewInt64Value(seed),
+			"bytes":    helpers.NewUint64Value(r.Uint64()),
+			"pid":      helpers.NewInt32Value(r.Int31()),
+			"port":     helpers.NewUint32Value(r.Uint32()),
+			"ratio":    helpers.NewFloat64Value(r.Float64()),
+			"load":     helpers.NewFloat32Value(r.Float32()),
+			"success":  helpers.NewBoolValue(r.Intn(2) == 0),
+			"error":    helpers.NewNullValue(),
+			"digest":   helpers.NewBytesValue([]byte(dataset)),
+			"created":  helpers.NewTimestampValue(ts.Add(-time.Duration(r.Intn(1000)) * time.Millisecond)),
+			"tags": helpers.NewListValue(&messages.ListValue{Values: []*messages.Value{
+				helpers.NewStringValue(input),
+				helpers.NewStringValue(dataset),
+			}}),
+			"host": helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+				"name": helpers.NewStringValue(fmt.Sprintf("host-%d", r.Intn(100))),
+				"ip":   helpers.NewStringValue(fmt.Sprintf("10.0.%d.%d", r.Intn(256), r.Intn(256))),
+			}}),
+		}},
+		Attachment: &messages.Attachment{
+			ContentType: "application/octet-stream",
+			Data:        []byte(fmt.Sprintf("%08x", r.Uint32())),
+		},
+		Pipeline:   dataset + "-pipeline",
+		RawIndex:   fmt.Sprintf("%s-%s-raw", input, dataset),
+		OpType:     string(messages.OpTypeCreate),
+		DocumentId: fmt.Sprintf("%016x", r.Uint64()),
+	}
+	if err := e.AddExtension(wrapperspb.String(input)); err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// NewEvents returns n events built from the seeds starting at the given one.
+func NewEvents(seed int64, n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = NewEvent(seed + int64(i))
+	}
+	return events
+}
@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package testutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestNewEvent(t *testing.T) {
+	require.True(t, proto.Equal(NewEvent(42), NewEvent(42)))
+	require.False(t, proto.Equal(NewEvent(42), NewEvent(43)))
+
+	event := NewEvent(42)
+	require.Equal(t, Epoch.Add(42*time.Second), event.GetTimestamp().AsTime())
+
+	// every field is populated, except the delta used to encode requests and
+	// the reference to the global metadata of a shipper
+	fields := event.ProtoReflect().Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if name := fields.Get(i).Name(); name == "metadata_delta" || name == "global_metadata_version" {
+			continue
+		}
+		require.True(t, event.ProtoReflect().Has(fields.Get(i)), fields.Get(i).Name())
+	}
+	kind := (&messages.Value{}).ProtoReflect().Descriptor().Oneofs().ByName("kind")
+	kinds := map[protoreflect.Name]bool{}
+	for _, v := range event.GetFields().GetData() {
+		kinds[v.ProtoReflect().WhichOneof(kind).Name()] = true
+	}
+	// string references only exist in requests encoded with a string table
+	require.Len(t, kinds, kind.Fields().Len()-1)
+	require.False(t, kinds["string_ref"])
+}
+
+func TestNewEvents(t *testing.T) {
+	events := NewEvents(10, 3)
+	require.Len(t, events, 3)
+	require.True(t, proto.Equal(NewEvent(12), events[2]))
+}
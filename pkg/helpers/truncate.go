@@ -0,0 +1,185 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// TruncatedKey is the key of the list of the truncated fields added by Truncate.
+const TruncatedKey = "_truncated"
+
+// TruncateOptions are the limits enforced by Truncate. Zero means no limit.
+type TruncateOptions struct {
+	// MaxFields is the maximum number of keys, the keys of the nested
+	// structs included. A list counts as a single field.
+	MaxFields int
+	// MaxStringLength is the maximum length in bytes of the string values.
+	MaxStringLength int
+	// MaxBytes is the maximum serialized size of the struct.
+	MaxBytes int
+}
+
+// Truncate enforces the limits on the struct, protecting against
+// pathological events that would be rejected by the shipper:
+//   - the strings longer than MaxStringLength are shortened, on a rune boundary,
+//   - the keys beyond MaxFields are dropped, in the order of the sorted keys,
+//   - the largest top-level fields are dropped until the struct fits in MaxBytes.
+//
+// The dotted paths of the shortened and dropped fields are appended to a list
+// under TruncatedKey, the marker itself is not truncated. It returns true if
+// the struct was changed.
+func Truncate(s *messages.Struct, opts TruncateOptions) bool {
+	if s == nil {
+		return false
+	}
+	t := truncater{opts: opts, seen: map[string]bool{}}
+	marker := s.Data[TruncatedKey]
+	delete(s.Data, TruncatedKey)
+
+	if opts.MaxStringLength > 0 {
+		t.shortenStruct("", s)
+	}
+	if opts.MaxFields > 0 {
+		count := 0
+		t.limitFields("", s, &count)
+	}
+	if opts.MaxBytes > 0 {
+		t.limitBytes(s, marker)
+	}
+
+	if len(t.paths) == 0 {
+		if marker != nil {
+			s.Data[TruncatedKey] = marker
+		}
+		return false
+	}
+	s.Data[TruncatedKey] = t.marker(marker)
+	return true
+}
+
+type truncater struct {
+	opts  TruncateOptions
+	paths []string
+	seen  map[string]bool
+}
+
+// record adds the path to the marker, once.
+func (t *truncater) record(path string) {
+	if !t.seen[path] {
+		t.seen[path] = true
+		t.paths = append(t.paths, path)
+	}
+}
+
+// marker returns the list of the truncated paths, following the paths of
+// the previous marker.
+func (t *truncater) marker(previous *messages.Value) *messages.Value {
+	values := append([]*messages.Value(nil), previous.GetListValue().GetValues()...)
+	for _, p := range t.paths {
+		values = append(values, NewStringValue(p))
+	}
+	return NewListValue(&messages.ListValue{Values: values})
+}
+
+func (t *truncater) shortenStruct(prefix string, s *messages.Struct) {
+	for k, v := range s.GetData() {
+		t.shortenValue(join(prefix, k), v)
+	}
+}
+
+func (t *truncater) shortenValue(path string, v *messages.Value) {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_StringValue:
+		if len(kind.StringValue) > t.opts.MaxStringLength {
+			kind.StringValue = truncateString(kind.StringValue, t.opts.MaxStringLength)
+			t.record(path)
+		}
+	case *messages.Value_StructValue:
+		t.shortenStruct(path, kind.StructValue)
+	case *messages.Value_ListValue:
+		// the elements of a list share its path
+		for _, item := range kind.ListValue.GetValues() {
+			t.shortenValue(path, item)
+		}
+	}
+}
+
+func (t *truncater) limitFields(prefix string, s *messages.Struct, count *int) {
+	for _, k := range sortedKeys(s) {
+		path := join(prefix, k)
+		if *count >= t.opts.MaxFields {
+			delete(s.Data, k)
+			t.record(path)
+			continue
+		}
+		*count++
+		if nested := s.Data[k].GetStructValue(); nested != nil {
+			t.limitFields(path, nested, count)
+		}
+	}
+}
+
+func (t *truncater) limitBytes(s *messages.Struct, previous *messages.Value) {
+	sizes := make(map[string]int, len(s.GetData()))
+	keys := sortedKeys(s)
+	for _, k := range keys {
+		sizes[k] = entrySize(k, s.Data[k])
+	}
+	// largest first, the sorted keys break the ties
+	sort.SliceStable(keys, func(i, j int) bool { return sizes[keys[i]] > sizes[keys[j]] })
+
+	size := proto.Size(s)
+	for _, k := range keys {
+		markerSize := 0
+		if len(t.paths) > 0 || previous != nil {
+			markerSize = entrySize(TruncatedKey, t.marker(previous))
+		}
+		if size+markerSize <= t.opts.MaxBytes {
+			return
+		}
+		delete(s.Data, k)
+		size -= sizes[k]
+		t.record(k)
+	}
+}
+
+// entrySize returns the serialized size of a key and its value in a Struct.
+func entrySize(key string, v *messages.Value) int {
+	n := protowire.SizeTag(1) + protowire.SizeBytes(len(key)) +
+		protowire.SizeTag(2) + protowire.SizeBytes(proto.Size(v))
+	return protowire.SizeTag(1) + protowire.SizeBytes(n)
+}
+
+// truncateString returns the longest prefix of s of at most n bytes that
+// doesn't split a rune.
+func truncateString(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func sortedKeys(s *messages.Struct) []string {
+	keys := make([]string, 0, len(s.GetData()))
+	for k := range s.GetData() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
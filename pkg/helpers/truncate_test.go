@@ -0,0 +1,72 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTruncate(t *testing.T) {
+	newStruct := func() map[string]interface{} {
+		return map[string]interface{}{
+			"message": "héllo world",
+			"tags":    []interface{}{"short", "a very long tag"},
+			"host":    map[string]interface{}{"name": "h", "os": map[string]interface{}{"family": "linux"}},
+			"count":   5,
+		}
+	}
+
+	s, err := NewStruct(newStruct())
+	require.NoError(t, err)
+	require.False(t, Truncate(s, TruncateOptions{MaxFields: 10, MaxStringLength: 100, MaxBytes: 1000}))
+	require.NotContains(t, s.GetData(), TruncatedKey)
+
+	s, err = NewStruct(newStruct())
+	require.NoError(t, err)
+	require.True(t, Truncate(s, TruncateOptions{MaxStringLength: 2}))
+	require.Equal(t, map[string]interface{}{
+		"message":    "h",
+		"tags":       []interface{}{"sh", "a "},
+		"host":       map[string]interface{}{"name": "h", "os": map[string]interface{}{"family": "li"}},
+		"count":      int64(5),
+		TruncatedKey: nil,
+	}, withoutMarkerValue(AsMap(s)))
+	require.ElementsMatch(t, []interface{}{"message", "tags", "host.os.family"}, AsInterface(s.GetData()[TruncatedKey]))
+
+	// host, host.name, host.os, then the limit is reached
+	s, err = NewStruct(newStruct())
+	require.NoError(t, err)
+	require.True(t, Truncate(s, TruncateOptions{MaxFields: 4}))
+	require.Equal(t, []interface{}{"host.os.family", "message", "tags"}, AsInterface(s.GetData()[TruncatedKey]))
+	require.Equal(t, int64(5), AsMap(s)["count"])
+
+	// the largest fields are dropped first
+	s, err = NewStruct(map[string]interface{}{
+		"small":  "a",
+		"medium": strings.Repeat("b", 50),
+		"large":  strings.Repeat("c", 100),
+	})
+	require.NoError(t, err)
+	require.True(t, Truncate(s, TruncateOptions{MaxBytes: 120}))
+	require.LessOrEqual(t, proto.Size(s), 120)
+	require.Equal(t, []interface{}{"large"}, AsInterface(s.GetData()[TruncatedKey]))
+
+	// a second truncation appends to the marker
+	require.True(t, Truncate(s, TruncateOptions{MaxFields: 1}))
+	require.Equal(t, []interface{}{"large", "small"}, AsInterface(s.GetData()[TruncatedKey]))
+	require.False(t, Truncate(s, TruncateOptions{MaxFields: 1}))
+	require.Len(t, s.GetData(), 2)
+}
+
+func withoutMarkerValue(m map[string]interface{}) map[string]interface{} {
+	if _, ok := m[TruncatedKey]; ok {
+		m[TruncatedKey] = nil
+	}
+	return m
+}
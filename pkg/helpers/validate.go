@@ -0,0 +1,122 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// ValidateOptions configures ValidateEvent.
+type ValidateOptions struct {
+	// MaxEventBytes is the maximum serialized size of an event. Zero means no limit.
+	MaxEventBytes int
+}
+
+// ValidationErrors lists all the invalid fields of an event.
+type ValidationErrors []*messages.ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidateEvent checks the event before it's published, so it's not rejected
+// by the shipper after a round trip:
+//   - it follows the rules of the API, checked by (*messages.Event).Validate,
+//   - it's not larger than MaxEventBytes once serialized,
+//   - all the strings, keys included, are valid UTF-8, or it can't be serialized.
+//
+// It returns ValidationErrors listing the first rule of the API the event
+// breaks and all the invalid strings, the size check is skipped when a string
+// isn't valid UTF-8.
+func ValidateEvent(e *messages.Event, opts ValidateOptions) error {
+	var errs ValidationErrors
+	report := func(field, reason string) {
+		errs = append(errs, &messages.ValidationError{Field: field, Reason: reason})
+	}
+
+	var v *messages.ValidationError
+	if errors.As(e.Validate(), &v) {
+		errs = append(errs, v)
+	}
+
+	invalidUTF8 := len(errs)
+	checkUTF8 := func(field, s string) {
+		if !utf8.ValidString(s) {
+			report(field, "invalid UTF-8")
+		}
+	}
+	checkUTF8("timestamp_raw", e.GetTimestampRaw())
+	checkUTF8("source.input_id", e.GetSource().GetInputId())
+	checkUTF8("source.stream_id", e.GetSource().GetStreamId())
+	checkUTF8("data_stream.type", e.GetDataStream().GetType())
+	checkUTF8("data_stream.dataset", e.GetDataStream().GetDataset())
+	checkUTF8("data_stream.namespace", e.GetDataStream().GetNamespace())
+	checkUTF8("attachment.content_type", e.GetAttachment().GetContentType())
+	checkUTF8("pipeline", e.GetPipeline())
+	checkUTF8("raw_index", e.GetRawIndex())
+	checkUTF8("document_id", e.GetDocumentId())
+	validateStructUTF8("metadata", e.GetMetadata(), report)
+	validateStructUTF8("fields", e.GetFields(), report)
+	for i, k := range e.GetMetadataDelta().GetRemovedKeys() {
+		checkUTF8(fmt.Sprintf("metadata_delta.removed_keys[%d]", i), k)
+	}
+	invalidUTF8 = len(errs) - invalidUTF8
+
+	if opts.MaxEventBytes > 0 && invalidUTF8 == 0 {
+		if size := proto.Size(e); size > opts.MaxEventBytes {
+			report("event", fmt.Sprintf("%d bytes, the maximum is %d", size, opts.MaxEventBytes))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateStructUTF8 reports the keys and the string values of the struct
+// that are not valid UTF-8, in the order of the keys.
+func validateStructUTF8(path string, s *messages.Struct, report func(field, reason string)) {
+	keys := make([]string, 0, len(s.GetData()))
+	for k := range s.GetData() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		field := path + "." + k
+		if !utf8.ValidString(k) {
+			report(path, fmt.Sprintf("key %q is invalid UTF-8", k))
+			field = path + "." + strings.ToValidUTF8(k, string(utf8.RuneError))
+		}
+		validateValueUTF8(field, s.GetData()[k], report)
+	}
+}
+
+func validateValueUTF8(path string, v *messages.Value, report func(field, reason string)) {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_StringValue:
+		if !utf8.ValidString(kind.StringValue) {
+			report(path, "invalid UTF-8")
+		}
+	case *messages.Value_StructValue:
+		validateStructUTF8(path, kind.StructValue, report)
+	case *messages.Value_ListValue:
+		for i, item := range kind.ListValue.GetValues() {
+			validateValueUTF8(fmt.Sprintf("%s[%d]", path, i), item, report)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package helpers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestValidateEvent(t *testing.T) {
+	fields, err := NewStruct(map[string]interface{}{"message": "hello"})
+	require.NoError(t, err)
+	valid := &messages.Event{
+		Timestamp:  timestamppb.Now(),
+		DataStream: &messages.DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+		Fields:     fields,
+	}
+	require.NoError(t, ValidateEvent(valid, ValidateOptions{}))
+
+	fieldsOf := func(err error) []string {
+		var errs ValidationErrors
+		require.ErrorAs(t, err, &errs)
+		var names []string
+		for _, e := range errs {
+			names = append(names, e.Field)
+		}
+		return names
+	}
+
+	err = ValidateEvent(&messages.Event{}, ValidateOptions{})
+	require.Equal(t, []string{"timestamp"}, fieldsOf(err))
+	require.EqualError(t, err, "invalid timestamp: required")
+
+	err = ValidateEvent(&messages.Event{Timestamp: valid.Timestamp}, ValidateOptions{})
+	require.Equal(t, []string{"data_stream"}, fieldsOf(err))
+
+	err = ValidateEvent(&messages.Event{
+		Timestamp:  valid.Timestamp,
+		DataStream: &messages.DataStream{Type: "logs", Namespace: "default"},
+	}, ValidateOptions{})
+	require.Equal(t, []string{"data_stream.dataset"}, fieldsOf(err))
+
+	err = ValidateEvent(&messages.Event{
+		Timestamp:  valid.Timestamp,
+		DataStream: &messages.DataStream{Type: "Logs", Dataset: "generic", Namespace: "default"},
+		Fields:     fields,
+	}, ValidateOptions{})
+	require.Equal(t, []string{"data_stream.type"}, fieldsOf(err))
+
+	// the rules are the ones of the API
+	for _, e := range []*messages.Event{valid, {}, {Timestamp: valid.Timestamp, DataStream: &messages.DataStream{Dataset: "a-b"}}} {
+		var v *messages.ValidationError
+		if errors.As(e.Validate(), &v) {
+			require.Equal(t, []string{v.Field}, fieldsOf(ValidateEvent(e, ValidateOptions{})))
+		} else {
+			require.NoError(t, ValidateEvent(e, ValidateOptions{}))
+		}
+	}
+
+	err = ValidateEvent(&messages.Event{
+		Timestamp:  valid.Timestamp,
+		DataStream: valid.DataStream,
+		Fields:     fields,
+		OpType:     string(messages.OpTypeDelete),
+		DocumentId: "a\xffb",
+	}, ValidateOptions{})
+	require.Equal(t, []string{"op_type", "document_id"}, fieldsOf(err))
+
+	bad := "a\xffb"
+	str := func(s string) *messages.Value {
+		return &messages.Value{Kind: &messages.Value_StringValue{StringValue: s}}
+	}
+	invalid := &messages.Struct{Data: map[string]*messages.Value{
+		"message": str(bad),
+		"tags":    {Kind: &messages.Value_ListValue{ListValue: &messages.ListValue{Values: []*messages.Value{str("ok"), str(bad)}}}},
+	}}
+	invalid.Data["nested"] = &messages.Value{Kind: &messages.Value_StructValue{StructValue: &messages.Struct{
+		Data: map[string]*messages.Value{bad: {Kind: &messages.Value_Int64Value{Int64Value: 1}}},
+	}}}
+	err = ValidateEvent(&messages.Event{
+		Timestamp:     valid.Timestamp,
+		DataStream:    valid.DataStream,
+		Source:        &messages.Source{InputId: bad},
+		Fields:        invalid,
+		MetadataDelta: &messages.MetadataDelta{RemovedKeys: []string{"ok", bad}},
+	}, ValidateOptions{MaxEventBytes: 1})
+	require.Equal(t, []string{
+		"source.input_id",
+		"fields.message",
+		"fields.nested",
+		"fields.tags[1]",
+		"metadata_delta.removed_keys[1]",
+	}, fieldsOf(err), "the size is not checked")
+
+	err = ValidateEvent(valid, ValidateOptions{MaxEventBytes: 10})
+	require.Equal(t, []string{"event"}, fieldsOf(err))
+	require.True(t, strings.HasSuffix(err.Error(), "the maximum is 10"))
+}
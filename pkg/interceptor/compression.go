@@ -0,0 +1,140 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AcceptEncodingHeader is the reply header in which the shipper lists the
+// compressors it can decompress. gRPC servers don't advertise them, so
+// clients can't know which ones are safe to use before this handshake.
+const AcceptEncodingHeader = "shipper-accept-encoding"
+
+// Zstd is the name of the zstd compressor, registered by importing this
+// package.
+const Zstd = "zstd"
+
+const publishEventsMethod = "/elastic.agent.shipper.v1.Producer/PublishEvents"
+
+// DefaultCompressors are the compressors preferred for the PublishEvents
+// calls, most preferred first.
+var DefaultCompressors = []string{Zstd, gzip.Name}
+
+// Compression negotiates the compression of the PublishEvents calls: the
+// calls are sent uncompressed until a reply lists the compressors the
+// shipper accepts, then the first preferred compressor that is registered
+// and accepted is used.
+type Compression struct {
+	preferred []string
+
+	mu         sync.Mutex
+	negotiated string
+}
+
+// NewCompression creates a new negotiation with the compressors in order of
+// preference. The DefaultCompressors are used when none is given.
+func NewCompression(preferred ...string) *Compression {
+	if len(preferred) == 0 {
+		preferred = DefaultCompressors
+	}
+	return &Compression{preferred: preferred}
+}
+
+// ClientCompression returns the dial options negotiating the compression of
+// the PublishEvents calls with the shipper.
+func ClientCompression(preferred ...string) []grpc.DialOption {
+	c := NewCompression(preferred...)
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor()),
+	}
+}
+
+// ServerCompression returns the server options advertising the registered
+// compressors among the given ones, or among the DefaultCompressors when
+// none is given. Importing this package registers gzip and zstd.
+func ServerCompression(accepted ...string) []grpc.ServerOption {
+	if len(accepted) == 0 {
+		accepted = DefaultCompressors
+	}
+	var names []string
+	for _, name := range accepted {
+		if encoding.GetCompressor(name) != nil {
+			names = append(names, name)
+		}
+	}
+	header := metadata.Pairs(AcceptEncodingHeader, strings.Join(names, ","))
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			_ = grpc.SetHeader(ctx, header)
+			return handler(ctx, req)
+		}),
+	}
+}
+
+// Negotiated returns the compressor currently used, empty when the calls are
+// not compressed.
+func (c *Compression) Negotiated() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.negotiated
+}
+
+// UnaryClientInterceptor compresses the PublishEvents calls with the
+// negotiated compressor. When the shipper can't decompress a call anymore,
+// for example after a downgrade, the call is sent again uncompressed.
+func (c *Compression) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if method != publishEventsMethod {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		name := c.Negotiated()
+		var header metadata.MD
+		callOpts := append(opts[:len(opts):len(opts)], grpc.Header(&header))
+		if name != "" {
+			callOpts = append(callOpts, grpc.UseCompressor(name))
+		}
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		if name != "" && status.Code(err) == codes.Unimplemented {
+			header = nil
+			err = invoker(ctx, method, req, reply, cc, append(opts[:len(opts):len(opts)], grpc.Header(&header))...)
+		}
+		if err == nil {
+			c.negotiate(header.Get(AcceptEncodingHeader))
+		}
+		return err
+	}
+}
+
+// negotiate picks the compressor out of the values of the accept header.
+func (c *Compression) negotiate(values []string) {
+	accepted := map[string]bool{}
+	for _, v := range values {
+		for _, name := range strings.Split(v, ",") {
+			accepted[strings.TrimSpace(name)] = true
+		}
+	}
+
+	negotiated := ""
+	for _, name := range c.preferred {
+		if accepted[name] && encoding.GetCompressor(name) != nil {
+			negotiated = name
+			break
+		}
+	}
+	c.mu.Lock()
+	c.negotiated = negotiated
+	c.mu.Unlock()
+}
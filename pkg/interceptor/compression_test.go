@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// countingCompressor is gzip registered under another name, counting the
+// compressed messages.
+type countingCompressor struct {
+	encoding.Compressor
+	count int64
+}
+
+func (c *countingCompressor) Name() string { return "counting" }
+
+func (c *countingCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	atomic.AddInt64(&c.count, 1)
+	return c.Compressor.Compress(w)
+}
+
+var counting = &countingCompressor{Compressor: encoding.GetCompressor(gzip.Name)}
+
+func init() {
+	encoding.RegisterCompressor(counting)
+}
+
+func TestCompression(t *testing.T) {
+	c := NewCompression(Zstd, "counting", gzip.Name)
+	client := startServer(t,
+		ServerCompression("counting", gzip.Name),
+		[]grpc.DialOption{grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor())},
+	)
+	ctx := context.Background()
+	before := atomic.LoadInt64(&counting.count)
+
+	// the first call learns the accepted compressors
+	_, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Equal(t, before, atomic.LoadInt64(&counting.count))
+	require.Equal(t, "counting", c.Negotiated())
+
+	// zstd isn't accepted, the next preferred one is used by both sides
+	reply, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+	require.Equal(t, before+2, atomic.LoadInt64(&counting.count))
+
+	// other calls are not compressed
+	_, err = client.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	require.Equal(t, before+2, atomic.LoadInt64(&counting.count))
+}
+
+func TestCompressionWithoutHandshake(t *testing.T) {
+	c := NewCompression()
+	client := startServer(t, nil, []grpc.DialOption{grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor())})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		require.Empty(t, c.Negotiated())
+	}
+}
+
+func TestServerCompressionOnlyRegistered(t *testing.T) {
+	c := NewCompression("br", gzip.Name)
+	client := startServer(t,
+		ServerCompression("br", gzip.Name),
+		[]grpc.DialOption{grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor())},
+	)
+	_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Equal(t, gzip.Name, c.Negotiated())
+}
+
+// encodingRecorder records the compression of the calls received by a server.
+type encodingRecorder struct {
+	mu        sync.Mutex
+	encodings []string
+}
+
+func (r *encodingRecorder) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (r *encodingRecorder) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if h, ok := s.(*stats.InHeader); ok {
+		r.mu.Lock()
+		r.encodings = append(r.encodings, h.Compression)
+		r.mu.Unlock()
+	}
+}
+
+func (r *encodingRecorder) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (r *encodingRecorder) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestZstdCompression(t *testing.T) {
+	var (
+		recorder encodingRecorder
+		received *messages.PublishRequest
+	)
+	serverOpts := append(ServerCompression(),
+		grpc.StatsHandler(&recorder),
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			received, _ = req.(*messages.PublishRequest)
+			return handler(ctx, req)
+		}),
+	)
+	c := NewCompression()
+	client := startServer(t, serverOpts, []grpc.DialOption{grpc.WithChainUnaryInterceptor(c.UnaryClientInterceptor())})
+
+	_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Equal(t, Zstd, c.Negotiated())
+
+	events := testEvents(1000)
+	for i, e := range events {
+		e.Fields = &messages.Struct{Data: map[string]*messages.Value{
+			"message": {Kind: &messages.Value_StringValue{StringValue: strings.Repeat("zstd ", i%50)}},
+		}}
+	}
+	// the pooled encoders and decoders are reused by the next calls
+	req := &messages.PublishRequest{Events: events}
+	for i := 0; i < 3; i++ {
+		_, err := client.PublishEvents(context.Background(), req)
+		require.NoError(t, err)
+		require.True(t, gproto.Equal(req, received))
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.Equal(t, []string{"", Zstd, Zstd, Zstd}, recorder.encodings)
+}
+
+func TestZstdCompressor(t *testing.T) {
+	c := encoding.GetCompressor(Zstd)
+	require.NotNil(t, c)
+	data := []byte(strings.Repeat("elastic agent shipper ", 1000))
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		w, err := c.Compress(&buf)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		require.Less(t, buf.Len(), len(data))
+
+		r, err := c.Decompress(&buf)
+		require.NoError(t, err)
+		got, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, data, got)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// LoggingConfig configures the logging interceptors.
+type LoggingConfig struct {
+	// SlowThreshold is the duration above which a call is logged as slow.
+	// Zero disables logging slow calls.
+	SlowThreshold time.Duration
+	// Interval is the sampling period.
+	Interval time.Duration
+	// First is the number of entries logged in every interval before sampling starts.
+	First int
+	// Thereafter is the sampling rate once First entries were logged in the
+	// interval: only every Thereafter-th entry is logged. Zero drops all of them.
+	Thereafter int
+}
+
+// DefaultLoggingConfig returns the default logging configuration.
+func DefaultLoggingConfig() LoggingConfig {
+	return LoggingConfig{
+		SlowThreshold: time.Second,
+		Interval:      10 * time.Second,
+		First:         10,
+		Thereafter:    100,
+	}
+}
+
+// Logging logs failed and slow calls with sampling, so a misbehaving
+// connection doesn't produce one log entry per batch.
+type Logging struct {
+	log     *logp.Logger
+	config  LoggingConfig
+	sampler *sampler
+}
+
+// NewLogging creates a new logging interceptor.
+func NewLogging(log *logp.Logger, config LoggingConfig) *Logging {
+	return &Logging{
+		log:     log,
+		config:  config,
+		sampler: &sampler{interval: config.Interval, first: config.First, thereafter: config.Thereafter},
+	}
+}
+
+// ClientLogging returns the dial options logging the client calls.
+func ClientLogging(log *logp.Logger, config LoggingConfig) []grpc.DialOption {
+	l := NewLogging(log, config)
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(l.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(l.StreamClientInterceptor()),
+	}
+}
+
+// ServerLogging returns the server options logging the server calls.
+func ServerLogging(log *logp.Logger, config LoggingConfig) []grpc.ServerOption {
+	l := NewLogging(log, config)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(l.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(l.StreamServerInterceptor()),
+	}
+}
+
+// UnaryClientInterceptor logs unary calls made by a client.
+func (l *Logging) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		l.logCall(method, req, reply, err, time.Since(start))
+		return err
+	}
+}
+
+// UnaryServerInterceptor logs unary calls handled by a server.
+func (l *Logging) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		reply, err := handler(ctx, req)
+		l.logCall(info.FullMethod, req, reply, err, time.Since(start))
+		return reply, err
+	}
+}
+
+// StreamClientInterceptor logs streams that failed to open.
+func (l *Logging) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			l.logCall(method, nil, nil, err, 0)
+		}
+		return stream, err
+	}
+}
+
+// StreamServerInterceptor logs streams that ended with an error.
+func (l *Logging) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err != nil {
+			l.logCall(info.FullMethod, nil, nil, err, 0)
+		}
+		return err
+	}
+}
+
+func (l *Logging) logCall(method string, req, reply interface{}, err error, duration time.Duration) {
+	slow := l.config.SlowThreshold > 0 && duration >= l.config.SlowThreshold
+	if err == nil && !slow {
+		return
+	}
+	if !l.sampler.allow(time.Now()) {
+		return
+	}
+
+	fields := []interface{}{"method", method, "duration", duration}
+	if r, ok := req.(*messages.PublishRequest); ok {
+		fields = append(fields, "batch_size", r.Len())
+		if r.GetUuid() != "" {
+			fields = append(fields, "request_uuid", r.GetUuid())
+		}
+		if r.GetBatchId() != "" {
+			fields = append(fields, "batch_id", r.GetBatchId())
+		}
+	}
+	if r, ok := reply.(*messages.PublishReply); ok && err == nil {
+		fields = append(fields, "uuid", r.GetUuid(), "accepted_count", r.GetAcceptedCount(), "accepted_index", r.GetAcceptedIndex())
+	}
+
+	if err != nil {
+		l.log.Errorw("shipper call failed", append(fields, "error", err)...)
+		return
+	}
+	l.log.Warnw("slow shipper call", fields...)
+}
+
+// sampler lets the first entries of every interval through, then only one
+// out of every thereafter entries.
+type sampler struct {
+	interval   time.Duration
+	first      int
+	thereafter int
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+func (s *sampler) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.start) >= s.interval {
+		s.start = now
+		s.count = 0
+	}
+	s.count++
+	if s.count <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (s.count-s.first)%s.thereafter == 0
+}
@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestLogging(t *testing.T) {
+	require.NoError(t, logp.DevelopmentSetup(logp.ToObserverOutput()))
+	config := LoggingConfig{Interval: time.Hour, First: 2, Thereafter: 3}
+	client := startServer(t, nil, ClientLogging(logp.NewLogger("shipper"), config))
+	ctx := context.Background()
+
+	_, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Empty(t, logp.ObserverLogs().TakeAll())
+
+	for i := 0; i < 8; i++ {
+		_, err = client.PublishEvents(ctx, &messages.PublishRequest{Uuid: "uuid"})
+		require.Error(t, err)
+	}
+	// the first two failures, then the 5th and 8th ones
+	logs := logp.ObserverLogs().TakeAll()
+	require.Len(t, logs, 4)
+	fields := logs[0].ContextMap()
+	require.Equal(t, "shipper call failed", logs[0].Message)
+	require.Equal(t, "/elastic.agent.shipper.v1.Producer/PublishEvents", fields["method"])
+	require.Equal(t, int64(0), fields["batch_size"])
+	require.Equal(t, "uuid", fields["request_uuid"])
+	require.Contains(t, fields["error"], "no events")
+}
+
+func TestLoggingSlowCalls(t *testing.T) {
+	require.NoError(t, logp.DevelopmentSetup(logp.ToObserverOutput()))
+	config := LoggingConfig{SlowThreshold: time.Nanosecond, Interval: time.Hour, First: 10}
+	client := startServer(t, ServerLogging(logp.NewLogger("shipper"), config), nil)
+
+	_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+
+	logs := logp.ObserverLogs().TakeAll()
+	require.Len(t, logs, 1)
+	fields := logs[0].ContextMap()
+	require.Equal(t, "slow shipper call", logs[0].Message)
+	require.Equal(t, int64(3), fields["batch_size"])
+	require.Equal(t, uint32(2), fields["accepted_count"])
+	require.Equal(t, "uuid", fields["uuid"])
+}
+
+func TestSampler(t *testing.T) {
+	s := &sampler{interval: time.Second, first: 1, thereafter: 2}
+	now := time.Now()
+	require.True(t, s.allow(now))
+	require.False(t, s.allow(now))
+	require.True(t, s.allow(now))
+	require.False(t, s.allow(now))
+	require.True(t, s.allow(now.Add(time.Second)))
+}
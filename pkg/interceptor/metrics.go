@@ -0,0 +1,213 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package interceptor contains gRPC interceptors for the shipper API that can
+// be installed on both the client and the server side.
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	metrics "github.com/rcrowley/go-metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-libs/monitoring/adapter"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Metrics records publish metrics into a monitoring registry.
+//
+// The following metrics are maintained:
+//   - publish.requests: number of PublishEvents calls
+//   - publish.failures: number of failed PublishEvents calls
+//   - publish.events.submitted: number of events sent in PublishEvents calls
+//   - publish.events.accepted: number of events accepted by the shipper
+//   - publish.batch_size: histogram of the number of events per call
+//   - publish.latency_us: histogram of the PublishEvents latency in microseconds
+//   - persisted_index: the last persisted index reported by the shipper
+//   - errors.<code>: number of failed calls per gRPC code, for all the calls
+type Metrics struct {
+	requests       *monitoring.Uint
+	failures       *monitoring.Uint
+	submitted      *monitoring.Uint
+	accepted       *monitoring.Uint
+	persistedIndex *monitoring.Uint
+	batchSize      metrics.Histogram
+	latency        metrics.Histogram
+
+	errorsMu  sync.Mutex
+	errorsReg *monitoring.Registry
+	errors    map[codes.Code]*monitoring.Uint
+}
+
+// NewMetrics creates the metrics in the given registry. The metrics already
+// registered are reused, so Metrics sharing a registry, e.g. the ones of
+// reconnecting clients, maintain the same metrics.
+func NewMetrics(reg *monitoring.Registry) *Metrics {
+	publishReg := getOrNewRegistry(reg, "publish")
+	eventsReg := getOrNewRegistry(publishReg, "events")
+	histograms := adapter.GetGoMetrics(reg, "publish", adapter.Accept)
+
+	return &Metrics{
+		requests:       getOrNewUint(publishReg, "requests"),
+		failures:       getOrNewUint(publishReg, "failures"),
+		submitted:      getOrNewUint(eventsReg, "submitted"),
+		accepted:       getOrNewUint(eventsReg, "accepted"),
+		persistedIndex: getOrNewUint(reg, "persisted_index"),
+		batchSize:      histograms.GetOrRegister("batch_size", metrics.NewHistogram(metrics.NewUniformSample(1024))).(metrics.Histogram),
+		latency:        histograms.GetOrRegister("latency_us", metrics.NewHistogram(metrics.NewUniformSample(1024))).(metrics.Histogram),
+		errorsReg:      getOrNewRegistry(reg, "errors"),
+		errors:         make(map[codes.Code]*monitoring.Uint),
+	}
+}
+
+func getOrNewRegistry(parent *monitoring.Registry, name string) *monitoring.Registry {
+	if reg := parent.GetRegistry(name); reg != nil {
+		return reg
+	}
+	return parent.NewRegistry(name)
+}
+
+func getOrNewUint(reg *monitoring.Registry, name string) *monitoring.Uint {
+	if v, ok := reg.Get(name).(*monitoring.Uint); ok {
+		return v
+	}
+	return monitoring.NewUint(reg, name)
+}
+
+// ClientMetrics returns the dial options recording the client metrics into reg.
+func ClientMetrics(reg *monitoring.Registry) []grpc.DialOption {
+	m := NewMetrics(reg)
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(m.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(m.StreamClientInterceptor()),
+	}
+}
+
+// ServerMetrics returns the server options recording the server metrics into reg.
+func ServerMetrics(reg *monitoring.Registry) []grpc.ServerOption {
+	m := NewMetrics(reg)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(m.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(m.StreamServerInterceptor()),
+	}
+}
+
+// UnaryClientInterceptor records metrics of unary calls made by a client.
+func (m *Metrics) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		m.observe(req, reply, err, time.Since(start))
+		return err
+	}
+}
+
+// UnaryServerInterceptor records metrics of unary calls handled by a server.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		reply, err := handler(ctx, req)
+		m.observe(req, reply, err, time.Since(start))
+		return reply, err
+	}
+}
+
+// StreamClientInterceptor records metrics of streams opened by a client.
+func (m *Metrics) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			m.observeError(err)
+			return nil, err
+		}
+		return &metricsClientStream{ClientStream: stream, metrics: m}, nil
+	}
+}
+
+// StreamServerInterceptor records metrics of streams handled by a server.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, &metricsServerStream{ServerStream: ss, metrics: m})
+		m.observeError(err)
+		return err
+	}
+}
+
+func (m *Metrics) observe(req, reply interface{}, err error, latency time.Duration) {
+	m.observeError(err)
+
+	publish, ok := req.(*messages.PublishRequest)
+	if !ok {
+		return
+	}
+	m.requests.Inc()
+	m.submitted.Add(uint64(publish.Len()))
+	m.batchSize.Update(int64(publish.Len()))
+	m.latency.Update(latency.Microseconds())
+	if err != nil {
+		m.failures.Inc()
+		return
+	}
+	if r, ok := reply.(*messages.PublishReply); ok {
+		m.accepted.Add(uint64(r.GetAcceptedCount()))
+	}
+}
+
+func (m *Metrics) observeMessage(msg interface{}) {
+	if r, ok := msg.(*messages.PersistedIndexReply); ok {
+		m.persistedIndex.Set(r.GetPersistedIndex())
+	}
+}
+
+func (m *Metrics) observeError(err error) {
+	if err == nil || errors.Is(err, io.EOF) {
+		return
+	}
+	code := status.Code(err)
+
+	m.errorsMu.Lock()
+	counter, ok := m.errors[code]
+	if !ok {
+		counter = getOrNewUint(m.errorsReg, code.String())
+		m.errors[code] = counter
+	}
+	m.errorsMu.Unlock()
+	counter.Inc()
+}
+
+type metricsClientStream struct {
+	grpc.ClientStream
+	metrics *Metrics
+}
+
+func (s *metricsClientStream) RecvMsg(msg interface{}) error {
+	err := s.ClientStream.RecvMsg(msg)
+	if err != nil {
+		s.metrics.observeError(err)
+		return err
+	}
+	s.metrics.observeMessage(msg)
+	return nil
+}
+
+type metricsServerStream struct {
+	grpc.ServerStream
+	metrics *Metrics
+}
+
+func (s *metricsServerStream) SendMsg(msg interface{}) error {
+	err := s.ServerStream.SendMsg(msg)
+	if err == nil {
+		s.metrics.observeMessage(msg)
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// testServer accepts up to two events per request and fails empty requests.
+type testServer struct {
+	proto.UnimplementedProducerServer
+}
+
+func (testServer) PublishEvents(_ context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	n := len(req.GetEvents())
+	if n == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no events")
+	}
+	if n > 2 {
+		n = 2
+	}
+	return &messages.PublishReply{Uuid: "uuid", AcceptedCount: uint32(n), AcceptedIndex: uint64(n)}, nil
+}
+
+func (testServer) PersistedIndex(_ *messages.PersistedIndexRequest, srv proto.Producer_PersistedIndexServer) error {
+	return srv.Send(&messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 42})
+}
+
+// startServer starts a test server over bufconn and returns a client connected to it.
+func startServer(t *testing.T, serverOpts []grpc.ServerOption, dialOpts []grpc.DialOption) proto.ProducerClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(serverOpts...)
+	proto.RegisterProducerServer(srv, testServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProducerClient(conn)
+}
+
+func testEvents(n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = &messages.Event{}
+	}
+	return events
+}
+
+func TestMetrics(t *testing.T) {
+	clientReg := monitoring.NewRegistry()
+	serverReg := monitoring.NewRegistry()
+	client := startServer(t, ServerMetrics(serverReg), ClientMetrics(clientReg))
+	ctx := context.Background()
+
+	_, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+	_, err = client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	_, err = client.PublishEvents(ctx, &messages.PublishRequest{})
+	require.Error(t, err)
+
+	stream, err := client.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+
+	for name, reg := range map[string]*monitoring.Registry{"client": clientReg, "server": serverReg} {
+		snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+		require.Equal(t, int64(3), snapshot.Ints["publish.requests"], name)
+		require.Equal(t, int64(1), snapshot.Ints["publish.failures"], name)
+		require.Equal(t, int64(4), snapshot.Ints["publish.events.submitted"], name)
+		require.Equal(t, int64(3), snapshot.Ints["publish.events.accepted"], name)
+		require.Equal(t, int64(3), snapshot.Ints["publish.batch_size.count"], name)
+		require.Equal(t, int64(3), snapshot.Ints["publish.batch_size.max"], name)
+		require.Equal(t, int64(3), snapshot.Ints["publish.latency_us.count"], name)
+		require.Equal(t, int64(1), snapshot.Ints["errors.InvalidArgument"], name)
+		require.Equal(t, int64(42), snapshot.Ints["persisted_index"], name)
+	}
+}
+
+func TestMetricsSharedRegistry(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		client := startServer(t, nil, ClientMetrics(reg))
+		_, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+		_, err = client.PublishEvents(ctx, &messages.PublishRequest{})
+		require.Error(t, err)
+	}
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	require.Equal(t, int64(4), snapshot.Ints["publish.requests"])
+	require.Equal(t, int64(2), snapshot.Ints["publish.failures"])
+	require.Equal(t, int64(2), snapshot.Ints["publish.events.accepted"])
+	require.Equal(t, int64(4), snapshot.Ints["publish.latency_us.count"])
+	require.Equal(t, int64(2), snapshot.Ints["errors.InvalidArgument"])
+}
@@ -0,0 +1,327 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package tracing contains gRPC interceptors creating OpenTelemetry spans for
+// the shipper API calls. It lives in its own package so that consumers not
+// tracing their pipeline don't depend on OpenTelemetry.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+const instrumentationName = "github.com/elastic/elastic-agent-shipper-client/pkg/interceptor/tracing"
+
+// Attributes set on the spans in addition to the RPC semantic conventions.
+const (
+	EventsCountKey   = attribute.Key("shipper.events.count")
+	EventsBytesKey   = attribute.Key("shipper.events.bytes")
+	AcceptedCountKey = attribute.Key("shipper.accepted_count")
+	AcceptedIndexKey = attribute.Key("shipper.accepted_index")
+	PersistedKey     = attribute.Key("shipper.persisted_index")
+	UUIDKey          = attribute.Key("shipper.uuid")
+)
+
+// Tracing creates spans for the shipper API calls.
+//
+// Spans of PublishEvents calls are linked to the trace context of the
+// published events. The trace context of an event is extracted from its
+// metadata with the configured propagator, e.g. the "traceparent" key for
+// the W3C trace context.
+type Tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	maxLinks   int
+}
+
+// Option configures Tracing.
+type Option func(*tracingConfig)
+
+type tracingConfig struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+	maxLinks   int
+}
+
+// WithTracerProvider sets the tracer provider, the global one is used by default.
+func WithTracerProvider(p trace.TracerProvider) Option {
+	return func(c *tracingConfig) {
+		c.provider = p
+	}
+}
+
+// WithPropagator sets the propagator, the global one is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *tracingConfig) {
+		c.propagator = p
+	}
+}
+
+// WithMaxLinks sets the maximum number of event links per span, 128 by default.
+// Zero disables linking events.
+func WithMaxLinks(n int) Option {
+	return func(c *tracingConfig) {
+		c.maxLinks = n
+	}
+}
+
+// New creates a new Tracing.
+func New(opts ...Option) *Tracing {
+	c := tracingConfig{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+		maxLinks:   128,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &Tracing{
+		tracer:     c.provider.Tracer(instrumentationName),
+		propagator: c.propagator,
+		maxLinks:   c.maxLinks,
+	}
+}
+
+// ClientTracing returns the dial options tracing the client calls.
+func ClientTracing(opts ...Option) []grpc.DialOption {
+	t := New(opts...)
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(t.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(t.StreamClientInterceptor()),
+	}
+}
+
+// ServerTracing returns the server options tracing the server calls.
+func ServerTracing(opts ...Option) []grpc.ServerOption {
+	t := New(opts...)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(t.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(t.StreamServerInterceptor()),
+	}
+}
+
+// UnaryClientInterceptor traces unary calls made by a client and propagates
+// the trace context to the server.
+func (t *Tracing) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := t.start(ctx, method, trace.SpanKindClient, req)
+		defer span.End()
+
+		ctx = t.inject(ctx)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		t.end(span, reply, err)
+		return err
+	}
+}
+
+// UnaryServerInterceptor traces unary calls handled by a server.
+func (t *Tracing) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := t.start(t.extract(ctx), info.FullMethod, trace.SpanKindServer, req)
+		defer span.End()
+
+		reply, err := handler(ctx, req)
+		t.end(span, reply, err)
+		return reply, err
+	}
+}
+
+// StreamClientInterceptor traces streams opened by a client, the span ends with the stream.
+func (t *Tracing) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := t.start(ctx, method, trace.SpanKindClient, nil)
+		stream, err := streamer(t.inject(ctx), desc, cc, method, opts...)
+		if err != nil {
+			t.end(span, nil, err)
+			span.End()
+			return nil, err
+		}
+		return &tracingClientStream{ClientStream: stream, tracing: t, span: span}, nil
+	}
+}
+
+// StreamServerInterceptor traces streams handled by a server.
+func (t *Tracing) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := t.start(t.extract(ss.Context()), info.FullMethod, trace.SpanKindServer, nil)
+		defer span.End()
+
+		err := handler(srv, &tracingServerStream{ServerStream: ss, ctx: ctx, span: span})
+		t.end(span, nil, err)
+		return err
+	}
+}
+
+func (t *Tracing) start(ctx context.Context, method string, kind trace.SpanKind, req interface{}) (context.Context, trace.Span) {
+	name := strings.TrimPrefix(method, "/")
+	attrs := []attribute.KeyValue{semconv.RPCSystemKey.String("grpc")}
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		attrs = append(attrs, semconv.RPCServiceKey.String(name[:i]), semconv.RPCMethodKey.String(name[i+1:]))
+	}
+
+	opts := []trace.SpanStartOption{trace.WithSpanKind(kind)}
+	if r, ok := req.(*messages.PublishRequest); ok {
+		attrs = append(attrs,
+			EventsCountKey.Int(r.Len()),
+			EventsBytesKey.Int(proto.Size(r)),
+		)
+		opts = append(opts, trace.WithLinks(t.eventLinks(r.GetEvents())...))
+	}
+	opts = append(opts, trace.WithAttributes(attrs...))
+	return t.tracer.Start(ctx, name, opts...)
+}
+
+func (t *Tracing) end(span trace.Span, reply interface{}, err error) {
+	if r, ok := reply.(*messages.PublishReply); ok && err == nil {
+		span.SetAttributes(
+			UUIDKey.String(r.GetUuid()),
+			AcceptedCountKey.Int64(int64(r.GetAcceptedCount())),
+			AcceptedIndexKey.Int64(int64(r.GetAcceptedIndex())),
+		)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		s, _ := status.FromError(err)
+		span.SetStatus(otelcodes.Error, s.Message())
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int64(int64(s.Code())))
+		return
+	}
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int64(0))
+}
+
+// eventLinks returns links to the valid trace contexts found in the events.
+func (t *Tracing) eventLinks(events []*messages.Event) []trace.Link {
+	var links []trace.Link
+	for _, e := range events {
+		if len(links) >= t.maxLinks {
+			break
+		}
+		if e.GetMetadata() == nil {
+			continue
+		}
+		ctx := t.propagator.Extract(context.Background(), structCarrier{e.GetMetadata()})
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+	return links
+}
+
+func (t *Tracing) inject(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	t.propagator.Inject(ctx, mdCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (t *Tracing) extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return t.propagator.Extract(ctx, mdCarrier(md))
+}
+
+type tracingClientStream struct {
+	grpc.ClientStream
+	tracing *Tracing
+	span    trace.Span
+	once    sync.Once
+}
+
+func (s *tracingClientStream) RecvMsg(msg interface{}) error {
+	err := s.ClientStream.RecvMsg(msg)
+	if err != nil {
+		s.once.Do(func() {
+			s.tracing.end(s.span, nil, err)
+			s.span.End()
+		})
+		return err
+	}
+	if r, ok := msg.(*messages.PersistedIndexReply); ok {
+		s.span.SetAttributes(UUIDKey.String(r.GetUuid()), PersistedKey.Int64(int64(r.GetPersistedIndex())))
+	}
+	return nil
+}
+
+type tracingServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	span trace.Span
+}
+
+func (s *tracingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tracingServerStream) SendMsg(msg interface{}) error {
+	err := s.ServerStream.SendMsg(msg)
+	if r, ok := msg.(*messages.PersistedIndexReply); ok && err == nil {
+		s.span.SetAttributes(UUIDKey.String(r.GetUuid()), PersistedKey.Int64(int64(r.GetPersistedIndex())))
+	}
+	return err
+}
+
+// mdCarrier adapts gRPC metadata to a propagation.TextMapCarrier.
+type mdCarrier metadata.MD
+
+func (c mdCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c mdCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c mdCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// structCarrier exposes the string values of an event metadata as a
+// read-only propagation.TextMapCarrier.
+type structCarrier struct {
+	s *messages.Struct
+}
+
+func (c structCarrier) Get(key string) string {
+	return c.s.GetData()[key].GetStringValue()
+}
+
+func (c structCarrier) Set(string, string) {}
+
+func (c structCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.s.GetData()))
+	for k := range c.s.GetData() {
+		keys = append(keys, k)
+	}
+	return keys
+}
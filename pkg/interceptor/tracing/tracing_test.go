@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package tracing
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+type testServer struct {
+	proto.UnimplementedProducerServer
+}
+
+func (testServer) PublishEvents(_ context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	n := uint32(req.Len())
+	return &messages.PublishReply{Uuid: "uuid", AcceptedCount: n, AcceptedIndex: uint64(n)}, nil
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts := []Option{WithTracerProvider(provider), WithPropagator(propagation.TraceContext{})}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(ServerTracing(opts...)...)
+	proto.RegisterProducerServer(srv, testServer{})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	dialOpts := append(ClientTracing(opts...),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	traced := &messages.Event{Metadata: &messages.Struct{Data: map[string]*messages.Value{
+		"traceparent": {Kind: &messages.Value_StringValue{StringValue: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}},
+	}}}
+	req := &messages.PublishRequest{Events: []*messages.Event{traced, {}}}
+	_, err = proto.NewProducerClient(conn).PublishEvents(context.Background(), req)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	server, client := spans[0], spans[1]
+	require.Equal(t, trace.SpanKindServer, server.SpanKind())
+	require.Equal(t, trace.SpanKindClient, client.SpanKind())
+	require.Equal(t, "elastic.agent.shipper.v1.Producer/PublishEvents", client.Name())
+	require.Equal(t, client.SpanContext().TraceID(), server.Parent().TraceID())
+	require.Equal(t, client.SpanContext().SpanID(), server.Parent().SpanID())
+
+	attrs := attributes(client)
+	require.Equal(t, int64(2), attrs[EventsCountKey].AsInt64())
+	require.Equal(t, int64(2), attrs[AcceptedCountKey].AsInt64())
+	require.Equal(t, "uuid", attrs[UUIDKey].AsString())
+	require.Equal(t, "PublishEvents", attrs["rpc.method"].AsString())
+
+	require.Len(t, client.Links(), 1)
+	require.Equal(t, "0af7651916cd43dd8448eb211c80319c", client.Links()[0].SpanContext.TraceID().String())
+
+	// metric samples and signals are counted like events
+	_, err = proto.NewProducerClient(conn).PublishEvents(context.Background(), &messages.PublishRequest{
+		Metrics: []*messages.MetricEvent{{Name: "cpu"}, {Name: "memory"}, {Name: "disk"}},
+	})
+	require.NoError(t, err)
+	spans = recorder.Ended()
+	require.Len(t, spans, 4)
+	require.Equal(t, int64(3), attributes(spans[3])[EventsCountKey].AsInt64())
+}
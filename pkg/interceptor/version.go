@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/apiversion"
+)
+
+// VersionCheck checks the API version of the shipper on the client side.
+// The calls carry the version of the client, and the replies the one of the
+// shipper. Once a reply shows the shipper is incompatible, the next calls
+// fail with a FailedPrecondition error without being sent. Shippers that
+// don't send their version are assumed to be compatible.
+type VersionCheck struct {
+	mu  sync.Mutex
+	err error
+}
+
+// ClientVersionCheck returns the dial options sending the API version of the
+// client and checking the one of the shipper.
+func ClientVersionCheck() []grpc.DialOption {
+	v := &VersionCheck{}
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(v.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(v.StreamClientInterceptor()),
+	}
+}
+
+// ServerVersionCheck returns the server options rejecting the calls of
+// incompatible clients with a FailedPrecondition error, and sending the API
+// version of the shipper in the reply headers. Clients that don't send their
+// version are accepted.
+func ServerVersionCheck() []grpc.ServerOption {
+	header := metadata.Pairs(apiversion.Header, apiversion.Current.String())
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			_ = grpc.SetHeader(ctx, header)
+			if err := checkClient(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			_ = ss.SetHeader(header)
+			if err := checkClient(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+}
+
+// Err returns the error of the incompatible shipper, nil until a reply shows
+// it's incompatible.
+func (v *VersionCheck) Err() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.err
+}
+
+// UnaryClientInterceptor sends the API version of the client and checks the
+// one of the shipper.
+func (v *VersionCheck) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := v.Err(); err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, apiversion.Header, apiversion.Current.String())
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts[:len(opts):len(opts)], grpc.Header(&header))...)
+		v.check(header.Get(apiversion.Header))
+		return err
+	}
+}
+
+// StreamClientInterceptor sends the API version of the client.
+func (v *VersionCheck) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if err := v.Err(); err != nil {
+			return nil, err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, apiversion.Header, apiversion.Current.String())
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// check records whether the shipper with the version in the header values
+// is compatible.
+func (v *VersionCheck) check(values []string) {
+	if len(values) == 0 {
+		return
+	}
+	var err error
+	server, parseErr := apiversion.Parse(values[0])
+	if parseErr != nil {
+		err = status.Errorf(codes.FailedPrecondition, "the shipper sent an invalid API version: %v", parseErr)
+	} else if checkErr := apiversion.Check(apiversion.Current, server); checkErr != nil {
+		err = status.Error(codes.FailedPrecondition, checkErr.Error())
+	}
+	v.mu.Lock()
+	v.err = err
+	v.mu.Unlock()
+}
+
+// checkClient returns an error if the client of the call is incompatible.
+func checkClient(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(apiversion.Header)
+	if len(values) == 0 {
+		return nil
+	}
+	client, err := apiversion.Parse(values[0])
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "the client sent an invalid API version: %v", err)
+	}
+	if err := apiversion.Check(client, apiversion.Current); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/apiversion"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestVersionCheck(t *testing.T) {
+	client := startServer(t, ServerVersionCheck(), ClientVersionCheck())
+	ctx := context.Background()
+
+	_, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	stream, err := client.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+}
+
+func TestVersionCheckWithoutHandshake(t *testing.T) {
+	// shippers and clients without the check are compatible
+	client := startServer(t, nil, ClientVersionCheck())
+	for i := 0; i < 2; i++ {
+		_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+		require.NoError(t, err)
+	}
+
+	client = startServer(t, ServerVersionCheck(), nil)
+	_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+}
+
+func TestVersionCheckIncompatibleShipper(t *testing.T) {
+	var calls int
+	future := grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		calls++
+		_ = grpc.SetHeader(ctx, metadata.Pairs(apiversion.Header, "2.0"))
+		return handler(ctx, req)
+	})
+	v := &VersionCheck{}
+	client := startServer(t, []grpc.ServerOption{future}, []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(v.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(v.StreamClientInterceptor()),
+	})
+	ctx := context.Background()
+
+	// the first call was handled, its result is returned
+	reply, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), reply.GetAcceptedCount())
+	require.Equal(t, codes.FailedPrecondition, status.Code(v.Err()))
+	require.Contains(t, v.Err().Error(), "major versions must match")
+
+	// the next calls fail without being sent
+	_, err = client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, 1, calls)
+}
+
+func TestVersionCheckIncompatibleClient(t *testing.T) {
+	client := startServer(t, ServerVersionCheck(), nil)
+
+	for _, version := range []string{"2.0", "invalid"} {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), apiversion.Header, version)
+		_, err := client.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err), version)
+
+		stream, err := client.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.FailedPrecondition, status.Code(err), version)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package jsonschema describes the JSON form of the messages, as written by
+// their MarshalFastJSON methods, with a JSON Schema.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document.
+type Schema map[string]interface{}
+
+var (
+	structName    = (&messages.Struct{}).ProtoReflect().Descriptor().FullName()
+	valueName     = (&messages.Value{}).ProtoReflect().Descriptor().FullName()
+	listName      = (&messages.ListValue{}).ProtoReflect().Descriptor().FullName()
+	timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+	anyName       = (&anypb.Any{}).ProtoReflect().Descriptor().FullName()
+)
+
+// Event returns the schema of the JSON form of messages.Event.
+func Event() Schema {
+	return ForMessage((&messages.Event{}).ProtoReflect().Descriptor())
+}
+
+// ForMessage returns the schema of the JSON form of a message. Every message
+// it refers to is described once in $defs, under its full name.
+//
+// The fields keep their protobuf names and are all optional. Struct, Value
+// and ListValue are free-form JSON objects, values and arrays, timestamps are
+// RFC 3339 strings, Any values are objects with their @type.
+func ForMessage(md protoreflect.MessageDescriptor) Schema {
+	g := generator{defs: Schema{}}
+	s := g.message(md)
+	s["$schema"] = Draft
+	s["title"] = string(md.Name())
+	s["$defs"] = g.defs
+	return s
+}
+
+// Marshal returns the indented JSON of the schema.
+func (s Schema) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the schema: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+type generator struct {
+	defs Schema
+}
+
+// message returns the schema of a message field, a reference to the
+// definition of the message that is added to $defs first if needed.
+func (g *generator) message(md protoreflect.MessageDescriptor) Schema {
+	switch md.FullName() {
+	case timestampName:
+		return Schema{"type": "string", "format": "date-time"}
+	case anyName:
+		return Schema{
+			"type":                 "object",
+			"properties":           Schema{"@type": Schema{"type": "string"}},
+			"required":             []string{"@type"},
+			"additionalProperties": Schema{},
+		}
+	case structName, valueName, listName:
+		g.dynamic()
+		return ref(md.FullName())
+	}
+
+	name := string(md.FullName())
+	if _, ok := g.defs[name]; !ok {
+		// added before the fields, for recursive messages
+		def := Schema{"type": "object", "additionalProperties": false}
+		g.defs[name] = def
+		properties := Schema{}
+		fields := md.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			properties[string(fields.Get(i).Name())] = g.field(fields.Get(i))
+		}
+		def["properties"] = properties
+	}
+	return ref(md.FullName())
+}
+
+// dynamic adds the definitions of Struct, Value and ListValue, which refer
+// to each other.
+func (g *generator) dynamic() {
+	if _, ok := g.defs[string(structName)]; ok {
+		return
+	}
+	g.defs[string(structName)] = Schema{
+		"type":                 "object",
+		"additionalProperties": ref(valueName),
+	}
+	g.defs[string(listName)] = Schema{
+		"type":  "array",
+		"items": ref(valueName),
+	}
+	g.defs[string(valueName)] = Schema{
+		"anyOf": []Schema{
+			{"type": "null"},
+			{"type": "boolean"},
+			{"type": "number"},
+			// strings and timestamps
+			{"type": "string"},
+			ref(structName),
+			ref(listName),
+		},
+	}
+}
+
+func (g *generator) field(fd protoreflect.FieldDescriptor) Schema {
+	switch {
+	case fd.IsMap():
+		return Schema{
+			"type":                 "object",
+			"additionalProperties": g.singular(fd.MapValue()),
+		}
+	case fd.IsList():
+		return Schema{
+			"type":  "array",
+			"items": g.singular(fd),
+		}
+	default:
+		return g.singular(fd)
+	}
+}
+
+func (g *generator) singular(fd protoreflect.FieldDescriptor) Schema {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.message(fd.Message())
+	case protoreflect.BoolKind:
+		return Schema{"type": "boolean"}
+	case protoreflect.StringKind:
+		return Schema{"type": "string"}
+	case protoreflect.BytesKind:
+		return Schema{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return Schema{"type": "number"}
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		names := make([]string, values.Len())
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return Schema{"type": "string", "enum": names}
+	default:
+		// all the integer kinds
+		return Schema{"type": "integer"}
+	}
+}
+
+func ref(name protoreflect.FullName) Schema {
+	return Schema{"$ref": "#/$defs/" + string(name)}
+}
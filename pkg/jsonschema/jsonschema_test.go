@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/fastjson"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestEventSchema(t *testing.T) {
+	data, err := Event().Marshal()
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+	require.Equal(t, Draft, schema["$schema"])
+	defs := schema["$defs"].(map[string]interface{})
+
+	events := append(testutil.NewEvents(0, 20), &messages.Event{})
+	for i, e := range events {
+		var w fastjson.Writer
+		require.NoError(t, e.MarshalFastJSON(&w))
+		var doc interface{}
+		require.NoError(t, json.Unmarshal(w.Bytes(), &doc), "event %d: %s", i, w.Bytes())
+		require.NoError(t, validate(defs, schema, doc, ""), "event %d: %s", i, w.Bytes())
+	}
+
+	invalid := []string{
+		`{"timestamp":"yesterday"}`,
+		`{"unknown":1}`,
+		`{"source":{"input_id":1}}`,
+		`{"fields":{"list":[{"a":[1,{"b":{}}]}, 1.5, "x", true, {"c":[null]}, []]}, "metadata":[]}`,
+	}
+	for _, doc := range invalid {
+		var v interface{}
+		require.NoError(t, json.Unmarshal([]byte(doc), &v))
+		require.Error(t, validate(defs, schema, v, ""), doc)
+	}
+}
+
+// validate checks a JSON document against the subset of JSON Schema used by
+// the generated schemas.
+func validate(defs map[string]interface{}, schema map[string]interface{}, doc interface{}, path string) error {
+	if r, ok := schema["$ref"].(string); ok {
+		def, ok := defs[strings.TrimPrefix(r, "#/$defs/")].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unknown reference %s", path, r)
+		}
+		if err := validate(defs, def, doc, path); err != nil {
+			return err
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, s := range anyOf {
+			if validate(defs, s.(map[string]interface{}), doc, path) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: no schema matches %v", path, doc)
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	switch typ {
+	case "null":
+		if doc != nil {
+			return fmt.Errorf("%s: not null", path)
+		}
+	case "boolean":
+		if _, ok := doc.(bool); !ok {
+			return fmt.Errorf("%s: not a boolean", path)
+		}
+	case "number", "integer":
+		if _, ok := doc.(float64); !ok {
+			return fmt.Errorf("%s: not a number", path)
+		}
+	case "string":
+		s, ok := doc.(string)
+		if !ok {
+			return fmt.Errorf("%s: not a string", path)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	case "array":
+		list, ok := doc.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: not an array", path)
+		}
+		for i, v := range list {
+			if err := validate(defs, schema["items"].(map[string]interface{}), v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: not an object", path)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for k, v := range obj {
+			s, ok := properties[k].(map[string]interface{})
+			if !ok {
+				additional, ok := schema["additionalProperties"].(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s: unexpected property %s", path, k)
+				}
+				s = additional
+			}
+			if err := validate(defs, s, v, path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
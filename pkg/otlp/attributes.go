@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package otlp converts OpenTelemetry data to shipper events and back, for
+// the OTLP receivers feeding the shipper.
+//
+// The package doesn't depend on the collector pdata module: the attribute
+// maps are exchanged in the raw form of pcommon.Map.AsRaw and
+// pcommon.Map.FromRaw, where the values are nil, bool, int64, float64,
+// string, []byte, map[string]interface{} and []interface{}.
+package otlp
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// StructFromRaw converts the raw form of a pcommon.Map, as returned by
+// AsRaw, to a Struct. The integers are int64 values, the doubles float64
+// values and the byte slices bytes values, so that StructToRaw gives the
+// same map back.
+func StructFromRaw(m map[string]interface{}) (*messages.Struct, error) {
+	s := &messages.Struct{Data: make(map[string]*messages.Value, len(m))}
+	for k, v := range m {
+		value, err := ValueFromRaw(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s attribute: %w", k, err)
+		}
+		s.Data[k] = value
+	}
+	return s, nil
+}
+
+// ValueFromRaw converts the raw form of a pcommon.Value, as returned by
+// AsRaw, to a Value. The other Go types are converted by helpers.NewValue.
+func ValueFromRaw(v interface{}) (*messages.Value, error) {
+	switch v := v.(type) {
+	case int64:
+		return helpers.NewInt64Value(v), nil
+	case float64:
+		return helpers.NewFloat64Value(v), nil
+	case []byte:
+		return helpers.NewBytesValue(v), nil
+	case map[string]interface{}:
+		s, err := StructFromRaw(v)
+		if err != nil {
+			return nil, err
+		}
+		return helpers.NewStructValue(s), nil
+	case []interface{}:
+		l := &messages.ListValue{Values: make([]*messages.Value, len(v))}
+		for i, item := range v {
+			value, err := ValueFromRaw(item)
+			if err != nil {
+				return nil, err
+			}
+			l.Values[i] = value
+		}
+		return helpers.NewListValue(l), nil
+	default:
+		return helpers.NewValue(v)
+	}
+}
+
+// StructToRaw converts a Struct to the raw form of a pcommon.Map, as
+// accepted by FromRaw. See ValueToRaw for the conversion of the values.
+func StructToRaw(s *messages.Struct) map[string]interface{} {
+	m := make(map[string]interface{}, len(s.GetData()))
+	for k, v := range s.GetData() {
+		m[k] = ValueToRaw(v)
+	}
+	return m
+}
+
+// ValueToRaw converts a Value to the raw form of a pcommon.Value, as
+// accepted by FromRaw. OpenTelemetry only has 64-bit integers and doubles:
+// the other integers are int64 values, except the uint64 values that
+// overflow an int64 that are float64 values like the float32 values. The
+// timestamps are RFC 3339 strings. The string references, that are only
+// valid in their request, are nil.
+func ValueToRaw(v *messages.Value) interface{} {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_BoolValue:
+		return kind.BoolValue
+	case *messages.Value_Int32Value:
+		return int64(kind.Int32Value)
+	case *messages.Value_Int64Value:
+		return kind.Int64Value
+	case *messages.Value_Uint32Value:
+		return int64(kind.Uint32Value)
+	case *messages.Value_Uint64Value:
+		if kind.Uint64Value > math.MaxInt64 {
+			return float64(kind.Uint64Value)
+		}
+		return int64(kind.Uint64Value)
+	case *messages.Value_Float32Value:
+		return float64(kind.Float32Value)
+	case *messages.Value_Float64Value:
+		return kind.Float64Value
+	case *messages.Value_StringValue:
+		return kind.StringValue
+	case *messages.Value_BytesValue:
+		return kind.BytesValue
+	case *messages.Value_TimestampValue:
+		return kind.TimestampValue.AsTime().Format(time.RFC3339Nano)
+	case *messages.Value_StructValue:
+		return StructToRaw(kind.StructValue)
+	case *messages.Value_ListValue:
+		values := kind.ListValue.GetValues()
+		l := make([]interface{}, len(values))
+		for i, item := range values {
+			l[i] = ValueToRaw(item)
+		}
+		return l
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestRawRoundTrip(t *testing.T) {
+	// as returned by pcommon.Map.AsRaw
+	raw := map[string]interface{}{
+		"count":   int64(3),
+		"ratio":   1.0,
+		"ok":      true,
+		"name":    "svc",
+		"payload": []byte{0, 1, 0xff},
+		"empty":   nil,
+		"nested": map[string]interface{}{
+			"list": []interface{}{int64(1), 2.5, "x", []byte{}},
+		},
+	}
+	s, err := StructFromRaw(raw)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), s.GetData()["count"].GetInt64Value())
+	require.IsType(t, &messages.Value_Float64Value{}, s.GetData()["ratio"].GetKind(), "a whole double stays a double")
+	require.Equal(t, []byte{0, 1, 0xff}, s.GetData()["payload"].GetBytesValue())
+	require.Equal(t, raw, StructToRaw(s))
+
+	back, err := StructFromRaw(StructToRaw(s))
+	require.NoError(t, err)
+	require.True(t, proto.Equal(s, back))
+
+	_, err = StructFromRaw(map[string]interface{}{"invalid": "\xff"})
+	require.Error(t, err)
+}
+
+func TestValueToRaw(t *testing.T) {
+	ts := time.Date(2022, time.January, 1, 0, 0, 0, 5, time.UTC)
+	for _, c := range []struct {
+		value *messages.Value
+		want  interface{}
+	}{
+		{helpers.NewInt32Value(-1), int64(-1)},
+		{helpers.NewUint32Value(math.MaxUint32), int64(math.MaxUint32)},
+		{helpers.NewUint64Value(math.MaxInt64), int64(math.MaxInt64)},
+		{helpers.NewUint64Value(math.MaxUint64), float64(math.MaxUint64)},
+		{helpers.NewFloat32Value(0.5), 0.5},
+		{helpers.NewTimestampValue(ts), "2022-01-01T00:00:00.000000005Z"},
+		{helpers.NewNullValue(), nil},
+		{&messages.Value{Kind: &messages.Value_StringRef{StringRef: 1}}, nil},
+	} {
+		require.Equal(t, c.want, ValueToRaw(c.value), c.value)
+	}
+}
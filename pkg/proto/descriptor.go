@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package proto
+
+import (
+	// embed the descriptor set
+	_ "embed"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// descriptorSet is the serialized FileDescriptorSet of the API, with its
+// imports, generated by `mage descriptors`.
+//
+//go:embed shipper.protoset
+var descriptorSet []byte
+
+// DescriptorSet returns the serialized FileDescriptorSet of the API,
+// including the imported files. It can be given to tools like grpcurl with
+// -protoset, or loaded by dynamic clients.
+func DescriptorSet() []byte {
+	return append([]byte(nil), descriptorSet...)
+}
+
+// Files returns the registry of the files in the descriptor set.
+func Files() (*protoregistry.Files, error) {
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(descriptorSet, &set); err != nil {
+		return nil, fmt.Errorf("failed to decode the descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package proto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestDescriptorSet(t *testing.T) {
+	files, err := Files()
+	require.NoError(t, err)
+
+	_, err = files.FindDescriptorByName("elastic.agent.shipper.v1.Producer")
+	require.NoError(t, err)
+
+	// the descriptor set must match the generated code
+	var n int
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		n++
+		compiled, err := protoregistry.GlobalFiles.FindFileByPath(fd.Path())
+		require.NoError(t, err, fd.Path())
+		want := protodesc.ToFileDescriptorProto(compiled)
+		want.SourceCodeInfo = nil
+		got := protodesc.ToFileDescriptorProto(fd)
+		require.True(t, proto.Equal(want, got), "%s is out of date, run `mage descriptors`", fd.Path())
+		return true
+	})
+	require.Greater(t, n, 1)
+}
@@ -0,0 +1,37 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// AddExtension packs the message and appends it to the extensions of the event.
+func (x *Event) AddExtension(m proto.Message) error {
+	a, err := anypb.New(m)
+	if err != nil {
+		return fmt.Errorf("failed to pack the extension: %w", err)
+	}
+	x.Extensions = append(x.Extensions, a)
+	return nil
+}
+
+// Extension unpacks into m the first extension of the event with the type of
+// m. It returns false if the event has no such extension.
+func (x *Event) Extension(m proto.Message) (bool, error) {
+	for _, a := range x.GetExtensions() {
+		if !a.MessageIs(m) {
+			continue
+		}
+		if err := a.UnmarshalTo(m); err != nil {
+			return false, fmt.Errorf("failed to unpack the extension %s: %w", a.GetTypeUrl(), err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
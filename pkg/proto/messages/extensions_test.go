@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/fastjson"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestExtensions(t *testing.T) {
+	var e Event
+	require.NoError(t, e.AddExtension(wrapperspb.String("first")))
+	require.NoError(t, e.AddExtension(wrapperspb.Int64(10)))
+	require.NoError(t, e.AddExtension(wrapperspb.String("second")))
+	require.Len(t, e.GetExtensions(), 3)
+
+	var s wrapperspb.StringValue
+	found, err := e.Extension(&s)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, "first", s.GetValue())
+
+	var i wrapperspb.Int64Value
+	found, err = e.Extension(&i)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, int64(10), i.GetValue())
+
+	found, err = e.Extension(&wrapperspb.BoolValue{})
+	require.NoError(t, err)
+	require.False(t, found)
+
+	e.Extensions[0].Value = []byte{0xff}
+	_, err = e.Extension(&s)
+	require.Error(t, err)
+}
+
+func TestMarshalEventExtensions(t *testing.T) {
+	var e Event
+	require.NoError(t, e.AddExtension(wrapperspb.String("known")))
+	e.Extensions = append(e.Extensions, &anypb.Any{TypeUrl: "type.example.com/custom.Type", Value: []byte{1, 2}})
+
+	var w fastjson.Writer
+	require.NoError(t, e.MarshalFastJSON(&w))
+	require.Equal(t, `{"extensions":[`+
+		`{"@type":"type.googleapis.com/google.protobuf.StringValue","value":"known"},`+
+		`{"@type":"type.example.com/custom.Type","value":"AQI="}]}`, string(w.Bytes()))
+}
@@ -0,0 +1,239 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v3.19.4
+// source: messages/global_metadata.proto
+
+package messages
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A request for the global metadata of the shipper, the metadata shared by
+// all the events of the agent, e.g. its host, agent and cloud fields.
+type GetGlobalMetadataRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *GetGlobalMetadataRequest) Reset() {
+	*x = GetGlobalMetadataRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_messages_global_metadata_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetGlobalMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGlobalMetadataRequest) ProtoMessage() {}
+
+func (x *GetGlobalMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_global_metadata_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGlobalMetadataRequest.ProtoReflect.Descriptor instead.
+func (*GetGlobalMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_messages_global_metadata_proto_rawDescGZIP(), []int{0}
+}
+
+type GetGlobalMetadataReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The uuid of the shipper process, generated on startup. The versions are
+	// specific to a shipper process.
+	Uuid string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
+	// The version of the global metadata, increased every time it changes.
+	// Events reference it with Event.global_metadata_version.
+	Version uint64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// The global metadata JSON object (map[string]google.protobuf.Value).
+	Metadata *Struct `protobuf:"bytes,3,opt,name=metadata,proto3" json:"metadata,omitempty"`
+}
+
+func (x *GetGlobalMetadataReply) Reset() {
+	*x = GetGlobalMetadataReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_messages_global_metadata_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetGlobalMetadataReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGlobalMetadataReply) ProtoMessage() {}
+
+func (x *GetGlobalMetadataReply) ProtoReflect() protoreflect.Message {
+	mi := &file_messages_global_metadata_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGlobalMetadataReply.ProtoReflect.Descriptor instead.
+func (*GetGlobalMetadataReply) Descriptor() ([]byte, []int) {
+	return file_messages_global_metadata_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetGlobalMetadataReply) GetUuid() string {
+	if x != nil {
+		return x.Uuid
+	}
+	return ""
+}
+
+func (x *GetGlobalMetadataReply) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *GetGlobalMetadataReply) GetMetadata() *Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+var File_messages_global_metadata_proto protoreflect.FileDescriptor
+
+var file_messages_global_metadata_proto_rawDesc = []byte{
+	0x0a, 0x1e, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x2f, 0x67, 0x6c, 0x6f, 0x62, 0x61,
+	0x6c, 0x5f, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x21, 0x65, 0x6c, 0x61, 0x73, 0x74, 0x69, 0x63, 0x2e, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2e,
+	0x73, 0x68, 0x69, 0x70, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x73, 0x1a, 0x15, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x2f, 0x73, 0x74,
+	0x72, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x1a, 0x0a, 0x18, 0x47, 0x65,
+	0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x8d, 0x01, 0x0a, 0x16, 0x47, 0x65, 0x74, 0x47, 0x6c,
+	0x6f, 0x62, 0x61, 0x6c, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x75, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x75, 0x75, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
+	0x45, 0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x29, 0x2e, 0x65, 0x6c, 0x61, 0x73, 0x74, 0x69, 0x63, 0x2e, 0x61, 0x67, 0x65, 0x6e,
+	0x74, 0x2e, 0x73, 0x68, 0x69, 0x70, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x73, 0x2e, 0x53, 0x74, 0x72, 0x75, 0x63, 0x74, 0x52, 0x08, 0x6d, 0x65,
+	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x42, 0x44, 0x5a, 0x42, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
+	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x6c, 0x61, 0x73, 0x74, 0x69, 0x63, 0x2f, 0x65, 0x6c, 0x61,
+	0x73, 0x74, 0x69, 0x63, 0x2d, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2d, 0x73, 0x68, 0x69, 0x70, 0x70,
+	0x65, 0x72, 0x2d, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2f, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_messages_global_metadata_proto_rawDescOnce sync.Once
+	file_messages_global_metadata_proto_rawDescData = file_messages_global_metadata_proto_rawDesc
+)
+
+func file_messages_global_metadata_proto_rawDescGZIP() []byte {
+	file_messages_global_metadata_proto_rawDescOnce.Do(func() {
+		file_messages_global_metadata_proto_rawDescData = protoimpl.X.CompressGZIP(file_messages_global_metadata_proto_rawDescData)
+	})
+	return file_messages_global_metadata_proto_rawDescData
+}
+
+var file_messages_global_metadata_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_messages_global_metadata_proto_goTypes = []interface{}{
+	(*GetGlobalMetadataRequest)(nil), // 0: elastic.agent.shipper.v1.messages.GetGlobalMetadataRequest
+	(*GetGlobalMetadataReply)(nil),   // 1: elastic.agent.shipper.v1.messages.GetGlobalMetadataReply
+	(*Struct)(nil),                   // 2: elastic.agent.shipper.v1.messages.Struct
+}
+var file_messages_global_metadata_proto_depIdxs = []int32{
+	2, // 0: elastic.agent.shipper.v1.messages.GetGlobalMetadataReply.metadata:type_name -> elastic.agent.shipper.v1.messages.Struct
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_messages_global_metadata_proto_init() }
+func file_messages_global_metadata_proto_init() {
+	if File_messages_global_metadata_proto != nil {
+		return
+	}
+	file_messages_struct_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_messages_global_metadata_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetGlobalMetadataRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_messages_global_metadata_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetGlobalMetadataReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_messages_global_metadata_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_messages_global_metadata_proto_goTypes,
+		DependencyIndexes: file_messages_global_metadata_proto_depIdxs,
+		MessageInfos:      file_messages_global_metadata_proto_msgTypes,
+	}.Build()
+	File_messages_global_metadata_proto = out.File
+	file_messages_global_metadata_proto_rawDesc = nil
+	file_messages_global_metadata_proto_goTypes = nil
+	file_messages_global_metadata_proto_depIdxs = nil
+}
@@ -1,230 +0,0 @@
-// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
-// or more contributor license agreements. Licensed under the Elastic License;
-// you may not use this file except in compliance with the Elastic License.
-
-// Code generated by protoc-gen-go. DO NOT EDIT.
-// versions:
-// 	protoc-gen-go v1.28.0
-// 	protoc        v3.19.4
-// source: messages/info.proto
-
-package messages
-
-import (
-	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
-	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
-	reflect "reflect"
-	sync "sync"
-)
-
-const (
-	// Verify that this generated code is sufficiently up-to-date.
-	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
-	// Verify that runtime/protoimpl is sufficiently up-to-date.
-	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
-)
-
-type InfoRequest struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-}
-
-func (x *InfoRequest) Reset() {
-	*x = InfoRequest{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_messages_info_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
-}
-
-func (x *InfoRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*InfoRequest) ProtoMessage() {}
-
-func (x *InfoRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_messages_info_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use InfoRequest.ProtoReflect.Descriptor instead.
-func (*InfoRequest) Descriptor() ([]byte, []int) {
-	return file_messages_info_proto_rawDescGZIP(), []int{0}
-}
-
-type InfoReply struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	// The uuid of the shipper process, generated on startup. Clients can use this
-	// to detect when the shipper restarts.
-	Uuid string `protobuf:"bytes,1,opt,name=uuid,proto3" json:"uuid,omitempty"`
-	// The most recent index assigned to an event accepted by the shipper. This
-	// is always at least as high as persisted_index.
-	AcceptedIndex int64 `protobuf:"varint,2,opt,name=accepted_index,json=acceptedIndex,proto3" json:"accepted_index,omitempty"`
-	// The highest sequential index that has been persisted. (See the API
-	// README for details on what "persisted" entails.)
-	PersistedIndex int64 `protobuf:"varint,3,opt,name=persisted_index,json=persistedIndex,proto3" json:"persisted_index,omitempty"`
-}
-
-func (x *InfoReply) Reset() {
-	*x = InfoReply{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_messages_info_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
-}
-
-func (x *InfoReply) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*InfoReply) ProtoMessage() {}
-
-func (x *InfoReply) ProtoReflect() protoreflect.Message {
-	mi := &file_messages_info_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
-	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use InfoReply.ProtoReflect.Descriptor instead.
-func (*InfoReply) Descriptor() ([]byte, []int) {
-	return file_messages_info_proto_rawDescGZIP(), []int{1}
-}
-
-func (x *InfoReply) GetUuid() string {
-	if x != nil {
-		return x.Uuid
-	}
-	return ""
-}
-
-func (x *InfoReply) GetAcceptedIndex() int64 {
-	if x != nil {
-		return x.AcceptedIndex
-	}
-	return 0
-}
-
-func (x *InfoReply) GetPersistedIndex() int64 {
-	if x != nil {
-		return x.PersistedIndex
-	}
-	return 0
-}
-
-var File_messages_info_proto protoreflect.FileDescriptor
-
-var file_messages_info_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x2f, 0x69, 0x6e, 0x66, 0x6f, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x21, 0x65, 0x6c, 0x61, 0x73, 0x74, 0x69, 0x63, 0x2e, 0x61,
-	0x67, 0x65, 0x6e, 0x74, 0x2e, 0x73, 0x68, 0x69, 0x70, 0x70, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e,
-	0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x22, 0x0d, 0x0a, 0x0b, 0x49, 0x6e, 0x66, 0x6f,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x6f, 0x0a, 0x09, 0x49, 0x6e, 0x66, 0x6f, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x75, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x04, 0x75, 0x75, 0x69, 0x64, 0x12, 0x25, 0x0a, 0x0e, 0x61, 0x63, 0x63, 0x65,
-	0x70, 0x74, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x0d, 0x61, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12,
-	0x27, 0x0a, 0x0f, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x64, 0x5f, 0x69, 0x6e, 0x64,
-	0x65, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0e, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73,
-	0x74, 0x65, 0x64, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x42, 0x44, 0x5a, 0x42, 0x67, 0x69, 0x74, 0x68,
-	0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x6c, 0x61, 0x73, 0x74, 0x69, 0x63, 0x2f, 0x65,
-	0x6c, 0x61, 0x73, 0x74, 0x69, 0x63, 0x2d, 0x61, 0x67, 0x65, 0x6e, 0x74, 0x2d, 0x73, 0x68, 0x69,
-	0x70, 0x70, 0x65, 0x72, 0x2d, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x2f, 0x70, 0x6b, 0x67, 0x2f,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
-}
-
-var (
-	file_messages_info_proto_rawDescOnce sync.Once
-	file_messages_info_proto_rawDescData = file_messages_info_proto_rawDesc
-)
-
-func file_messages_info_proto_rawDescGZIP() []byte {
-	file_messages_info_proto_rawDescOnce.Do(func() {
-		file_messages_info_proto_rawDescData = protoimpl.X.CompressGZIP(file_messages_info_proto_rawDescData)
-	})
-	return file_messages_info_proto_rawDescData
-}
-
-var file_messages_info_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_messages_info_proto_goTypes = []interface{}{
-	(*InfoRequest)(nil), // 0: elastic.agent.shipper.v1.messages.InfoRequest
-	(*InfoReply)(nil),   // 1: elastic.agent.shipper.v1.messages.InfoReply
-}
-var file_messages_info_proto_depIdxs = []int32{
-	0, // [0:0] is the sub-list for method output_type
-	0, // [0:0] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
-}
-
-func init() { file_messages_info_proto_init() }
-func file_messages_info_proto_init() {
-	if File_messages_info_proto != nil {
-		return
-	}
-	if !protoimpl.UnsafeEnabled {
-		file_messages_info_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InfoRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_messages_info_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InfoReply); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
-	type x struct{}
-	out := protoimpl.TypeBuilder{
-		File: protoimpl.DescBuilder{
-			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_messages_info_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   2,
-			NumExtensions: 0,
-			NumServices:   0,
-		},
-		GoTypes:           file_messages_info_proto_goTypes,
-		DependencyIndexes: file_messages_info_proto_depIdxs,
-		MessageInfos:      file_messages_info_proto_msgTypes,
-	}.Build()
-	File_messages_info_proto = out.File
-	file_messages_info_proto_rawDesc = nil
-	file_messages_info_proto_goTypes = nil
-	file_messages_info_proto_depIdxs = nil
-}
@@ -5,67 +5,185 @@
 package messages
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"time"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"go.elastic.co/fastjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// TimestampFormat is the JSON form of the timestamps.
+type TimestampFormat int
+
+const (
+	// TimestampRFC3339Nano writes RFC 3339 strings with up to nine
+	// fractional digits, trailing zeros removed.
+	TimestampRFC3339Nano TimestampFormat = iota
+	// TimestampRFC3339Millis writes RFC 3339 strings with exactly three
+	// fractional digits, the default format of the Elasticsearch date fields.
+	TimestampRFC3339Millis
+	// TimestampEpochMillis writes the number of milliseconds since the Unix epoch.
+	TimestampEpochMillis
+)
+
+// NonFinitePolicy is how the NaN and infinite floats, that have no JSON
+// representation, are marshaled.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteNull writes them as null.
+	NonFiniteNull NonFinitePolicy = iota
+	// NonFiniteString writes them as the "NaN", "Infinity" and "-Infinity" strings.
+	NonFiniteString
+	// NonFiniteError fails the marshaling with ErrNonFiniteFloat.
+	NonFiniteError
+)
+
+// ErrNonFiniteFloat is returned when marshaling a NaN or infinite float with
+// the NonFiniteError policy.
+var ErrNonFiniteFloat = errors.New("NaN and infinite floats can't be marshaled to JSON")
+
+// JSONOptions configures the JSON marshaling of the messages. The zero value
+// is the output of the MarshalFastJSON methods.
+type JSONOptions struct {
+	// ProtoJSON follows the protojson conventions for the scalar values:
+	// 64-bit integers are quoted, RFC3339Nano timestamps have 0, 3, 6 or 9
+	// fractional digits, non-finite floats are written with NonFiniteString
+	// unless the policy is NonFiniteError, and missing values are null.
+	ProtoJSON bool
+	// NonFinite is the policy for the NaN and infinite floats.
+	NonFinite NonFinitePolicy
+	// Timestamps is the format of the timestamp values and of the
+	// timestamp of the events.
+	Timestamps TimestampFormat
+	// DisableHTMLEscape writes <, > and & as they are in the strings and
+	// keys, instead of escaping them so the JSON is safe to embed in HTML.
+	DisableHTMLEscape bool
+	// ASCIIOnly escapes all the non-ASCII characters of the strings and
+	// keys, for the consumers that aren't UTF-8 clean.
+	ASCIIOnly bool
+	// SortKeys writes the keys of the structs in sorted order, for a
+	// reproducible output.
+	SortKeys bool
+	// MaxStringLength, when positive, truncates the string values longer
+	// than this many bytes at a character boundary and appends "…". The
+	// output is not the value anymore, it's meant for humans.
+	MaxStringLength int
+}
+
 // MarshalFastJSON implements the JSON interface for the value type
 func (val *Value) MarshalFastJSON(w *fastjson.Writer) error {
+	return JSONOptions{}.MarshalValue(w, val)
+}
+
+// MarshalFastJSON implements the JSON interface for the struct type
+func (sv *Struct) MarshalFastJSON(w *fastjson.Writer) error {
+	return JSONOptions{}.MarshalStruct(w, sv)
+}
+
+// MarshalFastJSON implements the JSON interface for the list Value type
+func (lv *ListValue) MarshalFastJSON(w *fastjson.Writer) error {
+	return JSONOptions{}.MarshalList(w, lv)
+}
+
+// MarshalFastJSON implements the JSON interface for the event type. The
+// fields keep their protobuf names, the unset messages are omitted.
+func (e *Event) MarshalFastJSON(w *fastjson.Writer) error {
+	return JSONOptions{}.MarshalEvent(w, e)
+}
+
+// MarshalValue writes the value to w.
+func (o JSONOptions) MarshalValue(w *fastjson.Writer, val *Value) error {
 	switch typ := val.GetKind().(type) {
 	case *Value_NullValue:
 		w.RawString("null")
 		return nil
 	case *Value_Float32Value:
+		if ok, err := o.nonFinite(w, float64(typ.Float32Value)); ok || err != nil {
+			return err
+		}
 		w.Float32(typ.Float32Value)
 	case *Value_Float64Value:
+		if ok, err := o.nonFinite(w, typ.Float64Value); ok || err != nil {
+			return err
+		}
 		w.Float64(typ.Float64Value)
 		return nil
 	case *Value_Int32Value:
 		w.Int64(int64(typ.Int32Value))
 		return nil
 	case *Value_Int64Value:
+		if o.ProtoJSON {
+			w.RawByte('"')
+			w.Int64(typ.Int64Value)
+			w.RawByte('"')
+			return nil
+		}
 		w.Int64(typ.Int64Value)
 		return nil
 	case *Value_Uint32Value:
 		w.Uint64(uint64(typ.Uint32Value))
 		return nil
 	case *Value_Uint64Value:
+		if o.ProtoJSON {
+			w.RawByte('"')
+			w.Uint64(typ.Uint64Value)
+			w.RawByte('"')
+			return nil
+		}
 		w.Uint64(typ.Uint64Value)
 		return nil
 	case *Value_StringValue:
-		w.String(typ.StringValue)
+		o.string(w, o.truncate(typ.StringValue))
 		return nil
 	case *Value_BoolValue:
 		w.Bool(typ.BoolValue)
 		return nil
+	case *Value_BytesValue:
+		// like the bytes fields in protojson
+		w.RawByte('"')
+		writeBase64(w, typ.BytesValue)
+		w.RawByte('"')
+		return nil
 	case *Value_StructValue:
-		err := typ.StructValue.MarshalFastJSON(w)
+		err := o.MarshalStruct(w, typ.StructValue)
 		if err != nil {
 			return fmt.Errorf("error marshaling within value: %w", err)
 		}
 		// return data, nil
 	case *Value_ListValue:
-		err := typ.ListValue.MarshalFastJSON(w)
+		err := o.MarshalList(w, typ.ListValue)
 		if err != nil {
 			return fmt.Errorf("error marshaling within value: %w", err)
 		}
 		return nil
 	case *Value_TimestampValue:
-		w.RawByte('"')
-		w.Time(typ.TimestampValue.AsTime(), time.RFC3339Nano)
-		w.RawByte('"')
+		o.timestamp(w, typ.TimestampValue)
+	case nil:
+		if !o.ProtoJSON {
+			return fmt.Errorf("Unknown type %T in event", typ)
+		}
+		w.RawString("null")
 	default:
 		return fmt.Errorf("Unknown type %T in event", typ)
 	}
 	return nil
 }
 
-// MarshalFastJSON implements the JSON interface for the struct type
-func (sv *Struct) MarshalFastJSON(w *fastjson.Writer) error {
-	if sv.GetData() == nil {
-		return nil
+// MarshalStruct writes the struct to w as an object.
+func (o JSONOptions) MarshalStruct(w *fastjson.Writer, sv *Struct) error {
+	if o.SortKeys {
+		return o.marshalSortedStruct(w, sv)
 	}
 	w.RawByte('{')
 	beginning := true
@@ -76,10 +194,9 @@ func (sv *Struct) MarshalFastJSON(w *fastjson.Writer) error {
 			beginning = false
 		}
 
-		w.RawString("\"")
-		w.RawString(key)
-		w.RawString("\":")
-		err := val.MarshalFastJSON(w)
+		o.string(w, key)
+		w.RawByte(':')
+		err := o.MarshalValue(w, val)
 		if err != nil {
 			return fmt.Errorf("error marshaling value in map: %w", err)
 		}
@@ -88,18 +205,404 @@ func (sv *Struct) MarshalFastJSON(w *fastjson.Writer) error {
 	return nil
 }
 
-// MarshalFastJSON implements the JSON interface for the list Value type
-func (lv *ListValue) MarshalFastJSON(w *fastjson.Writer) error {
-	if lv.GetValues() == nil {
-		return nil
+func (o JSONOptions) marshalSortedStruct(w *fastjson.Writer, sv *Struct) error {
+	w.RawByte('{')
+	var err error
+	first := true
+	sv.RangeSorted(func(key string, val *Value) bool {
+		if !first {
+			w.RawByte(',')
+		}
+		first = false
+		o.string(w, key)
+		w.RawByte(':')
+		if err = o.MarshalValue(w, val); err != nil {
+			err = fmt.Errorf("error marshaling value in map: %w", err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	w.RawByte('}')
+	return nil
+}
+
+// MarshalFastJSONFields writes the projection of the struct on the dotted
+// paths to w, see JSONOptions.MarshalStructFields.
+func MarshalFastJSONFields(w *fastjson.Writer, sv *Struct, paths []string) error {
+	return JSONOptions{}.MarshalStructFields(w, sv, paths)
+}
+
+// MarshalStructFields writes the projection of the struct on the dotted
+// paths to w: only the values at the paths are written, nested in their
+// parent objects. A path matches the keys containing dots as well as the
+// nested keys, "host.name" selects {"host.name":...} and {"host":{"name":...}}.
+// The paths that match nothing are ignored.
+//
+// The keys are written in the order of the paths, the other values of the
+// struct are never looked at, so the cost depends on the selected values only.
+func (o JSONOptions) MarshalStructFields(w *fastjson.Writer, sv *Struct, paths []string) error {
+	type selection struct {
+		key   string
+		whole bool
+		paths []string
 	}
+	var selected []*selection
+	byKey := make(map[string]*selection)
+	for _, path := range paths {
+		// every prefix of the path ending before a dot or at its end can be a key
+		for end := 0; end <= len(path); end++ {
+			if end < len(path) && path[end] != '.' {
+				continue
+			}
+			key := path[:end]
+			if _, ok := sv.GetData()[key]; !ok {
+				continue
+			}
+			sel := byKey[key]
+			if sel == nil {
+				sel = &selection{key: key}
+				byKey[key] = sel
+				selected = append(selected, sel)
+			}
+			if end == len(path) {
+				sel.whole = true
+			} else {
+				sel.paths = append(sel.paths, path[end+1:])
+			}
+		}
+	}
+
+	w.RawByte('{')
+	first := true
+	for _, sel := range selected {
+		val := sv.GetData()[sel.key]
+		if !sel.whole && val.GetStructValue() == nil {
+			// the paths go through a value that isn't an object
+			continue
+		}
+		start := w.Size()
+		if !first {
+			w.RawByte(',')
+		}
+		o.string(w, sel.key)
+		w.RawByte(':')
+		if sel.whole {
+			if err := o.MarshalValue(w, val); err != nil {
+				return fmt.Errorf("error marshaling value in map: %w", err)
+			}
+		} else {
+			nested := w.Size()
+			if err := o.MarshalStructFields(w, val.GetStructValue(), sel.paths); err != nil {
+				return err
+			}
+			if w.Size()-nested == len("{}") {
+				// nothing selected in the nested object
+				w.Rewind(start)
+				continue
+			}
+		}
+		first = false
+	}
+	w.RawByte('}')
+	return nil
+}
+
+// MarshalList writes the list to w as an array.
+func (o JSONOptions) MarshalList(w *fastjson.Writer, lv *ListValue) error {
 	w.RawByte('[')
 	for iter, val := range lv.GetValues() {
 		if iter > 0 {
 			w.RawByte(',')
 		}
-		val.MarshalFastJSON(w)
+		if err := o.MarshalValue(w, val); err != nil {
+			return fmt.Errorf("error marshaling value in list: %w", err)
+		}
+	}
+	w.RawByte(']')
+	return nil
+}
+
+// MarshalEvent writes the event to w.
+func (o JSONOptions) MarshalEvent(w *fastjson.Writer, e *Event) error {
+	w.RawByte('{')
+	first := true
+	field := func(name string) {
+		if !first {
+			w.RawByte(',')
+		}
+		first = false
+		w.String(name)
+		w.RawByte(':')
+	}
+	if e.GetTimestamp() != nil {
+		field("timestamp")
+		o.timestamp(w, e.GetTimestamp())
+	}
+	if e.GetTimestampRaw() != "" {
+		field("timestamp_raw")
+		w.String(e.GetTimestampRaw())
+	}
+	if e.GetSource() != nil {
+		field("source")
+		o.source(w, e.GetSource())
+	}
+	if e.GetDataStream() != nil {
+		field("data_stream")
+		o.dataStream(w, e.GetDataStream())
+	}
+	if e.GetMetadata() != nil {
+		field("metadata")
+		if err := o.MarshalStruct(w, e.GetMetadata()); err != nil {
+			return fmt.Errorf("error marshaling the metadata: %w", err)
+		}
+	}
+	if e.GetFields() != nil {
+		field("fields")
+		if err := o.MarshalStruct(w, e.GetFields()); err != nil {
+			return fmt.Errorf("error marshaling the fields: %w", err)
+		}
+	}
+	if e.GetAttachment() != nil {
+		field("attachment")
+		o.attachment(w, e.GetAttachment())
+	}
+	if len(e.GetExtensions()) > 0 {
+		field("extensions")
+		if err := o.extensions(w, e.GetExtensions()); err != nil {
+			return fmt.Errorf("error marshaling the extensions: %w", err)
+		}
+	}
+	if e.GetPipeline() != "" {
+		field("pipeline")
+		w.String(e.GetPipeline())
+	}
+	if e.GetRawIndex() != "" {
+		field("raw_index")
+		w.String(e.GetRawIndex())
+	}
+	if e.GetOpType() != "" {
+		field("op_type")
+		w.String(e.GetOpType())
+	}
+	if e.GetDocumentId() != "" {
+		field("document_id")
+		w.String(e.GetDocumentId())
+	}
+	if e.GetGlobalMetadataVersion() != 0 {
+		field("global_metadata_version")
+		w.Uint64(e.GetGlobalMetadataVersion())
+	}
+	w.RawByte('}')
+	return nil
+}
+
+func (o JSONOptions) timestamp(w *fastjson.Writer, ts *timestamppb.Timestamp) {
+	t := ts.AsTime()
+	if o.Timestamps == TimestampEpochMillis {
+		w.Int64(t.UnixMilli())
+		return
+	}
+
+	w.RawByte('"')
+	switch {
+	case o.Timestamps == TimestampRFC3339Millis:
+		w.Time(t, "2006-01-02T15:04:05.000Z07:00")
+	case o.ProtoJSON:
+		// the format of the well-known Timestamp type in protojson
+		var scratch [32]byte
+		b := t.AppendFormat(scratch[:0], "2006-01-02T15:04:05.000000000")
+		b = bytes.TrimSuffix(b, []byte("000"))
+		b = bytes.TrimSuffix(b, []byte("000"))
+		b = bytes.TrimSuffix(b, []byte(".000"))
+		w.RawBytes(b)
+		w.RawByte('Z')
+	default:
+		w.Time(t, time.RFC3339Nano)
+	}
+	w.RawByte('"')
+}
+
+// nonFinite writes f according to the policy if it's NaN or infinite, it
+// returns false if f is finite.
+func (o JSONOptions) nonFinite(w *fastjson.Writer, f float64) (bool, error) {
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return false, nil
+	}
+	policy := o.NonFinite
+	if o.ProtoJSON && policy == NonFiniteNull {
+		policy = NonFiniteString
+	}
+	switch policy {
+	case NonFiniteString:
+		switch {
+		case math.IsNaN(f):
+			w.RawString(`"NaN"`)
+		case f > 0:
+			w.RawString(`"Infinity"`)
+		default:
+			w.RawString(`"-Infinity"`)
+		}
+	case NonFiniteError:
+		return false, fmt.Errorf("%w: %v", ErrNonFiniteFloat, f)
+	default:
+		w.RawString("null")
+	}
+	return true, nil
+}
+
+func (o JSONOptions) source(w *fastjson.Writer, s *Source) {
+	w.RawString(`{"input_id":`)
+	o.string(w, s.GetInputId())
+	w.RawString(`,"stream_id":`)
+	o.string(w, s.GetStreamId())
+	w.RawByte('}')
+}
+
+func (o JSONOptions) dataStream(w *fastjson.Writer, ds *DataStream) {
+	w.RawString(`{"type":`)
+	o.string(w, ds.GetType())
+	w.RawString(`,"dataset":`)
+	o.string(w, ds.GetDataset())
+	w.RawString(`,"namespace":`)
+	o.string(w, ds.GetNamespace())
+	w.RawByte('}')
+}
+
+// attachment writes the data of the attachment as a base64 string, like
+// protojson.
+func (o JSONOptions) attachment(w *fastjson.Writer, a *Attachment) {
+	w.RawString(`{"content_type":`)
+	o.string(w, a.GetContentType())
+	w.RawString(`,"data":"`)
+	writeBase64(w, a.GetData())
+	w.RawString(`"}`)
+}
+
+// extensions writes the extensions of the registered types in their protojson
+// form, and the other ones as their type URL and base64 value.
+func (o JSONOptions) extensions(w *fastjson.Writer, extensions []*anypb.Any) error {
+	w.RawByte('[')
+	for i, a := range extensions {
+		if i > 0 {
+			w.RawByte(',')
+		}
+		if _, err := protoregistry.GlobalTypes.FindMessageByURL(a.GetTypeUrl()); err != nil {
+			w.RawString(`{"@type":`)
+			o.string(w, a.GetTypeUrl())
+			w.RawString(`,"value":"`)
+			writeBase64(w, a.GetValue())
+			w.RawString(`"}`)
+			continue
+		}
+		data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(a)
+		if err != nil {
+			return fmt.Errorf("error marshaling extension %s: %w", a.GetTypeUrl(), err)
+		}
+		// protojson randomizes its whitespaces
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err != nil {
+			return fmt.Errorf("error marshaling extension %s: %w", a.GetTypeUrl(), err)
+		}
+		w.RawBytes(compact.Bytes())
 	}
 	w.RawByte(']')
 	return nil
 }
+
+// writeBase64 writes b base64 encoded. The small values are encoded on the
+// stack, without the string EncodeToString allocates and the writer copies.
+func writeBase64(w *fastjson.Writer, b []byte) {
+	var scratch [256]byte
+	n := base64.StdEncoding.EncodedLen(len(b))
+	buf := scratch[:0]
+	if n <= len(scratch) {
+		buf = scratch[:n]
+	} else {
+		buf = make([]byte, n)
+	}
+	base64.StdEncoding.Encode(buf, b)
+	w.RawBytes(buf)
+}
+
+const hex = "0123456789abcdef"
+
+// string writes s as a JSON string. The default escaping is the one of
+// fastjson, the other ones follow its rules for the rest of the characters.
+func (o JSONOptions) string(w *fastjson.Writer, s string) {
+	if !o.DisableHTMLEscape && !o.ASCIIOnly {
+		w.String(s)
+		return
+	}
+
+	w.RawByte('"')
+	p := 0 // start of the characters not written yet
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			html := c == '<' || c == '>' || c == '&'
+			if c >= 0x20 && c != '"' && c != '\\' && (!html || o.DisableHTMLEscape) {
+				i++
+				continue
+			}
+			w.RawString(s[p:i])
+			switch c {
+			case '\t':
+				w.RawString(`\t`)
+			case '\r':
+				w.RawString(`\r`)
+			case '\n':
+				w.RawString(`\n`)
+			case '\\':
+				w.RawString(`\\`)
+			case '"':
+				w.RawString(`\"`)
+			default:
+				escapeRune(w, rune(c))
+			}
+			i++
+			p = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if o.ASCIIOnly || r == utf8.RuneError || r == '\u2028' || r == '\u2029' {
+			// invalid UTF-8 is replaced by U+FFFD, like fastjson does
+			w.RawString(s[p:i])
+			if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				escapeRune(w, r1)
+				escapeRune(w, r2)
+			} else {
+				escapeRune(w, r)
+			}
+			p = i + size
+		}
+		i += size
+	}
+	w.RawString(s[p:])
+	w.RawByte('"')
+}
+
+// truncate returns s cut to MaxStringLength.
+func (o JSONOptions) truncate(s string) string {
+	if o.MaxStringLength <= 0 || len(s) <= o.MaxStringLength {
+		return s
+	}
+	end := o.MaxStringLength
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "…"
+}
+
+// escapeRune writes the \u escape of a rune of the basic multilingual plane.
+func escapeRune(w *fastjson.Writer, r rune) {
+	w.RawString(`\u`)
+	w.RawByte(hex[r>>12&0xf])
+	w.RawByte(hex[r>>8&0xf])
+	w.RawByte(hex[r>>4&0xf])
+	w.RawByte(hex[r&0xf])
+}
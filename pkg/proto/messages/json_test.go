@@ -0,0 +1,266 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.elastic.co/fastjson"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoJSON(t *testing.T) {
+	opts := JSONOptions{ProtoJSON: true}
+	tests := []struct {
+		name  string
+		value *Value
+		// want is the protojson encoding of the equivalent well-known type
+		want proto.Message
+	}{
+		{"int32", &Value{Kind: &Value_Int32Value{Int32Value: -5}}, wrapperspb.Int32(-5)},
+		{"int64", &Value{Kind: &Value_Int64Value{Int64Value: math.MinInt64}}, wrapperspb.Int64(math.MinInt64)},
+		{"uint32", &Value{Kind: &Value_Uint32Value{Uint32Value: 5}}, wrapperspb.UInt32(5)},
+		{"uint64", &Value{Kind: &Value_Uint64Value{Uint64Value: math.MaxUint64}}, wrapperspb.UInt64(math.MaxUint64)},
+		{"float64", &Value{Kind: &Value_Float64Value{Float64Value: 0.5}}, wrapperspb.Double(0.5)},
+		{"NaN", &Value{Kind: &Value_Float64Value{Float64Value: math.NaN()}}, wrapperspb.Double(math.NaN())},
+		{"infinity", &Value{Kind: &Value_Float32Value{Float32Value: float32(math.Inf(1))}}, wrapperspb.Float(float32(math.Inf(1)))},
+		{"negative infinity", &Value{Kind: &Value_Float64Value{Float64Value: math.Inf(-1)}}, wrapperspb.Double(math.Inf(-1))},
+		{"string", &Value{Kind: &Value_StringValue{StringValue: "a\"b"}}, wrapperspb.String("a\"b")},
+		{"seconds", &Value{Kind: &Value_TimestampValue{TimestampValue: &timestamppb.Timestamp{Seconds: 1}}}, &timestamppb.Timestamp{Seconds: 1}},
+		{"millis", &Value{Kind: &Value_TimestampValue{TimestampValue: &timestamppb.Timestamp{Seconds: 1, Nanos: 1e6}}}, &timestamppb.Timestamp{Seconds: 1, Nanos: 1e6}},
+		{"micros", &Value{Kind: &Value_TimestampValue{TimestampValue: &timestamppb.Timestamp{Seconds: 1, Nanos: 1e3}}}, &timestamppb.Timestamp{Seconds: 1, Nanos: 1e3}},
+		{"nanos", &Value{Kind: &Value_TimestampValue{TimestampValue: &timestamppb.Timestamp{Seconds: 1, Nanos: 1}}}, &timestamppb.Timestamp{Seconds: 1, Nanos: 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want, err := protojson.Marshal(tc.want)
+			require.NoError(t, err)
+			var compact bytes.Buffer
+			require.NoError(t, json.Compact(&compact, want))
+
+			var w fastjson.Writer
+			require.NoError(t, opts.MarshalValue(&w, tc.value))
+			require.Equal(t, compact.String(), string(w.Bytes()))
+		})
+	}
+}
+
+func TestProtoJSONNulls(t *testing.T) {
+	s := &Struct{Data: map[string]*Value{"missing": nil}}
+
+	var w fastjson.Writer
+	require.Error(t, s.MarshalFastJSON(&w))
+
+	w.Reset()
+	require.NoError(t, JSONOptions{ProtoJSON: true}.MarshalStruct(&w, s))
+	require.Equal(t, `{"missing":null}`, string(w.Bytes()))
+}
+
+func TestProtoJSONEvent(t *testing.T) {
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 6e8, time.UTC)
+	e := &Event{
+		Timestamp: timestamppb.New(ts),
+		Fields:    &Struct{Data: map[string]*Value{"offset": {Kind: &Value_Int64Value{Int64Value: 10}}}},
+	}
+
+	var w fastjson.Writer
+	require.NoError(t, e.MarshalFastJSON(&w))
+	require.Equal(t, `{"timestamp":"2022-01-02T03:04:05.6Z","fields":{"offset":10}}`, string(w.Bytes()))
+
+	w.Reset()
+	require.NoError(t, JSONOptions{ProtoJSON: true}.MarshalEvent(&w, e))
+	require.Equal(t, `{"timestamp":"2022-01-02T03:04:05.600Z","fields":{"offset":"10"}}`, string(w.Bytes()))
+}
+
+func TestMarshalEventAttachment(t *testing.T) {
+	e := &Event{Attachment: &Attachment{ContentType: "application/octet-stream", Data: []byte{0, 1, 0xff}}}
+	var w fastjson.Writer
+	require.NoError(t, e.MarshalFastJSON(&w))
+	require.Equal(t, `{"attachment":{"content_type":"application/octet-stream","data":"AAH/"}}`, string(w.Bytes()))
+}
+
+func TestMarshalEventTimestampRaw(t *testing.T) {
+	e := &Event{TimestampRaw: "2022-01-02T03:04:05Z"}
+	var w fastjson.Writer
+	require.NoError(t, e.MarshalFastJSON(&w))
+	require.Equal(t, `{"timestamp_raw":"2022-01-02T03:04:05Z"}`, string(w.Bytes()))
+}
+
+func TestMarshalEventRoutingHints(t *testing.T) {
+	e := &Event{Pipeline: "logs-generic", RawIndex: "raw", OpType: "create", DocumentId: "id"}
+	var w fastjson.Writer
+	require.NoError(t, e.MarshalFastJSON(&w))
+	require.Equal(t, `{"pipeline":"logs-generic","raw_index":"raw","op_type":"create","document_id":"id"}`, string(w.Bytes()))
+}
+
+func TestMarshalEventGlobalMetadataVersion(t *testing.T) {
+	e := &Event{GlobalMetadataVersion: 3}
+	var w fastjson.Writer
+	require.NoError(t, e.MarshalFastJSON(&w))
+	require.Equal(t, `{"global_metadata_version":3}`, string(w.Bytes()))
+}
+
+func TestTimestampFormats(t *testing.T) {
+	value := &Value{Kind: &Value_TimestampValue{TimestampValue: timestamppb.New(time.Date(2022, 1, 2, 3, 4, 5, 6e6+7, time.UTC))}}
+	before := &Value{Kind: &Value_TimestampValue{TimestampValue: timestamppb.New(time.Date(1969, 12, 31, 23, 59, 59, 5e8, time.UTC))}}
+
+	tests := []struct {
+		name       string
+		opts       JSONOptions
+		want       string
+		wantBefore string
+	}{
+		{"default", JSONOptions{}, `"2022-01-02T03:04:05.006000007Z"`, `"1969-12-31T23:59:59.5Z"`},
+		{"protojson", JSONOptions{ProtoJSON: true}, `"2022-01-02T03:04:05.006000007Z"`, `"1969-12-31T23:59:59.500Z"`},
+		{"millis", JSONOptions{Timestamps: TimestampRFC3339Millis}, `"2022-01-02T03:04:05.006Z"`, `"1969-12-31T23:59:59.500Z"`},
+		{"epoch millis", JSONOptions{Timestamps: TimestampEpochMillis}, `1641092645006`, `-500`},
+		{"epoch millis protojson", JSONOptions{ProtoJSON: true, Timestamps: TimestampEpochMillis}, `1641092645006`, `-500`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var w fastjson.Writer
+			require.NoError(t, tc.opts.MarshalValue(&w, value))
+			require.Equal(t, tc.want, string(w.Bytes()))
+
+			w.Reset()
+			require.NoError(t, tc.opts.MarshalValue(&w, before))
+			require.Equal(t, tc.wantBefore, string(w.Bytes()))
+
+			w.Reset()
+			require.NoError(t, tc.opts.MarshalEvent(&w, &Event{Timestamp: value.GetTimestampValue()}))
+			require.Equal(t, `{"timestamp":`+tc.want+`}`, string(w.Bytes()))
+		})
+	}
+}
+
+func TestNonFinitePolicy(t *testing.T) {
+	list := &ListValue{Values: []*Value{
+		{Kind: &Value_Float64Value{Float64Value: math.NaN()}},
+		{Kind: &Value_Float32Value{Float32Value: float32(math.Inf(1))}},
+		{Kind: &Value_Float64Value{Float64Value: math.Inf(-1)}},
+		{Kind: &Value_Float64Value{Float64Value: 1.5}},
+	}}
+
+	tests := []struct {
+		name string
+		opts JSONOptions
+		want string
+	}{
+		{"default", JSONOptions{}, `[null,null,null,1.5]`},
+		{"string", JSONOptions{NonFinite: NonFiniteString}, `["NaN","Infinity","-Infinity",1.5]`},
+		{"protojson", JSONOptions{ProtoJSON: true}, `["NaN","Infinity","-Infinity",1.5]`},
+		{"protojson null", JSONOptions{ProtoJSON: true, NonFinite: NonFiniteNull}, `["NaN","Infinity","-Infinity",1.5]`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var w fastjson.Writer
+			require.NoError(t, tc.opts.MarshalList(&w, list))
+			require.Equal(t, tc.want, string(w.Bytes()))
+			require.True(t, json.Valid(w.Bytes()))
+		})
+	}
+
+	for _, opts := range []JSONOptions{{NonFinite: NonFiniteError}, {ProtoJSON: true, NonFinite: NonFiniteError}} {
+		var w fastjson.Writer
+		err := opts.MarshalList(&w, list)
+		require.ErrorIs(t, err, ErrNonFiniteFloat)
+	}
+
+	var w fastjson.Writer
+	require.NoError(t, list.MarshalFastJSON(&w))
+	require.True(t, json.Valid(w.Bytes()))
+}
+
+func TestEscaping(t *testing.T) {
+	u := func(r rune) string { return fmt.Sprintf("%cu%04x", '\\', r) }
+	value := "a&b\t\"\\ é😀 " + string(rune(0x2028)) + " \x01 \xff"
+	s := &Struct{Data: map[string]*Value{"<é>": {Kind: &Value_StringValue{StringValue: value}}}}
+
+	tail := `\t\"\\ `
+	tests := []struct {
+		name string
+		opts JSONOptions
+		want string
+	}{
+		{"default", JSONOptions{}, `{"` + u('<') + `é` + u('>') + `":"a` + u('&') + `b` + tail + `é😀 ` + u(0x2028) + ` ` + u(1) + ` ` + u(0xfffd) + `"}`},
+		{"no html", JSONOptions{DisableHTMLEscape: true}, `{"<é>":"a&b` + tail + `é😀 ` + u(0x2028) + ` ` + u(1) + ` ` + u(0xfffd) + `"}`},
+		{"ascii", JSONOptions{ASCIIOnly: true}, `{"` + u('<') + u('é') + u('>') + `":"a` + u('&') + `b` + tail + u('é') + u(0xd83d) + u(0xde00) + ` ` + u(0x2028) + ` ` + u(1) + ` ` + u(0xfffd) + `"}`},
+		{"ascii no html", JSONOptions{ASCIIOnly: true, DisableHTMLEscape: true}, `{"<` + u('é') + `>":"a&b` + tail + u('é') + u(0xd83d) + u(0xde00) + ` ` + u(0x2028) + ` ` + u(1) + ` ` + u(0xfffd) + `"}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var w fastjson.Writer
+			require.NoError(t, tc.opts.MarshalStruct(&w, s))
+			require.Equal(t, tc.want, string(w.Bytes()))
+
+			var decoded map[string]string
+			require.NoError(t, json.Unmarshal(w.Bytes(), &decoded))
+			require.Equal(t, map[string]string{"<é>": strings.ToValidUTF8(value, "\ufffd")}, decoded)
+		})
+	}
+
+	e := &Event{Source: &Source{InputId: "<é>"}, DataStream: &DataStream{Dataset: "é"}}
+	var w fastjson.Writer
+	require.NoError(t, JSONOptions{ASCIIOnly: true}.MarshalEvent(&w, e))
+	require.Equal(t, `{"source":{"input_id":"`+u('<')+u('é')+u('>')+`","stream_id":""},"data_stream":{"type":"","dataset":"`+u('é')+`","namespace":""}}`, string(w.Bytes()))
+}
+
+func TestMarshalFastJSONFields(t *testing.T) {
+	str := func(s string) *Value { return &Value{Kind: &Value_StringValue{StringValue: s}} }
+	obj := func(data map[string]*Value) *Value {
+		return &Value{Kind: &Value_StructValue{StructValue: &Struct{Data: data}}}
+	}
+	s := &Struct{Data: map[string]*Value{
+		"message": str("hello"),
+		"host": obj(map[string]*Value{
+			"name": str("h"),
+			"os":   obj(map[string]*Value{"family": str("linux"), "version": str("1")}),
+		}),
+		"log.level": str("info"),
+		"log":       obj(map[string]*Value{"offset": str("10")}),
+		"empty":     obj(map[string]*Value{}),
+	}}
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{"none", nil, `{}`},
+		{"top level", []string{"message"}, `{"message":"hello"}`},
+		{"nested", []string{"host.os.family", "message"}, `{"host":{"os":{"family":"linux"}},"message":"hello"}`},
+		{"siblings", []string{"host.name", "host.os.version"}, `{"host":{"name":"h","os":{"version":"1"}}}`},
+		{"dotted and nested keys", []string{"log.level", "log.offset"}, `{"log":{"offset":"10"},"log.level":"info"}`},
+		{"missing", []string{"host.ip", "nope", "message.length", "host.name.first"}, `{}`},
+		{"empty object", []string{"empty"}, `{"empty":{}}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var w fastjson.Writer
+			require.NoError(t, MarshalFastJSONFields(&w, s, tc.paths))
+			require.Equal(t, tc.want, string(w.Bytes()))
+		})
+	}
+
+	// the keys of a whole object are in the order of the map
+	var w fastjson.Writer
+	require.NoError(t, MarshalFastJSONFields(&w, s, []string{"host.os", "host.os.family"}))
+	require.JSONEq(t, `{"host":{"os":{"family":"linux","version":"1"}}}`, string(w.Bytes()))
+
+	// the first of the paths selecting the whole object sets its position
+	w.Reset()
+	require.NoError(t, MarshalFastJSONFields(&w, s, []string{"host.os.family", "message", "host"}))
+	require.JSONEq(t, `{"host":{"name":"h","os":{"family":"linux","version":"1"}},"message":"hello"}`, string(w.Bytes()))
+}
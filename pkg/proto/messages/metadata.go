@@ -0,0 +1,124 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+// The metadata keys with a meaning for the outputs, spelled as the Beats
+// @metadata fields.
+const (
+	// MetadataKeyID is the id of the document. The document_id field of the
+	// event takes precedence over it.
+	MetadataKeyID = "_id"
+	// MetadataKeyPipeline is the ingest pipeline processing the event. The
+	// pipeline field of the event takes precedence over it.
+	MetadataKeyPipeline = "pipeline"
+	// MetadataKeyRawIndex is the index the event is written to, bypassing
+	// the data stream of the event. The raw_index field of the event takes
+	// precedence over it.
+	MetadataKeyRawIndex = "raw_index"
+	// MetadataKeyOpType is the bulk operation of the event, one of the OpType
+	// values. The op_type field of the event takes precedence over it.
+	MetadataKeyOpType = "op_type"
+)
+
+// OpType is the bulk operation indexing an event.
+type OpType string
+
+// The values of the MetadataKeyOpType metadata. The op_type field of the
+// event is only OpTypeCreate or OpTypeIndex.
+const (
+	OpTypeCreate OpType = "create"
+	OpTypeIndex  OpType = "index"
+	OpTypeDelete OpType = "delete"
+)
+
+// ID returns the document id of the event: the document_id field, or the
+// MetadataKeyID metadata of the producers that still set it. It's empty if
+// neither is set.
+func (x *Event) ID() string {
+	if id := x.GetDocumentId(); id != "" {
+		return id
+	}
+	return x.metadataString(MetadataKeyID)
+}
+
+// SetID sets the document_id field of the event and removes the
+// MetadataKeyID metadata, so they can't disagree.
+func (x *Event) SetID(id string) {
+	x.DocumentId = id
+	x.setMetadataString(MetadataKeyID, "")
+}
+
+// PipelineHint returns the ingest pipeline of the event: the pipeline field,
+// or the MetadataKeyPipeline metadata of the producers that still set it.
+// It's empty if neither is set.
+func (x *Event) PipelineHint() string {
+	if p := x.GetPipeline(); p != "" {
+		return p
+	}
+	return x.metadataString(MetadataKeyPipeline)
+}
+
+// SetPipeline sets the pipeline field of the event and removes the
+// MetadataKeyPipeline metadata, so they can't disagree.
+func (x *Event) SetPipeline(pipeline string) {
+	x.Pipeline = pipeline
+	x.setMetadataString(MetadataKeyPipeline, "")
+}
+
+// RawIndexHint returns the index the event is written to: the raw_index
+// field, or the MetadataKeyRawIndex metadata of the producers that still set
+// it. It's empty if neither is set.
+func (x *Event) RawIndexHint() string {
+	if i := x.GetRawIndex(); i != "" {
+		return i
+	}
+	return x.metadataString(MetadataKeyRawIndex)
+}
+
+// SetRawIndex sets the raw_index field of the event and removes the
+// MetadataKeyRawIndex metadata, so they can't disagree.
+func (x *Event) SetRawIndex(index string) {
+	x.RawIndex = index
+	x.setMetadataString(MetadataKeyRawIndex, "")
+}
+
+// OpTypeHint returns the write operation of the event: the op_type field, or
+// the MetadataKeyOpType metadata of the producers that still set it. It's
+// empty if neither is set.
+func (x *Event) OpTypeHint() OpType {
+	if op := x.GetOpType(); op != "" {
+		return OpType(op)
+	}
+	return OpType(x.metadataString(MetadataKeyOpType))
+}
+
+// SetOpType sets the op_type field of the event and removes the
+// MetadataKeyOpType metadata, so they can't disagree. Only OpTypeCreate and
+// OpTypeIndex pass Validate.
+func (x *Event) SetOpType(op OpType) {
+	x.OpType = string(op)
+	x.setMetadataString(MetadataKeyOpType, "")
+}
+
+// metadataString returns the string metadata at key, empty if it's not set
+// or isn't a string.
+func (x *Event) metadataString(key string) string {
+	return x.GetMetadata().GetData()[key].GetStringValue()
+}
+
+// setMetadataString sets the string metadata at key, an empty value removes it.
+func (x *Event) setMetadataString(key, value string) {
+	if value == "" {
+		delete(x.GetMetadata().GetData(), key)
+		return
+	}
+	if x.Metadata == nil {
+		x.Metadata = &Struct{}
+	}
+	if x.Metadata.Data == nil {
+		x.Metadata.Data = make(map[string]*Value)
+	}
+	x.Metadata.Data[key] = &Value{Kind: &Value_StringValue{StringValue: value}}
+}
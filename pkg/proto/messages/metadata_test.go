@@ -0,0 +1,67 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataAccessors(t *testing.T) {
+	var e Event
+	require.Empty(t, e.ID())
+	require.Empty(t, e.OpTypeHint())
+
+	// the metadata keys of older producers are still honored
+	e.setMetadataString(MetadataKeyID, "metadata-id")
+	e.setMetadataString(MetadataKeyOpType, string(OpTypeDelete))
+	require.Equal(t, "metadata-id", e.ID())
+	require.Equal(t, OpTypeDelete, e.OpTypeHint())
+
+	// values that aren't strings are ignored
+	e.Metadata.Data[MetadataKeyID] = &Value{Kind: &Value_Int64Value{Int64Value: 1}}
+	require.Empty(t, e.ID())
+
+	// the setters move the values out of the metadata
+	e.SetID("id")
+	e.SetOpType(OpTypeCreate)
+	require.Equal(t, "id", e.GetDocumentId())
+	require.Equal(t, "id", e.ID())
+	require.Equal(t, "create", e.GetOpType())
+	require.Equal(t, OpTypeCreate, e.OpTypeHint())
+	require.Empty(t, e.GetMetadata().GetData())
+
+	var nilEvent *Event
+	require.Empty(t, nilEvent.ID())
+	require.Empty(t, nilEvent.RawIndexHint())
+}
+
+func TestRoutingHints(t *testing.T) {
+	var e Event
+	require.Empty(t, e.PipelineHint())
+	require.Empty(t, e.RawIndexHint())
+
+	// the metadata keys of older producers are still honored
+	e.setMetadataString(MetadataKeyPipeline, "from-metadata")
+	e.setMetadataString(MetadataKeyRawIndex, "index-from-metadata")
+	require.Equal(t, "from-metadata", e.PipelineHint())
+	require.Equal(t, "index-from-metadata", e.RawIndexHint())
+
+	// the fields take precedence
+	e.RawIndex = "index"
+	require.Equal(t, "index", e.RawIndexHint())
+
+	// the setters move the hints out of the metadata
+	e.SetPipeline("pipeline")
+	e.SetRawIndex("index")
+	require.Equal(t, "pipeline", e.GetPipeline())
+	require.Equal(t, "pipeline", e.PipelineHint())
+	require.Equal(t, "index", e.GetRawIndex())
+	require.Empty(t, e.GetMetadata().GetData())
+
+	e.SetPipeline("")
+	require.Empty(t, e.PipelineHint())
+}
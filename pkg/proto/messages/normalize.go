@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The values of the data stream fields that are not set.
+const (
+	DefaultDataStreamType = "logs"
+	DefaultDataset        = "generic"
+	DefaultNamespace      = "default"
+)
+
+// maxDataStreamPart is the maximum length in bytes of each part of a data
+// stream name, the name must fit in the index name limit.
+const maxDataStreamPart = 100
+
+// Normalize applies the defaults every input otherwise applies by hand:
+//   - the event has a data stream, its empty fields get the default values,
+//   - the dataset and the namespace are valid in a data stream name: they are
+//     lowercased, the characters Elasticsearch rejects and the dashes are
+//     replaced by underscores, and they are truncated to 100 bytes,
+//   - the event has a timestamp, the current time if it had none, not even
+//     a raw one, which is left to the shipper to parse,
+//   - empty metadata and fields are removed.
+func (x *Event) Normalize() {
+	if !x.HasTimestamp() {
+		x.Timestamp = timestamppb.Now()
+	}
+
+	if x.DataStream == nil {
+		x.DataStream = &DataStream{}
+	}
+	ds := x.DataStream
+	ds.Type = strings.ToLower(ds.Type)
+	if ds.Type == "" {
+		ds.Type = DefaultDataStreamType
+	}
+	ds.Dataset = sanitizeDataStreamPart(ds.Dataset)
+	if ds.Dataset == "" {
+		ds.Dataset = DefaultDataset
+	}
+	ds.Namespace = sanitizeDataStreamPart(ds.Namespace)
+	if ds.Namespace == "" {
+		ds.Namespace = DefaultNamespace
+	}
+
+	if len(x.Metadata.GetData()) == 0 {
+		x.Metadata = nil
+	}
+	if len(x.Fields.GetData()) == 0 {
+		x.Fields = nil
+	}
+}
+
+// sanitizeDataStreamPart returns s lowercased, with the characters that are
+// not valid in a data stream name replaced by underscores, and truncated to
+// maxDataStreamPart bytes.
+func sanitizeDataStreamPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case strings.ContainsRune(`\/*?"<>|,#:-`, r), unicode.IsSpace(r), r == utf8.RuneError:
+			return '_'
+		}
+		return unicode.ToLower(r)
+	}, s)
+	if len(s) <= maxDataStreamPart {
+		return s
+	}
+	end := maxDataStreamPart
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestNormalize(t *testing.T) {
+	var e Event
+	before := time.Now()
+	e.Normalize()
+	require.False(t, e.GetTimestamp().AsTime().Before(before.Truncate(time.Microsecond)))
+	require.True(t, proto.Equal(&DataStream{Type: "logs", Dataset: "generic", Namespace: "default"}, e.GetDataStream()))
+	require.NoError(t, e.Validate())
+
+	ts := timestamppb.New(time.Unix(10, 0))
+	e = Event{
+		Timestamp:  ts,
+		DataStream: &DataStream{Type: "Metrics", Dataset: "System.CPU-Usage", Namespace: "Team A/B*#1"},
+		Metadata:   &Struct{},
+		Fields:     &Struct{Data: map[string]*Value{"a": {Kind: &Value_BoolValue{BoolValue: true}}}},
+	}
+	e.Normalize()
+	require.Same(t, ts, e.GetTimestamp())
+	require.True(t, proto.Equal(&DataStream{Type: "metrics", Dataset: "system.cpu_usage", Namespace: "team_a_b__1"}, e.GetDataStream()))
+	require.Nil(t, e.GetMetadata())
+	require.Len(t, e.GetFields().GetData(), 1)
+
+	e = Event{DataStream: &DataStream{Dataset: strings.Repeat("a", 99) + "é", Namespace: "-"}, Fields: &Struct{}}
+	e.Normalize()
+	require.Equal(t, strings.Repeat("a", 99), e.GetDataStream().GetDataset())
+	require.True(t, utf8.ValidString(e.GetDataStream().GetDataset()))
+	require.Equal(t, "_", e.GetDataStream().GetNamespace())
+	require.Nil(t, e.GetFields())
+
+	// the raw timestamp is left to the shipper
+	e = Event{TimestampRaw: "2022-01-02T03:04:05Z"}
+	e.Normalize()
+	require.Nil(t, e.GetTimestamp())
+	require.NoError(t, e.Validate())
+}
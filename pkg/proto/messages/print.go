@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"fmt"
+
+	"go.elastic.co/fastjson"
+
+	"github.com/elastic/elastic-agent-shipper-client/internal/unsafeconv"
+)
+
+// PrintMaxStringLength is the length at which the string values are
+// truncated by the StringToPrint methods.
+const PrintMaxStringLength = 256
+
+// printOptions are the JSON options of the StringToPrint methods.
+var printOptions = JSONOptions{
+	NonFinite:         NonFiniteString,
+	DisableHTMLEscape: true,
+	SortKeys:          true,
+	MaxStringLength:   PrintMaxStringLength,
+}
+
+// StringToPrint returns the value as compact JSON for logs and test
+// failures, see Struct.StringToPrint. The String method generated by
+// protoc-gen-go returns the protobuf text format.
+func (val *Value) StringToPrint() string {
+	return toPrint(val, func(w *fastjson.Writer) error { return printOptions.MarshalValue(w, val) })
+}
+
+// StringToPrint returns the struct as compact JSON for logs and test
+// failures, with sorted keys and the long strings truncated. The values
+// that have no JSON representation, like the string references, make it
+// fall back to the protobuf text format.
+func (sv *Struct) StringToPrint() string {
+	return toPrint(sv, func(w *fastjson.Writer) error { return printOptions.MarshalStruct(w, sv) })
+}
+
+// StringToPrint returns the list as compact JSON, see Struct.StringToPrint.
+func (lv *ListValue) StringToPrint() string {
+	return toPrint(lv, func(w *fastjson.Writer) error { return printOptions.MarshalList(w, lv) })
+}
+
+// StringToPrint returns the event as compact JSON, see Struct.StringToPrint.
+func (e *Event) StringToPrint() string {
+	return toPrint(e, func(w *fastjson.Writer) error { return printOptions.MarshalEvent(w, e) })
+}
+
+func toPrint(m fmt.Stringer, marshal func(w *fastjson.Writer) error) string {
+	var w fastjson.Writer
+	if err := marshal(&w); err != nil {
+		return m.String()
+	}
+	return unsafeconv.String(w.Bytes())
+}
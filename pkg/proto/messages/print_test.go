@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestStringToPrint(t *testing.T) {
+	long := strings.Repeat("a", PrintMaxStringLength-1) + "éb"
+	s := &Struct{Data: map[string]*Value{
+		"z":    {Kind: &Value_StringValue{StringValue: "<last>"}},
+		"a":    {Kind: &Value_Int64Value{Int64Value: 1}},
+		"nan":  {Kind: &Value_Float64Value{Float64Value: math.NaN()}},
+		"long": {Kind: &Value_StringValue{StringValue: long}},
+		"m": {Kind: &Value_StructValue{StructValue: &Struct{Data: map[string]*Value{
+			"y": {Kind: &Value_BoolValue{BoolValue: true}},
+			"x": {Kind: &Value_NullValue{}},
+		}}}},
+	}}
+	// the é doesn't fit, it's not cut in the middle
+	want := `{"a":1,"long":"` + long[:PrintMaxStringLength-1] + `…","m":{"x":null,"y":true},"nan":"NaN","z":"<last>"}`
+	for i := 0; i < 10; i++ {
+		require.Equal(t, want, s.StringToPrint())
+	}
+	require.Equal(t, `{"x":null,"y":true}`, s.GetData()["m"].StringToPrint())
+	require.Equal(t, `[1]`, (&ListValue{Values: []*Value{s.GetData()["a"]}}).StringToPrint())
+
+	e := &Event{
+		Timestamp: timestamppb.New(time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		Fields:    &Struct{Data: map[string]*Value{"b": s.GetData()["a"], "a": s.GetData()["z"]}},
+	}
+	require.Equal(t, `{"timestamp":"2022-01-01T00:00:00Z","fields":{"a":"<last>","b":1}}`, e.StringToPrint())
+
+	// no JSON representation, the protobuf text format
+	ref := &Value{Kind: &Value_StringRef{StringRef: 1}}
+	require.Equal(t, ref.String(), ref.StringToPrint())
+}
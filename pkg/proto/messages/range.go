@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import "sort"
+
+// Len returns the number of keys of the struct.
+func (sv *Struct) Len() int {
+	return len(sv.GetData())
+}
+
+// Range calls f with the keys and the values of the struct, in no
+// particular order, until f returns false. Walking the struct with Range
+// rather than its Data map keeps the callers working when the
+// representation of the structs changes.
+func (sv *Struct) Range(f func(key string, v *Value) bool) {
+	for k, v := range sv.GetData() {
+		if !f(k, v) {
+			return
+		}
+	}
+}
+
+// RangeSorted is Range with the keys in sorted order, for a reproducible
+// output.
+func (sv *Struct) RangeSorted(f func(key string, v *Value) bool) {
+	keys := make([]string, 0, len(sv.GetData()))
+	for k := range sv.GetData() {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !f(k, sv.GetData()[k]) {
+			return
+		}
+	}
+}
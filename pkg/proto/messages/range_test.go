@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRange(t *testing.T) {
+	s := &Struct{Data: map[string]*Value{}}
+	for _, k := range []string{"c", "a", "d", "b"} {
+		s.Data[k] = &Value{Kind: &Value_StringValue{StringValue: k}}
+	}
+	require.Equal(t, 4, s.Len())
+
+	seen := map[string]bool{}
+	s.Range(func(key string, v *Value) bool {
+		require.Equal(t, key, v.GetStringValue())
+		seen[key] = true
+		return true
+	})
+	require.Len(t, seen, 4)
+
+	var keys []string
+	s.RangeSorted(func(key string, v *Value) bool {
+		keys = append(keys, key)
+		return key != "c"
+	})
+	require.Equal(t, []string{"a", "b", "c"}, keys, "stopped after c")
+
+	calls := 0
+	s.Range(func(string, *Value) bool {
+		calls++
+		return false
+	})
+	require.Equal(t, 1, calls)
+
+	var nilStruct *Struct
+	require.Equal(t, 0, nilStruct.Len())
+	nilStruct.Range(func(string, *Value) bool { panic("no keys") })
+	nilStruct.RangeSorted(func(string, *Value) bool { panic("no keys") })
+}
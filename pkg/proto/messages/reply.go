@@ -0,0 +1,23 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+// EventIndex returns the index assigned to the i-th accepted event of the
+// request, zero if it wasn't accepted.
+//
+// Without event_indexes, the index is derived from accepted_index: the
+// shipper assigns consecutive indexes to the events of a request.
+func (x *PublishReply) EventIndex(i int) uint64 {
+	if i < 0 || i >= int(x.GetAcceptedCount()) {
+		return 0
+	}
+	if indexes := x.GetEventIndexes(); len(indexes) > 0 {
+		if i < len(indexes) {
+			return indexes[i]
+		}
+		return 0
+	}
+	return x.GetAcceptedIndex() - uint64(x.GetAcceptedCount()) + 1 + uint64(i)
+}
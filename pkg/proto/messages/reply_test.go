@@ -0,0 +1,25 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventIndex(t *testing.T) {
+	derived := &PublishReply{AcceptedCount: 3, AcceptedIndex: 12}
+	require.Equal(t, uint64(10), derived.EventIndex(0))
+	require.Equal(t, uint64(12), derived.EventIndex(2))
+	require.Zero(t, derived.EventIndex(3))
+	require.Zero(t, derived.EventIndex(-1))
+
+	explicit := &PublishReply{AcceptedCount: 3, AcceptedIndex: 30, EventIndexes: []uint64{1, 2, 30}}
+	require.Equal(t, uint64(2), explicit.EventIndex(1))
+	require.Equal(t, uint64(30), explicit.EventIndex(2))
+
+	require.Zero(t, (&PublishReply{}).EventIndex(0))
+}
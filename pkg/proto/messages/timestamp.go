@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// HasTimestamp returns true if the event has a timestamp, parsed or raw.
+func (x *Event) HasTimestamp() bool {
+	return x.GetTimestamp() != nil || x.GetTimestampRaw() != ""
+}
+
+// ParseTimestampRaw sets the timestamp of the event from its timestamp_raw
+// when it has none, trying the layouts in order, time.RFC3339Nano when none
+// is given. The timestamp_raw is kept. It does nothing if the event already
+// has a timestamp or has no raw one.
+func (x *Event) ParseTimestampRaw(layouts ...string) error {
+	if x.GetTimestamp() != nil || x.GetTimestampRaw() == "" {
+		return nil
+	}
+	if len(layouts) == 0 {
+		layouts = []string{time.RFC3339Nano}
+	}
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, x.TimestampRaw); err == nil {
+			x.Timestamp = timestamppb.New(t)
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to parse the raw timestamp %q: %w", x.TimestampRaw, err)
+}
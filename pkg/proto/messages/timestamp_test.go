@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestParseTimestampRaw(t *testing.T) {
+	var e Event
+	require.False(t, e.HasTimestamp())
+	require.NoError(t, e.ParseTimestampRaw())
+	require.Nil(t, e.GetTimestamp())
+
+	e.TimestampRaw = "2022-01-02T03:04:05.123456789Z"
+	require.True(t, e.HasTimestamp())
+	require.NoError(t, e.ParseTimestampRaw())
+	require.Equal(t, time.Date(2022, 1, 2, 3, 4, 5, 123456789, time.UTC), e.GetTimestamp().AsTime())
+	require.Equal(t, "2022-01-02T03:04:05.123456789Z", e.GetTimestampRaw())
+
+	// the parsed timestamp is kept
+	e.TimestampRaw = "garbage"
+	require.NoError(t, e.ParseTimestampRaw())
+
+	e = Event{TimestampRaw: "02/Jan/2022:03:04:05 +0000"}
+	require.Error(t, e.ParseTimestampRaw())
+	require.Nil(t, e.GetTimestamp())
+	require.NoError(t, e.ParseTimestampRaw(time.RFC3339, "02/Jan/2006:15:04:05 -0700"))
+	require.True(t, e.GetTimestamp().AsTime().Equal(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+	e = Event{Timestamp: timestamppb.Now()}
+	require.True(t, e.HasTimestamp())
+}
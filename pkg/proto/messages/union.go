@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Sizes of the trace identifiers, as in W3C Trace Context.
+const (
+	TraceIDSize = 16
+	SpanIDSize  = 8
+)
+
+// WrapEvent returns the union holding the log event.
+func WrapEvent(e *Event) *EventUnion {
+	return &EventUnion{Kind: &EventUnion_Event{Event: e}}
+}
+
+// WrapMetric returns the union holding the metric sample.
+func WrapMetric(m *MetricEvent) *EventUnion {
+	return &EventUnion{Kind: &EventUnion_Metric{Metric: m}}
+}
+
+// WrapSpan returns the union holding the span.
+func WrapSpan(s *Span) *EventUnion {
+	return &EventUnion{Kind: &EventUnion_Span{Span: s}}
+}
+
+// Unwrap returns the *Event, *MetricEvent or *Span held by the union, nil
+// when it's empty.
+func (x *EventUnion) Unwrap() proto.Message {
+	switch kind := x.GetKind().(type) {
+	case *EventUnion_Event:
+		return kind.Event
+	case *EventUnion_Metric:
+		return kind.Metric
+	case *EventUnion_Span:
+		return kind.Span
+	}
+	return nil
+}
+
+// Validate checks the event held by the union with its Validate method. It
+// returns a *ValidationError for the first invalid field.
+func (x *EventUnion) Validate() error {
+	switch kind := x.GetKind().(type) {
+	case *EventUnion_Event:
+		return prefixField("event", kind.Event.Validate())
+	case *EventUnion_Metric:
+		return prefixField("metric", kind.Metric.Validate())
+	case *EventUnion_Span:
+		return prefixField("span", kind.Span.Validate())
+	}
+	return &ValidationError{Field: "kind", Reason: "required"}
+}
+
+// Validate checks the span against the rules of the API: it has a trace ID
+// and a span ID of the right sizes, a name and a start time, and its data
+// stream, if any, has a valid name. It returns a *ValidationError for the
+// first invalid field.
+func (x *Span) Validate() error {
+	switch {
+	case len(x.GetTraceId()) != TraceIDSize:
+		return &ValidationError{Field: "trace_id", Reason: fmt.Sprintf("must be %d bytes", TraceIDSize)}
+	case len(x.GetSpanId()) != SpanIDSize:
+		return &ValidationError{Field: "span_id", Reason: fmt.Sprintf("must be %d bytes", SpanIDSize)}
+	case len(x.GetParentSpanId()) != 0 && len(x.GetParentSpanId()) != SpanIDSize:
+		return &ValidationError{Field: "parent_span_id", Reason: fmt.Sprintf("must be empty or %d bytes", SpanIDSize)}
+	case x.GetName() == "":
+		return &ValidationError{Field: "name", Reason: "must not be empty"}
+	case x.GetStartTime() == nil:
+		return &ValidationError{Field: "start_time", Reason: "required"}
+	}
+	if x.GetDataStream() != nil {
+		if err := x.GetDataStream().ValidateName(); err != nil {
+			return prefixField("data_stream", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEventUnion(t *testing.T) {
+	e := &Event{}
+	m := &MetricEvent{}
+	s := &Span{}
+	require.Same(t, e, WrapEvent(e).Unwrap())
+	require.Same(t, m, WrapMetric(m).Unwrap())
+	require.Same(t, s, WrapSpan(s).Unwrap())
+	require.Same(t, s, WrapSpan(s).GetSpan())
+	require.Nil(t, (&EventUnion{}).Unwrap())
+}
+
+func TestEventUnionValidate(t *testing.T) {
+	span := func() *Span {
+		return &Span{
+			TraceId:   make([]byte, TraceIDSize),
+			SpanId:    make([]byte, SpanIDSize),
+			Name:      "GET /",
+			StartTime: timestamppb.Now(),
+		}
+	}
+	require.NoError(t, WrapSpan(span()).Validate())
+
+	invalid := []struct {
+		union *EventUnion
+		field string
+	}{
+		{&EventUnion{}, "kind"},
+		{WrapEvent(&Event{}), "event.timestamp"},
+		{WrapMetric(&MetricEvent{Timestamp: timestamppb.Now()}), "metric.name"},
+		{WrapSpan(&Span{}), "span.trace_id"},
+		{WrapSpan(func() *Span { s := span(); s.ParentSpanId = []byte{1}; return s }()), "span.parent_span_id"},
+		{WrapSpan(func() *Span { s := span(); s.StartTime = nil; return s }()), "span.start_time"},
+		{WrapSpan(func() *Span { s := span(); s.DataStream = &DataStream{Type: "Traces"}; return s }()), "span.data_stream.type"},
+	}
+	for _, tc := range invalid {
+		var verr *ValidationError
+		require.True(t, errors.As(tc.union.Validate(), &verr), tc.field)
+		require.Equal(t, tc.field, verr.Field)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+// Update copies the values of other into the struct, like mapstr.M.Update
+// does. The existing keys are only replaced when overwrite is set.
+//
+// The values are not copied, they are shared by both structs afterwards.
+func (sv *Struct) Update(other *Struct, overwrite bool) {
+	if sv.Data == nil {
+		sv.Data = make(map[string]*Value, len(other.GetData()))
+	}
+	for k, v := range other.GetData() {
+		if _, exists := sv.Data[k]; overwrite || !exists {
+			sv.Data[k] = v
+		}
+	}
+}
+
+// DeepUpdate copies the values of other into the struct, merging the nested
+// structs present in both, like mapstr.M.DeepUpdate does, or
+// mapstr.M.DeepUpdateNoOverwrite without overwrite.
+//
+// As with mapstr, without overwrite the existing values are kept, except the
+// ones that aren't structs where other has a struct: those are replaced.
+//
+// The values are not copied, they are shared by both structs afterwards.
+func (sv *Struct) DeepUpdate(other *Struct, overwrite bool) {
+	if sv.Data == nil {
+		sv.Data = make(map[string]*Value, len(other.GetData()))
+	}
+	for k, v := range other.GetData() {
+		if s := v.GetStructValue(); s != nil {
+			if old := sv.Data[k].GetStructValue(); old != nil {
+				old.DeepUpdate(s, overwrite)
+			} else {
+				sv.Data[k] = v
+			}
+			continue
+		}
+		if _, exists := sv.Data[k]; overwrite || !exists {
+			sv.Data[k] = v
+		}
+	}
+}
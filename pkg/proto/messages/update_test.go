@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestUpdate(t *testing.T) {
+	str := func(s string) *Value { return &Value{Kind: &Value_StringValue{StringValue: s}} }
+	obj := func(data map[string]*Value) *Value {
+		return &Value{Kind: &Value_StructValue{StructValue: &Struct{Data: data}}}
+	}
+	base := func() *Struct {
+		return &Struct{Data: map[string]*Value{
+			"message": str("hello"),
+			"host":    obj(map[string]*Value{"name": str("h")}),
+			"labels":  str("none"),
+		}}
+	}
+	other := &Struct{Data: map[string]*Value{
+		"message": str("world"),
+		"host":    obj(map[string]*Value{"ip": str("127.0.0.1"), "name": str("other")}),
+		"labels":  obj(map[string]*Value{"env": str("prod")}),
+		"new":     str("x"),
+	}}
+
+	tests := []struct {
+		name   string
+		update func(s *Struct)
+		want   *Struct
+	}{
+		{
+			name:   "update",
+			update: func(s *Struct) { s.Update(other, true) },
+			want:   other,
+		},
+		{
+			name:   "update without overwrite",
+			update: func(s *Struct) { s.Update(other, false) },
+			want: &Struct{Data: map[string]*Value{
+				"message": str("hello"),
+				"host":    obj(map[string]*Value{"name": str("h")}),
+				"labels":  str("none"),
+				"new":     str("x"),
+			}},
+		},
+		{
+			name:   "deep update",
+			update: func(s *Struct) { s.DeepUpdate(other, true) },
+			want: &Struct{Data: map[string]*Value{
+				"message": str("world"),
+				"host":    obj(map[string]*Value{"ip": str("127.0.0.1"), "name": str("other")}),
+				"labels":  obj(map[string]*Value{"env": str("prod")}),
+				"new":     str("x"),
+			}},
+		},
+		{
+			name:   "deep update without overwrite",
+			update: func(s *Struct) { s.DeepUpdate(other, false) },
+			want: &Struct{Data: map[string]*Value{
+				"message": str("hello"),
+				"host":    obj(map[string]*Value{"ip": str("127.0.0.1"), "name": str("h")}),
+				"labels":  obj(map[string]*Value{"env": str("prod")}),
+				"new":     str("x"),
+			}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := base()
+			tc.update(s)
+			require.True(t, proto.Equal(tc.want, s), "got %v", s)
+		})
+	}
+
+	var empty Struct
+	empty.DeepUpdate(other, false)
+	require.True(t, proto.Equal(other, &empty))
+}
@@ -0,0 +1,286 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxPublishEvents is the maximum number of events in a PublishRequest.
+const MaxPublishEvents = 10000
+
+// MaxCursorBytes is the maximum size of the cursor of a PublishRequest.
+const MaxCursorBytes = 64 * 1024
+
+// ValidationError is returned by the Validate methods when a field of a
+// message breaks a rule of the API.
+type ValidationError struct {
+	// Field is the path of the invalid field, e.g. `events[2].timestamp`.
+	Field string
+	// Reason explains the rule the field breaks.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+// Len returns the number of events, metric samples or signals of the
+// request: the unit of the accepted count and of the indexes of the replies.
+// Compressed events are counted with their count field.
+func (x *PublishRequest) Len() int {
+	return len(x.GetEvents()) + len(x.GetMetrics()) + len(x.GetSignals()) + int(x.GetCompressedEvents().GetCount())
+}
+
+// Validate checks the request against the rules of the API: it has between 1
+// and MaxPublishEvents events, metric samples or signals, every one of them
+// is valid, the references of the events are in the string table, and its
+// cursor is at most MaxCursorBytes. Compressed events are only checked to
+// have a codec, data and at most MaxPublishEvents events, they can't be
+// checked before they are unpacked. It returns a *ValidationError for the
+// first invalid field.
+func (x *PublishRequest) Validate() error {
+	lists := []struct {
+		field    string
+		len      int
+		validate func(i int) error
+	}{
+		{"events", len(x.GetEvents()), func(i int) error { return x.Events[i].Validate() }},
+		{"metrics", len(x.GetMetrics()), func(i int) error { return x.Metrics[i].Validate() }},
+		{"signals", len(x.GetSignals()), func(i int) error { return x.Signals[i].Validate() }},
+	}
+
+	if n := len(x.GetCursor()); n > MaxCursorBytes {
+		return &ValidationError{Field: "cursor", Reason: fmt.Sprintf("%d bytes, the maximum is %d", n, MaxCursorBytes)}
+	}
+
+	if packed := x.GetCompressedEvents(); packed != nil {
+		for _, l := range lists {
+			if l.len > 0 {
+				return &ValidationError{Field: "compressed_events", Reason: "mutually exclusive with " + l.field}
+			}
+		}
+		switch {
+		case len(x.GetStringTable()) > 0:
+			return &ValidationError{Field: "string_table", Reason: "must be compressed with the events"}
+		case packed.GetCodec() == "":
+			return &ValidationError{Field: "compressed_events.codec", Reason: "must not be empty"}
+		case len(packed.GetData()) == 0:
+			return &ValidationError{Field: "compressed_events.data", Reason: "must not be empty"}
+		case packed.GetCount() > MaxPublishEvents:
+			return &ValidationError{Field: "compressed_events.count", Reason: fmt.Sprintf("%d events, the maximum is %d", packed.GetCount(), MaxPublishEvents)}
+		}
+		return nil
+	}
+
+	found := -1
+	for i, l := range lists {
+		if l.len == 0 {
+			continue
+		}
+		if found >= 0 {
+			return &ValidationError{Field: l.field, Reason: "mutually exclusive with " + lists[found].field}
+		}
+		found = i
+	}
+	if found < 0 {
+		return &ValidationError{Field: "events", Reason: "at least one event is required"}
+	}
+	l := lists[found]
+	if l.len > MaxPublishEvents {
+		return &ValidationError{Field: l.field, Reason: fmt.Sprintf("%d events, the maximum is %d", l.len, MaxPublishEvents)}
+	}
+	for i := 0; i < l.len; i++ {
+		if err := l.validate(i); err != nil {
+			return prefixField(fmt.Sprintf("%s[%d]", l.field, i), err)
+		}
+	}
+	for i, e := range x.GetEvents() {
+		if err := e.validateRefs(len(x.GetStringTable())); err != nil {
+			return prefixField(fmt.Sprintf("events[%d]", i), err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the event against the rules of the API: it has a timestamp,
+// parsed or raw, a valid data stream with a valid name, and its op_type, if
+// any, is "create" or "index". It returns a *ValidationError for the first
+// invalid field.
+func (x *Event) Validate() error {
+	if !x.HasTimestamp() {
+		return &ValidationError{Field: "timestamp", Reason: "required"}
+	}
+	if x.GetDataStream() == nil {
+		return &ValidationError{Field: "data_stream", Reason: "required"}
+	}
+	if err := x.GetDataStream().Validate(); err != nil {
+		return prefixField("data_stream", err)
+	}
+	switch OpType(x.GetOpType()) {
+	case "", OpTypeCreate, OpTypeIndex:
+	default:
+		return &ValidationError{Field: "op_type", Reason: fmt.Sprintf("must be %q or %q, got %q", OpTypeCreate, OpTypeIndex, x.GetOpType())}
+	}
+	return nil
+}
+
+// Validate checks the metric sample against the rules of the API: it has a
+// timestamp and a name, and its data stream, if any, has a valid name. It
+// returns a *ValidationError for the first invalid field.
+func (x *MetricEvent) Validate() error {
+	if x.GetTimestamp() == nil {
+		return &ValidationError{Field: "timestamp", Reason: "required"}
+	}
+	if x.GetName() == "" {
+		return &ValidationError{Field: "name", Reason: "must not be empty"}
+	}
+	if x.GetDataStream() != nil {
+		if err := x.GetDataStream().ValidateName(); err != nil {
+			return prefixField("data_stream", err)
+		}
+	}
+	return nil
+}
+
+// Validate checks the data stream against the rules of the API: the type, the
+// dataset and the namespace are not empty, or are references to the string
+// table, and the name is valid, see ValidateName. It returns a
+// *ValidationError for the first invalid field.
+func (x *DataStream) Validate() error {
+	for _, p := range []struct {
+		field string
+		value string
+		ref   uint32
+	}{
+		{"type", x.GetType(), x.GetTypeRef()},
+		{"dataset", x.GetDataset(), x.GetDatasetRef()},
+		{"namespace", x.GetNamespace(), x.GetNamespaceRef()},
+	} {
+		if p.value == "" && p.ref == 0 {
+			return &ValidationError{Field: p.field, Reason: "must not be empty"}
+		}
+	}
+	return x.ValidateName()
+}
+
+// validateRefs checks that the references of the event are in the string
+// table of the request, of n strings.
+func (x *Event) validateRefs(n int) error {
+	ds := x.GetDataStream()
+	for _, f := range []struct {
+		field string
+		ref   uint32
+	}{
+		{"type_ref", ds.GetTypeRef()},
+		{"dataset_ref", ds.GetDatasetRef()},
+		{"namespace_ref", ds.GetNamespaceRef()},
+	} {
+		if err := validateRef(f.field, f.ref, n); err != nil {
+			return prefixField("data_stream", err)
+		}
+	}
+	if err := validateStructRefs(x.GetMetadata(), n); err != nil {
+		return prefixField("metadata", err)
+	}
+	if err := validateStructRefs(x.GetFields(), n); err != nil {
+		return prefixField("fields", err)
+	}
+	return nil
+}
+
+func validateRef(field string, ref uint32, n int) error {
+	if int64(ref) > int64(n) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("the reference %d is not in the string table of %d strings", ref, n)}
+	}
+	return nil
+}
+
+func validateStructRefs(s *Struct, n int) error {
+	for k, v := range s.GetData() {
+		if err := validateValueRefs(v, n); err != nil {
+			return prefixField(k, err)
+		}
+	}
+	for ref, v := range s.GetRefData() {
+		field := fmt.Sprintf("ref_data[%d]", ref)
+		if ref == 0 {
+			return &ValidationError{Field: field, Reason: "the reference 0 is not in the string table"}
+		}
+		if err := validateRef(field, ref, n); err != nil {
+			return err
+		}
+		if err := validateValueRefs(v, n); err != nil {
+			return prefixField(field, err)
+		}
+	}
+	return nil
+}
+
+func validateValueRefs(v *Value, n int) error {
+	switch kind := v.GetKind().(type) {
+	case *Value_StringRef:
+		if kind.StringRef == 0 {
+			return &ValidationError{Field: "string_ref", Reason: "the reference 0 is not in the string table"}
+		}
+		return validateRef("string_ref", kind.StringRef, n)
+	case *Value_StructValue:
+		return validateStructRefs(kind.StructValue, n)
+	case *Value_ListValue:
+		for i, item := range kind.ListValue.GetValues() {
+			if err := validateValueRefs(item, n); err != nil {
+				return prefixField(fmt.Sprintf("[%d]", i), err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateName checks the parts of the data stream name against the
+// Elasticsearch data stream naming scheme. Empty parts are allowed, the
+// shipper sets the default value of the optional data streams of the metric
+// samples and the spans. It returns a *ValidationError for the first invalid
+// field.
+func (x *DataStream) ValidateName() error {
+	parts := []struct {
+		field, value string
+	}{
+		{"type", x.GetType()},
+		{"dataset", x.GetDataset()},
+		{"namespace", x.GetNamespace()},
+	}
+	for _, p := range parts {
+		if p.value == "" {
+			continue
+		}
+		if len(p.value) > maxDataStreamPart {
+			return &ValidationError{Field: p.field, Reason: fmt.Sprintf("longer than %d bytes", maxDataStreamPart)}
+		}
+		if strings.ToLower(p.value) != p.value {
+			return &ValidationError{Field: p.field, Reason: "must be lowercase"}
+		}
+		if strings.ContainsAny(p.value, `\/*?"<>| ,#:-`) {
+			return &ValidationError{Field: p.field, Reason: `must not contain \, /, *, ?, ", <, >, |, space, comma, #, : or -`}
+		}
+		if strings.HasPrefix(p.value, "_") || strings.HasPrefix(p.value, "+") || strings.HasPrefix(p.value, ".") {
+			return &ValidationError{Field: p.field, Reason: "must not start with _, + or ."}
+		}
+	}
+	return nil
+}
+
+// prefixField adds the path of the parent message to a ValidationError.
+func prefixField(prefix string, err error) error {
+	if v, ok := err.(*ValidationError); ok {
+		sep := "."
+		if strings.HasPrefix(v.Field, "[") {
+			// an index of a list
+			sep = ""
+		}
+		return &ValidationError{Field: prefix + sep + v.Field, Reason: v.Reason}
+	}
+	return err
+}
@@ -0,0 +1,255 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package messages
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestValidate(t *testing.T) {
+	valid := func() *Event {
+		return &Event{
+			Timestamp:  timestamppb.Now(),
+			DataStream: &DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		req   *PublishRequest
+		field string
+	}{
+		{
+			name: "valid",
+			req:  &PublishRequest{Events: []*Event{valid(), valid()}},
+		},
+		{
+			name:  "no events",
+			req:   &PublishRequest{},
+			field: "events",
+		},
+		{
+			name:  "too many events",
+			req:   &PublishRequest{Events: make([]*Event, MaxPublishEvents+1)},
+			field: "events",
+		},
+		{
+			name: "no timestamp",
+			req: &PublishRequest{Events: []*Event{valid(), {
+				DataStream: &DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+			}}},
+			field: "events[1].timestamp",
+		},
+		{
+			name: "no data stream",
+			req: &PublishRequest{Events: []*Event{{
+				Timestamp: timestamppb.Now(),
+			}}},
+			field: "events[0].data_stream",
+		},
+		{
+			name: "raw timestamp",
+			req: &PublishRequest{Events: []*Event{{
+				TimestampRaw: "2022-01-02T03:04:05Z",
+				DataStream:   &DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+			}}},
+		},
+		{
+			name: "idempotent write",
+			req: &PublishRequest{Events: []*Event{{
+				Timestamp:  timestamppb.Now(),
+				DataStream: &DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+				OpType:     string(OpTypeCreate),
+				DocumentId: "id",
+			}}},
+		},
+		{
+			name: "invalid op type",
+			req: &PublishRequest{Events: []*Event{valid(), {
+				Timestamp:  timestamppb.Now(),
+				DataStream: &DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+				OpType:     string(OpTypeDelete),
+			}}},
+			field: "events[1].op_type",
+		},
+		{
+			name: "compressed",
+			req:  &PublishRequest{CompressedEvents: &CompressedEvents{Codec: "gzip", Data: []byte{1}}},
+		},
+		{
+			name: "compressed and events",
+			req: &PublishRequest{
+				Events:           []*Event{valid()},
+				CompressedEvents: &CompressedEvents{Codec: "gzip", Data: []byte{1}},
+			},
+			field: "compressed_events",
+		},
+		{
+			name:  "compressed without codec",
+			req:   &PublishRequest{CompressedEvents: &CompressedEvents{Data: []byte{1}}},
+			field: "compressed_events.codec",
+		},
+		{
+			name: "empty dataset",
+			req: &PublishRequest{Events: []*Event{{
+				Timestamp:  timestamppb.Now(),
+				DataStream: &DataStream{Type: "logs", Namespace: "default"},
+			}}},
+			field: "events[0].data_stream.dataset",
+		},
+		{
+			name: "empty namespace",
+			req: &PublishRequest{Events: []*Event{{
+				Timestamp:  timestamppb.Now(),
+				DataStream: &DataStream{Type: "logs", Dataset: "generic"},
+			}}},
+			field: "events[0].data_stream.namespace",
+		},
+		{
+			name: "invalid data stream name",
+			req: &PublishRequest{Events: []*Event{valid(), {
+				Timestamp:  timestamppb.Now(),
+				DataStream: &DataStream{Type: "logs", Dataset: "nginx-access", Namespace: "default"},
+			}}},
+			field: "events[1].data_stream.dataset",
+		},
+		{
+			name: "string table",
+			req: &PublishRequest{
+				StringTable: []string{"generic", "host"},
+				Events: []*Event{{
+					Timestamp:  timestamppb.Now(),
+					DataStream: &DataStream{Type: "logs", DatasetRef: 1, Namespace: "default"},
+					Fields: &Struct{
+						Data:    map[string]*Value{"tags": {Kind: &Value_ListValue{ListValue: &ListValue{Values: []*Value{{Kind: &Value_StringRef{StringRef: 2}}}}}}},
+						RefData: map[uint32]*Value{2: {Kind: &Value_StringRef{StringRef: 1}}},
+					},
+				}},
+			},
+		},
+		{
+			name: "data stream reference out of the table",
+			req: &PublishRequest{
+				StringTable: []string{"generic"},
+				Events:      []*Event{{Timestamp: timestamppb.Now(), DataStream: &DataStream{Type: "logs", DatasetRef: 1, NamespaceRef: 2}}},
+			},
+			field: "events[0].data_stream.namespace_ref",
+		},
+		{
+			name: "value reference out of the table",
+			req: &PublishRequest{
+				StringTable: []string{"generic"},
+				Events: []*Event{valid(), {
+					Timestamp:  timestamppb.Now(),
+					DataStream: &DataStream{Type: "logs", DatasetRef: 1, Namespace: "default"},
+					Fields: &Struct{Data: map[string]*Value{
+						"tags": {Kind: &Value_ListValue{ListValue: &ListValue{Values: []*Value{{Kind: &Value_StringRef{StringRef: 2}}}}}},
+					}},
+				}},
+			},
+			field: "events[1].fields.tags[0].string_ref",
+		},
+		{
+			name: "key reference without table",
+			req: &PublishRequest{Events: []*Event{{
+				Timestamp:  timestamppb.Now(),
+				DataStream: &DataStream{Type: "logs", Dataset: "generic", Namespace: "default"},
+				Metadata:   &Struct{RefData: map[uint32]*Value{1: {Kind: &Value_NullValue{}}}},
+			}}},
+			field: "events[0].metadata.ref_data[1]",
+		},
+		{
+			name:  "compressed with count",
+			req:   &PublishRequest{CompressedEvents: &CompressedEvents{Codec: "gzip", Data: []byte{1}, Count: MaxPublishEvents + 1}},
+			field: "compressed_events.count",
+		},
+		{
+			name: "compressed and string table",
+			req: &PublishRequest{
+				StringTable:      []string{"generic"},
+				CompressedEvents: &CompressedEvents{Codec: "gzip", Data: []byte{1}},
+			},
+			field: "string_table",
+		},
+		{
+			name: "metrics",
+			req:  &PublishRequest{Metrics: []*MetricEvent{{Timestamp: timestamppb.Now(), Name: "cpu"}}},
+		},
+		{
+			name: "metrics and events",
+			req: &PublishRequest{
+				Events:  []*Event{valid()},
+				Metrics: []*MetricEvent{{Timestamp: timestamppb.Now(), Name: "cpu"}},
+			},
+			field: "metrics",
+		},
+		{
+			name: "metric without name",
+			req: &PublishRequest{Metrics: []*MetricEvent{
+				{Timestamp: timestamppb.Now(), Name: "cpu"},
+				{Timestamp: timestamppb.Now()},
+			}},
+			field: "metrics[1].name",
+		},
+		{
+			name: "metric with invalid data stream",
+			req: &PublishRequest{Metrics: []*MetricEvent{{
+				Timestamp:  timestamppb.Now(),
+				Name:       "cpu",
+				DataStream: &DataStream{Namespace: "Prod"},
+			}}},
+			field: "metrics[0].data_stream.namespace",
+		},
+		{
+			name: "signals",
+			req:  &PublishRequest{Signals: []*EventUnion{WrapEvent(valid()), WrapMetric(&MetricEvent{Timestamp: timestamppb.Now(), Name: "cpu"})}},
+		},
+		{
+			name: "signals and metrics",
+			req: &PublishRequest{
+				Metrics: []*MetricEvent{{Timestamp: timestamppb.Now(), Name: "cpu"}},
+				Signals: []*EventUnion{WrapEvent(valid())},
+			},
+			field: "signals",
+		},
+		{
+			name:  "invalid signal",
+			req:   &PublishRequest{Signals: []*EventUnion{WrapEvent(valid()), WrapEvent(&Event{})}},
+			field: "signals[1].event.timestamp",
+		},
+		{
+			name: "cursor",
+			req:  &PublishRequest{Events: []*Event{valid()}, Cursor: make([]byte, MaxCursorBytes), CursorKey: "file"},
+		},
+		{
+			name:  "cursor too large",
+			req:   &PublishRequest{Events: []*Event{valid()}, Cursor: make([]byte, MaxCursorBytes+1)},
+			field: "cursor",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.req.Validate()
+			if tc.field == "" {
+				require.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), "unexpected error %v", err)
+			require.Equal(t, tc.field, verr.Field)
+		})
+	}
+}
+
+func TestPublishRequestLen(t *testing.T) {
+	require.Equal(t, 0, (*PublishRequest)(nil).Len())
+	require.Equal(t, 2, (&PublishRequest{Events: make([]*Event, 2)}).Len())
+	require.Equal(t, 3, (&PublishRequest{CompressedEvents: &CompressedEvents{Count: 3}}).Len())
+}
@@ -0,0 +1,12 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package mocks contains gomock mocks of the gRPC interfaces of the proto
+// package, generated by `mage mocks`.
+//
+// ProducerServer wraps the generated MockProducerServer so it can be
+// registered on a gRPC server.
+package mocks
+
+//go:generate go run github.com/golang/mock/mockgen -destination=shipper_grpc.go -package=mocks github.com/elastic/elastic-agent-shipper-client/pkg/proto ProducerClient,Producer_PersistedIndexClient,ProducerServer,Producer_PersistedIndexServer
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mocks
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestProducerClientMock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	stream := NewMockProducer_PersistedIndexClient(ctrl)
+	gomock.InOrder(
+		stream.EXPECT().Recv().Return(&messages.PersistedIndexReply{Uuid: "a", PersistedIndex: 2}, nil),
+		stream.EXPECT().Recv().Return(nil, io.EOF),
+	)
+	mock := NewMockProducerClient(ctrl)
+	mock.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *messages.PublishRequest, _ ...grpc.CallOption) (*messages.PublishReply, error) {
+			return &messages.PublishReply{Uuid: "a", AcceptedCount: uint32(len(in.GetEvents())), AcceptedIndex: 2}, nil
+		})
+	mock.EXPECT().PersistedIndex(gomock.Any(), gomock.Any()).Return(stream, nil)
+
+	reply, err := client.New(mock).Publish(context.Background(), &messages.PublishRequest{Events: []*messages.Event{{}, {}}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+
+	s, err := mock.PersistedIndex(context.Background(), &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	persisted, err := s.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), persisted.GetPersistedIndex())
+	_, err = s.Recv()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestProducerServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mock := NewProducerServer(ctrl)
+	mock.EXPECT().PublishEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, in *messages.PublishRequest) (*messages.PublishReply, error) {
+			return &messages.PublishReply{AcceptedCount: uint32(len(in.GetEvents()))}, nil
+		})
+	mock.EXPECT().PersistedIndex(gomock.Any(), gomock.Any()).Return(status.Error(codes.Unimplemented, "no index"))
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer()
+	proto.RegisterProducerServer(srv, mock)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	producer := proto.NewProducerClient(conn)
+
+	reply, err := producer.PublishEvents(context.Background(), &messages.PublishRequest{Events: []*messages.Event{{}}})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), reply.GetAcceptedCount())
+
+	stream, err := producer.PersistedIndex(context.Background(), &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
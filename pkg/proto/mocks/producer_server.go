@@ -0,0 +1,32 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package mocks
+
+import (
+	"github.com/golang/mock/gomock"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+)
+
+var _ proto.ProducerServer = &ProducerServer{}
+
+// ProducerServer is a MockProducerServer that can be registered on a gRPC
+// server. A server must embed proto.UnimplementedProducerServer, which the
+// generated mock can't do, so this type is written by hand.
+type ProducerServer struct {
+	*MockProducerServer
+	// unimplemented is one level deeper than the mock, so the methods of
+	// the mock take precedence.
+	unimplemented
+}
+
+type unimplemented struct {
+	proto.UnimplementedProducerServer
+}
+
+// NewProducerServer creates a new ProducerServer with the given controller.
+func NewProducerServer(ctrl *gomock.Controller) *ProducerServer {
+	return &ProducerServer{MockProducerServer: NewMockProducerServer(ctrl)}
+}
@@ -39,6 +39,15 @@ type ProducerClient interface {
 	PublishEvents(ctx context.Context, in *messages.PublishRequest, opts ...grpc.CallOption) (*messages.PublishReply, error)
 	// Returns the shipper's uuid and its current position in the event stream (persisted index).
 	PersistedIndex(ctx context.Context, in *messages.PersistedIndexRequest, opts ...grpc.CallOption) (Producer_PersistedIndexClient, error)
+	// Asks the shipper to persist the accepted events up to the requested index without waiting
+	// for its regular flushes. Blocks until they are persisted and returns the resulting persisted index.
+	Flush(ctx context.Context, in *messages.FlushRequest, opts ...grpc.CallOption) (*messages.FlushReply, error)
+	// Returns the shipper's uuid and start time without publishing anything.
+	WhoAmI(ctx context.Context, in *messages.WhoAmIRequest, opts ...grpc.CallOption) (*messages.WhoAmIReply, error)
+	// Returns the cursor stored with the last persisted request having a cursor under the given key.
+	GetCursor(ctx context.Context, in *messages.GetCursorRequest, opts ...grpc.CallOption) (*messages.GetCursorReply, error)
+	// Returns the current global metadata of the shipper and its version.
+	GetGlobalMetadata(ctx context.Context, in *messages.GetGlobalMetadataRequest, opts ...grpc.CallOption) (*messages.GetGlobalMetadataReply, error)
 }
 
 type producerClient struct {
@@ -90,6 +99,42 @@ func (x *producerPersistedIndexClient) Recv() (*messages.PersistedIndexReply, er
 	return m, nil
 }
 
+func (c *producerClient) Flush(ctx context.Context, in *messages.FlushRequest, opts ...grpc.CallOption) (*messages.FlushReply, error) {
+	out := new(messages.FlushReply)
+	err := c.cc.Invoke(ctx, "/elastic.agent.shipper.v1.Producer/Flush", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *producerClient) WhoAmI(ctx context.Context, in *messages.WhoAmIRequest, opts ...grpc.CallOption) (*messages.WhoAmIReply, error) {
+	out := new(messages.WhoAmIReply)
+	err := c.cc.Invoke(ctx, "/elastic.agent.shipper.v1.Producer/WhoAmI", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *producerClient) GetCursor(ctx context.Context, in *messages.GetCursorRequest, opts ...grpc.CallOption) (*messages.GetCursorReply, error) {
+	out := new(messages.GetCursorReply)
+	err := c.cc.Invoke(ctx, "/elastic.agent.shipper.v1.Producer/GetCursor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *producerClient) GetGlobalMetadata(ctx context.Context, in *messages.GetGlobalMetadataRequest, opts ...grpc.CallOption) (*messages.GetGlobalMetadataReply, error) {
+	out := new(messages.GetGlobalMetadataReply)
+	err := c.cc.Invoke(ctx, "/elastic.agent.shipper.v1.Producer/GetGlobalMetadata", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProducerServer is the server API for Producer service.
 // All implementations must embed UnimplementedProducerServer
 // for forward compatibility
@@ -106,6 +151,15 @@ type ProducerServer interface {
 	PublishEvents(context.Context, *messages.PublishRequest) (*messages.PublishReply, error)
 	// Returns the shipper's uuid and its current position in the event stream (persisted index).
 	PersistedIndex(*messages.PersistedIndexRequest, Producer_PersistedIndexServer) error
+	// Asks the shipper to persist the accepted events up to the requested index without waiting
+	// for its regular flushes. Blocks until they are persisted and returns the resulting persisted index.
+	Flush(context.Context, *messages.FlushRequest) (*messages.FlushReply, error)
+	// Returns the shipper's uuid and start time without publishing anything.
+	WhoAmI(context.Context, *messages.WhoAmIRequest) (*messages.WhoAmIReply, error)
+	// Returns the cursor stored with the last persisted request having a cursor under the given key.
+	GetCursor(context.Context, *messages.GetCursorRequest) (*messages.GetCursorReply, error)
+	// Returns the current global metadata of the shipper and its version.
+	GetGlobalMetadata(context.Context, *messages.GetGlobalMetadataRequest) (*messages.GetGlobalMetadataReply, error)
 	mustEmbedUnimplementedProducerServer()
 }
 
@@ -119,6 +173,18 @@ func (UnimplementedProducerServer) PublishEvents(context.Context, *messages.Publ
 func (UnimplementedProducerServer) PersistedIndex(*messages.PersistedIndexRequest, Producer_PersistedIndexServer) error {
 	return status.Errorf(codes.Unimplemented, "method PersistedIndex not implemented")
 }
+func (UnimplementedProducerServer) Flush(context.Context, *messages.FlushRequest) (*messages.FlushReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flush not implemented")
+}
+func (UnimplementedProducerServer) WhoAmI(context.Context, *messages.WhoAmIRequest) (*messages.WhoAmIReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WhoAmI not implemented")
+}
+func (UnimplementedProducerServer) GetCursor(context.Context, *messages.GetCursorRequest) (*messages.GetCursorReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCursor not implemented")
+}
+func (UnimplementedProducerServer) GetGlobalMetadata(context.Context, *messages.GetGlobalMetadataRequest) (*messages.GetGlobalMetadataReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGlobalMetadata not implemented")
+}
 func (UnimplementedProducerServer) mustEmbedUnimplementedProducerServer() {}
 
 // UnsafeProducerServer may be embedded to opt out of forward compatibility for this service.
@@ -171,6 +237,78 @@ func (x *producerPersistedIndexServer) Send(m *messages.PersistedIndexReply) err
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Producer_Flush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(messages.FlushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProducerServer).Flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/elastic.agent.shipper.v1.Producer/Flush",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProducerServer).Flush(ctx, req.(*messages.FlushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Producer_WhoAmI_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(messages.WhoAmIRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProducerServer).WhoAmI(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/elastic.agent.shipper.v1.Producer/WhoAmI",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProducerServer).WhoAmI(ctx, req.(*messages.WhoAmIRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Producer_GetCursor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(messages.GetCursorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProducerServer).GetCursor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/elastic.agent.shipper.v1.Producer/GetCursor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProducerServer).GetCursor(ctx, req.(*messages.GetCursorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Producer_GetGlobalMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(messages.GetGlobalMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProducerServer).GetGlobalMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/elastic.agent.shipper.v1.Producer/GetGlobalMetadata",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProducerServer).GetGlobalMetadata(ctx, req.(*messages.GetGlobalMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Producer_ServiceDesc is the grpc.ServiceDesc for Producer service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -182,6 +320,22 @@ var Producer_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "PublishEvents",
 			Handler:    _Producer_PublishEvents_Handler,
 		},
+		{
+			MethodName: "Flush",
+			Handler:    _Producer_Flush_Handler,
+		},
+		{
+			MethodName: "WhoAmI",
+			Handler:    _Producer_WhoAmI_Handler,
+		},
+		{
+			MethodName: "GetCursor",
+			Handler:    _Producer_GetCursor_Handler,
+		},
+		{
+			MethodName: "GetGlobalMetadata",
+			Handler:    _Producer_GetGlobalMetadata_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/shipperuuid"
+)
+
+// Batch is the outcome of accepting the events of a PublishRequest.
+type Batch struct {
+	// Accepted is the number of accepted events, they are always the first
+	// events of the request.
+	Accepted int
+	// FirstIndex and LastIndex are the indexes assigned to the first and the
+	// last accepted events. Both are zero when no event is accepted.
+	FirstIndex uint64
+	LastIndex  uint64
+	// Reply is the reply to send to the client.
+	Reply *messages.PublishReply
+}
+
+// AccountBatch computes the outcome of a request for a shipper with the
+// given uuid whose last accepted index is lastIndex, when its queue has room
+// for capacity events.
+//
+// No event is accepted if the request uuid does not match. The accepted index
+// of the reply is lastIndex in that case, or when nothing fits in the queue,
+// so that it never goes backwards.
+func AccountBatch(uuid string, lastIndex uint64, req *messages.PublishRequest, capacity int) Batch {
+	accepted := req.Len()
+	if !shipperuuid.Accepts(uuid, req.GetUuid()) {
+		accepted = 0
+	}
+	if capacity < accepted {
+		accepted = capacity
+	}
+	if accepted < 0 {
+		accepted = 0
+	}
+
+	b := Batch{
+		Accepted: accepted,
+		Reply: &messages.PublishReply{
+			Uuid:          uuid,
+			AcceptedCount: uint32(accepted),
+			AcceptedIndex: lastIndex + uint64(accepted),
+			BatchId:       req.GetBatchId(),
+		},
+	}
+	if accepted > 0 {
+		b.FirstIndex = lastIndex + 1
+		b.LastIndex = lastIndex + uint64(accepted)
+	}
+	if accepted > 0 && req.GetReturnEventIndexes() {
+		b.Reply.EventIndexes = make([]uint64, accepted)
+		for i := range b.Reply.EventIndexes {
+			b.Reply.EventIndexes[i] = b.FirstIndex + uint64(i)
+		}
+	}
+	return b
+}
@@ -0,0 +1,125 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func request(uuid string, n int) *messages.PublishRequest {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = &messages.Event{}
+	}
+	return &messages.PublishRequest{Uuid: uuid, Events: events}
+}
+
+func TestAccountBatch(t *testing.T) {
+	cases := []struct {
+		name      string
+		req       *messages.PublishRequest
+		lastIndex uint64
+		capacity  int
+		expected  Batch
+	}{
+		{
+			name:      "everything fits",
+			req:       request("", 3),
+			lastIndex: 10,
+			capacity:  5,
+			expected: Batch{Accepted: 3, FirstIndex: 11, LastIndex: 13,
+				Reply: &messages.PublishReply{Uuid: "uuid", AcceptedCount: 3, AcceptedIndex: 13}},
+		},
+		{
+			name:      "exactly fits",
+			req:       request("uuid", 3),
+			lastIndex: 0,
+			capacity:  3,
+			expected: Batch{Accepted: 3, FirstIndex: 1, LastIndex: 3,
+				Reply: &messages.PublishReply{Uuid: "uuid", AcceptedCount: 3, AcceptedIndex: 3}},
+		},
+		{
+			name:      "partial accept",
+			req:       request("", 5),
+			lastIndex: 7,
+			capacity:  2,
+			expected: Batch{Accepted: 2, FirstIndex: 8, LastIndex: 9,
+				Reply: &messages.PublishReply{Uuid: "uuid", AcceptedCount: 2, AcceptedIndex: 9}},
+		},
+		{
+			name: "event indexes",
+			req: func() *messages.PublishRequest {
+				req := request("", 5)
+				req.ReturnEventIndexes = true
+				return req
+			}(),
+			lastIndex: 7,
+			capacity:  2,
+			expected: Batch{Accepted: 2, FirstIndex: 8, LastIndex: 9,
+				Reply: &messages.PublishReply{Uuid: "uuid", AcceptedCount: 2, AcceptedIndex: 9, EventIndexes: []uint64{8, 9}}},
+		},
+		{
+			name: "batch id",
+			req: func() *messages.PublishRequest {
+				req := request("old", 2)
+				req.BatchId = "batch-1"
+				return req
+			}(),
+			lastIndex: 7,
+			capacity:  2,
+			expected:  Batch{Reply: &messages.PublishReply{Uuid: "uuid", AcceptedIndex: 7, BatchId: "batch-1"}},
+		},
+		{
+			name:      "queue full",
+			req:       request("", 5),
+			lastIndex: 7,
+			capacity:  0,
+			expected:  Batch{Reply: &messages.PublishReply{Uuid: "uuid", AcceptedIndex: 7}},
+		},
+		{
+			name:      "negative capacity",
+			req:       request("", 5),
+			lastIndex: 7,
+			capacity:  -3,
+			expected:  Batch{Reply: &messages.PublishReply{Uuid: "uuid", AcceptedIndex: 7}},
+		},
+		{
+			name:      "empty request",
+			req:       request("", 0),
+			lastIndex: 7,
+			capacity:  10,
+			expected:  Batch{Reply: &messages.PublishReply{Uuid: "uuid", AcceptedIndex: 7}},
+		},
+		{
+			name:      "uuid mismatch",
+			req:       request("old", 5),
+			lastIndex: 7,
+			capacity:  10,
+			expected:  Batch{Reply: &messages.PublishReply{Uuid: "uuid", AcceptedIndex: 7}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, AccountBatch("uuid", c.lastIndex, c.req, c.capacity))
+		})
+	}
+}
+
+func TestIndexTrackerAcceptBatch(t *testing.T) {
+	tracker := NewIndexTrackerWithUUID("uuid", 0)
+	b := tracker.AcceptBatch(request("", 5), 3)
+	require.Equal(t, uint64(1), b.FirstIndex)
+	require.Equal(t, uint64(3), b.LastIndex)
+	b = tracker.AcceptBatch(request("", 2), 0)
+	require.Zero(t, b.Accepted)
+	require.Equal(t, uint64(3), b.Reply.GetAcceptedIndex())
+	b = tracker.AcceptBatch(request("uuid", 2), 10)
+	require.Equal(t, uint64(4), b.FirstIndex)
+	require.Equal(t, uint64(5), tracker.Accepted())
+}
@@ -0,0 +1,166 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// KeyFunc returns the id of an event, empty if it has none.
+type KeyFunc func(*messages.Event) string
+
+// DocumentID is the default KeyFunc, it returns the document_id field of the
+// event. The _id metadata of older producers is not used, the metadata is
+// free-form and may not identify the event.
+func DocumentID(e *messages.Event) string {
+	return e.GetDocumentId()
+}
+
+// DedupConfig configures a Dedup window.
+type DedupConfig struct {
+	// MaxEntries is the maximum number of ids remembered, the least
+	// recently seen ones are forgotten first.
+	MaxEntries int
+	// TTL is how long an id is remembered. Zero means until it's evicted.
+	TTL time.Duration
+	// Key returns the id of an event, DocumentID if nil.
+	Key KeyFunc
+}
+
+// DefaultDedupConfig returns the default dedup configuration.
+func DefaultDedupConfig() DedupConfig {
+	return DedupConfig{
+		MaxEntries: 10000,
+		TTL:        5 * time.Minute,
+	}
+}
+
+// Dedup remembers the ids of the recently accepted events, so that the
+// events sent again by a client retrying a request can be dropped.
+//
+// Events without an id are never considered duplicates. A dropped duplicate
+// was accepted before, so it must still be counted in accepted_count.
+//
+// If a registry is given, the number of duplicates is maintained in the
+// dedup.duplicates metric, and the number of remembered ids in dedup.entries.
+type Dedup struct {
+	config DedupConfig
+	now    func() time.Time
+
+	duplicates *monitoring.Uint
+	entries    *monitoring.Uint
+
+	mu    sync.Mutex
+	lru   *list.List
+	index map[string]*list.Element
+}
+
+type dedupEntry struct {
+	id   string
+	seen time.Time
+}
+
+// NewDedup creates a new dedup window, reg can be nil.
+func NewDedup(config DedupConfig, reg *monitoring.Registry) *Dedup {
+	if config.Key == nil {
+		config.Key = DocumentID
+	}
+	d := &Dedup{
+		config: config,
+		now:    time.Now,
+		lru:    list.New(),
+		index:  make(map[string]*list.Element),
+	}
+	if reg != nil {
+		dedupReg := reg.NewRegistry("dedup")
+		d.duplicates = monitoring.NewUint(dedupReg, "duplicates")
+		d.entries = monitoring.NewUint(dedupReg, "entries")
+	}
+	return d
+}
+
+// Filter returns the events that were not seen before, in order, and the
+// number of duplicates that were dropped. An id repeated within the events
+// is a duplicate too, only its first event is kept. It does not record the
+// events, Record must be called once they are accepted.
+func (d *Dedup) Filter(events []*messages.Event) ([]*messages.Event, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+
+	var unique []*messages.Event
+	batch := make(map[string]struct{}, len(events))
+	for _, e := range events {
+		id := d.config.Key(e)
+		if d.seenLocked(id, now) {
+			continue
+		}
+		if id != "" {
+			if _, ok := batch[id]; ok {
+				continue
+			}
+			batch[id] = struct{}{}
+		}
+		unique = append(unique, e)
+	}
+	dropped := len(events) - len(unique)
+	if dropped == 0 {
+		return events, 0
+	}
+	if d.duplicates != nil {
+		d.duplicates.Add(uint64(dropped))
+	}
+	return unique, dropped
+}
+
+// Record remembers the ids of accepted events.
+func (d *Dedup) Record(events []*messages.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	for _, e := range events {
+		id := d.config.Key(e)
+		if id == "" {
+			continue
+		}
+		if el, ok := d.index[id]; ok {
+			el.Value.(*dedupEntry).seen = now
+			d.lru.MoveToFront(el)
+			continue
+		}
+		d.index[id] = d.lru.PushFront(&dedupEntry{id: id, seen: now})
+		if d.config.MaxEntries > 0 && d.lru.Len() > d.config.MaxEntries {
+			d.removeLocked(d.lru.Back())
+		}
+	}
+	if d.entries != nil {
+		d.entries.Set(uint64(d.lru.Len()))
+	}
+}
+
+func (d *Dedup) seenLocked(id string, now time.Time) bool {
+	if id == "" {
+		return false
+	}
+	el, ok := d.index[id]
+	if !ok {
+		return false
+	}
+	if d.config.TTL > 0 && now.Sub(el.Value.(*dedupEntry).seen) > d.config.TTL {
+		d.removeLocked(el)
+		return false
+	}
+	return true
+}
+
+func (d *Dedup) removeLocked(el *list.Element) {
+	d.lru.Remove(el)
+	delete(d.index, el.Value.(*dedupEntry).id)
+}
@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func eventWithID(id string) *messages.Event {
+	return &messages.Event{DocumentId: id}
+}
+
+func TestDedup(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	d := NewDedup(DefaultDedupConfig(), reg)
+
+	first := []*messages.Event{eventWithID("a"), eventWithID("b"), eventWithID("")}
+	unique, dropped := d.Filter(first)
+	require.Equal(t, first, unique)
+	require.Zero(t, dropped)
+	d.Record(first)
+
+	// the client retries with a new event
+	retry := []*messages.Event{eventWithID("a"), eventWithID("b"), eventWithID(""), eventWithID("c")}
+	unique, dropped = d.Filter(retry)
+	require.Equal(t, retry[2:], unique)
+	require.Equal(t, 2, dropped)
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	require.Equal(t, int64(2), snapshot.Ints["dedup.duplicates"])
+	require.Equal(t, int64(2), snapshot.Ints["dedup.entries"])
+}
+
+func TestDedupWithinBatch(t *testing.T) {
+	d := NewDedup(DefaultDedupConfig(), nil)
+
+	events := []*messages.Event{eventWithID("a"), eventWithID(""), eventWithID("a"), eventWithID(""), eventWithID("b"), eventWithID("a")}
+	unique, dropped := d.Filter(events)
+	require.Equal(t, []*messages.Event{events[0], events[1], events[3], events[4]}, unique)
+	require.Equal(t, 2, dropped)
+}
+
+func TestDedupMetadataID(t *testing.T) {
+	d := NewDedup(DefaultDedupConfig(), nil)
+
+	// the _id metadata is not the key of the events
+	e := &messages.Event{Metadata: &messages.Struct{Data: map[string]*messages.Value{
+		messages.MetadataKeyID: helpers.NewStringValue("a"),
+	}}}
+	d.Record([]*messages.Event{e})
+	_, dropped := d.Filter([]*messages.Event{e, e})
+	require.Zero(t, dropped)
+}
+
+func TestDedupEviction(t *testing.T) {
+	now := time.Now()
+	d := NewDedup(DedupConfig{MaxEntries: 2, TTL: time.Minute}, nil)
+	d.now = func() time.Time { return now }
+
+	d.Record([]*messages.Event{eventWithID("a"), eventWithID("b")})
+	d.Record([]*messages.Event{eventWithID("a"), eventWithID("c")})
+	// b was the least recently seen
+	_, dropped := d.Filter([]*messages.Event{eventWithID("a"), eventWithID("b"), eventWithID("c")})
+	require.Equal(t, 2, dropped)
+
+	now = now.Add(2 * time.Minute)
+	_, dropped = d.Filter([]*messages.Event{eventWithID("a"), eventWithID("c")})
+	require.Zero(t, dropped)
+}
+
+func TestDedupKey(t *testing.T) {
+	d := NewDedup(DedupConfig{Key: func(e *messages.Event) string { return e.GetSource().GetInputId() }}, nil)
+	e := &messages.Event{Source: &messages.Source{InputId: "input"}}
+	d.Record([]*messages.Event{e})
+	_, dropped := d.Filter([]*messages.Event{e})
+	require.Equal(t, 1, dropped)
+}
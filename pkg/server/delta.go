@@ -0,0 +1,64 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// DecodeMetadataDeltas replaces the metadata deltas of the events, encoded
+// by client.EncodeMetadataDeltas, with the full metadata. Each event gets its
+// own Struct, the values are shared between the events.
+//
+// It returns an InvalidArgument error when the first event has a delta.
+func DecodeMetadataDeltas(req *messages.PublishRequest) error {
+	events := req.GetEvents()
+	for i, e := range events {
+		delta := e.GetMetadataDelta()
+		if delta == nil {
+			continue
+		}
+		if i == 0 {
+			return status.Error(codes.InvalidArgument, "the first event has a metadata delta")
+		}
+
+		previous := events[i-1].GetMetadata().GetData()
+		data := make(map[string]*messages.Value, len(previous)+len(e.GetMetadata().GetData()))
+		for k, v := range previous {
+			data[k] = v
+		}
+		for _, k := range delta.GetRemovedKeys() {
+			delete(data, k)
+		}
+		for k, v := range e.GetMetadata().GetData() {
+			data[k] = v
+		}
+		e.Metadata = &messages.Struct{Data: data}
+		e.MetadataDelta = nil
+	}
+	return nil
+}
+
+// ServerMetadataDeltas returns the server options decoding the metadata
+// deltas of the PublishRequests before they reach the handler. They must
+// come after the string decoding options and before the validation options.
+func ServerMetadataDeltas() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if r, ok := req.(*messages.PublishRequest); ok {
+				if err := DecodeMetadataDeltas(r); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}),
+	}
+}
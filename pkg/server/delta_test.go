@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// uniformEvents returns valid events sharing most of their metadata.
+func uniformEvents(n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		e := validEvent()
+		e.Metadata = &messages.Struct{Data: map[string]*messages.Value{
+			"pipeline": helpers.NewStringValue("nginx-pipeline"),
+			"host":     helpers.NewStringValue("host-1"),
+			"offset":   helpers.NewInt64Value(int64(i / 2)),
+		}}
+		if i%3 == 0 {
+			e.Metadata.Data["retry"] = helpers.NewBoolValue(true)
+		}
+		events[i] = e
+	}
+	return events
+}
+
+func TestDecodeMetadataDeltas(t *testing.T) {
+	req := &messages.PublishRequest{Events: uniformEvents(10)}
+	encoded := client.EncodeMetadataDeltas(req)
+	require.NotSame(t, req, encoded)
+
+	require.NoError(t, DecodeMetadataDeltas(encoded))
+	require.True(t, gproto.Equal(req, encoded))
+
+	invalid := &messages.PublishRequest{Events: []*messages.Event{{MetadataDelta: &messages.MetadataDelta{}}}}
+	require.Equal(t, codes.InvalidArgument, status.Code(DecodeMetadataDeltas(invalid)))
+}
+
+func TestServerMetadataDeltas(t *testing.T) {
+	var opts []grpc.ServerOption
+	opts = append(opts, ServerUnpacking(0)...)
+	opts = append(opts, ServerStringDecoding()...)
+	opts = append(opts, ServerMetadataDeltas()...)
+	opts = append(opts, ServerValidation(DefaultValidationConfig())...)
+	recorder := &recordRequests{}
+	producer := newBufconnClient(t, recorder, opts,
+		grpc.WithChainUnaryInterceptor(
+			client.MetadataDeltaInterceptor(),
+			client.StringTableInterceptor(),
+			client.PackingInterceptor(gzip.Name),
+		),
+	)
+
+	req := &messages.PublishRequest{Events: uniformEvents(20)}
+	reply, err := producer.PublishEvents(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, uint32(20), reply.GetAcceptedCount())
+	require.Len(t, recorder.requests, 1)
+	require.True(t, gproto.Equal(req, recorder.requests[0]))
+}
@@ -0,0 +1,151 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+// FlowControlConfig configures a FlowControl.
+type FlowControlConfig struct {
+	// MaxUnpersisted is the maximum number of events of a client that are
+	// accepted and not persisted yet.
+	MaxUnpersisted int
+	// RetryDelay is the delay suggested to a client whose window is full.
+	RetryDelay time.Duration
+}
+
+// DefaultFlowControlConfig returns the default flow control configuration.
+func DefaultFlowControlConfig() FlowControlConfig {
+	return FlowControlConfig{
+		MaxUnpersisted: 4096,
+		RetryDelay:     100 * time.Millisecond,
+	}
+}
+
+// FlowControl bounds the number of events every client has accepted and
+// not persisted yet, so that a single client can't fill the shipper queue.
+//
+// Clients are identified by a key chosen by the server, e.g. the input id.
+// When the window of a client is full its requests fail with a QUEUE_FULL
+// error carrying a retry delay, which the client retries after that delay.
+type FlowControl struct {
+	config FlowControlConfig
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// window holds the index ranges of the unpersisted batches of a client.
+type window struct {
+	batches     []indexRange
+	unpersisted int
+}
+
+type indexRange struct {
+	first, last uint64
+}
+
+// NewFlowControl creates a new FlowControl.
+func NewFlowControl(config FlowControlConfig) *FlowControl {
+	return &FlowControl{
+		config:  config,
+		windows: make(map[string]*window),
+	}
+}
+
+// Capacity returns the number of events the client can still send.
+func (f *FlowControl) Capacity(client string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.capacity(client)
+}
+
+func (f *FlowControl) capacity(client string) int {
+	w, ok := f.windows[client]
+	if !ok {
+		return f.config.MaxUnpersisted
+	}
+	if c := f.config.MaxUnpersisted - w.unpersisted; c > 0 {
+		return c
+	}
+	return 0
+}
+
+// Accepted adds a batch accepted for the client to its window.
+func (f *FlowControl) Accepted(client string, b Batch) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accepted(client, b)
+}
+
+func (f *FlowControl) accepted(client string, b Batch) {
+	if b.Accepted == 0 {
+		return
+	}
+	w, ok := f.windows[client]
+	if !ok {
+		w = &window{}
+		f.windows[client] = w
+	}
+	w.batches = append(w.batches, indexRange{first: b.FirstIndex, last: b.LastIndex})
+	w.unpersisted += b.Accepted
+}
+
+// Persisted releases the events of all the clients up to the persisted index.
+func (f *FlowControl) Persisted(index uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for client, w := range f.windows {
+		n := 0
+		for ; n < len(w.batches) && w.batches[n].first <= index; n++ {
+			b := &w.batches[n]
+			if b.last > index {
+				// partially persisted
+				w.unpersisted -= int(index - b.first + 1)
+				b.first = index + 1
+				break
+			}
+			w.unpersisted -= int(b.last - b.first + 1)
+		}
+		w.batches = w.batches[n:]
+		if len(w.batches) == 0 {
+			delete(f.windows, client)
+		}
+	}
+}
+
+// Reset forgets all the windows, e.g. when the shipper uuid changes.
+func (f *FlowControl) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.windows = make(map[string]*window)
+}
+
+// Accept accepts as many events of the request as both the window of the
+// client and the queue capacity allow, and records them in the window.
+// If the window is full, it returns a QUEUE_FULL error with the configured
+// retry delay instead. The window is locked until the events are recorded, so
+// the concurrent requests of a client can't exceed it together.
+func (f *FlowControl) Accept(client string, tracker *IndexTracker, req *messages.PublishRequest, queueCapacity int) (*messages.PublishReply, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	capacity := f.capacity(client)
+	if capacity == 0 && req.Len() > 0 {
+		return nil, sherror.New(sherror.ReasonQueueFull, "too many unpersisted events, the maximum is %d", f.config.MaxUnpersisted).
+			WithRetryDelay(f.config.RetryDelay)
+	}
+	if queueCapacity < capacity {
+		capacity = queueCapacity
+	}
+	b := tracker.AcceptBatch(req, capacity)
+	f.accepted(client, b)
+	return b.Reply, nil
+}
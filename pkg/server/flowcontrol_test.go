@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+func TestFlowControl(t *testing.T) {
+	fc := NewFlowControl(FlowControlConfig{MaxUnpersisted: 5, RetryDelay: time.Second})
+	tracker := NewIndexTrackerWithUUID("uuid", 0)
+
+	// the window caps the accepted events
+	reply, err := fc.Accept("a", tracker, request("", 3), 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), reply.GetAcceptedCount())
+	reply, err = fc.Accept("a", tracker, request("", 3), 100)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+	require.Zero(t, fc.Capacity("a"))
+
+	// other clients have their own window, still bound by the queue
+	reply, err = fc.Accept("b", tracker, request("", 3), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), reply.GetAcceptedCount())
+	require.Equal(t, 4, fc.Capacity("b"))
+
+	_, err = fc.Accept("a", tracker, request("", 1), 100)
+	require.Equal(t, codes.ResourceExhausted, status.Code(status.Convert(err).Err()))
+	e := sherror.FromError(status.Convert(err).Err())
+	require.True(t, errors.Is(e, sherror.ErrQueueFull))
+	require.Equal(t, time.Second, e.RetryDelay)
+
+	// a's batches have indexes 1-3 and 4-5, b's batch has index 6
+	fc.Persisted(2)
+	require.Equal(t, 2, fc.Capacity("a"))
+	fc.Persisted(5)
+	require.Equal(t, 5, fc.Capacity("a"))
+	require.Equal(t, 4, fc.Capacity("b"))
+
+	fc.Reset()
+	require.Equal(t, 5, fc.Capacity("b"))
+}
+
+func TestFlowControlConcurrentAccept(t *testing.T) {
+	fc := NewFlowControl(FlowControlConfig{MaxUnpersisted: 100, RetryDelay: time.Second})
+	tracker := NewIndexTrackerWithUUID("uuid", 0)
+
+	var (
+		wg       sync.WaitGroup
+		start    = make(chan struct{})
+		accepted int64
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := 0; j < 20; j++ {
+				reply, err := fc.Accept("a", tracker, request("", 3), 1000)
+				if err == nil {
+					atomic.AddInt64(&accepted, int64(reply.GetAcceptedCount()))
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	// the concurrent requests never accept more than the window together
+	require.Equal(t, int64(100), accepted)
+	require.Zero(t, fc.Capacity("a"))
+}
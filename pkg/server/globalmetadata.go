@@ -0,0 +1,114 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// globalMetadataVersions is the number of versions of the global metadata
+// kept for the events still referencing an older version.
+const globalMetadataVersions = 8
+
+// GlobalMetadata holds the global metadata of a shipper, the metadata shared
+// by all the events of the agent, and its previous versions. The events
+// reference a version with their global_metadata_version field instead of
+// carrying it, see Resolve.
+type GlobalMetadata struct {
+	mu       sync.Mutex
+	version  uint64
+	versions map[uint64]*messages.Struct
+}
+
+// NewGlobalMetadata creates a new GlobalMetadata without any version, the
+// first version set is 1.
+func NewGlobalMetadata() *GlobalMetadata {
+	return &GlobalMetadata{versions: make(map[uint64]*messages.Struct)}
+}
+
+// Set sets the current global metadata and returns its version. The oldest
+// versions are forgotten.
+func (g *GlobalMetadata) Set(metadata *messages.Struct) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.version++
+	g.versions[g.version] = metadata
+	if g.version > globalMetadataVersions {
+		delete(g.versions, g.version-globalMetadataVersions)
+	}
+	return g.version
+}
+
+// Current returns the current global metadata and its version, zero when
+// none was set.
+func (g *GlobalMetadata) Current() (uint64, *messages.Struct) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.version, g.versions[g.version]
+}
+
+// Reply implements the GetGlobalMetadata call of proto.ProducerServer for
+// the shipper with the given uuid.
+func (g *GlobalMetadata) Reply(uuid string) *messages.GetGlobalMetadataReply {
+	version, metadata := g.Current()
+	return &messages.GetGlobalMetadataReply{Uuid: uuid, Version: version, Metadata: metadata}
+}
+
+// Resolve adds the global metadata referenced by the events of the request to
+// their metadata, the keys of the events taking precedence, and clears their
+// global metadata version. Each event gets its own Struct, the values are
+// shared between the events.
+//
+// It returns a FailedPrecondition error when an event references a version
+// that is unknown or forgotten: the client must get the current version.
+func (g *GlobalMetadata) Resolve(req *messages.PublishRequest) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, e := range req.GetEvents() {
+		version := e.GetGlobalMetadataVersion()
+		if version == 0 {
+			continue
+		}
+		global, ok := g.versions[version]
+		if !ok {
+			return status.Errorf(codes.FailedPrecondition, "unknown global metadata version %d, the current version is %d", version, g.version)
+		}
+
+		data := make(map[string]*messages.Value, len(global.GetData())+len(e.GetMetadata().GetData()))
+		for k, v := range global.GetData() {
+			data[k] = v
+		}
+		for k, v := range e.GetMetadata().GetData() {
+			data[k] = v
+		}
+		e.Metadata = &messages.Struct{Data: data}
+		e.GlobalMetadataVersion = 0
+	}
+	return nil
+}
+
+// ServerGlobalMetadata returns the server options resolving the global
+// metadata of the PublishRequests before they reach the handler. They must
+// come after the metadata delta decoding options and before the validation
+// options.
+func ServerGlobalMetadata(g *GlobalMetadata) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if r, ok := req.(*messages.PublishRequest); ok {
+				if err := g.Resolve(r); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}),
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestGlobalMetadata(t *testing.T) {
+	g := NewGlobalMetadata()
+	version, metadata := g.Current()
+	require.Zero(t, version)
+	require.Nil(t, metadata)
+
+	global := &messages.Struct{Data: map[string]*messages.Value{
+		"host":  helpers.NewStringValue("host-1"),
+		"agent": helpers.NewStringValue("agent-1"),
+	}}
+	require.Equal(t, uint64(1), g.Set(global))
+	require.True(t, gproto.Equal(&messages.GetGlobalMetadataReply{Uuid: "uuid", Version: 1, Metadata: global}, g.Reply("uuid")))
+
+	e := validEvent()
+	e.Metadata = &messages.Struct{Data: map[string]*messages.Value{"host": helpers.NewStringValue("host-2")}}
+	e.GlobalMetadataVersion = 1
+	plain := validEvent()
+	req := &messages.PublishRequest{Events: []*messages.Event{e, plain}}
+	require.NoError(t, g.Resolve(req))
+	require.Zero(t, e.GetGlobalMetadataVersion())
+	require.True(t, gproto.Equal(&messages.Struct{Data: map[string]*messages.Value{
+		"host":  helpers.NewStringValue("host-2"),
+		"agent": helpers.NewStringValue("agent-1"),
+	}}, e.GetMetadata()))
+	require.Nil(t, plain.GetMetadata())
+	require.Len(t, global.GetData(), 2, "the global metadata must not change")
+
+	// the oldest versions are forgotten
+	for i := 0; i < globalMetadataVersions; i++ {
+		g.Set(global)
+	}
+	old := validEvent()
+	old.GlobalMetadataVersion = 1
+	require.Equal(t, codes.FailedPrecondition, status.Code(g.Resolve(&messages.PublishRequest{Events: []*messages.Event{old}})))
+	old.GlobalMetadataVersion = 2
+	require.NoError(t, g.Resolve(&messages.PublishRequest{Events: []*messages.Event{old}}))
+}
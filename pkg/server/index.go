@@ -0,0 +1,288 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/shipperuuid"
+)
+
+// IndexTracker keeps the uuid, accepted index and persisted index of a
+// shipper process, see the API README for their semantics.
+//
+// Events are numbered from 1 in the order they are accepted, the accepted
+// index is the number of the last accepted event. The persisted index never
+// goes beyond it.
+//
+// It also keeps the cursors of the requests, see PublishRequest.cursor: the
+// cursor of a request accepted with AcceptBatch is returned by GetCursor once
+// the persisted index covers the request.
+type IndexTracker struct {
+	mu        sync.Mutex
+	uuid      string
+	started   time.Time
+	accepted  uint64
+	persisted uint64
+	// changed is closed and replaced every time the persisted index or the uuid change
+	changed chan struct{}
+	// pendingCursors are the cursors of the accepted requests that are not
+	// persisted yet, in the order of their indexes
+	pendingCursors []pendingCursor
+	// cursors are the persisted cursors by key
+	cursors map[string][]byte
+}
+
+type pendingCursor struct {
+	index  uint64
+	key    string
+	cursor []byte
+}
+
+// NewIndexTracker creates a new tracker with a random uuid.
+func NewIndexTracker() *IndexTracker {
+	return NewIndexTrackerWithUUID(shipperuuid.New(), 0)
+}
+
+// NewIndexTrackerWithUUID creates a new tracker with the given uuid, e.g.
+// one restored with shipperuuid.LoadOrCreate. Both indexes start at index,
+// the last index persisted before the restart.
+func NewIndexTrackerWithUUID(uuid string, index uint64) *IndexTracker {
+	return &IndexTracker{
+		uuid:      uuid,
+		started:   time.Now(),
+		accepted:  index,
+		persisted: index,
+		changed:   make(chan struct{}),
+		cursors:   make(map[string][]byte),
+	}
+}
+
+// UUID returns the current uuid.
+func (t *IndexTracker) UUID() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.uuid
+}
+
+// WhoAmI implements the WhoAmI call of proto.ProducerServer: it returns the
+// uuid and the time the tracker was created or last reset.
+func (t *IndexTracker) WhoAmI() *messages.WhoAmIReply {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &messages.WhoAmIReply{Uuid: t.uuid, StartTime: timestamppb.New(t.started)}
+}
+
+// Accept assigns indexes to the accepted events of a request and returns the
+// reply to send. If requestUUID is set and does not match the current uuid,
+// no event is accepted.
+func (t *IndexTracker) Accept(requestUUID string, accepted int) *messages.PublishReply {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !shipperuuid.Accepts(t.uuid, requestUUID) {
+		accepted = 0
+	}
+	t.accepted += uint64(accepted)
+	return &messages.PublishReply{
+		Uuid:          t.uuid,
+		AcceptedCount: uint32(accepted),
+		AcceptedIndex: t.accepted,
+	}
+}
+
+// AcceptBatch accepts as many events of the request as the queue has room
+// for and assigns them indexes, see AccountBatch.
+func (t *IndexTracker) AcceptBatch(req *messages.PublishRequest, capacity int) Batch {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := AccountBatch(t.uuid, t.accepted, req, capacity)
+	t.accepted = b.Reply.GetAcceptedIndex()
+	if len(req.GetCursor()) > 0 && b.Accepted > 0 && b.Accepted == req.Len() {
+		t.pendingCursors = append(t.pendingCursors, pendingCursor{index: b.LastIndex, key: req.GetCursorKey(), cursor: req.GetCursor()})
+	}
+	return b
+}
+
+// Persist advances the persisted index. Lower values than the current one
+// are ignored and the index is capped to the accepted index.
+func (t *IndexTracker) Persist(index uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if index > t.accepted {
+		index = t.accepted
+	}
+	if index <= t.persisted {
+		return
+	}
+	t.persisted = index
+	var i int
+	for ; i < len(t.pendingCursors) && t.pendingCursors[i].index <= index; i++ {
+		t.cursors[t.pendingCursors[i].key] = t.pendingCursors[i].cursor
+	}
+	t.pendingCursors = t.pendingCursors[i:]
+	t.notifyLocked()
+}
+
+// Accepted returns the accepted index.
+func (t *IndexTracker) Accepted() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.accepted
+}
+
+// Persisted returns the reply describing the current state.
+func (t *IndexTracker) Persisted() *messages.PersistedIndexReply {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &messages.PersistedIndexReply{Uuid: t.uuid, PersistedIndex: t.persisted}
+}
+
+// Changed returns a channel closed on the next change of the persisted index or the uuid.
+func (t *IndexTracker) Changed() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+// Wait blocks until the persisted index of the given uuid reaches index or
+// ctx is done. It returns sherror.ErrUUIDMismatch if the uuid is not the
+// current one, or changes while waiting.
+func (t *IndexTracker) Wait(ctx context.Context, uuid string, index uint64) error {
+	for {
+		t.mu.Lock()
+		if t.uuid != uuid {
+			t.mu.Unlock()
+			return sherror.ErrUUIDMismatch
+		}
+		if t.persisted >= index {
+			t.mu.Unlock()
+			return nil
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Flush implements the Flush call of proto.ProducerServer. It resolves the
+// requested index, zero meaning the accepted index, calls persist to make the
+// shipper persist the events up to it, and waits until they are. If the
+// shipper restarts while waiting, the reply has the new uuid and index.
+func (t *IndexTracker) Flush(ctx context.Context, req *messages.FlushRequest, persist func(index uint64)) (*messages.FlushReply, error) {
+	t.mu.Lock()
+	uuid, index := t.uuid, req.GetAcceptedIndex()
+	if index == 0 {
+		index = t.accepted
+	}
+	if index > t.accepted {
+		accepted := t.accepted
+		t.mu.Unlock()
+		return nil, status.Errorf(codes.InvalidArgument, "accepted index %d is beyond the last accepted event %d", index, accepted)
+	}
+	t.mu.Unlock()
+
+	persist(index)
+	err := t.Wait(ctx, uuid, index)
+	if err != nil && !errors.Is(err, sherror.ErrUUIDMismatch) {
+		return nil, status.FromContextError(err).Err()
+	}
+	persisted := t.Persisted()
+	return &messages.FlushReply{Uuid: persisted.GetUuid(), PersistedIndex: persisted.GetPersistedIndex()}, nil
+}
+
+// GetCursor implements the GetCursor call of proto.ProducerServer: it returns
+// the last persisted cursor under the key of the request.
+func (t *IndexTracker) GetCursor(req *messages.GetCursorRequest) *messages.GetCursorReply {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return &messages.GetCursorReply{Uuid: t.uuid, Cursor: t.cursors[req.GetKey()]}
+}
+
+// Cursors returns a copy of the persisted cursors by key, for the shipper to
+// store them with its queue.
+func (t *IndexTracker) Cursors() map[string][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cursors := make(map[string][]byte, len(t.cursors))
+	for k, c := range t.cursors {
+		cursors[k] = c
+	}
+	return cursors
+}
+
+// RestoreCursor sets the persisted cursor of a key, e.g. one stored by a
+// previous shipper process.
+func (t *IndexTracker) RestoreCursor(key string, cursor []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cursors[key] = cursor
+}
+
+// Reset generates a new uuid and restarts the indexes from zero, as when
+// the shipper process restarts without keeping its queue. It returns the new
+// uuid. The persisted cursors are kept, the pending ones are lost.
+func (t *IndexTracker) Reset() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uuid = shipperuuid.New()
+	t.started = time.Now()
+	t.accepted = 0
+	t.persisted = 0
+	t.pendingCursors = nil
+	t.notifyLocked()
+	return t.uuid
+}
+
+// Serve implements the PersistedIndex call of proto.ProducerServer. It sends
+// the current state, then every polling interval if it changed, until the
+// client goes away. With a zero polling interval only the current state is sent.
+func (t *IndexTracker) Serve(req *messages.PersistedIndexRequest, srv proto.Producer_PersistedIndexServer) error {
+	last := t.Persisted()
+	if err := srv.Send(last); err != nil {
+		return err
+	}
+	interval := req.GetPollingInterval().AsDuration()
+	if interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+		current := t.Persisted()
+		if current.GetUuid() == last.GetUuid() && current.GetPersistedIndex() == last.GetPersistedIndex() {
+			continue
+		}
+		if err := srv.Send(current); err != nil {
+			return err
+		}
+		last = current
+	}
+}
+
+func (t *IndexTracker) notifyLocked() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
@@ -0,0 +1,185 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+func TestIndexTracker(t *testing.T) {
+	tracker := NewIndexTracker()
+	uuid := tracker.UUID()
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+
+	reply := tracker.Accept("", 3)
+	require.Equal(t, &messages.PublishReply{Uuid: uuid, AcceptedCount: 3, AcceptedIndex: 3}, reply)
+	reply = tracker.Accept(uuid, 2)
+	require.Equal(t, uint64(5), reply.GetAcceptedIndex())
+	reply = tracker.Accept("other", 2)
+	require.Zero(t, reply.GetAcceptedCount())
+	require.Equal(t, uint64(5), tracker.Accepted())
+
+	changed := tracker.Changed()
+	tracker.Persist(10)
+	<-changed
+	require.Equal(t, uint64(5), tracker.Persisted().GetPersistedIndex())
+	tracker.Persist(1)
+	require.Equal(t, uint64(5), tracker.Persisted().GetPersistedIndex())
+
+	newUUID := tracker.Reset()
+	require.NotEqual(t, uuid, newUUID)
+	require.Equal(t, &messages.PersistedIndexReply{Uuid: newUUID}, tracker.Persisted())
+	require.Zero(t, tracker.Accepted())
+}
+
+func TestIndexTrackerWithUUID(t *testing.T) {
+	tracker := NewIndexTrackerWithUUID("uuid", 10)
+	require.Equal(t, &messages.PersistedIndexReply{Uuid: "uuid", PersistedIndex: 10}, tracker.Persisted())
+	require.Equal(t, uint64(12), tracker.Accept("uuid", 2).GetAcceptedIndex())
+}
+
+func TestIndexTrackerWhoAmI(t *testing.T) {
+	start := time.Now()
+	tracker := NewIndexTracker()
+	reply := tracker.WhoAmI()
+	require.Equal(t, tracker.UUID(), reply.GetUuid())
+	require.False(t, reply.GetStartTime().AsTime().Before(start.Truncate(time.Microsecond)))
+
+	uuid := tracker.Reset()
+	restarted := tracker.WhoAmI()
+	require.Equal(t, uuid, restarted.GetUuid())
+	require.False(t, restarted.GetStartTime().AsTime().Before(reply.GetStartTime().AsTime()))
+}
+
+func TestIndexTrackerWait(t *testing.T) {
+	tracker := NewIndexTracker()
+	uuid := tracker.Accept("", 5).GetUuid()
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() { done <- tracker.Wait(ctx, uuid, 4) }()
+	tracker.Persist(2)
+	tracker.Persist(4)
+	require.NoError(t, <-done)
+
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, tracker.Wait(timeout, uuid, 5), context.DeadlineExceeded)
+
+	go func() { done <- tracker.Wait(ctx, uuid, 5) }()
+	tracker.Reset()
+	require.ErrorIs(t, <-done, sherror.ErrUUIDMismatch)
+	require.ErrorIs(t, tracker.Wait(ctx, uuid, 1), sherror.ErrUUIDMismatch)
+}
+
+func TestIndexTrackerFlush(t *testing.T) {
+	tracker := NewIndexTracker()
+	uuid := tracker.Accept("", 5).GetUuid()
+	ctx := context.Background()
+
+	var flushed []uint64
+	persist := func(index uint64) {
+		flushed = append(flushed, index)
+		tracker.Persist(index)
+	}
+	reply, err := tracker.Flush(ctx, &messages.FlushRequest{AcceptedIndex: 3}, persist)
+	require.NoError(t, err)
+	require.Equal(t, &messages.FlushReply{Uuid: uuid, PersistedIndex: 3}, reply)
+	reply, err = tracker.Flush(ctx, &messages.FlushRequest{}, persist)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), reply.GetPersistedIndex())
+	require.Equal(t, []uint64{3, 5}, flushed)
+
+	_, err = tracker.Flush(ctx, &messages.FlushRequest{AcceptedIndex: 6}, persist)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	tracker.Accept("", 1)
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	_, err = tracker.Flush(timeout, &messages.FlushRequest{}, func(uint64) {})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	reply, err = tracker.Flush(ctx, &messages.FlushRequest{}, func(uint64) { tracker.Reset() })
+	require.NoError(t, err)
+	require.NotEqual(t, uuid, reply.GetUuid())
+	require.Zero(t, reply.GetPersistedIndex())
+}
+
+type trackerServer struct {
+	proto.UnimplementedProducerServer
+	tracker *IndexTracker
+}
+
+func (s trackerServer) PersistedIndex(req *messages.PersistedIndexRequest, srv proto.Producer_PersistedIndexServer) error {
+	return s.tracker.Serve(req, srv)
+}
+
+func TestIndexTrackerCursor(t *testing.T) {
+	tracker := NewIndexTracker()
+	events := func(n int) []*messages.Event { return make([]*messages.Event, n) }
+
+	tracker.AcceptBatch(&messages.PublishRequest{Events: events(2), Cursor: []byte("a1"), CursorKey: "a"}, 10)
+	tracker.AcceptBatch(&messages.PublishRequest{Events: events(2), Cursor: []byte("b1"), CursorKey: "b"}, 10)
+	// partially accepted, the cursor is not stored
+	tracker.AcceptBatch(&messages.PublishRequest{Events: events(10), Cursor: []byte("a2"), CursorKey: "a"}, 7)
+	require.Nil(t, tracker.GetCursor(&messages.GetCursorRequest{Key: "a"}).GetCursor())
+
+	tracker.Persist(3)
+	require.Equal(t, []byte("a1"), tracker.GetCursor(&messages.GetCursorRequest{Key: "a"}).GetCursor())
+	require.Nil(t, tracker.GetCursor(&messages.GetCursorRequest{Key: "b"}).GetCursor())
+	tracker.Persist(4)
+	require.Equal(t, map[string][]byte{"a": []byte("a1"), "b": []byte("b1")}, tracker.Cursors())
+
+	// the persisted cursors survive a reset, the pending ones don't
+	tracker.AcceptBatch(&messages.PublishRequest{Events: events(1), Cursor: []byte("a3"), CursorKey: "a"}, 10)
+	uuid := tracker.Reset()
+	tracker.Persist(1)
+	require.Equal(t, &messages.GetCursorReply{Uuid: uuid, Cursor: []byte("a1")}, tracker.GetCursor(&messages.GetCursorRequest{Key: "a"}))
+
+	restored := NewIndexTracker()
+	restored.RestoreCursor("a", []byte("a1"))
+	require.Equal(t, []byte("a1"), restored.GetCursor(&messages.GetCursorRequest{Key: "a"}).GetCursor())
+}
+
+func TestIndexTrackerServe(t *testing.T) {
+	tracker := NewIndexTracker()
+	tracker.Accept("", 5)
+
+	client := newBufconnClient(t, trackerServer{tracker: tracker}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.PersistedIndex(ctx, &messages.PersistedIndexRequest{PollingInterval: durationpb.New(time.Millisecond)})
+	require.NoError(t, err)
+	reply, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, tracker.UUID(), reply.GetUuid())
+	require.Zero(t, reply.GetPersistedIndex())
+
+	tracker.Persist(3)
+	reply, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), reply.GetPersistedIndex())
+
+	// a zero interval only sends the current state
+	stream, err = client.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	reply, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), reply.GetPersistedIndex())
+	_, err = stream.Recv()
+	require.Error(t, err)
+}
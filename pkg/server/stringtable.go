@@ -0,0 +1,137 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// DecodeStrings replaces the references to the string table of the request,
+// encoded by client.EncodeStrings, with the strings, and removes the table.
+// Requests without a string table are left untouched.
+//
+// It returns an InvalidArgument error when a reference is out of the table.
+func DecodeStrings(req *messages.PublishRequest) error {
+	if len(req.GetStringTable()) == 0 {
+		return nil
+	}
+	dec := stringDecoder{table: req.GetStringTable()}
+	for _, e := range req.GetEvents() {
+		if err := dec.dataStream(e.GetDataStream()); err != nil {
+			return err
+		}
+		if err := dec.structure(e.GetMetadata()); err != nil {
+			return err
+		}
+		if err := dec.structure(e.GetFields()); err != nil {
+			return err
+		}
+	}
+	req.StringTable = nil
+	return nil
+}
+
+// ServerStringDecoding returns the server options decoding the string table
+// of the PublishRequests before they reach the handler. They must come after
+// the unpacking options and before the validation options.
+func ServerStringDecoding() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if r, ok := req.(*messages.PublishRequest); ok {
+				if err := DecodeStrings(r); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}),
+	}
+}
+
+type stringDecoder struct {
+	table []string
+}
+
+func (dec stringDecoder) lookup(ref uint32) (string, error) {
+	if ref == 0 || int(ref) > len(dec.table) {
+		return "", status.Errorf(codes.InvalidArgument, "the string reference %d is not in the table of %d strings", ref, len(dec.table))
+	}
+	return dec.table[ref-1], nil
+}
+
+func (dec stringDecoder) dataStream(ds *messages.DataStream) error {
+	if ds == nil {
+		return nil
+	}
+	for _, f := range []struct {
+		ref   *uint32
+		value *string
+	}{
+		{&ds.TypeRef, &ds.Type},
+		{&ds.DatasetRef, &ds.Dataset},
+		{&ds.NamespaceRef, &ds.Namespace},
+	} {
+		if *f.ref == 0 {
+			continue
+		}
+		s, err := dec.lookup(*f.ref)
+		if err != nil {
+			return err
+		}
+		*f.value, *f.ref = s, 0
+	}
+	return nil
+}
+
+func (dec stringDecoder) structure(s *messages.Struct) error {
+	if s == nil {
+		return nil
+	}
+	for _, v := range s.GetData() {
+		if err := dec.value(v); err != nil {
+			return err
+		}
+	}
+	for ref, v := range s.GetRefData() {
+		key, err := dec.lookup(ref)
+		if err != nil {
+			return err
+		}
+		if err := dec.value(v); err != nil {
+			return err
+		}
+		if s.Data == nil {
+			s.Data = make(map[string]*messages.Value, len(s.RefData))
+		}
+		s.Data[key] = v
+	}
+	s.RefData = nil
+	return nil
+}
+
+func (dec stringDecoder) value(v *messages.Value) error {
+	switch kind := v.GetKind().(type) {
+	case *messages.Value_StringRef:
+		s, err := dec.lookup(kind.StringRef)
+		if err != nil {
+			return err
+		}
+		v.Kind = &messages.Value_StringValue{StringValue: s}
+	case *messages.Value_StructValue:
+		return dec.structure(kind.StructValue)
+	case *messages.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			if err := dec.value(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
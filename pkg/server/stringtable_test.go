@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers/testutil"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestDecodeStrings(t *testing.T) {
+	req := &messages.PublishRequest{Uuid: "uuid", Events: testutil.NewEvents(0, 20)}
+	encoded := client.EncodeStrings(req)
+	require.NotEmpty(t, encoded.GetStringTable())
+
+	require.NoError(t, DecodeStrings(encoded))
+	require.True(t, gproto.Equal(req, encoded))
+
+	// a decoded request is left untouched
+	require.NoError(t, DecodeStrings(encoded))
+	require.True(t, gproto.Equal(req, encoded))
+}
+
+func TestDecodeStringsInvalidReference(t *testing.T) {
+	for _, e := range []*messages.Event{
+		{DataStream: &messages.DataStream{DatasetRef: 2}},
+		{Fields: &messages.Struct{RefData: map[uint32]*messages.Value{3: {}}}},
+		{Metadata: &messages.Struct{Data: map[string]*messages.Value{
+			"list": {Kind: &messages.Value_ListValue{ListValue: &messages.ListValue{Values: []*messages.Value{
+				{Kind: &messages.Value_StringRef{StringRef: 0}},
+			}}}},
+		}}},
+	} {
+		req := &messages.PublishRequest{StringTable: []string{"a"}, Events: []*messages.Event{e}}
+		require.Equal(t, codes.InvalidArgument, status.Code(DecodeStrings(req)), e.String())
+	}
+}
+
+// recordRequests keeps the requests it accepts.
+type recordRequests struct {
+	acceptAll
+	requests []*messages.PublishRequest
+}
+
+func (r *recordRequests) PublishEvents(ctx context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	r.requests = append(r.requests, req)
+	return r.acceptAll.PublishEvents(ctx, req)
+}
+
+func TestServerStringDecoding(t *testing.T) {
+	var opts []grpc.ServerOption
+	opts = append(opts, ServerUnpacking(0)...)
+	opts = append(opts, ServerStringDecoding()...)
+	opts = append(opts, ServerValidation(DefaultValidationConfig())...)
+	recorder := &recordRequests{}
+	producer := newBufconnClient(t, recorder, opts,
+		grpc.WithChainUnaryInterceptor(client.StringTableInterceptor(), client.PackingInterceptor(gzip.Name)),
+	)
+
+	req := &messages.PublishRequest{Events: testutil.NewEvents(0, 10)}
+	reply, err := producer.PublishEvents(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, uint32(10), reply.GetAcceptedCount())
+	require.Len(t, recorder.requests, 1)
+	require.True(t, gproto.Equal(req, recorder.requests[0]))
+}
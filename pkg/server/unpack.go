@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Unpack replaces the compressed events of the request, packed by
+// client.Pack, with the events they hold. Requests without compressed events
+// are left untouched.
+//
+// maxBytes limits the size of the decompressed events, zero means no limit.
+// It returns an Unimplemented error when the codec isn't registered and an
+// InvalidArgument error when the request can't be unpacked.
+func Unpack(req *messages.PublishRequest, maxBytes int) error {
+	packed := req.GetCompressedEvents()
+	if packed == nil {
+		return nil
+	}
+	if len(req.GetEvents()) > 0 || len(req.GetMetrics()) > 0 || len(req.GetSignals()) > 0 || len(req.GetStringTable()) > 0 {
+		return status.Error(codes.InvalidArgument, "the request has both events and compressed events")
+	}
+	compressor := encoding.GetCompressor(packed.GetCodec())
+	if compressor == nil {
+		return status.Errorf(codes.Unimplemented, "the compressed events codec %q is not supported", packed.GetCodec())
+	}
+
+	r, err := compressor.Decompress(bytes.NewReader(packed.GetData()))
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to decompress the events: %v", err)
+	}
+	if maxBytes > 0 {
+		r = io.LimitReader(r, int64(maxBytes)+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to decompress the events: %v", err)
+	}
+	if maxBytes > 0 && len(data) > maxBytes {
+		return status.Errorf(codes.InvalidArgument, "the decompressed events are larger than %d bytes", maxBytes)
+	}
+
+	var unpacked messages.PublishRequest
+	if err := proto.Unmarshal(data, &unpacked); err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to unmarshal the events: %v", err)
+	}
+	if count := int(packed.GetCount()); count > 0 && count != len(unpacked.GetEvents()) {
+		return status.Errorf(codes.InvalidArgument, "the compressed events hold %d events, the request says %d", len(unpacked.GetEvents()), count)
+	}
+	req.Events = unpacked.GetEvents()
+	req.StringTable = unpacked.GetStringTable()
+	req.CompressedEvents = nil
+	return nil
+}
+
+// ServerUnpacking returns the server options unpacking the compressed events
+// of the PublishRequests before they reach the handler. They must come before
+// the validation options, which expect the events.
+func ServerUnpacking(maxBytes int) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if r, ok := req.(*messages.PublishRequest); ok {
+				if err := Unpack(r, maxBytes); err != nil {
+					return nil, err
+				}
+			}
+			return handler(ctx, req)
+		}),
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestUnpack(t *testing.T) {
+	events := []*messages.Event{validEvent(), validEvent()}
+	packed, err := client.Pack(&messages.PublishRequest{Uuid: "uuid", Events: events}, gzip.Name)
+	require.NoError(t, err)
+	require.Empty(t, packed.GetEvents())
+
+	req := gproto.Clone(packed).(*messages.PublishRequest)
+	require.NoError(t, Unpack(req, 0))
+	require.Nil(t, req.GetCompressedEvents())
+	require.Equal(t, "uuid", req.GetUuid())
+	require.Len(t, req.GetEvents(), 2)
+	for i, e := range req.GetEvents() {
+		require.True(t, gproto.Equal(events[i], e))
+	}
+
+	// without compressed events, nothing changes
+	require.NoError(t, Unpack(req, 0))
+	require.Len(t, req.GetEvents(), 2)
+
+	req = gproto.Clone(packed).(*messages.PublishRequest)
+	err = Unpack(req, 10)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req = gproto.Clone(packed).(*messages.PublishRequest)
+	req.Events = events
+	require.Equal(t, codes.InvalidArgument, status.Code(Unpack(req, 0)))
+
+	req = gproto.Clone(packed).(*messages.PublishRequest)
+	req.CompressedEvents.Count = 3
+	require.Equal(t, codes.InvalidArgument, status.Code(Unpack(req, 0)))
+
+	// the count is optional
+	req = gproto.Clone(packed).(*messages.PublishRequest)
+	req.CompressedEvents.Count = 0
+	require.NoError(t, Unpack(req, 0))
+	require.Len(t, req.GetEvents(), 2)
+
+	req = gproto.Clone(packed).(*messages.PublishRequest)
+	req.CompressedEvents.Codec = "unknown"
+	require.Equal(t, codes.Unimplemented, status.Code(Unpack(req, 0)))
+
+	req = gproto.Clone(packed).(*messages.PublishRequest)
+	req.CompressedEvents.Data = []byte("not gzip")
+	require.Equal(t, codes.InvalidArgument, status.Code(Unpack(req, 0)))
+}
+
+func TestServerUnpacking(t *testing.T) {
+	producer := newBufconnClient(t, acceptAll{},
+		append(ServerUnpacking(0), ServerValidation(DefaultValidationConfig())...),
+		grpc.WithUnaryInterceptor(client.PackingInterceptor(gzip.Name)),
+	)
+
+	req := &messages.PublishRequest{Events: []*messages.Event{validEvent(), validEvent(), validEvent()}}
+	reply, err := producer.PublishEvents(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, uint32(3), reply.GetAcceptedCount())
+	require.Len(t, req.GetEvents(), 3)
+	require.Nil(t, req.GetCompressedEvents())
+
+	// the validation applies to the unpacked events
+	_, err = producer.PublishEvents(context.Background(), &messages.PublishRequest{Events: []*messages.Event{{}}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,157 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package server contains helpers for implementing the shipper side of the API.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+// ValidationConfig configures the validation of PublishRequests.
+type ValidationConfig struct {
+	// MaxEvents is the maximum number of events in a request. Zero means no limit.
+	MaxEvents int
+	// MaxEventBytes is the maximum serialized size of an event. Zero means no limit.
+	MaxEventBytes int
+	// MaxBadEvents is the maximum number of rejected events reported in the
+	// status details. Zero means all of them.
+	MaxBadEvents int
+}
+
+// DefaultValidationConfig returns the default validation configuration.
+func DefaultValidationConfig() ValidationConfig {
+	return ValidationConfig{
+		MaxBadEvents: 100,
+	}
+}
+
+// Validator checks the PublishRequests received by a shipper.
+type Validator struct {
+	config ValidationConfig
+}
+
+// NewValidator creates a new validator.
+func NewValidator(config ValidationConfig) *Validator {
+	return &Validator{config: config}
+}
+
+// ServerValidation returns the server options rejecting invalid PublishRequests.
+func ServerValidation(config ValidationConfig) []grpc.ServerOption {
+	v := NewValidator(config)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(v.UnaryServerInterceptor()),
+	}
+}
+
+// UnaryServerInterceptor rejects invalid PublishRequests before they reach
+// the handler. Other calls are passed through.
+func (v *Validator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if r, ok := req.(*messages.PublishRequest); ok {
+			if err := v.Validate(r); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Validate returns an InvalidArgument error if the request is invalid.
+//
+// Every event, metric sample and signal is checked with its Validate method,
+// the rules of the API shared with the clients, and the rejected ones are
+// attached to the status as BadEventDetails. When an event is too large the
+// error has the EVENT_TOO_LARGE reason, otherwise when a data stream is
+// invalid it has the INVALID_DATA_STREAM reason. The rules of the request
+// itself are checked by PublishRequest.Validate once all its events are
+// valid.
+func (v *Validator) Validate(req *messages.PublishRequest) error {
+	n := req.Len()
+	if v.config.MaxEvents > 0 && n > v.config.MaxEvents {
+		return status.Errorf(codes.InvalidArgument, "the request has %d events, the maximum is %d", n, v.config.MaxEvents)
+	}
+
+	items := make([]validatable, 0, n)
+	for _, e := range req.GetEvents() {
+		items = append(items, e)
+	}
+	for _, m := range req.GetMetrics() {
+		items = append(items, m)
+	}
+	for _, u := range req.GetSignals() {
+		if e, ok := u.Unwrap().(validatable); ok {
+			items = append(items, e)
+		} else {
+			// an empty EventUnion
+			items = append(items, u)
+		}
+	}
+
+	var (
+		bad       []*messages.BadEventDetail
+		rejected  int
+		tooLarge  bool
+		badStream bool
+	)
+	report := func(d *messages.BadEventDetail) {
+		if v.config.MaxBadEvents == 0 || len(bad) < v.config.MaxBadEvents {
+			bad = append(bad, d)
+		}
+	}
+	for i, e := range items {
+		invalid := false
+		if v.config.MaxEventBytes > 0 {
+			if size := proto.Size(e); size > v.config.MaxEventBytes {
+				report(sherror.BadEvent(i, "", fmt.Sprintf("the event is %d bytes, the maximum is %d", size, v.config.MaxEventBytes)))
+				invalid, tooLarge = true, true
+			}
+		}
+		var verr *messages.ValidationError
+		if errors.As(e.Validate(), &verr) {
+			report(sherror.BadEvent(i, verr.Field, verr.Reason))
+			invalid = true
+			if strings.HasPrefix(verr.Field, "data_stream") {
+				badStream = true
+			}
+		}
+		if invalid {
+			rejected++
+		}
+	}
+	if rejected == 0 {
+		if err := req.Validate(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil
+	}
+
+	message := fmt.Sprintf("%d of %d events are invalid", rejected, n)
+	switch {
+	case tooLarge:
+		return sherror.New(sherror.ReasonEventTooLarge, "%s", message).WithBadEvents(bad...)
+	case badStream:
+		return sherror.New(sherror.ReasonInvalidDataStream, "%s", message).WithBadEvents(bad...)
+	default:
+		return sherror.InvalidEvents(message, bad...)
+	}
+}
+
+// validatable is an event, a metric sample, a span or an empty signal,
+// checked by its Validate method.
+type validatable interface {
+	proto.Message
+	Validate() error
+}
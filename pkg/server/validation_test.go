@@ -0,0 +1,181 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+type acceptAll struct {
+	proto.UnimplementedProducerServer
+}
+
+func (acceptAll) PublishEvents(_ context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	return &messages.PublishReply{Uuid: "uuid", AcceptedCount: uint32(len(req.GetEvents()))}, nil
+}
+
+// newBufconnClient serves srv with serverOpts over bufconn and returns a
+// client connected to it with dialOpts. Both are stopped with the test.
+func newBufconnClient(t *testing.T, srv proto.ProducerServer, serverOpts []grpc.ServerOption, dialOpts ...grpc.DialOption) proto.ProducerClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(serverOpts...)
+	proto.RegisterProducerServer(server, srv)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	dialOpts = append(dialOpts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	conn, err := grpc.Dial("bufnet", dialOpts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProducerClient(conn)
+}
+
+func validEvent() *messages.Event {
+	return &messages.Event{
+		Timestamp:  timestamppb.Now(),
+		DataStream: &messages.DataStream{Type: "logs", Dataset: "nginx.access", Namespace: "default"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	v := NewValidator(DefaultValidationConfig())
+
+	require.NoError(t, v.Validate(&messages.PublishRequest{Events: []*messages.Event{validEvent(), validEvent()}}))
+	require.Equal(t, codes.InvalidArgument, status.Code(v.Validate(&messages.PublishRequest{})))
+
+	noTimestamp := validEvent()
+	noTimestamp.Timestamp = nil
+	err := v.Validate(&messages.PublishRequest{Events: []*messages.Event{validEvent(), noTimestamp}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Nil(t, sherror.FromError(err))
+	bad := sherror.BadEvents(err)
+	require.Len(t, bad, 1)
+	require.Equal(t, uint32(1), bad[0].GetEventIndex())
+	require.Equal(t, "timestamp", bad[0].GetField())
+
+	noStream := validEvent()
+	noStream.DataStream = nil
+	err = v.Validate(&messages.PublishRequest{Events: []*messages.Event{noStream}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, "data_stream", sherror.BadEvents(err)[0].GetField())
+
+	for _, ds := range []*messages.DataStream{
+		{Dataset: "nginx.access", Namespace: "default"},
+		{Type: "logs", Namespace: "default"},
+		{Type: "logs", Dataset: "nginx.access"},
+		{Type: "Logs", Dataset: "nginx.access", Namespace: "default"},
+		{Type: "logs", Dataset: "nginx-access", Namespace: "default"},
+		{Type: "logs", Dataset: "nginx.access", Namespace: "a b"},
+		{Type: "logs", Dataset: "nginx.access", Namespace: "_default"},
+	} {
+		e := validEvent()
+		e.DataStream = ds
+		err := v.Validate(&messages.PublishRequest{Events: []*messages.Event{e}})
+		require.True(t, errors.Is(sherror.FromError(err), sherror.ErrInvalidDataStream), ds.String())
+		require.Len(t, sherror.BadEvents(err), 1)
+	}
+
+	raw := validEvent()
+	raw.Timestamp, raw.TimestampRaw = nil, "2022-01-02T03:04:05Z"
+	require.NoError(t, v.Validate(&messages.PublishRequest{Events: []*messages.Event{raw}}))
+
+	create := validEvent()
+	create.OpType = string(messages.OpTypeCreate)
+	require.NoError(t, v.Validate(&messages.PublishRequest{Events: []*messages.Event{create}}))
+	deletion := validEvent()
+	deletion.OpType = string(messages.OpTypeDelete)
+	err = v.Validate(&messages.PublishRequest{Events: []*messages.Event{deletion}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, "op_type", sherror.BadEvents(err)[0].GetField())
+
+	// the rules of the request are checked too
+	err = v.Validate(&messages.PublishRequest{Events: []*messages.Event{validEvent()}, Cursor: make([]byte, messages.MaxCursorBytes+1)})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Contains(t, status.Convert(err).Message(), "cursor")
+}
+
+func TestValidateLimits(t *testing.T) {
+	config := DefaultValidationConfig()
+	config.MaxEvents = 2
+	config.MaxEventBytes = 100
+	config.MaxBadEvents = 1
+	v := NewValidator(config)
+
+	err := v.Validate(&messages.PublishRequest{Events: []*messages.Event{validEvent(), validEvent(), validEvent()}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	large := validEvent()
+	large.Fields = &messages.Struct{Data: map[string]*messages.Value{"message": helpers.NewStringValue(string(make([]byte, 200)))}}
+	noTimestamp := validEvent()
+	noTimestamp.Timestamp = nil
+	err = v.Validate(&messages.PublishRequest{Events: []*messages.Event{large, noTimestamp}})
+	require.True(t, errors.Is(sherror.FromError(err), sherror.ErrEventTooLarge))
+	require.Len(t, sherror.BadEvents(err), 1)
+	require.Equal(t, "2 of 2 events are invalid", status.Convert(err).Message())
+}
+
+func TestValidateSignals(t *testing.T) {
+	v := NewValidator(DefaultValidationConfig())
+	metric := &messages.MetricEvent{Timestamp: timestamppb.Now(), Name: "cpu"}
+	span := &messages.Span{
+		TraceId:   make([]byte, messages.TraceIDSize),
+		SpanId:    make([]byte, messages.SpanIDSize),
+		Name:      "GET /",
+		StartTime: timestamppb.Now(),
+	}
+
+	require.NoError(t, v.Validate(&messages.PublishRequest{Metrics: []*messages.MetricEvent{metric}}))
+	require.NoError(t, v.Validate(&messages.PublishRequest{Signals: []*messages.EventUnion{
+		messages.WrapEvent(validEvent()), messages.WrapMetric(metric), messages.WrapSpan(span),
+	}}))
+
+	err := v.Validate(&messages.PublishRequest{Events: []*messages.Event{validEvent()}, Metrics: []*messages.MetricEvent{metric}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = v.Validate(&messages.PublishRequest{Signals: []*messages.EventUnion{
+		messages.WrapMetric(&messages.MetricEvent{Timestamp: timestamppb.Now()}),
+		messages.WrapSpan(&messages.Span{Name: "GET /"}),
+		{},
+	}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	var fields []string
+	for _, b := range sherror.BadEvents(err) {
+		fields = append(fields, b.GetField())
+	}
+	require.Equal(t, []string{"name", "trace_id", "kind"}, fields)
+}
+
+func TestServerValidation(t *testing.T) {
+	client := newBufconnClient(t, acceptAll{}, ServerValidation(DefaultValidationConfig()))
+
+	_, err := client.PublishEvents(context.Background(), &messages.PublishRequest{Events: []*messages.Event{validEvent()}})
+	require.NoError(t, err)
+
+	_, err = client.PublishEvents(context.Background(), &messages.PublishRequest{Events: []*messages.Event{{}}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, "timestamp", sherror.BadEvents(err)[0].GetField())
+}
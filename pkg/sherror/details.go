@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sherror
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// BadEvent returns the detail of a rejected event.
+func BadEvent(index int, field, reason string) *messages.BadEventDetail {
+	return &messages.BadEventDetail{
+		EventIndex: uint32(index),
+		Field:      field,
+		Reason:     reason,
+	}
+}
+
+// InvalidEvents returns an InvalidArgument error for a PublishRequest with
+// the given rejected events attached to its status details.
+func InvalidEvents(message string, bad ...*messages.BadEventDetail) error {
+	return withBadEvents(status.New(codes.InvalidArgument, message), bad).Err()
+}
+
+// BadEvents returns the rejected events attached to the status of err.
+func BadEvents(err error) []*messages.BadEventDetail {
+	if e := FromError(err); e != nil && len(e.BadEvents) > 0 {
+		return e.BadEvents
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	return badEventDetails(st)
+}
+
+func badEventDetails(st *status.Status) []*messages.BadEventDetail {
+	var bad []*messages.BadEventDetail
+	for _, d := range st.Details() {
+		if b, ok := d.(*messages.BadEventDetail); ok {
+			bad = append(bad, b)
+		}
+	}
+	return bad
+}
+
+func withBadEvents(st *status.Status, bad []*messages.BadEventDetail) *status.Status {
+	for _, b := range bad {
+		withDetail, err := st.WithDetails(b)
+		if err != nil {
+			return st
+		}
+		st = withDetail
+	}
+	return st
+}
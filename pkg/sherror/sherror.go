@@ -0,0 +1,201 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package sherror contains the typed errors returned by the shipper and
+// their mapping to gRPC statuses.
+//
+// Every error is sent as a gRPC status with an ErrorInfo detail in the shipper
+// domain, so clients can check the condition without matching error messages:
+//
+//	if errors.Is(sherror.FromError(err), sherror.ErrQueueFull) {
+//		...
+//	}
+package sherror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Domain is the ErrorInfo domain of the shipper errors.
+const Domain = "elastic-agent-shipper"
+
+// Reason identifies a shipper condition, it's the ErrorInfo reason of the error.
+type Reason string
+
+const (
+	// ReasonUUIDMismatch means the request uuid is not the current shipper uuid.
+	ReasonUUIDMismatch Reason = "UUID_MISMATCH"
+	// ReasonQueueFull means the shipper queue has no room for the events.
+	ReasonQueueFull Reason = "QUEUE_FULL"
+	// ReasonEventTooLarge means an event exceeds the size accepted by the shipper.
+	ReasonEventTooLarge Reason = "EVENT_TOO_LARGE"
+	// ReasonInvalidDataStream means an event has an invalid data stream.
+	ReasonInvalidDataStream Reason = "INVALID_DATA_STREAM"
+	// ReasonShuttingDown means the shipper is shutting down and no longer accepts events.
+	ReasonShuttingDown Reason = "SHUTTING_DOWN"
+)
+
+var codesByReason = map[Reason]codes.Code{
+	ReasonUUIDMismatch:      codes.FailedPrecondition,
+	ReasonQueueFull:         codes.ResourceExhausted,
+	ReasonEventTooLarge:     codes.InvalidArgument,
+	ReasonInvalidDataStream: codes.InvalidArgument,
+	ReasonShuttingDown:      codes.Unavailable,
+}
+
+// Sentinel errors to compare with errors.Is, they match any Error with the same reason.
+var (
+	ErrUUIDMismatch      = &Error{Reason: ReasonUUIDMismatch, Message: "shipper uuid mismatch"}
+	ErrQueueFull         = &Error{Reason: ReasonQueueFull, Message: "shipper queue is full"}
+	ErrEventTooLarge     = &Error{Reason: ReasonEventTooLarge, Message: "event is too large"}
+	ErrInvalidDataStream = &Error{Reason: ReasonInvalidDataStream, Message: "invalid data stream"}
+	ErrShuttingDown      = &Error{Reason: ReasonShuttingDown, Message: "shipper is shutting down"}
+)
+
+// Error is a shipper condition.
+type Error struct {
+	Reason  Reason
+	Message string
+	// Metadata is additional information about the condition, e.g. the
+	// current uuid on a uuid mismatch.
+	Metadata map[string]string
+	// BadEvents are the events of the request that caused the error, if any.
+	BadEvents []*messages.BadEventDetail
+	// RetryDelay, if set, is sent as a RetryInfo detail telling the client
+	// how long to wait before retrying.
+	RetryDelay time.Duration
+}
+
+// New creates a new error with the given reason and a formatted message.
+func New(reason Reason, format string, args ...interface{}) *Error {
+	return &Error{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithMetadata returns a copy of the error with the key set in its metadata.
+func (e *Error) WithMetadata(key, value string) *Error {
+	md := make(map[string]string, len(e.Metadata)+1)
+	for k, v := range e.Metadata {
+		md[k] = v
+	}
+	md[key] = value
+	c := *e
+	c.Metadata = md
+	return &c
+}
+
+// WithBadEvents returns a copy of the error with the rejected events attached.
+func (e *Error) WithBadEvents(bad ...*messages.BadEventDetail) *Error {
+	events := make([]*messages.BadEventDetail, 0, len(e.BadEvents)+len(bad))
+	events = append(append(events, e.BadEvents...), bad...)
+	c := *e
+	c.BadEvents = events
+	return &c
+}
+
+// WithRetryDelay returns a copy of the error asking the client to wait for d before retrying.
+func (e *Error) WithRetryDelay(d time.Duration) *Error {
+	c := *e
+	c.RetryDelay = d
+	return &c
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is returns true if target is an Error with the same reason.
+func (e *Error) Is(target error) bool {
+	if e == nil {
+		return false
+	}
+	var t *Error
+	return errors.As(target, &t) && t.Reason == e.Reason
+}
+
+// Code returns the gRPC code of the error, Unknown for an unknown reason.
+func (e *Error) Code() codes.Code {
+	if c, ok := codesByReason[e.Reason]; ok {
+		return c
+	}
+	return codes.Unknown
+}
+
+// GRPCStatus returns the status sent for the error, with an ErrorInfo detail,
+// a RetryInfo detail if there is a retry delay, and the rejected events.
+// It lets gRPC servers return the Error directly.
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(e.Code(), e.Message)
+	withInfo, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   string(e.Reason),
+		Domain:   Domain,
+		Metadata: e.Metadata,
+	})
+	if err != nil {
+		return st
+	}
+	if e.RetryDelay > 0 {
+		withRetry, err := withInfo.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryDelay)})
+		if err == nil {
+			withInfo = withRetry
+		}
+	}
+	return withBadEvents(withInfo, e.BadEvents)
+}
+
+// FromError returns the shipper error contained in err, either an Error in
+// its chain or a gRPC status with a shipper ErrorInfo detail. It returns nil
+// if there is none.
+func FromError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != Domain {
+			continue
+		}
+		return &Error{
+			Reason:     Reason(info.GetReason()),
+			Message:    st.Message(),
+			Metadata:   info.GetMetadata(),
+			BadEvents:  badEventDetails(st),
+			RetryDelay: retryDelay(st),
+		}
+	}
+	return nil
+}
+
+func retryDelay(st *status.Status) time.Duration {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
+
+// ReasonOf returns the reason of the shipper error contained in err, empty if there is none.
+func ReasonOf(err error) Reason {
+	if e := FromError(err); e != nil {
+		return e.Reason
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sherror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestStatusRoundTrip(t *testing.T) {
+	cases := map[*Error]codes.Code{
+		ErrUUIDMismatch:      codes.FailedPrecondition,
+		ErrQueueFull:         codes.ResourceExhausted,
+		ErrEventTooLarge:     codes.InvalidArgument,
+		ErrInvalidDataStream: codes.InvalidArgument,
+		ErrShuttingDown:      codes.Unavailable,
+	}
+	for sentinel, code := range cases {
+		t.Run(string(sentinel.Reason), func(t *testing.T) {
+			sent := New(sentinel.Reason, "details").WithMetadata("uuid", "abc")
+			// what a client receives from the server
+			received := status.Convert(sent).Err()
+			require.Equal(t, code, status.Code(received))
+
+			e := FromError(received)
+			require.NotNil(t, e)
+			require.Equal(t, sentinel.Reason, e.Reason)
+			require.Equal(t, "details", e.Message)
+			require.Equal(t, map[string]string{"uuid": "abc"}, e.Metadata)
+			require.ErrorIs(t, e, sentinel)
+			require.Equal(t, sentinel.Reason, ReasonOf(received))
+		})
+	}
+}
+
+func TestFromError(t *testing.T) {
+	require.Nil(t, FromError(nil))
+	require.Nil(t, FromError(errors.New("plain")))
+	require.Nil(t, FromError(status.Error(codes.Unavailable, "no details")))
+	require.False(t, errors.Is(FromError(nil), ErrQueueFull))
+	require.Empty(t, ReasonOf(errors.New("plain")))
+
+	wrapped := fmt.Errorf("publishing: %w", ErrQueueFull)
+	require.Equal(t, ErrQueueFull, FromError(wrapped))
+	require.ErrorIs(t, wrapped, ErrQueueFull)
+	require.False(t, errors.Is(wrapped, ErrShuttingDown))
+}
+
+func TestBadEvents(t *testing.T) {
+	bad := []*messages.BadEventDetail{
+		BadEvent(0, "timestamp", "missing timestamp"),
+		BadEvent(3, "data_stream.type", "invalid type"),
+	}
+
+	received := status.Convert(InvalidEvents("2 invalid events", bad...)).Err()
+	require.Equal(t, codes.InvalidArgument, status.Code(received))
+	require.Nil(t, FromError(received))
+	got := BadEvents(received)
+	require.Len(t, got, 2)
+	for i := range bad {
+		require.True(t, proto.Equal(bad[i], got[i]))
+	}
+
+	sent := New(ReasonEventTooLarge, "event too large").WithBadEvents(BadEvent(1, "", "1MB over the limit"))
+	received = status.Convert(sent).Err()
+	e := FromError(received)
+	require.ErrorIs(t, e, ErrEventTooLarge)
+	require.Len(t, e.BadEvents, 1)
+	require.Equal(t, uint32(1), BadEvents(received)[0].GetEventIndex())
+
+	require.Nil(t, BadEvents(errors.New("plain")))
+}
+
+func TestRetryDelay(t *testing.T) {
+	received := status.Convert(ErrQueueFull.WithRetryDelay(time.Second)).Err()
+	require.Equal(t, codes.ResourceExhausted, status.Code(received))
+	require.Equal(t, time.Second, FromError(received).RetryDelay)
+	require.Zero(t, ErrQueueFull.RetryDelay)
+}
@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package shipperuuid handles the uuid identifying a shipper process, so that
+// clients and servers apply the contract described in publish.proto the same way.
+package shipperuuid
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+// New returns a new random version 4 uuid.
+func New() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate a uuid: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// LoadOrCreate returns the uuid stored in the file, or generates a new one
+// and stores it if the file does not exist.
+//
+// A shipper keeps its uuid across restarts only when the events it accepted
+// survive them too, e.g. with a disk queue. Otherwise it must use New.
+func LoadOrCreate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		uuid := strings.TrimSpace(string(data))
+		if uuid == "" {
+			return "", fmt.Errorf("the uuid file %s is empty", path)
+		}
+		return uuid, nil
+	case !errors.Is(err, os.ErrNotExist):
+		return "", fmt.Errorf("failed to read the uuid: %w", err)
+	}
+
+	uuid := New()
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", fmt.Errorf("failed to create the uuid directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(uuid+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("failed to store the uuid: %w", err)
+	}
+	return uuid, nil
+}
+
+// Accepts returns true if a shipper with the current uuid may accept the
+// events of a request sent with requestUUID. A request without uuid is always accepted.
+func Accepts(current, requestUUID string) bool {
+	return requestUUID == "" || requestUUID == current
+}
+
+// Restarted returns true if a reply with replyUUID shows that the shipper
+// restarted since requestUUID was obtained. A request without uuid never
+// detects a restart.
+func Restarted(requestUUID, replyUUID string) bool {
+	return requestUUID != "" && replyUUID != requestUUID
+}
+
+// CheckReply checks the reply to a request sent with requestUUID. It returns
+// an error matching sherror.ErrUUIDMismatch if the shipper restarted, and
+// a contract violation error if it accepted events anyway.
+func CheckReply(requestUUID string, reply *messages.PublishReply) error {
+	if !Restarted(requestUUID, reply.GetUuid()) {
+		return nil
+	}
+	if reply.GetAcceptedCount() != 0 {
+		return fmt.Errorf("the shipper accepted %d events sent with uuid %s while its uuid is %s",
+			reply.GetAcceptedCount(), requestUUID, reply.GetUuid())
+	}
+	return sherror.New(sherror.ReasonUUIDMismatch, "the shipper uuid changed from %s to %s", requestUUID, reply.GetUuid()).
+		WithMetadata("uuid", reply.GetUuid())
+}
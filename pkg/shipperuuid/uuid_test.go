@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package shipperuuid
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/sherror"
+)
+
+func TestNew(t *testing.T) {
+	uuid := New()
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+	require.NotEqual(t, uuid, New())
+}
+
+func TestLoadOrCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "shipper.uuid")
+	uuid, err := LoadOrCreate(path)
+	require.NoError(t, err)
+
+	loaded, err := LoadOrCreate(path)
+	require.NoError(t, err)
+	require.Equal(t, uuid, loaded)
+
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	_, err = LoadOrCreate(path)
+	require.Error(t, err)
+}
+
+func TestContract(t *testing.T) {
+	require.True(t, Accepts("a", ""))
+	require.True(t, Accepts("a", "a"))
+	require.False(t, Accepts("a", "b"))
+
+	require.False(t, Restarted("", "a"))
+	require.False(t, Restarted("a", "a"))
+	require.True(t, Restarted("a", "b"))
+
+	require.NoError(t, CheckReply("", &messages.PublishReply{Uuid: "a", AcceptedCount: 1}))
+	require.NoError(t, CheckReply("a", &messages.PublishReply{Uuid: "a", AcceptedCount: 1}))
+
+	err := CheckReply("a", &messages.PublishReply{Uuid: "b"})
+	require.ErrorIs(t, err, sherror.ErrUUIDMismatch)
+	require.Equal(t, "b", sherror.FromError(err).Metadata["uuid"])
+
+	err = CheckReply("a", &messages.PublishReply{Uuid: "b", AcceptedCount: 1})
+	require.Error(t, err)
+	require.NotErrorIs(t, err, sherror.ErrUUIDMismatch)
+}
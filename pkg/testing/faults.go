@@ -0,0 +1,159 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package testing
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Faults holds the failures injected into the calls of a MockShipper.
+// Every fault applies to the next calls, in the order they are received,
+// so that tests are deterministic.
+type Faults struct {
+	mu sync.Mutex
+	// errors returned by the next PublishEvents calls
+	errors []error
+	// maximum number of events accepted by the next PublishEvents calls
+	accepts []int
+	// restart the shipper before the next PublishEvents calls
+	restarts int
+	// persistDelay delays persisting the accepted events
+	persistDelay time.Duration
+}
+
+// FailNext makes the next n PublishEvents calls fail with err.
+func (f *Faults) FailNext(n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < n; i++ {
+		f.errors = append(f.errors, err)
+	}
+}
+
+// ResourceExhausted makes the next n PublishEvents calls fail with a
+// ResourceExhausted error, as a shipper under pressure does.
+func (f *Faults) ResourceExhausted(n int) {
+	f.FailNext(n, status.Error(codes.ResourceExhausted, "injected: the shipper queue is full"))
+}
+
+// AcceptAtMost makes the next PublishEvents calls accept at most the given
+// number of events each, one call per value.
+func (f *Faults) AcceptAtMost(counts ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.accepts = append(f.accepts, counts...)
+}
+
+// RestartBeforeNext makes the shipper restart right before handling the
+// next PublishEvents call.
+func (f *Faults) RestartBeforeNext() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.restarts++
+}
+
+// DelayPersistence makes the shipper persist every accepted batch d after
+// accepting it, instead of following the PersistInterval. Zero disables it.
+// It has no effect with ManualPersist.
+func (f *Faults) DelayPersistence(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.persistDelay = d
+}
+
+// Reset removes all the pending faults.
+func (f *Faults) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = nil
+	f.accepts = nil
+	f.restarts = 0
+	f.persistDelay = 0
+}
+
+// nextPublish pops the faults of the next PublishEvents call. maxAccept is
+// negative if there is no limit.
+func (f *Faults) nextPublish() (maxAccept int, restart bool, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	maxAccept = -1
+	if f.restarts > 0 {
+		f.restarts--
+		restart = true
+	}
+	if len(f.errors) > 0 {
+		err = f.errors[0]
+		f.errors = f.errors[1:]
+		return maxAccept, restart, err
+	}
+	if len(f.accepts) > 0 {
+		maxAccept = f.accepts[0]
+		f.accepts = f.accepts[1:]
+	}
+	return maxAccept, restart, nil
+}
+
+func (f *Faults) delay() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.persistDelay
+}
+
+// DropConnections closes all the client connections to the mock shipper,
+// as a network failure would. The shipper keeps serving new connections.
+func (m *MockShipper) DropConnections() {
+	m.lis.closeConns()
+}
+
+// trackingListener keeps the accepted connections so they can be dropped.
+type trackingListener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func newTrackingListener(l net.Listener) *trackingListener {
+	return &trackingListener{Listener: l, conns: map[net.Conn]struct{}{}}
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	tc := &trackedConn{Conn: c, l: l}
+	l.conns[tc] = struct{}{}
+	return tc, nil
+}
+
+func (l *trackingListener) closeConns() {
+	l.mu.Lock()
+	conns := l.conns
+	l.conns = map[net.Conn]struct{}{}
+	l.mu.Unlock()
+	for c := range conns {
+		c.Close()
+	}
+}
+
+type trackedConn struct {
+	net.Conn
+	l *trackingListener
+}
+
+func (c *trackedConn) Close() error {
+	c.l.mu.Lock()
+	delete(c.l.conns, c)
+	c.l.mu.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,95 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestFaults(t *testing.T) {
+	m := StartMockShipper(MockConfig{})
+	defer m.Stop()
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	m.Faults().ResourceExhausted(2)
+	m.Faults().AcceptAtMost(1)
+	for i := 0; i < 2; i++ {
+		_, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(2)})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	}
+	reply, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), reply.GetAcceptedCount())
+	reply, err = producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+
+	uuid := m.UUID()
+	m.Faults().RestartBeforeNext()
+	reply, err = producer.PublishEvents(ctx, &messages.PublishRequest{Uuid: uuid, Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Zero(t, reply.GetAcceptedCount())
+	require.NotEqual(t, uuid, reply.GetUuid())
+	// the events were persisted as soon as they were accepted
+	require.Len(t, m.Events(), 3)
+
+	m.Faults().FailNext(1, status.Error(codes.Internal, "boom"))
+	m.Faults().Reset()
+	_, err = producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+}
+
+func TestFaultsDelayPersistence(t *testing.T) {
+	m := StartMockShipper(MockConfig{})
+	defer m.Stop()
+	producer := dial(t, m)
+
+	m.Faults().DelayPersistence(50 * time.Millisecond)
+	reply, err := producer.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	require.Zero(t, m.tracker.Persisted().GetPersistedIndex())
+	require.Eventually(t, func() bool {
+		return m.tracker.Persisted().GetPersistedIndex() == reply.GetAcceptedIndex()
+	}, time.Second, time.Millisecond)
+
+	// delayed persistence is dropped on restart
+	_, err = producer.PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(2)})
+	require.NoError(t, err)
+	m.Restart()
+	time.Sleep(100 * time.Millisecond)
+	require.Zero(t, m.tracker.Persisted().GetPersistedIndex())
+	require.Len(t, m.Events(), 2)
+}
+
+func TestDropConnections(t *testing.T) {
+	m := StartMockShipper(MockConfig{})
+	defer m.Stop()
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	stream, err := producer.PersistedIndex(ctx, &messages.PersistedIndexRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	m.DropConnections()
+	_, err = stream.Recv()
+	require.Error(t, err)
+
+	// the client reconnects
+	require.Eventually(t, func() bool {
+		_, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(1)})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
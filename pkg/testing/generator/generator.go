@@ -0,0 +1,166 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package generator produces synthetic events for benchmarks and load tests.
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// Config describes the shape of the generated events.
+type Config struct {
+	// Seed makes the generated events reproducible.
+	Seed int64
+	// Fields is the number of fields of every object.
+	Fields int
+	// Depth is the number of nested objects below the root of the document.
+	Depth int
+	// StringSize is the length of the string values.
+	StringSize int
+	// Cardinality is the number of distinct values of every string field,
+	// zero means every value is unique.
+	Cardinality int
+	// Start is the timestamp of the first event, the next events are one
+	// millisecond apart.
+	Start time.Time
+}
+
+// DefaultConfig returns a config producing small, typical log events.
+func DefaultConfig() Config {
+	return Config{
+		Seed:        1,
+		Fields:      10,
+		Depth:       2,
+		StringSize:  16,
+		Cardinality: 100,
+		Start:       time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Generator produces events of a given shape. It is not safe for concurrent use.
+type Generator struct {
+	config Config
+	rand   *rand.Rand
+	// pool holds the string values when the cardinality is limited
+	pool  []string
+	count int
+}
+
+// New returns a generator of events with the given shape.
+func New(config Config) *Generator {
+	g := &Generator{
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)), //nolint:gosec // not used for security
+	}
+	for i := 0; i < config.Cardinality; i++ {
+		g.pool = append(g.pool, g.randomString())
+	}
+	return g
+}
+
+// Document returns the next document.
+func (g *Generator) Document() map[string]interface{} {
+	doc := g.object(g.config.Depth)
+	doc["@timestamp"] = g.timestamp().Format(time.RFC3339Nano)
+	g.count++
+	return doc
+}
+
+// Event returns the next event, the document is stored in its fields.
+func (g *Generator) Event() *messages.Event {
+	ts := g.timestamp()
+	fields, err := helpers.NewStruct(g.object(g.config.Depth))
+	if err != nil {
+		// all the generated values are supported
+		panic(err)
+	}
+	g.count++
+	return &messages.Event{
+		Timestamp: timestamppb.New(ts),
+		Source: &messages.Source{
+			InputId:  "generator",
+			StreamId: "generator-" + strconv.Itoa(g.count%4),
+		},
+		DataStream: &messages.DataStream{
+			Type:      "logs",
+			Dataset:   "generator",
+			Namespace: "default",
+		},
+		Fields: fields,
+	}
+}
+
+// Events returns the next n events.
+func (g *Generator) Events(n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = g.Event()
+	}
+	return events
+}
+
+// WriteNDJSON writes the next n documents to w, one per line.
+func (g *Generator) WriteNDJSON(w io.Writer, n int) error {
+	enc := json.NewEncoder(w)
+	for i := 0; i < n; i++ {
+		if err := enc.Encode(g.Document()); err != nil {
+			return fmt.Errorf("failed to write the document: %w", err)
+		}
+	}
+	return nil
+}
+
+func (g *Generator) timestamp() time.Time {
+	return g.config.Start.Add(time.Duration(g.count) * time.Millisecond)
+}
+
+// object returns an object with the configured number of fields, the first
+// one is a nested object until the depth is reached.
+func (g *Generator) object(depth int) map[string]interface{} {
+	obj := make(map[string]interface{}, g.config.Fields)
+	for i := 0; i < g.config.Fields; i++ {
+		name := "field_" + strconv.Itoa(i)
+		if i == 0 && depth > 0 {
+			obj[name] = g.object(depth - 1)
+			continue
+		}
+		switch i % 4 {
+		case 0, 1:
+			obj[name] = g.string()
+		case 2:
+			obj[name] = g.rand.Int63n(1 << 32)
+		case 3:
+			obj[name] = g.rand.Float64()
+		}
+	}
+	return obj
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func (g *Generator) string() string {
+	if len(g.pool) > 0 {
+		return g.pool[g.rand.Intn(len(g.pool))]
+	}
+	return g.randomString()
+}
+
+func (g *Generator) randomString() string {
+	b := make([]byte, g.config.StringSize)
+	for i := range b {
+		b[i] = letters[g.rand.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGenerator(t *testing.T) {
+	config := DefaultConfig()
+	config.Fields = 5
+	config.Depth = 1
+	config.StringSize = 8
+	config.Cardinality = 2
+
+	doc := New(config).Document()
+	require.Len(t, doc, 6)
+	require.Equal(t, "2022-01-01T00:00:00Z", doc["@timestamp"])
+	nested, ok := doc["field_0"].(map[string]interface{})
+	require.True(t, ok)
+	require.Len(t, nested, 5)
+	require.Len(t, nested["field_1"], 8)
+	require.IsType(t, int64(0), nested["field_2"])
+	require.IsType(t, float64(0), nested["field_3"])
+
+	// the string values come from a pool of the configured size
+	g := New(config)
+	values := map[interface{}]bool{}
+	for i := 0; i < 100; i++ {
+		values[g.Document()["field_1"]] = true
+	}
+	require.Len(t, values, 2)
+}
+
+func TestGeneratorIsReproducible(t *testing.T) {
+	a, b := New(DefaultConfig()), New(DefaultConfig())
+	for i := 0; i < 10; i++ {
+		require.True(t, proto.Equal(a.Event(), b.Event()))
+	}
+
+	config := DefaultConfig()
+	config.Seed = 2
+	require.False(t, proto.Equal(New(DefaultConfig()).Event(), New(config).Event()))
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, New(DefaultConfig()).WriteNDJSON(&buf, 3))
+
+	var lines int
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+		lines++
+	}
+	require.Equal(t, 3, lines)
+}
@@ -0,0 +1,282 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package testing contains an in-memory shipper to test inputs against.
+package testing
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/server"
+)
+
+const bufferSize = 1024 * 1024
+
+// MockConfig configures a MockShipper.
+type MockConfig struct {
+	// QueueSize is the maximum number of accepted events that are not
+	// persisted yet, requests are partially accepted beyond it. Zero means no limit.
+	QueueSize int
+	// PersistInterval is how often the accepted events are persisted. Zero
+	// means they are persisted as soon as they are accepted.
+	PersistInterval time.Duration
+	// ManualPersist disables persisting the events automatically, they are
+	// only persisted by calling Persist. Flush calls wait until they are.
+	ManualPersist bool
+	// GlobalMetadata is the initial global metadata, version 1, if set.
+	GlobalMetadata *messages.Struct
+}
+
+// MockShipper is an in-memory shipper served over bufconn. It accepts
+// events, persists them on a schedule, and follows the uuid contract.
+type MockShipper struct {
+	proto.UnimplementedProducerServer
+
+	config  MockConfig
+	tracker *server.IndexTracker
+	global  *server.GlobalMetadata
+	faults  Faults
+	bufconn *bufconn.Listener
+	lis     *trackingListener
+	srv     *grpc.Server
+	done    chan struct{}
+	wg      sync.WaitGroup
+
+	mu sync.Mutex
+	// accepted holds the accepted events and metric samples, in order
+	accepted []gproto.Message
+}
+
+// StartMockShipper starts a new mock shipper with the given server options.
+func StartMockShipper(config MockConfig, opts ...grpc.ServerOption) *MockShipper {
+	m := &MockShipper{
+		config:  config,
+		tracker: server.NewIndexTracker(),
+		global:  server.NewGlobalMetadata(),
+		bufconn: bufconn.Listen(bufferSize),
+		srv:     grpc.NewServer(opts...),
+		done:    make(chan struct{}),
+	}
+	if config.GlobalMetadata != nil {
+		m.global.Set(config.GlobalMetadata)
+	}
+	m.lis = newTrackingListener(m.bufconn)
+	proto.RegisterProducerServer(m.srv, m)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		_ = m.srv.Serve(m.lis)
+	}()
+	if !config.ManualPersist && config.PersistInterval > 0 {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.persistLoop()
+		}()
+	}
+	return m
+}
+
+// Dial connects to the mock shipper.
+func (m *MockShipper) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return m.bufconn.DialContext(ctx)
+		}),
+	}, opts...)
+	return grpc.Dial("bufnet", opts...)
+}
+
+// Stop stops the mock shipper, closing all the connections.
+func (m *MockShipper) Stop() {
+	close(m.done)
+	m.srv.Stop()
+	m.wg.Wait()
+}
+
+// Faults returns the failures to inject into the calls of the shipper.
+func (m *MockShipper) Faults() *Faults {
+	return &m.faults
+}
+
+// UUID returns the current uuid of the shipper.
+func (m *MockShipper) UUID() string {
+	return m.tracker.UUID()
+}
+
+// Events returns the accepted events, in order.
+func (m *MockShipper) Events() []*messages.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []*messages.Event
+	for _, msg := range m.accepted {
+		if e, ok := msg.(*messages.Event); ok {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Metrics returns the accepted metric samples, in order.
+func (m *MockShipper) Metrics() []*messages.MetricEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var metrics []*messages.MetricEvent
+	for _, msg := range m.accepted {
+		if e, ok := msg.(*messages.MetricEvent); ok {
+			metrics = append(metrics, e)
+		}
+	}
+	return metrics
+}
+
+// Signals returns the accepted signals, in order.
+func (m *MockShipper) Signals() []*messages.EventUnion {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var signals []*messages.EventUnion
+	for _, msg := range m.accepted {
+		if u, ok := msg.(*messages.EventUnion); ok {
+			signals = append(signals, u)
+		}
+	}
+	return signals
+}
+
+// SetGlobalMetadata changes the global metadata of the shipper and returns
+// its new version.
+func (m *MockShipper) SetGlobalMetadata(metadata *messages.Struct) uint64 {
+	return m.global.Set(metadata)
+}
+
+// Persist persists the events up to the given index.
+func (m *MockShipper) Persist(index uint64) {
+	m.tracker.Persist(index)
+}
+
+// PersistAll persists all the accepted events.
+func (m *MockShipper) PersistAll() {
+	m.tracker.Persist(m.tracker.Accepted())
+}
+
+// Restart simulates a restart of the shipper: the uuid changes, the indexes
+// start again from zero, and the events that were not persisted are lost.
+func (m *MockShipper) Restart() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	persisted := m.tracker.Persisted().GetPersistedIndex()
+	m.accepted = m.accepted[:len(m.accepted)-int(m.tracker.Accepted()-persisted)]
+	m.tracker.Reset()
+}
+
+// PublishEvents implements proto.ProducerServer.
+func (m *MockShipper) PublishEvents(_ context.Context, req *messages.PublishRequest) (*messages.PublishReply, error) {
+	maxAccept, restart, err := m.faults.nextPublish()
+	if restart {
+		m.Restart()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the events are stored with the global metadata they reference
+	if err := m.global.Resolve(req); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	capacity := req.Len()
+	if m.config.QueueSize > 0 {
+		capacity = m.config.QueueSize - int(m.tracker.Accepted()-m.tracker.Persisted().GetPersistedIndex())
+	}
+	if maxAccept >= 0 && maxAccept < capacity {
+		capacity = maxAccept
+	}
+	b := m.tracker.AcceptBatch(req, capacity)
+	for i := 0; i < b.Accepted; i++ {
+		switch {
+		case len(req.GetMetrics()) > 0:
+			m.accepted = append(m.accepted, req.GetMetrics()[i])
+		case len(req.GetSignals()) > 0:
+			m.accepted = append(m.accepted, req.GetSignals()[i])
+		default:
+			m.accepted = append(m.accepted, req.GetEvents()[i])
+		}
+	}
+	m.mu.Unlock()
+
+	if m.config.ManualPersist || b.Accepted == 0 {
+		return b.Reply, nil
+	}
+	if delay := m.faults.delay(); delay > 0 {
+		// persisted later, unless the shipper restarts in the meantime
+		uuid := b.Reply.GetUuid()
+		time.AfterFunc(delay, func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.tracker.UUID() == uuid {
+				m.tracker.Persist(b.LastIndex)
+			}
+		})
+	} else if m.config.PersistInterval == 0 {
+		m.PersistAll()
+	}
+	return b.Reply, nil
+}
+
+// PersistedIndex implements proto.ProducerServer.
+func (m *MockShipper) PersistedIndex(req *messages.PersistedIndexRequest, srv proto.Producer_PersistedIndexServer) error {
+	return m.tracker.Serve(req, srv)
+}
+
+// Flush implements proto.ProducerServer. The events are persisted right
+// away, except with ManualPersist.
+func (m *MockShipper) Flush(ctx context.Context, req *messages.FlushRequest) (*messages.FlushReply, error) {
+	return m.tracker.Flush(ctx, req, func(index uint64) {
+		if !m.config.ManualPersist {
+			m.tracker.Persist(index)
+		}
+	})
+}
+
+// WhoAmI implements proto.ProducerServer.
+func (m *MockShipper) WhoAmI(context.Context, *messages.WhoAmIRequest) (*messages.WhoAmIReply, error) {
+	return m.tracker.WhoAmI(), nil
+}
+
+// GetCursor implements proto.ProducerServer.
+func (m *MockShipper) GetCursor(_ context.Context, req *messages.GetCursorRequest) (*messages.GetCursorReply, error) {
+	return m.tracker.GetCursor(req), nil
+}
+
+// GetGlobalMetadata implements proto.ProducerServer.
+func (m *MockShipper) GetGlobalMetadata(context.Context, *messages.GetGlobalMetadataRequest) (*messages.GetGlobalMetadataReply, error) {
+	return m.global.Reply(m.tracker.UUID()), nil
+}
+
+func (m *MockShipper) persistLoop() {
+	ticker := time.NewTicker(m.config.PersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			if m.faults.delay() == 0 {
+				m.PersistAll()
+			}
+		}
+	}
+}
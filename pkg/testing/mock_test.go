@@ -0,0 +1,221 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	gproto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/client"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func testEvents(n int) []*messages.Event {
+	events := make([]*messages.Event, n)
+	for i := range events {
+		events[i] = &messages.Event{}
+	}
+	return events
+}
+
+func dial(t *testing.T, m *MockShipper) proto.ProducerClient {
+	t.Helper()
+	conn, err := m.Dial()
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewProducerClient(conn)
+}
+
+func TestMockShipper(t *testing.T) {
+	m := StartMockShipper(MockConfig{ManualPersist: true, QueueSize: 4})
+	defer m.Stop()
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	reply, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+	require.True(t, gproto.Equal(&messages.PublishReply{Uuid: m.UUID(), AcceptedCount: 3, AcceptedIndex: 3}, reply))
+
+	// the queue is almost full
+	reply, err = producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), reply.GetAcceptedCount())
+	require.Len(t, m.Events(), 4)
+
+	stream, err := producer.PersistedIndex(ctx, &messages.PersistedIndexRequest{PollingInterval: durationpb.New(time.Millisecond)})
+	require.NoError(t, err)
+	persisted, err := stream.Recv()
+	require.NoError(t, err)
+	require.Zero(t, persisted.GetPersistedIndex())
+	m.Persist(3)
+	persisted, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), persisted.GetPersistedIndex())
+
+	// the unpersisted event is lost on restart, requests with the old uuid are rejected
+	uuid := m.UUID()
+	whoami, err := producer.WhoAmI(ctx, &messages.WhoAmIRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uuid, whoami.GetUuid())
+	m.Restart()
+	whoami, err = producer.WhoAmI(ctx, &messages.WhoAmIRequest{})
+	require.NoError(t, err)
+	require.Equal(t, m.UUID(), whoami.GetUuid())
+	require.Len(t, m.Events(), 3)
+	reply, err = producer.PublishEvents(ctx, &messages.PublishRequest{Uuid: uuid, Events: testEvents(1)})
+	require.NoError(t, err)
+	require.Zero(t, reply.GetAcceptedCount())
+	require.NotEqual(t, uuid, reply.GetUuid())
+}
+
+func TestMockShipperFlush(t *testing.T) {
+	m := StartMockShipper(MockConfig{PersistInterval: time.Hour})
+	defer m.Stop()
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	_, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(3)})
+	require.NoError(t, err)
+	reply, err := producer.Flush(ctx, &messages.FlushRequest{AcceptedIndex: 2})
+	require.NoError(t, err)
+	require.True(t, gproto.Equal(&messages.FlushReply{Uuid: m.UUID(), PersistedIndex: 2}, reply))
+	reply, err = producer.Flush(ctx, &messages.FlushRequest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), reply.GetPersistedIndex())
+
+	_, err = producer.Flush(ctx, &messages.FlushRequest{AcceptedIndex: 4})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMockShipperCursor(t *testing.T) {
+	m := StartMockShipper(MockConfig{ManualPersist: true})
+	defer m.Stop()
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	_, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: testEvents(3), Cursor: []byte("offset=42"), CursorKey: "file"})
+	require.NoError(t, err)
+	reply, err := producer.GetCursor(ctx, &messages.GetCursorRequest{Key: "file"})
+	require.NoError(t, err)
+	require.Empty(t, reply.GetCursor())
+
+	m.PersistAll()
+	reply, err = producer.GetCursor(ctx, &messages.GetCursorRequest{Key: "file"})
+	require.NoError(t, err)
+	require.True(t, gproto.Equal(&messages.GetCursorReply{Uuid: m.UUID(), Cursor: []byte("offset=42")}, reply))
+}
+
+func TestMockShipperGlobalMetadata(t *testing.T) {
+	global := &messages.Struct{Data: map[string]*messages.Value{"host": helpers.NewStringValue("host-1")}}
+	m := StartMockShipper(MockConfig{GlobalMetadata: global})
+	defer m.Stop()
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	reply, err := producer.GetGlobalMetadata(ctx, &messages.GetGlobalMetadataRequest{})
+	require.NoError(t, err)
+	require.True(t, gproto.Equal(&messages.GetGlobalMetadataReply{Uuid: m.UUID(), Version: 1, Metadata: global}, reply))
+
+	events := testEvents(1)
+	events[0].GlobalMetadataVersion = reply.GetVersion()
+	_, err = producer.PublishEvents(ctx, &messages.PublishRequest{Events: events})
+	require.NoError(t, err)
+	require.Equal(t, "host-1", m.Events()[0].GetMetadata().GetData()["host"].GetStringValue())
+
+	events[0].GlobalMetadataVersion = 2
+	_, err = producer.PublishEvents(ctx, &messages.PublishRequest{Events: events})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, uint64(2), m.SetGlobalMetadata(global))
+}
+
+func TestMockShipperWithClient(t *testing.T) {
+	m := StartMockShipper(MockConfig{PersistInterval: time.Millisecond})
+	defer m.Stop()
+
+	config := client.DefaultAsyncConfig()
+	config.PersistedIndexInterval = time.Millisecond
+	p := client.NewAsyncProducer(client.New(dial(t, m)), config)
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	f, err := p.Publish(ctx, testEvents(5))
+	require.NoError(t, err)
+	require.NoError(t, f.Wait(ctx))
+	require.Len(t, m.Events(), 5)
+}
+
+func TestMockShipperSignals(t *testing.T) {
+	m := StartMockShipper(MockConfig{ManualPersist: true, QueueSize: 3})
+	defer m.Stop()
+	c := client.New(dial(t, m), client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+	ctx := context.Background()
+
+	_, err := c.Publish(ctx, &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	metrics := []*messages.MetricEvent{
+		{Timestamp: timestamppb.Now(), Name: "cpu", Value: 0.5, Unit: "percent", Dimensions: map[string]string{"host.name": "a"}},
+		{Timestamp: timestamppb.Now(), Name: "memory", Value: 1024, Unit: "byte"},
+		{Timestamp: timestamppb.Now(), Name: "disk", Value: 10, Unit: "byte"},
+	}
+	// the queue is full after two samples
+	reply, err := c.PublishMetrics(ctx, metrics)
+	var partial *client.PartialError
+	require.ErrorAs(t, err, &partial)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+	require.Equal(t, uint64(3), reply.GetAcceptedIndex())
+	require.Len(t, m.Events(), 1)
+	require.Len(t, m.Metrics(), 2)
+	require.True(t, gproto.Equal(metrics[0], m.Metrics()[0]))
+
+	// the unpersisted samples are lost on restart
+	m.Persist(2)
+	m.Restart()
+	require.Len(t, m.Events(), 1)
+	require.Len(t, m.Metrics(), 1)
+
+	m.PersistAll()
+	signals := []*messages.EventUnion{messages.WrapEvent(&messages.Event{}), messages.WrapMetric(metrics[2])}
+	reply, err = c.PublishSignals(ctx, signals)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), reply.GetAcceptedCount())
+	require.Len(t, m.Signals(), 2)
+	require.Len(t, m.Events(), 1, "the signals are kept apart")
+}
+
+func TestMockShipperBatchID(t *testing.T) {
+	m := StartMockShipper(MockConfig{QueueSize: 2})
+	defer m.Stop()
+	producer := dial(t, m)
+	var requests []*messages.PublishRequest
+	c := client.New(recordingProducer{producer, &requests}, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 2}))
+
+	reply, err := c.Publish(context.Background(), &messages.PublishRequest{Events: testEvents(3), BatchId: "batch-1"})
+	require.NoError(t, err)
+	require.Equal(t, "batch-1", reply.GetBatchId())
+	require.Len(t, requests, 2)
+	require.Equal(t, "batch-1", requests[1].GetBatchId(), "the retries keep the batch id")
+}
+
+type recordingProducer struct {
+	proto.ProducerClient
+	requests *[]*messages.PublishRequest
+}
+
+func (p recordingProducer) PublishEvents(ctx context.Context, req *messages.PublishRequest, opts ...grpc.CallOption) (*messages.PublishReply, error) {
+	*p.requests = append(*p.requests, req)
+	return p.ProducerClient.PublishEvents(ctx, req, opts...)
+}
@@ -0,0 +1,204 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package testing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+const publishEventsMethod = "/elastic.agent.shipper.v1.Producer/PublishEvents"
+
+// Record is a PublishEvents call captured by a Recorder.
+type Record struct {
+	Time    time.Time
+	Request *messages.PublishRequest
+	// Reply is nil if the call failed.
+	Reply *messages.PublishReply
+	// Code and Message describe the status of a failed call.
+	Code    codes.Code
+	Message string
+}
+
+// record is how a Record is written, one JSON object per line.
+type record struct {
+	Time    time.Time       `json:"time"`
+	Request json.RawMessage `json:"request"`
+	Reply   json.RawMessage `json:"reply,omitempty"`
+	Code    codes.Code      `json:"code,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// Recorder writes all the PublishEvents calls going through its interceptors
+// as NDJSON, so the traffic can be replayed later with Replay.
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	now func() time.Time
+}
+
+// NewRecorder returns a Recorder writing to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// UnaryServerInterceptor records the calls received by a server.
+func (r *Recorder) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if info.FullMethod == publishEventsMethod {
+			r.record(req, resp, err)
+		}
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor records the calls made by a client.
+func (r *Recorder) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if method == publishEventsMethod {
+			r.record(req, reply, err)
+		}
+		return err
+	}
+}
+
+// Write records a single call.
+func (r *Recorder) Write(rec Record) error {
+	out := record{
+		Time:    rec.Time,
+		Code:    rec.Code,
+		Message: rec.Message,
+	}
+	var err error
+	out.Request, err = protojson.Marshal(rec.Request)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the request: %w", err)
+	}
+	if rec.Reply != nil {
+		out.Reply, err = protojson.Marshal(rec.Reply)
+		if err != nil {
+			return fmt.Errorf("failed to serialize the reply: %w", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(out); err != nil {
+		return fmt.Errorf("failed to write the record: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) record(req, resp interface{}, err error) {
+	request, ok := req.(*messages.PublishRequest)
+	if !ok {
+		return
+	}
+	rec := Record{Time: r.now(), Request: request}
+	if err != nil {
+		s := status.Convert(err)
+		rec.Code, rec.Message = s.Code(), s.Message()
+	} else {
+		rec.Reply, _ = resp.(*messages.PublishReply)
+	}
+	// recording is best effort, it must not break the traffic
+	_ = r.Write(rec)
+}
+
+// ReadRecords reads the records written by a Recorder.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var in record
+		if err := json.Unmarshal(scanner.Bytes(), &in); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		rec := Record{
+			Time:    in.Time,
+			Request: &messages.PublishRequest{},
+			Code:    in.Code,
+			Message: in.Message,
+		}
+		if err := protojson.Unmarshal(in.Request, rec.Request); err != nil {
+			return nil, fmt.Errorf("invalid request on line %d: %w", line, err)
+		}
+		if len(in.Reply) > 0 {
+			rec.Reply = &messages.PublishReply{}
+			if err := protojson.Unmarshal(in.Reply, rec.Reply); err != nil {
+				return nil, fmt.Errorf("invalid reply on line %d: %w", line, err)
+			}
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the records: %w", err)
+	}
+	return records, nil
+}
+
+// ReplayResult is the outcome of replaying a Record.
+type ReplayResult struct {
+	Record Record
+	Reply  *messages.PublishReply
+	Err    error
+}
+
+// Replay sends the recorded requests to a producer, in order.
+//
+// The uuids of the recorded shipper are mapped to the uuids of the shipper the
+// requests are replayed against, so the replayed traffic follows the same
+// restarts as the recorded one. Replay stops on context errors only, failed
+// calls are part of the results.
+func Replay(ctx context.Context, producer proto.ProducerClient, records []Record) ([]ReplayResult, error) {
+	uuids := map[string]string{}
+	var current string
+	results := make([]ReplayResult, 0, len(records))
+	for _, rec := range records {
+		req := rec.Request
+		if recorded := req.GetUuid(); recorded != "" {
+			uuid, ok := uuids[recorded]
+			if !ok {
+				// a shipper uuid we haven't seen a reply from, use the last one
+				uuid = current
+			}
+			req = gproto.Clone(req).(*messages.PublishRequest)
+			req.Uuid = uuid
+		}
+
+		reply, err := producer.PublishEvents(ctx, req)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		if err == nil {
+			current = reply.GetUuid()
+			if recorded := rec.Reply.GetUuid(); recorded != "" {
+				uuids[recorded] = current
+			}
+		}
+		results = append(results, ReplayResult{Record: rec, Reply: reply, Err: err})
+	}
+	return results, nil
+}
@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package testing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	gproto "google.golang.org/protobuf/proto"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := NewRecorder(&buf)
+	m := StartMockShipper(MockConfig{}, grpc.UnaryInterceptor(recorder.UnaryServerInterceptor()))
+	producer := dial(t, m)
+	ctx := context.Background()
+
+	events := []*messages.Event{{Metadata: &messages.Struct{Data: map[string]*messages.Value{
+		"_id": {Kind: &messages.Value_StringValue{StringValue: "a"}},
+	}}}}
+	reply, err := producer.PublishEvents(ctx, &messages.PublishRequest{Events: events})
+	require.NoError(t, err)
+	m.Faults().ResourceExhausted(1)
+	_, err = producer.PublishEvents(ctx, &messages.PublishRequest{Uuid: reply.GetUuid(), Events: testEvents(2)})
+	require.Error(t, err)
+	_, err = producer.PublishEvents(ctx, &messages.PublishRequest{Uuid: reply.GetUuid(), Events: testEvents(2)})
+	require.NoError(t, err)
+	m.Stop()
+
+	records, err := ReadRecords(&buf)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	require.True(t, gproto.Equal(&messages.PublishRequest{Events: events}, records[0].Request))
+	require.True(t, gproto.Equal(reply, records[0].Reply))
+	require.Nil(t, records[1].Reply)
+	require.Equal(t, codes.ResourceExhausted, records[1].Code)
+	require.False(t, records[2].Time.IsZero())
+
+	// the requests follow the uuid of the new shipper
+	replayed := StartMockShipper(MockConfig{})
+	defer replayed.Stop()
+	results, err := Replay(ctx, dial(t, replayed), records)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	for _, r := range results {
+		require.NoError(t, r.Err)
+	}
+	require.Equal(t, replayed.UUID(), results[2].Reply.GetUuid())
+	require.Equal(t, uint64(5), results[2].Reply.GetAcceptedIndex())
+	require.Len(t, replayed.Events(), 5)
+}
+
+func TestRecorderClientInterceptor(t *testing.T) {
+	m := StartMockShipper(MockConfig{})
+	defer m.Stop()
+	var buf bytes.Buffer
+	conn, err := m.Dial(grpc.WithUnaryInterceptor(NewRecorder(&buf).UnaryClientInterceptor()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = proto.NewProducerClient(conn).PublishEvents(context.Background(), &messages.PublishRequest{Events: testEvents(1)})
+	require.NoError(t, err)
+	records, err := ReadRecords(&buf)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	require.Equal(t, uint32(1), records[0].Reply.GetAcceptedCount())
+}
@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+// Package zaplog converts the log entries of zap, as written by logp and
+// ecszap, to shipper events, so that Go components can ship their own logs
+// through the shipper without writing them to files first.
+package zaplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+// NewEvent returns an event on the data stream holding the log entry and its
+// fields, with the ECS fields written by ecszap:
+//   - message, the message of the entry,
+//   - log.level, the level of the entry, e.g. "info",
+//   - log.logger, the name of the logger when it has one,
+//   - log.origin.file.name, log.origin.file.line and log.origin.function,
+//     the caller when it's known,
+//   - log.origin.stack_trace, the stack trace when there is one,
+//   - error.message, the message of an error field.
+//
+// The fields are encoded with a zapcore.MapObjectEncoder. Their values keep
+// their kind, the durations are nanoseconds like in ecszap, and the reflected
+// structs are converted through JSON to honor their tags.
+func NewEvent(entry zapcore.Entry, fields []zapcore.Field, dataStream *messages.DataStream) (*messages.Event, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		if f.Type == zapcore.ErrorType {
+			// like ecszap, the error is an object holding the message
+			if err, ok := f.Interface.(error); ok {
+				enc.Fields[f.Key] = map[string]interface{}{"message": err.Error()}
+				continue
+			}
+		}
+		f.AddTo(enc)
+	}
+
+	data, err := toStruct(enc.Fields)
+	if err != nil {
+		return nil, err
+	}
+	data.Data["message"] = helpers.NewStringValue(entry.Message)
+	logData := map[string]*messages.Value{
+		"level": helpers.NewStringValue(entry.Level.String()),
+	}
+	if entry.LoggerName != "" {
+		logData["logger"] = helpers.NewStringValue(entry.LoggerName)
+	}
+	origin := map[string]*messages.Value{}
+	if entry.Caller.Defined {
+		origin["file"] = helpers.NewStructValue(&messages.Struct{Data: map[string]*messages.Value{
+			"name": helpers.NewStringValue(entry.Caller.TrimmedPath()),
+			"line": helpers.NewInt64Value(int64(entry.Caller.Line)),
+		}})
+		if entry.Caller.Function != "" {
+			origin["function"] = helpers.NewStringValue(entry.Caller.Function)
+		}
+	}
+	if entry.Stack != "" {
+		origin["stack_trace"] = helpers.NewStringValue(entry.Stack)
+	}
+	if len(origin) > 0 {
+		logData["origin"] = helpers.NewStructValue(&messages.Struct{Data: origin})
+	}
+	data.Data["log"] = helpers.NewStructValue(&messages.Struct{Data: logData})
+
+	return &messages.Event{
+		Timestamp:  timestamppb.New(entry.Time),
+		DataStream: dataStream,
+		Fields:     data,
+	}, nil
+}
+
+func toStruct(m map[string]interface{}) (*messages.Struct, error) {
+	s := &messages.Struct{Data: make(map[string]*messages.Value, len(m)+2)}
+	for k, v := range m {
+		value, err := toValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s field: %w", k, err)
+		}
+		s.Data[k] = value
+	}
+	return s, nil
+}
+
+// toValue converts a value written by a zapcore.MapObjectEncoder.
+func toValue(v interface{}) (*messages.Value, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s, err := toStruct(v)
+		if err != nil {
+			return nil, err
+		}
+		return helpers.NewStructValue(s), nil
+	case []interface{}:
+		l := &messages.ListValue{Values: make([]*messages.Value, len(v))}
+		for i, item := range v {
+			value, err := toValue(item)
+			if err != nil {
+				return nil, err
+			}
+			l.Values[i] = value
+		}
+		return helpers.NewListValue(l), nil
+	case []byte:
+		return helpers.NewBytesValue(v), nil
+	case time.Duration:
+		return helpers.NewInt64Value(int64(v)), nil
+	case int8:
+		return helpers.NewInt32Value(int32(v)), nil
+	case int16:
+		return helpers.NewInt32Value(int32(v)), nil
+	case uint8:
+		return helpers.NewUint32Value(uint32(v)), nil
+	case uint16:
+		return helpers.NewUint32Value(uint32(v)), nil
+	case uintptr:
+		return helpers.NewUint64Value(uint64(v)), nil
+	case complex64, complex128:
+		return helpers.NewStringValue(fmt.Sprint(v)), nil
+	case time.Time:
+		return helpers.NewTimestampValue(v), nil
+	}
+
+	if kind := reflect.ValueOf(v).Kind(); kind != reflect.Struct && kind != reflect.Ptr {
+		return helpers.NewValue(v)
+	}
+	// a reflected struct, encoded with its JSON tags like the JSON encoder
+	// of zap does
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return helpers.NewValue(decoded)
+}
@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package zaplog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/elastic/elastic-agent-shipper-client/pkg/helpers"
+	"github.com/elastic/elastic-agent-shipper-client/pkg/proto/messages"
+)
+
+var testDataStream = &messages.DataStream{Type: "logs", Dataset: "elastic_agent.shipper_client", Namespace: "default"}
+
+func TestNewEvent(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)).Named("publisher")
+	logger.Error("publish failed",
+		zap.Error(errors.New("connection refused")),
+		zap.Int("attempt", 3),
+		zap.Uint8("code", 7),
+		zap.Duration("backoff", 2*time.Second),
+		zap.Binary("cursor", []byte{1, 2}),
+		zap.Any("target", struct {
+			Host string `json:"host"`
+		}{Host: "localhost"}),
+		zap.Namespace("batch"),
+		zap.Strings("ids", []string{"a", "b"}),
+	)
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+
+	e, err := NewEvent(entry.Entry, entry.Context, testDataStream)
+	require.NoError(t, err)
+	require.NoError(t, e.Validate())
+	require.True(t, entry.Time.Equal(e.GetTimestamp().AsTime()))
+	require.Same(t, testDataStream, e.GetDataStream())
+
+	fields := helpers.AsMap(e.GetFields())
+	log := fields["log"].(map[string]interface{})
+	origin := log["origin"].(map[string]interface{})
+	delete(fields, "log")
+	require.Equal(t, map[string]interface{}{
+		"message": "publish failed",
+		"error":   map[string]interface{}{"message": "connection refused"},
+		"attempt": int64(3),
+		"code":    uint32(7),
+		"backoff": int64(2 * time.Second),
+		"cursor":  []byte{1, 2},
+		"target":  map[string]interface{}{"host": "localhost"},
+		"batch":   map[string]interface{}{"ids": []interface{}{"a", "b"}},
+	}, fields)
+	require.Equal(t, "error", log["level"])
+	require.Equal(t, "publisher", log["logger"])
+	require.Equal(t, int64(entry.Caller.Line), origin["file"].(map[string]interface{})["line"])
+	require.Contains(t, origin["file"].(map[string]interface{})["name"], "zaplog_test.go")
+	require.Contains(t, origin["function"], "TestNewEvent")
+	require.NotEmpty(t, origin["stack_trace"])
+}
+
+func TestNewEventMinimal(t *testing.T) {
+	entry := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "started"}
+	e, err := NewEvent(entry, nil, testDataStream)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"message": "started",
+		"log":     map[string]interface{}{"level": "info"},
+	}, helpers.AsMap(e.GetFields()))
+
+	_, err = NewEvent(entry, []zapcore.Field{zap.Any("invalid", func() {})}, testDataStream)
+	require.Error(t, err)
+}